@@ -25,6 +25,9 @@ var (
 	kubeConfigPath     string
 	kubeContext        string
 	ippoolRef          string
+	leaderElection     bool
+	namespace          string
+	serverIP           string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -53,6 +56,10 @@ var rootCmd = &cobra.Command{
 				Namespace: ipPoolNamespace,
 				Name:      ipPoolName,
 			},
+			LeaderElection: leaderElection,
+			Name:           name,
+			Namespace:      namespace,
+			ServerIP:       serverIP,
 		}
 
 		if err := run(options); err != nil {
@@ -76,6 +83,9 @@ func init() {
 	rootCmd.Flags().BoolVar(&enableCacheDumpAPI, "enable-cache-dump-api", false, "Enable cache dump APIs")
 	rootCmd.Flags().StringVar(&ippoolRef, "ippool-ref", os.Getenv("IPPOOL_REF"), "The IPPool object the agent should sync with")
 	rootCmd.Flags().StringVar(&nic, "nic", agent.DefaultNetworkInterface, "The network interface the embedded DHCP server listens on")
+	rootCmd.Flags().BoolVar(&leaderElection, "leader-election", false, "Serve DHCP only while holding the lease of the IPPool")
+	rootCmd.Flags().StringVar(&namespace, "namespace", os.Getenv("POD_NAMESPACE"), "The namespace of the lease of the IPPool")
+	rootCmd.Flags().StringVar(&serverIP, "server-ip", "", "The IP address, in CIDR notation, the leader takes on the network interface")
 }
 
 // execute adds all child commands to the root command and sets flags appropriately.
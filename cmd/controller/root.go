@@ -7,6 +7,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
 	"github.com/harvester/vm-dhcp-controller/pkg/util"
@@ -23,6 +25,9 @@ var (
 	agentNamespace          string
 	agentImage              string
 	agentServiceAccountName string
+	agentPodTemplate        string
+	agentReplicas           int32
+	agentRolloutStrategy    string
 	noDHCP                  bool
 )
 
@@ -59,11 +64,36 @@ var rootCmd = &cobra.Command{
 			os.Exit(0)
 		}
 
+		if agentReplicas < 1 {
+			fmt.Fprintf(os.Stderr, "Error agent replicas %d is less than 1\n", agentReplicas)
+			os.Exit(1)
+		}
+
+		switch appsv1.DeploymentStrategyType(agentRolloutStrategy) {
+		case "", appsv1.RecreateDeploymentStrategyType, appsv1.RollingUpdateDeploymentStrategyType:
+		default:
+			fmt.Fprintf(os.Stderr, "Error agent rollout strategy %s is not one of %s and %s\n", agentRolloutStrategy, appsv1.RecreateDeploymentStrategyType, appsv1.RollingUpdateDeploymentStrategyType)
+			os.Exit(1)
+		}
+
+		var podTemplate *corev1.PodTemplateSpec
+		if agentPodTemplate != "" {
+			var err error
+			podTemplate, err = config.LoadPodTemplate(agentPodTemplate)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+				os.Exit(1)
+			}
+		}
+
 		options := &config.ControllerOptions{
 			NoAgent:                 noAgent,
 			AgentNamespace:          agentNamespace,
 			AgentImage:              image,
 			AgentServiceAccountName: agentServiceAccountName,
+			AgentPodTemplate:        podTemplate,
+			AgentReplicas:           agentReplicas,
+			AgentRolloutStrategy:    appsv1.DeploymentStrategyType(agentRolloutStrategy),
 			NoDHCP:                  noDHCP,
 		}
 
@@ -89,6 +119,9 @@ func init() {
 	rootCmd.Flags().StringVar(&agentNamespace, "namespace", os.Getenv("AGENT_NAMESPACE"), "The namespace for the spawned agents")
 	rootCmd.Flags().StringVar(&agentImage, "image", os.Getenv("AGENT_IMAGE"), "The container image for the spawned agents")
 	rootCmd.Flags().StringVar(&agentServiceAccountName, "service-account-name", os.Getenv("AGENT_SERVICE_ACCOUNT_NAME"), "The service account for the spawned agents")
+	rootCmd.Flags().Int32Var(&agentReplicas, "agent-replicas", 1, "The number of agent replicas per IPPool, of which only the leader serves DHCP")
+	rootCmd.Flags().StringVar(&agentRolloutStrategy, "agent-rollout-strategy", "", "The rollout strategy of the agents, either Recreate or RollingUpdate, defaulting to Recreate for a single replica and RollingUpdate otherwise. Agents rolled out with RollingUpdate elect a leader even with a single replica")
+	rootCmd.Flags().StringVar(&agentPodTemplate, "agent-pod-template", os.Getenv("AGENT_POD_TEMPLATE"), "The pod template file the spawned agents are merged with")
 }
 
 // execute adds all child commands to the root command and sets flags appropriately.
@@ -18,6 +18,7 @@ import (
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
 	"github.com/harvester/vm-dhcp-controller/pkg/webhook/ippool"
+	"github.com/harvester/vm-dhcp-controller/pkg/webhook/vm"
 	"github.com/harvester/vm-dhcp-controller/pkg/webhook/vmnetcfg"
 )
 
@@ -53,7 +54,9 @@ func newCaches(ctx context.Context, cfg *rest.Config, threadiness int) (*caches,
 	}
 
 	// Indexer must be added before starting the informer, otherwise panic `cannot add indexers to running index` happens
+	c.ippoolCache.AddIndexer(indexer.IPPoolByNetworkIndex, indexer.IPPoolByNetwork)
 	c.vmnetcfgCache.AddIndexer(indexer.VmNetCfgByNetworkIndex, indexer.VmNetCfgByNetwork)
+	c.vmnetcfgCache.AddIndexer(indexer.VmNetCfgByMACAddressIndex, indexer.VmNetCfgByMACAddress)
 
 	if err := start.All(ctx, threadiness, starters...); err != nil {
 		return nil, err
@@ -73,8 +76,9 @@ func run(ctx context.Context, cfg *rest.Config, options *config.Options) error {
 	webhookServer := server.NewWebhookServer(ctx, cfg, name, options)
 
 	if err := webhookServer.RegisterValidators(
-		ippool.NewValidator(serviceCIDR, c.nadCache, c.vmnetcfgCache),
-		vmnetcfg.NewValidator(c.ippoolCache),
+		ippool.NewValidator(serviceCIDR, c.nadCache, c.ippoolCache, c.vmnetcfgCache),
+		vmnetcfg.NewValidator(c.ippoolCache, c.vmnetcfgCache),
+		vm.NewValidator(c.ippoolCache),
 	); err != nil {
 		return err
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/harvester/vm-dhcp-controller/pkg/agent/ippool"
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
 	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
+	"github.com/harvester/vm-dhcp-controller/pkg/ra"
 )
 
 const DefaultNetworkInterface = "eth1"
@@ -19,30 +20,48 @@ type Agent struct {
 	nic     string
 	poolRef types.NamespacedName
 
+	leaderElection bool
+	name           string
+	namespace      string
+	serverIP       string
+
 	ippoolEventHandler *ippool.EventHandler
 	DHCPAllocator      *dhcp.DHCPAllocator
+	advertiser         *ra.Advertiser
 	poolCache          map[string]string
 }
 
 func NewAgent(options *config.AgentOptions) *Agent {
 	dhcpAllocator := dhcp.NewDHCPAllocator()
+	advertiser := ra.NewAdvertiser(options.Nic)
 	poolCache := make(map[string]string, 10)
 
+	ippoolEventHandler := ippool.NewEventHandler(
+		options.KubeConfigPath,
+		options.KubeContext,
+		nil,
+		options.IPPoolRef,
+		dhcpAllocator,
+		advertiser,
+		poolCache,
+	)
+	dhcpAllocator.OnDecline(ippoolEventHandler.ReportDecline)
+	dhcpAllocator.OnActivity(ippoolEventHandler.ReportActivity)
+
 	return &Agent{
 		dryRun:  options.DryRun,
 		nic:     options.Nic,
 		poolRef: options.IPPoolRef,
 
-		DHCPAllocator: dhcpAllocator,
-		ippoolEventHandler: ippool.NewEventHandler(
-			options.KubeConfigPath,
-			options.KubeContext,
-			nil,
-			options.IPPoolRef,
-			dhcpAllocator,
-			poolCache,
-		),
-		poolCache: poolCache,
+		leaderElection: options.LeaderElection,
+		name:           options.Name,
+		namespace:      options.Namespace,
+		serverIP:       options.ServerIP,
+
+		DHCPAllocator:      dhcpAllocator,
+		advertiser:         advertiser,
+		ippoolEventHandler: ippoolEventHandler,
+		poolCache:          poolCache,
 	}
 }
 
@@ -51,17 +70,22 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	eg, egctx := errgroup.WithContext(ctx)
 
-	eg.Go(func() error {
-		if a.dryRun {
-			return a.DHCPAllocator.DryRun(egctx, a.nic)
-		}
-		return a.DHCPAllocator.Run(egctx, a.nic)
-	})
+	// Standbys keep the lease store up to date all the same, so that they're
+	// ready to serve DHCP as soon as they become the leader
+	if a.leaderElection {
+		eg.Go(func() error {
+			return a.lead(egctx, eg)
+		})
+	} else {
+		a.serve(egctx, eg)
+	}
 
 	eg.Go(func() error {
 		if err := a.ippoolEventHandler.Init(); err != nil {
 			return err
 		}
+		go a.ippoolEventHandler.VmNetCfgListener(egctx)
+		go a.ippoolEventHandler.ActivityReporter(egctx)
 		a.ippoolEventHandler.EventListener(egctx)
 		return nil
 	})
@@ -79,3 +103,32 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	return nil
 }
+
+// serve starts the DHCP and Router Advertisement services in eg.
+func (a *Agent) serve(ctx context.Context, eg *errgroup.Group) {
+	eg.Go(func() error {
+		if a.dryRun {
+			return a.DHCPAllocator.DryRun(ctx, a.nic)
+		}
+		if err := a.DHCPAllocator.Run(ctx, a.nic); err != nil {
+			return err
+		}
+		// DHCPv6 only matters to dual-stack IPPools, so failing to serve it
+		// must not take down DHCPv4
+		if err := a.DHCPAllocator.Run6(ctx, a.nic); err != nil {
+			logrus.Warnf("cannot start DHCPv6 service on nic %s: %v", a.nic, err)
+		}
+		return nil
+	})
+
+	if !a.dryRun {
+		eg.Go(func() error {
+			// Likewise, Router Advertisements are only sent for IPPools that
+			// ask for them
+			if err := a.advertiser.Run(ctx); err != nil {
+				logrus.Warnf("cannot start router advertisement service on nic %s: %v", a.nic, err)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package ippool
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
+)
+
+// activityReportInterval is how often the lease activities are published to
+// the IPPools at most, so that a DHCP storm can't overload the apiserver.
+const activityReportInterval = 10 * time.Second
+
+// ReportActivity queues the activity of the lease of the DHCP client with the
+// hardware address for publishing. Only the latest activity of each client is
+// kept until the next publishing.
+func (e *EventHandler) ReportActivity(hwAddr string, lease dhcp.DHCPLease) {
+	e.activityMutex.Lock()
+	defer e.activityMutex.Unlock()
+
+	e.activities[hwAddr] = lease
+}
+
+// ActivityReporter publishes the queued lease activities to the status of the
+// IPPools they belong to, once every activityReportInterval.
+func (e *EventHandler) ActivityReporter(ctx context.Context) {
+	logrus.Info("(eventhandler.ActivityReporter) starting lease activity reporter")
+
+	ticker := time.NewTicker(activityReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.Info("(eventhandler.ActivityReporter) lease activity reporter terminated")
+			return
+		case <-ticker.C:
+			e.publishActivities()
+		}
+	}
+}
+
+func (e *EventHandler) publishActivities() {
+	e.activityMutex.Lock()
+	activities := e.activities
+	e.activities = make(map[string]dhcp.DHCPLease)
+	e.activityMutex.Unlock()
+
+	if len(activities) == 0 || e.k8sClientset == nil {
+		return
+	}
+
+	ipPool, err := e.ownIPPool()
+	if err != nil {
+		logrus.Errorf("(eventhandler.publishActivities) failed to get ippool %s: %v", e.poolRef.String(), err)
+		e.requeueActivities(activities)
+		return
+	}
+
+	grouped := groupActivities(append(e.servedIPPools(), ipPool), activities)
+	for poolRef, poolActivities := range grouped {
+		if err := e.updateLeaseActivities(poolRef, poolActivities); err != nil {
+			logrus.Errorf("(eventhandler.publishActivities) failed to publish lease activities to ippool %s: %v", poolRef.String(), err)
+			failed := make(map[string]dhcp.DHCPLease, len(poolActivities))
+			for hwAddr := range poolActivities {
+				failed[hwAddr] = activities[hwAddr]
+			}
+			e.requeueActivities(failed)
+		}
+	}
+}
+
+// groupActivities groups the activities by the IPPool among ipPools having
+// the IP address of the lease allocated to the client.
+func groupActivities(ipPools []*networkv1.IPPool, activities map[string]dhcp.DHCPLease) map[types.NamespacedName]map[string]networkv1.LeaseActivity {
+	grouped := make(map[types.NamespacedName]map[string]networkv1.LeaseActivity)
+	for _, ipPool := range ipPools {
+		poolRef := types.NamespacedName{Namespace: ipPool.Namespace, Name: ipPool.Name}
+		if ipPool.Status.IPv4 == nil {
+			continue
+		}
+		for hwAddr, lease := range activities {
+			if ipPool.Status.IPv4.Allocated[lease.ClientIP.String()] != hwAddr {
+				continue
+			}
+			if grouped[poolRef] == nil {
+				grouped[poolRef] = make(map[string]networkv1.LeaseActivity)
+			}
+			grouped[poolRef][hwAddr] = toLeaseActivity(lease)
+		}
+	}
+	return grouped
+}
+
+// requeueActivities queues the activities again unless newer ones have been
+// queued in the meantime.
+func (e *EventHandler) requeueActivities(activities map[string]dhcp.DHCPLease) {
+	e.activityMutex.Lock()
+	defer e.activityMutex.Unlock()
+
+	for hwAddr, lease := range activities {
+		if _, exists := e.activities[hwAddr]; !exists {
+			e.activities[hwAddr] = lease
+		}
+	}
+}
+
+func (e *EventHandler) updateLeaseActivities(poolRef types.NamespacedName, activities map[string]networkv1.LeaseActivity) error {
+	ipPools := e.k8sClientset.NetworkV1alpha1().IPPools(poolRef.Namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ipPool, err := ipPools.Get(context.TODO(), poolRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		ipPoolCpy := ipPool.DeepCopy()
+		if ipPoolCpy.Status.IPv4 == nil {
+			ipPoolCpy.Status.IPv4 = new(networkv1.IPv4Status)
+		}
+		if ipPoolCpy.Status.IPv4.Leases == nil {
+			ipPoolCpy.Status.IPv4.Leases = make(map[string]networkv1.LeaseActivity)
+		}
+		for hwAddr, activity := range activities {
+			ipPoolCpy.Status.IPv4.Leases[hwAddr] = activity
+		}
+
+		// Nothing changed, e.g., clients keep asking for the same leases
+		if reflect.DeepEqual(ipPoolCpy.Status, ipPool.Status) {
+			return nil
+		}
+
+		_, err = ipPools.UpdateStatus(context.TODO(), ipPoolCpy, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func toLeaseActivity(lease dhcp.DHCPLease) networkv1.LeaseActivity {
+	activity := networkv1.LeaseActivity{
+		State:       string(lease.State),
+		Hostname:    lease.ClientHostname,
+		VendorClass: lease.VendorClass,
+	}
+	// Timestamps are made the same as they come back from the apiserver, for
+	// them to be compared
+	if !lease.FirstSeenAt.IsZero() {
+		firstSeen := metav1.NewTime(lease.FirstSeenAt).Rfc3339Copy()
+		activity.FirstSeen = &firstSeen
+	}
+	if !lease.LastAckAt.IsZero() {
+		lastAck := metav1.NewTime(lease.LastAckAt).Rfc3339Copy()
+		activity.LastAck = &lastAck
+	}
+	return activity
+}
@@ -12,6 +12,7 @@ import (
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
+	"github.com/harvester/vm-dhcp-controller/pkg/ra"
 )
 
 type Controller struct {
@@ -22,7 +23,18 @@ type Controller struct {
 
 	poolRef       types.NamespacedName
 	dhcpAllocator *dhcp.DHCPAllocator
+	advertiser    *ra.Advertiser
 	poolCache     map[string]string
+	poolCache6    map[string]string
+	// serverIP is the server IP address of the IPPool of the agent, which is
+	// also the server identifier of the leases of the relayed IPPools
+	serverIP string
+	// relayedPoolCaches holds the pool caches of the IPPools served through
+	// DHCP relay agents by their keys
+	relayedPoolCaches map[string]map[string]string
+	// ipv4Configs holds the IPv4 configurations the leases of the IPPools
+	// were built with by their keys
+	ipv4Configs map[string]networkv1.IPv4Config
 }
 
 func NewController(
@@ -31,6 +43,7 @@ func NewController(
 	informer cache.Controller,
 	poolRef types.NamespacedName,
 	dhcpAllocator *dhcp.DHCPAllocator,
+	advertiser *ra.Advertiser,
 	poolCache map[string]string,
 ) *Controller {
 	return &Controller{
@@ -40,7 +53,12 @@ func NewController(
 		queue:         queue,
 		poolRef:       poolRef,
 		dhcpAllocator: dhcpAllocator,
+		advertiser:    advertiser,
 		poolCache:     poolCache,
+		poolCache6:    make(map[string]string),
+
+		relayedPoolCaches: make(map[string]map[string]string),
+		ipv4Configs:       make(map[string]networkv1.IPv4Config),
 	}
 }
 
@@ -70,16 +88,18 @@ func (c *Controller) sync(event Event) (err error) {
 		return
 	}
 
-	if event.poolName != c.poolRef.Name {
-		logrus.Debugf("(controller.sync) IPPool %s is not our target", event.key)
-		return
+	if event.key != c.poolRef.String() {
+		// A deleted IPPool comes as nil
+		ipPool, _ := obj.(*networkv1.IPPool)
+		return c.UpdateRelayed(event.key, ipPool)
 	}
 
 	switch event.action {
-	case UPDATE:
+	case ADD, UPDATE:
 		ipPool, ok := obj.(*networkv1.IPPool)
 		if !ok {
 			logrus.Error("(controller.sync) failed to assert obj during UPDATE")
+			return
 		}
 		logrus.Infof("(controller.sync) UPDATE %s/%s", ipPool.Namespace, ipPool.Name)
 		if err := c.Update(ipPool); err != nil {
@@ -90,6 +110,27 @@ func (c *Controller) sync(event Event) (err error) {
 	return
 }
 
+// requeueRelayed queues the IPPools served through DHCP relay agents for
+// another sync.
+func (c *Controller) requeueRelayed() {
+	for _, obj := range c.indexer.List() {
+		ipPool, ok := obj.(*networkv1.IPPool)
+		if !ok || ipPool.Spec.ServedBy != c.poolRef.String() {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(ipPool)
+		if err != nil {
+			continue
+		}
+		c.queue.Add(Event{
+			key:             key,
+			action:          UPDATE,
+			poolName:        ipPool.Name,
+			poolNetworkName: ipPool.Spec.NetworkName,
+		})
+	}
+}
+
 func (c *Controller) handleErr(err error, key interface{}) {
 	if err == nil {
 		c.queue.Forget(key)
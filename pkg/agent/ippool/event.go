@@ -2,19 +2,26 @@ package ippool
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"sync"
 
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
 	clientset "github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
+	"github.com/harvester/vm-dhcp-controller/pkg/ra"
 	"github.com/harvester/vm-dhcp-controller/pkg/util"
 )
 
@@ -32,7 +39,17 @@ type EventHandler struct {
 
 	poolRef       types.NamespacedName
 	dhcpAllocator *dhcp.DHCPAllocator
+	advertiser    *ra.Advertiser
 	poolCache     map[string]string
+
+	// activities holds the lease activities to be published by hardware
+	// address
+	activities    map[string]dhcp.DHCPLease
+	activityMutex sync.Mutex
+
+	// ipPoolIndexer is the IPPool cache of the event listener, once started
+	ipPoolIndexer cache.Indexer
+	indexerMutex  sync.RWMutex
 }
 
 type Event struct {
@@ -48,6 +65,7 @@ func NewEventHandler(
 	kubeRestConfig *rest.Config,
 	poolRef types.NamespacedName,
 	dhcpAllocator *dhcp.DHCPAllocator,
+	advertiser *ra.Advertiser,
 	poolCache map[string]string,
 ) *EventHandler {
 	return &EventHandler{
@@ -56,7 +74,9 @@ func NewEventHandler(
 		kubeRestConfig: kubeRestConfig,
 		poolRef:        poolRef,
 		dhcpAllocator:  dhcpAllocator,
+		advertiser:     advertiser,
 		poolCache:      poolCache,
+		activities:     make(map[string]dhcp.DHCPLease),
 	}
 }
 
@@ -74,6 +94,11 @@ func (e *EventHandler) Init() (err error) {
 	return
 }
 
+// RestConfig returns the config the event handler talks to the cluster with.
+func (e *EventHandler) RestConfig() (*rest.Config, error) {
+	return e.getKubeConfig()
+}
+
 func (e *EventHandler) getKubeConfig() (config *rest.Config, err error) {
 	if !util.FileExists(e.kubeConfig) {
 		return rest.InClusterConfig()
@@ -93,12 +118,24 @@ func (e *EventHandler) getKubeConfig() (config *rest.Config, err error) {
 func (e *EventHandler) EventListener(ctx context.Context) {
 	logrus.Info("(eventhandler.EventListener) starting IPPool event listener")
 
-	// TODO: could be more specific on what namespaces we want to watch and what fields we need
-	watcher := cache.NewListWatchFromClient(e.k8sClientset.NetworkV1alpha1().RESTClient(), "ippools", e.poolRef.Namespace, fields.Everything())
+	// IPPools served through DHCP relay agents can be in any namespace
+	// TODO: could be more specific on what fields we need
+	watcher := cache.NewListWatchFromClient(e.k8sClientset.NetworkV1alpha1().RESTClient(), "ippools", metav1.NamespaceAll, fields.Everything())
 
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 
-	indexer, informer := cache.NewIndexerInformer(watcher, &networkv1.IPPool{}, 0, cache.ResourceEventHandlerFuncs{
+	ipPoolIndexer, informer := cache.NewIndexerInformer(watcher, &networkv1.IPPool{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			key, err := cache.MetaNamespaceKeyFunc(obj)
+			if err == nil {
+				queue.Add(Event{
+					key:             key,
+					action:          ADD,
+					poolName:        obj.(*networkv1.IPPool).ObjectMeta.Name,
+					poolNetworkName: obj.(*networkv1.IPPool).Spec.NetworkName,
+				})
+			}
+		},
 		UpdateFunc: func(old interface{}, new interface{}) {
 			key, err := cache.MetaNamespaceKeyFunc(new)
 			if err == nil {
@@ -110,9 +147,30 @@ func (e *EventHandler) EventListener(ctx context.Context) {
 				})
 			}
 		},
-	}, cache.Indexers{})
+		DeleteFunc: func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err == nil {
+				queue.Add(Event{
+					key:    key,
+					action: DELETE,
+				})
+			}
+		},
+	}, cache.Indexers{
+		indexer.IPPoolByServedByIndex: func(obj interface{}) ([]string, error) {
+			ipPool, ok := obj.(*networkv1.IPPool)
+			if !ok {
+				return nil, nil
+			}
+			return indexer.IPPoolByServedBy(ipPool)
+		},
+	})
+
+	e.indexerMutex.Lock()
+	e.ipPoolIndexer = ipPoolIndexer
+	e.indexerMutex.Unlock()
 
-	controller := NewController(queue, indexer, informer, e.poolRef, e.dhcpAllocator, e.poolCache)
+	controller := NewController(queue, ipPoolIndexer, informer, e.poolRef, e.dhcpAllocator, e.advertiser, e.poolCache)
 
 	go controller.Run(1)
 
@@ -121,3 +179,121 @@ func (e *EventHandler) EventListener(ctx context.Context) {
 
 	logrus.Info("(eventhandler.Run) IPPool event listener terminated")
 }
+
+// ReportDecline records the IP address declined by the DHCP client with the
+// hardware address in the IPPool status, so that the controller can take it
+// out of circulation.
+func (e *EventHandler) ReportDecline(hwAddr string, ipAddr net.IP) {
+	if e.k8sClientset == nil {
+		logrus.Warnf("(eventhandler.ReportDecline) client not initialized, drop decline of ip %s from hwaddr %s", ipAddr, hwAddr)
+		return
+	}
+
+	poolRef := e.declinedIPPool(hwAddr, ipAddr)
+	ipPools := e.k8sClientset.NetworkV1alpha1().IPPools(poolRef.Namespace)
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ipPool, err := ipPools.Get(context.TODO(), poolRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		ipPoolCpy := ipPool.DeepCopy()
+		var declined map[string]string
+		if ipAddr.To4() != nil {
+			if ipPoolCpy.Status.IPv4 == nil {
+				ipPoolCpy.Status.IPv4 = new(networkv1.IPv4Status)
+			}
+			if ipPoolCpy.Status.IPv4.Declined == nil {
+				ipPoolCpy.Status.IPv4.Declined = make(map[string]string)
+			}
+			declined = ipPoolCpy.Status.IPv4.Declined
+		} else {
+			if ipPoolCpy.Status.IPv6 == nil {
+				ipPoolCpy.Status.IPv6 = new(networkv1.IPv6Status)
+			}
+			if ipPoolCpy.Status.IPv6.Declined == nil {
+				ipPoolCpy.Status.IPv6.Declined = make(map[string]string)
+			}
+			declined = ipPoolCpy.Status.IPv6.Declined
+		}
+		if declined[ipAddr.String()] == hwAddr {
+			return nil
+		}
+		declined[ipAddr.String()] = hwAddr
+
+		_, err = ipPools.UpdateStatus(context.TODO(), ipPoolCpy, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		logrus.Errorf("(eventhandler.ReportDecline) failed to report decline of ip %s from hwaddr %s: %v", ipAddr, hwAddr, err)
+		return
+	}
+
+	logrus.Infof("(eventhandler.ReportDecline) decline of ip %s from hwaddr %s reported to ippool %s", ipAddr, hwAddr, poolRef.String())
+}
+
+// declinedIPPool finds the IPPool the declined IP address belongs to. It's
+// either one of the IPPools served through DHCP relay agents which has the IP
+// address allocated to hwAddr, or the IPPool of the agent.
+func (e *EventHandler) declinedIPPool(hwAddr string, ipAddr net.IP) types.NamespacedName {
+	for _, ipPool := range e.servedIPPools() {
+		if ipPool.Status.IPv4 != nil && ipPool.Status.IPv4.Allocated[ipAddr.String()] == hwAddr {
+			return types.NamespacedName{Namespace: ipPool.Namespace, Name: ipPool.Name}
+		}
+		if ipPool.Status.IPv6 != nil && ipPool.Status.IPv6.Allocated[ipAddr.String()] == hwAddr {
+			return types.NamespacedName{Namespace: ipPool.Namespace, Name: ipPool.Name}
+		}
+	}
+
+	return e.poolRef
+}
+
+// ownIPPool returns the IPPool of the agent from the IPPool cache of the event
+// listener.
+func (e *EventHandler) ownIPPool() (*networkv1.IPPool, error) {
+	e.indexerMutex.RLock()
+	ipPoolIndexer := e.ipPoolIndexer
+	e.indexerMutex.RUnlock()
+
+	if ipPoolIndexer == nil {
+		return nil, fmt.Errorf("event listener not started")
+	}
+
+	obj, exists, err := ipPoolIndexer.GetByKey(e.poolRef.String())
+	if err != nil {
+		return nil, err
+	}
+	ipPool, ok := obj.(*networkv1.IPPool)
+	if !exists || !ok {
+		return nil, fmt.Errorf("ippool %s not found", e.poolRef.String())
+	}
+
+	return ipPool, nil
+}
+
+// servedIPPools returns the IPPools served by the agent through DHCP relay
+// agents, as found in the IPPool cache of the event listener. There are none
+// until the event listener is started.
+func (e *EventHandler) servedIPPools() []*networkv1.IPPool {
+	e.indexerMutex.RLock()
+	ipPoolIndexer := e.ipPoolIndexer
+	e.indexerMutex.RUnlock()
+
+	if ipPoolIndexer == nil {
+		return nil
+	}
+
+	objs, err := ipPoolIndexer.ByIndex(indexer.IPPoolByServedByIndex, e.poolRef.String())
+	if err != nil {
+		logrus.Errorf("(eventhandler.servedIPPools) failed to look up ippools served by %s: %v", e.poolRef.String(), err)
+		return nil
+	}
+
+	ipPools := make([]*networkv1.IPPool, 0, len(objs))
+	for _, obj := range objs {
+		if ipPool, ok := obj.(*networkv1.IPPool); ok {
+			ipPools = append(ipPools, ipPool)
+		}
+	}
+	return ipPools
+}
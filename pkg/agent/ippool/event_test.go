@@ -0,0 +1,125 @@
+package ippool
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
+)
+
+func newTestIPPool(namespace, name, servedBy string, allocated map[string]string) *networkv1.IPPool {
+	return &networkv1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: networkv1.IPPoolSpec{
+			ServedBy: servedBy,
+		},
+		Status: networkv1.IPPoolStatus{
+			IPv4: &networkv1.IPv4Status{
+				Allocated: allocated,
+			},
+		},
+	}
+}
+
+func TestDeclinedIPPool(t *testing.T) {
+	poolRef := types.NamespacedName{Namespace: "default", Name: "transit"}
+
+	ipPoolIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		indexer.IPPoolByServedByIndex: func(obj interface{}) ([]string, error) {
+			return indexer.IPPoolByServedBy(obj.(*networkv1.IPPool))
+		},
+	})
+	for _, ipPool := range []*networkv1.IPPool{
+		newTestIPPool("default", "transit", "", map[string]string{testIP1: testMAC1}),
+		newTestIPPool("routed", "net-1", poolRef.String(), map[string]string{testIP2: testMAC2}),
+		newTestIPPool("routed", "net-2", "default/other", map[string]string{testIP2: testMAC3}),
+	} {
+		if err := ipPoolIndexer.Add(ipPool); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		indexer cache.Indexer
+		hwAddr  string
+		ipAddr  string
+		want    types.NamespacedName
+	}{
+		{
+			name:    "ip of the ippool of the agent",
+			indexer: ipPoolIndexer,
+			hwAddr:  testMAC1,
+			ipAddr:  testIP1,
+			want:    poolRef,
+		},
+		{
+			name:    "ip of a served ippool",
+			indexer: ipPoolIndexer,
+			hwAddr:  testMAC2,
+			ipAddr:  testIP2,
+			want:    types.NamespacedName{Namespace: "routed", Name: "net-1"},
+		},
+		{
+			name:    "ip of an ippool served by another agent",
+			indexer: ipPoolIndexer,
+			hwAddr:  testMAC3,
+			ipAddr:  testIP2,
+			want:    poolRef,
+		},
+		{
+			name:   "event listener not started",
+			hwAddr: testMAC2,
+			ipAddr: testIP2,
+			want:   poolRef,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &EventHandler{
+				poolRef:       poolRef,
+				ipPoolIndexer: tc.indexer,
+			}
+			if got := e.declinedIPPool(tc.hwAddr, net.ParseIP(tc.ipAddr)); got != tc.want {
+				t.Errorf("got %s, wanted %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGroupActivities(t *testing.T) {
+	ipPools := []*networkv1.IPPool{
+		newTestIPPool("routed", "net-1", "default/transit", map[string]string{testIP2: testMAC2}),
+		newTestIPPool("default", "transit", "", map[string]string{testIP1: testMAC1}),
+	}
+	activities := map[string]dhcp.DHCPLease{
+		testMAC1: {ClientIP: net.ParseIP(testIP1), State: dhcp.LeaseStateBound},
+		testMAC2: {ClientIP: net.ParseIP(testIP2), State: dhcp.LeaseStateOffered},
+		// The IP address was allocated to another client in the meantime
+		testMAC3: {ClientIP: net.ParseIP(testIP1), State: dhcp.LeaseStateBound},
+	}
+
+	want := map[types.NamespacedName]map[string]networkv1.LeaseActivity{
+		{Namespace: "default", Name: "transit"}: {
+			testMAC1: {State: string(dhcp.LeaseStateBound)},
+		},
+		{Namespace: "routed", Name: "net-1"}: {
+			testMAC2: {State: string(dhcp.LeaseStateOffered)},
+		},
+	}
+
+	if got := groupActivities(ipPools, activities); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
@@ -1,9 +1,12 @@
 package ippool
 
 import (
+	"reflect"
+
 	"github.com/sirupsen/logrus"
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/ra"
 	"github.com/harvester/vm-dhcp-controller/pkg/util"
 )
 
@@ -17,31 +20,120 @@ func (c *Controller) Update(ipPool *networkv1.IPPool) error {
 		return nil
 	}
 	allocated := ipPool.Status.IPv4.Allocated
-	filterExcludedAndReserved(allocated)
-	return c.updatePoolCacheAndLeaseStore(allocated, ipPool.Spec.IPv4Config)
+	filterMarked(allocated)
+	if err := c.updatePoolCacheAndLeaseStore(c.poolRef.String(), c.poolCache, allocated, ipPool.Spec.IPv4Config); err != nil {
+		return err
+	}
+
+	// The relayed IPPools wait for the server IP address to be known
+	if c.serverIP != ipPool.Spec.IPv4Config.ServerIP {
+		c.serverIP = ipPool.Spec.IPv4Config.ServerIP
+		c.requeueRelayed()
+	}
+
+	// Leases of single-stack IPPools, or of those whose IPv6 configuration
+	// has been dropped, are all removed
+	var allocated6 map[string]string
+	if ipPool.Spec.IPv6Config != nil && ipPool.Status.IPv6 != nil {
+		allocated6 = ipPool.Status.IPv6.Allocated
+		filterMarked(allocated6)
+	}
+	if err := c.updatePoolCache6AndLeaseStore(allocated6, ipPool.Spec.IPv6Config); err != nil {
+		return err
+	}
+
+	raConfig, err := ra.NewConfig(ipPool.Spec.IPv6Config)
+	if err != nil {
+		return err
+	}
+	c.advertiser.SetConfig(raConfig)
+
+	return nil
 }
 
-func (c *Controller) updatePoolCacheAndLeaseStore(latest map[string]string, ipv4Config networkv1.IPv4Config) error {
-	for ip, mac := range c.poolCache {
-		if newMAC, exists := latest[ip]; exists {
-			if mac != newMAC {
-				logrus.Infof("set %s with new value %s", ip, newMAC)
-				// TODO: update lease
-				c.poolCache[ip] = newMAC
-			}
-		} else {
-			logrus.Infof("remove %s", ip)
-			if err := c.dhcpAllocator.DeleteLease(c.poolCache[ip]); err != nil {
-				return err
-			}
-			delete(c.poolCache, ip)
+// UpdateRelayed syncs the leases of the IPPool with key, which the agent
+// serves through DHCP relay agents as long as the IPPool is served by the
+// IPPool of the agent. The leases are all removed otherwise, e.g., when the
+// IPPool is deleted, in which case ipPool is nil.
+func (c *Controller) UpdateRelayed(key string, ipPool *networkv1.IPPool) error {
+	poolCache, exists := c.relayedPoolCaches[key]
+
+	served := ipPool != nil && ipPool.Spec.ServedBy == c.poolRef.String()
+
+	var allocated map[string]string
+	var ipv4Config networkv1.IPv4Config
+	if served {
+		if !networkv1.CacheReady.IsTrue(ipPool) {
+			logrus.Warningf("relayed ippool %s is not ready", key)
+			return nil
+		}
+		if ipPool.Status.IPv4 == nil {
+			logrus.Warningf("relayed ippool %s status has no records", key)
+			return nil
+		}
+		if c.serverIP == "" {
+			logrus.Infof("relayed ippool %s waits for ippool %s", key, c.poolRef.String())
+			return nil
+		}
+		allocated = ipPool.Status.IPv4.Allocated
+		filterMarked(allocated)
+		ipv4Config = ipPool.Spec.IPv4Config
+		// Clients behind relay agents reach the agent with its own address
+		ipv4Config.ServerIP = c.serverIP
+		if !exists {
+			logrus.Infof("serve relayed ippool %s", key)
+			poolCache = make(map[string]string)
+			c.relayedPoolCaches[key] = poolCache
+		}
+	} else if !exists {
+		logrus.Debugf("(controller.UpdateRelayed) IPPool %s is not our target", key)
+		return nil
+	}
+
+	if err := c.updatePoolCacheAndLeaseStore(key, poolCache, allocated, ipv4Config); err != nil {
+		return err
+	}
+
+	if !served {
+		logrus.Infof("stop serving relayed ippool %s", key)
+		delete(c.relayedPoolCaches, key)
+		delete(c.ipv4Configs, key)
+	}
+
+	return nil
+}
+
+// updatePoolCacheAndLeaseStore syncs the pool cache and the leases of the
+// IPPool with key to the latest allocated IP addresses. The leases of the IP
+// addresses whose MAC addresses changed are replaced, and the rest are rebuilt
+// in place if the IPv4 configuration they were built with changed.
+func (c *Controller) updatePoolCacheAndLeaseStore(key string, poolCache map[string]string, latest map[string]string, ipv4Config networkv1.IPv4Config) error {
+	prevConfig, exists := c.ipv4Configs[key]
+	configChanged := exists && leaseConfigChanged(prevConfig, ipv4Config)
+	if configChanged {
+		logrus.Infof("ipv4 config of ippool %s changed, update leases", key)
+	}
+
+	// Remove the leases of the IP addresses gone or taken over by other MAC
+	// addresses first, for a MAC address may move from one to another
+	for ip, mac := range poolCache {
+		if newMAC, exists := latest[ip]; exists && mac == newMAC {
+			continue
+		}
+		logrus.Infof("remove %s", ip)
+		if err := c.dhcpAllocator.DeleteLease(mac); err != nil {
+			return err
 		}
+		delete(poolCache, ip)
 	}
 
 	for newIP, newMAC := range latest {
-		if _, exists := c.poolCache[newIP]; !exists {
-			logrus.Infof("add %s with value %s", newIP, newMAC)
-			if err := c.dhcpAllocator.AddLease(
+		if _, exists := poolCache[newIP]; exists {
+			if !configChanged {
+				continue
+			}
+			logrus.Infof("update %s with value %s", newIP, newMAC)
+			if err := c.dhcpAllocator.UpdateLease(
 				newMAC,
 				ipv4Config.ServerIP,
 				newIP,
@@ -52,19 +144,87 @@ func (c *Controller) updatePoolCacheAndLeaseStore(latest map[string]string, ipv4
 				ipv4Config.DomainSearch,
 				ipv4Config.NTP,
 				ipv4Config.LeaseTime,
+				ipv4Config.Routes,
+				ipv4Config.Boot,
+				ipv4Config.CustomOptions,
+				ipv4Config.MTU,
+				ipv4Config.DisableHostname,
+			); err != nil {
+				return err
+			}
+			continue
+		}
+		logrus.Infof("add %s with value %s", newIP, newMAC)
+		if err := c.dhcpAllocator.AddLease(
+			newMAC,
+			ipv4Config.ServerIP,
+			newIP,
+			ipv4Config.CIDR,
+			ipv4Config.Router,
+			ipv4Config.DNS,
+			ipv4Config.DomainName,
+			ipv4Config.DomainSearch,
+			ipv4Config.NTP,
+			ipv4Config.LeaseTime,
+			ipv4Config.Routes,
+			ipv4Config.Boot,
+			ipv4Config.CustomOptions,
+			ipv4Config.MTU,
+			ipv4Config.DisableHostname,
+		); err != nil {
+			return err
+		}
+		poolCache[newIP] = newMAC
+	}
+
+	c.ipv4Configs[key] = ipv4Config
+
+	return nil
+}
+
+// leaseConfigChanged tells if the IPv4 configuration the leases are built
+// with differs, leaving out the settings only the IPAM cares about.
+func leaseConfigChanged(prev, ipv4Config networkv1.IPv4Config) bool {
+	prev.Pool, ipv4Config.Pool = networkv1.Pool{}, networkv1.Pool{}
+	prev.AllocationStrategy, ipv4Config.AllocationStrategy = "", ""
+	prev.QuarantineDuration, ipv4Config.QuarantineDuration = nil, nil
+	return !reflect.DeepEqual(prev, ipv4Config)
+}
+
+func (c *Controller) updatePoolCache6AndLeaseStore(latest map[string]string, ipv6Config *networkv1.IPv6Config) error {
+	for ip, mac := range c.poolCache6 {
+		if newMAC, exists := latest[ip]; exists && mac == newMAC {
+			continue
+		}
+		logrus.Infof("remove ipv6 %s", ip)
+		if err := c.dhcpAllocator.DeleteLease6(mac); err != nil {
+			return err
+		}
+		delete(c.poolCache6, ip)
+	}
+
+	for newIP, newMAC := range latest {
+		if _, exists := c.poolCache6[newIP]; !exists {
+			logrus.Infof("add ipv6 %s with value %s", newIP, newMAC)
+			if err := c.dhcpAllocator.AddLease6(
+				newMAC,
+				newIP,
+				ipv6Config.DNS,
+				ipv6Config.DomainSearch,
+				ipv6Config.LeaseTime,
 			); err != nil {
 				return err
 			}
-			c.poolCache[newIP] = newMAC
+			c.poolCache6[newIP] = newMAC
 		}
 	}
 
 	return nil
 }
 
-func filterExcludedAndReserved(allocated map[string]string) {
+func filterMarked(allocated map[string]string) {
 	for ip, mac := range allocated {
-		if mac == util.ExcludedMark || mac == util.ReservedMark {
+		if util.IsMark(mac) {
 			delete(allocated, ip)
 		}
 	}
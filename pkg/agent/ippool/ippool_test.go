@@ -0,0 +1,212 @@
+package ippool
+
+import (
+	"net"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
+)
+
+const (
+	testPoolKey = "default/net-1"
+	testIP1     = "192.168.0.10"
+	testIP2     = "192.168.0.11"
+	testMAC1    = "11:22:33:44:55:66"
+	testMAC2    = "22:33:44:55:66:77"
+	testMAC3    = "33:44:55:66:77:88"
+)
+
+func newTestIPv4Config(dnsServers ...string) networkv1.IPv4Config {
+	return networkv1.IPv4Config{
+		ServerIP: "192.168.0.2",
+		CIDR:     "192.168.0.0/24",
+		Pool: networkv1.Pool{
+			Start: "192.168.0.10",
+			End:   "192.168.0.20",
+		},
+		Router: "192.168.0.1",
+		DNS:    dnsServers,
+	}
+}
+
+func TestUpdatePoolCacheAndLeaseStore(t *testing.T) {
+	type lease struct {
+		ip  string
+		dns string
+	}
+
+	testCases := []struct {
+		name           string
+		givenAllocated map[string]string
+		givenConfig    networkv1.IPv4Config
+		allocated      map[string]string
+		config         networkv1.IPv4Config
+		expected       map[string]lease
+	}{
+		{
+			name:           "ip added",
+			givenAllocated: map[string]string{testIP1: testMAC1},
+			givenConfig:    newTestIPv4Config("8.8.8.8"),
+			allocated:      map[string]string{testIP1: testMAC1, testIP2: testMAC2},
+			config:         newTestIPv4Config("8.8.8.8"),
+			expected: map[string]lease{
+				testMAC1: {ip: testIP1, dns: "8.8.8.8"},
+				testMAC2: {ip: testIP2, dns: "8.8.8.8"},
+			},
+		},
+		{
+			name:           "ip removed",
+			givenAllocated: map[string]string{testIP1: testMAC1, testIP2: testMAC2},
+			givenConfig:    newTestIPv4Config("8.8.8.8"),
+			allocated:      map[string]string{testIP1: testMAC1},
+			config:         newTestIPv4Config("8.8.8.8"),
+			expected: map[string]lease{
+				testMAC1: {ip: testIP1, dns: "8.8.8.8"},
+			},
+		},
+		{
+			name:           "dns servers changed",
+			givenAllocated: map[string]string{testIP1: testMAC1, testIP2: testMAC2},
+			givenConfig:    newTestIPv4Config("8.8.8.8"),
+			allocated:      map[string]string{testIP1: testMAC1, testIP2: testMAC2},
+			config:         newTestIPv4Config("1.1.1.1"),
+			expected: map[string]lease{
+				testMAC1: {ip: testIP1, dns: "1.1.1.1"},
+				testMAC2: {ip: testIP2, dns: "1.1.1.1"},
+			},
+		},
+		{
+			name:           "mac changed",
+			givenAllocated: map[string]string{testIP1: testMAC1},
+			givenConfig:    newTestIPv4Config("8.8.8.8"),
+			allocated:      map[string]string{testIP1: testMAC3},
+			config:         newTestIPv4Config("8.8.8.8"),
+			expected: map[string]lease{
+				testMAC3: {ip: testIP1, dns: "8.8.8.8"},
+			},
+		},
+		{
+			name:           "macs swapped along with dns servers changed",
+			givenAllocated: map[string]string{testIP1: testMAC1, testIP2: testMAC2},
+			givenConfig:    newTestIPv4Config("8.8.8.8"),
+			allocated:      map[string]string{testIP1: testMAC2, testIP2: testMAC1},
+			config:         newTestIPv4Config("1.1.1.1"),
+			expected: map[string]lease{
+				testMAC1: {ip: testIP2, dns: "1.1.1.1"},
+				testMAC2: {ip: testIP1, dns: "1.1.1.1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		c := &Controller{
+			dhcpAllocator: dhcp.New(),
+			ipv4Configs:   make(map[string]networkv1.IPv4Config),
+		}
+		poolCache := make(map[string]string)
+
+		if err := c.updatePoolCacheAndLeaseStore(testPoolKey, poolCache, tc.givenAllocated, tc.givenConfig); err != nil {
+			t.Fatalf("%s: %s", tc.name, err.Error())
+		}
+		if err := c.updatePoolCacheAndLeaseStore(testPoolKey, poolCache, tc.allocated, tc.config); err != nil {
+			t.Errorf("%s: %s", tc.name, err.Error())
+			continue
+		}
+
+		leases, _ := c.dhcpAllocator.ListAll("")
+		if len(leases) != len(tc.expected) {
+			t.Errorf("%s: got %d leases, wanted %d", tc.name, len(leases), len(tc.expected))
+		}
+		for mac, want := range tc.expected {
+			got := c.dhcpAllocator.GetLease(mac)
+			if !got.ClientIP.Equal(net.ParseIP(want.ip)) {
+				t.Errorf("%s: got ip %s for %s, wanted %s", tc.name, got.ClientIP, mac, want.ip)
+			}
+			if len(got.DNS) != 1 || !got.DNS[0].Equal(net.ParseIP(want.dns)) {
+				t.Errorf("%s: got dns %v for %s, wanted [%s]", tc.name, got.DNS, mac, want.dns)
+			}
+		}
+		if len(poolCache) != len(tc.allocated) {
+			t.Errorf("%s: got pool cache %v, wanted %v", tc.name, poolCache, tc.allocated)
+		}
+		for ip, mac := range tc.allocated {
+			if poolCache[ip] != mac {
+				t.Errorf("%s: got pool cache %v, wanted %v", tc.name, poolCache, tc.allocated)
+				break
+			}
+		}
+	}
+}
+
+func TestLeaseConfigChanged(t *testing.T) {
+	leaseTime := 600
+	domainName := "example.com"
+
+	testCases := []struct {
+		name     string
+		modify   func(ipv4Config *networkv1.IPv4Config)
+		expected bool
+	}{
+		{
+			name:     "nothing changed",
+			modify:   func(ipv4Config *networkv1.IPv4Config) {},
+			expected: false,
+		},
+		{
+			name: "dns servers changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.DNS = []string{"1.1.1.1"}
+			},
+			expected: true,
+		},
+		{
+			name: "ntp servers changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.NTP = []string{"pool.ntp.org"}
+			},
+			expected: true,
+		},
+		{
+			name: "domain name changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.DomainName = &domainName
+			},
+			expected: true,
+		},
+		{
+			name: "lease time changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.LeaseTime = &leaseTime
+			},
+			expected: true,
+		},
+		{
+			name: "server ip changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.ServerIP = "192.168.0.3"
+			},
+			expected: true,
+		},
+		{
+			name: "only pool, allocation strategy and quarantine duration changed",
+			modify: func(ipv4Config *networkv1.IPv4Config) {
+				ipv4Config.Pool.Exclude = []string{"192.168.0.15"}
+				ipv4Config.AllocationStrategy = networkv1.AllocationStrategyRoundRobin
+				ipv4Config.QuarantineDuration = &metav1.Duration{}
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		prev := newTestIPv4Config("8.8.8.8")
+		ipv4Config := newTestIPv4Config("8.8.8.8")
+		tc.modify(&ipv4Config)
+		if got := leaseConfigChanged(prev, ipv4Config); got != tc.expected {
+			t.Errorf("%s: got %t, wanted %t", tc.name, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ippool
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+// VmNetCfgListener keeps the per-client settings of the DHCP allocator in sync
+// with the VirtualMachineNetworkConfigs.
+func (e *EventHandler) VmNetCfgListener(ctx context.Context) {
+	logrus.Info("(eventhandler.VmNetCfgListener) starting VirtualMachineNetworkConfig event listener")
+
+	watcher := cache.NewListWatchFromClient(e.k8sClientset.NetworkV1alpha1().RESTClient(), "virtualmachinenetworkconfigs", metav1.NamespaceAll, fields.Everything())
+
+	_, informer := cache.NewInformer(watcher, &networkv1.VirtualMachineNetworkConfig{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			e.syncClients(nil, obj.(*networkv1.VirtualMachineNetworkConfig))
+		},
+		UpdateFunc: func(old interface{}, new interface{}) {
+			e.syncClients(old.(*networkv1.VirtualMachineNetworkConfig), new.(*networkv1.VirtualMachineNetworkConfig))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if vmNetCfg, ok := obj.(*networkv1.VirtualMachineNetworkConfig); ok {
+				e.syncClients(vmNetCfg, nil)
+			}
+		},
+	})
+
+	informer.Run(ctx.Done())
+
+	logrus.Info("(eventhandler.VmNetCfgListener) VirtualMachineNetworkConfig event listener terminated")
+}
+
+// syncClients applies the VM name and the custom options of the network
+// interfaces, and drops those of the interfaces no longer there. Interfaces of
+// all networks are taken into account, since the agent may serve the networks
+// of other IPPools through DHCP relay agents. The settings only take effect
+// for clients having a lease anyway.
+func (e *EventHandler) syncClients(oldVmNetCfg, vmNetCfg *networkv1.VirtualMachineNetworkConfig) {
+	current := make(map[string]bool)
+	if vmNetCfg != nil {
+		for _, nc := range vmNetCfg.Spec.NetworkConfigs {
+			current[nc.MACAddress] = true
+			if err := e.dhcpAllocator.SetClient(nc.MACAddress, vmNetCfg.Spec.VMName, nc.CustomOptions); err != nil {
+				logrus.Errorf("(eventhandler.syncClients) failed to set custom options for hwaddr %s of vmnetcfg %s/%s: %v",
+					nc.MACAddress, vmNetCfg.Namespace, vmNetCfg.Name, err)
+				// Still hand out the host name without the faulty custom options
+				_ = e.dhcpAllocator.SetClient(nc.MACAddress, vmNetCfg.Spec.VMName, nil)
+			}
+		}
+	}
+
+	if oldVmNetCfg != nil {
+		for _, nc := range oldVmNetCfg.Spec.NetworkConfigs {
+			if !current[nc.MACAddress] {
+				e.dhcpAllocator.DeleteClient(nc.MACAddress)
+			}
+		}
+	}
+}
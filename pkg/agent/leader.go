@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+// A standby takes over within leaseDuration after the leader is gone, or
+// right away if the leader steps down gracefully
+const (
+	leaseDuration = 10 * time.Second
+	renewDeadline = 5 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// lead blocks until the agent becomes the leader of the IPPool, then takes
+// the server IP address and serves DHCP in eg. The lease is named after the
+// agent deployment. Once the leadership is lost, lead gives up the server IP
+// address and returns an error so that the agent gets restarted as a
+// standby.
+func (a *Agent) lead(ctx context.Context, eg *errgroup.Group) error {
+	restConfig, err := a.ippoolEventHandler.RestConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: a.namespace,
+			Name:      util.SafeAgentConcatName(a.poolRef.Namespace, a.poolRef.Name),
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: a.name,
+		},
+	}
+
+	leCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var leadErr error
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logrus.Infof("(agent.lead) %s became the leader of ippool %s", a.name, a.poolRef.String())
+				if !a.dryRun {
+					if err := assignIPAddress(a.nic, a.serverIP); err != nil {
+						// Step down for another replica to take over
+						leadErr = err
+						cancel()
+						return
+					}
+				}
+				a.serve(ctx, eg)
+			},
+			OnStoppedLeading: func() {
+				logrus.Infof("(agent.lead) %s stopped leading ippool %s", a.name, a.poolRef.String())
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	elector.Run(leCtx)
+
+	if !a.dryRun {
+		if err := unassignIPAddress(a.nic, a.serverIP); err != nil {
+			logrus.Errorf("(agent.lead) cannot remove ip %s from nic %s: %v", a.serverIP, a.nic, err)
+		}
+	}
+
+	if leadErr != nil {
+		return leadErr
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("agent %s lost the leadership of ippool %s", a.name, a.poolRef.String())
+}
+
+// assignIPAddress adds ipAddress, in CIDR notation, to nic and announces it
+// with gratuitous ARP so that the DHCP clients learn the new owner.
+func assignIPAddress(nic, ipAddress string) error {
+	prefix, err := netip.ParsePrefix(ipAddress)
+	if err != nil {
+		return fmt.Errorf("server ip %s is malformed: %w", ipAddress, err)
+	}
+
+	if out, err := exec.Command("ip", "address", "replace", prefix.String(), "dev", nic).CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot add ip %s to nic %s: %s", prefix, nic, out)
+	}
+
+	if out, err := exec.Command("arping", "-U", "-c", "3", "-I", nic, prefix.Addr().String()).CombinedOutput(); err != nil {
+		logrus.Warnf("(agent.assignIPAddress) cannot announce ip %s on nic %s: %s", prefix.Addr(), nic, out)
+	}
+
+	return nil
+}
+
+func unassignIPAddress(nic, ipAddress string) error {
+	if out, err := exec.Command("ip", "address", "delete", ipAddress, "dev", nic).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s", out)
+	}
+	return nil
+}
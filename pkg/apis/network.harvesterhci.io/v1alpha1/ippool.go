@@ -21,6 +21,7 @@ var (
 // +kubebuilder:printcolumn:name="NETWORK",type=string,JSONPath=`.spec.networkName`
 // +kubebuilder:printcolumn:name="AVAILABLE",type=integer,JSONPath=`.status.ipv4.available`
 // +kubebuilder:printcolumn:name="USED",type=integer,JSONPath=`.status.ipv4.used`
+// +kubebuilder:printcolumn:name="BOUND",type=integer,JSONPath=`.status.ipv4.bound`
 // +kubebuilder:printcolumn:name="REGISTERED",type=string,JSONPath=`.status.conditions[?(@.type=='Registered')].status`
 // +kubebuilder:printcolumn:name="CACHEREADY",type=string,JSONPath=`.status.conditions[?(@.type=='CacheReady')].status`
 // +kubebuilder:printcolumn:name="AGENTREADY",type=string,JSONPath=`.status.conditions[?(@.type=='AgentReady')].status`
@@ -37,6 +38,10 @@ type IPPool struct {
 type IPPoolSpec struct {
 	IPv4Config IPv4Config `json:"ipv4Config,omitempty"`
 
+	// +optional
+	// +kubebuilder:validation:Optional
+	IPv6Config *IPv6Config `json:"ipv6Config,omitempty"`
+
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="NetworkName is immutable"
 	// +kubebuilder:validation:MaxLength=64
@@ -45,8 +50,54 @@ type IPPoolSpec struct {
 	// +optional
 	// +kubebuilder:validation:Optional
 	Paused *bool `json:"paused,omitempty"`
+
+	// ServedBy is the namespaced name of another IPPool whose agent serves
+	// this IPPool as well. It's meant for routed networks, whose DHCP
+	// requests reach that agent through DHCP relay agents.
+	// +optional
+	// +kubebuilder:validation:Optional
+	ServedBy string `json:"servedBy,omitempty"`
+
+	// Reclaim releases the IP addresses of the network interfaces not using
+	// them for long, so that they can be allocated to others. Nothing is
+	// reclaimed if it's not set.
+	// +optional
+	// +kubebuilder:validation:Optional
+	Reclaim *ReclaimPolicy `json:"reclaim,omitempty"`
+}
+
+type ReclaimPolicy struct {
+	// InactivePeriod is how long a network interface can go without a bound
+	// DHCP lease before its IP address is reclaimed. Network interfaces of
+	// ready VMs are not reclaimed for inactivity.
+	// +optional
+	// +kubebuilder:validation:Optional
+	InactivePeriod *metav1.Duration `json:"inactivePeriod,omitempty"`
+
+	// StoppedGracePeriod is how long a VM can stay stopped before the IP
+	// addresses of its network interfaces are reclaimed.
+	// +optional
+	// +kubebuilder:validation:Optional
+	StoppedGracePeriod *metav1.Duration `json:"stoppedGracePeriod,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=lowest-free;random;round-robin;mac-hash
+type AllocationStrategy string
+
+const (
+	// AllocationStrategyLowestFree allocates the lowest free IP address
+	AllocationStrategyLowestFree AllocationStrategy = "lowest-free"
+	// AllocationStrategyRandom allocates a free IP address at random
+	AllocationStrategyRandom AllocationStrategy = "random"
+	// AllocationStrategyRoundRobin allocates the next free IP address after
+	// the last allocated one, so that recently used IP addresses are the last
+	// to be reused
+	AllocationStrategyRoundRobin AllocationStrategy = "round-robin"
+	// AllocationStrategyMACHash allocates the IP address derived from the MAC
+	// address, or else the next free one after it
+	AllocationStrategyMACHash AllocationStrategy = "mac-hash"
+)
+
 // +kubebuilder:validation:XValidation:rule="!has(oldSelf.router) || has(self.router)", message="Router is required once set"
 type IPv4Config struct {
 	// +kubebuilder:validation:Required
@@ -61,6 +112,12 @@ type IPv4Config struct {
 	// +kubebuilder:validation:Required
 	Pool Pool `json:"pool"`
 
+	// AllocationStrategy decides which free IP address is allocated when
+	// none is designated. Defaults to lowest-free.
+	// +optional
+	// +kubebuilder:validation:Optional
+	AllocationStrategy AllocationStrategy `json:"allocationStrategy,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Format=ipv4
@@ -89,23 +146,248 @@ type IPv4Config struct {
 	// +optional
 	// +kubebuilder:validation:Optional
 	LeaseTime *int `json:"leaseTime,omitempty"`
+
+	// QuarantineDuration is how long a released IP address is kept from
+	// being allocated again, so that a stale client can't collide with a
+	// new one.
+	// IP addresses declined by DHCP clients are kept from being allocated
+	// again for as long, or for an hour if it's shorter.
+	// +optional
+	// +kubebuilder:validation:Optional
+	QuarantineDuration *metav1.Duration `json:"quarantineDuration,omitempty"`
+
+	// Reservations hold IP addresses of the pool for particular network
+	// interfaces or VMs, even before they are created.
+	// +optional
+	// +kubebuilder:validation:Optional
+	Reservations []Reservation `json:"reservations,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	Routes []Route `json:"routes,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	Boot *BootConfig `json:"boot,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	CustomOptions []DHCPOption `json:"customOptions,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=68
+	// +kubebuilder:validation:Maximum=65535
+	MTU *int `json:"mtu,omitempty"`
+
+	// DisableHostname stops handing the VM name to the clients as their host
+	// name.
+	// +optional
+	// +kubebuilder:validation:Optional
+	DisableHostname *bool `json:"disableHostname,omitempty"`
 }
 
-// +kubebuilder:validation:XValidation:rule="!has(oldSelf.exclude) || has(self.exclude)", message="End is required once set"
+// Reservation holds an IP address for the network interface with the MAC
+// address or, if the VM is given instead, for the first network interface of
+// the VM in the network that has no IP address designated or reserved by its
+// MAC address.
+// +kubebuilder:validation:XValidation:rule="has(self.macAddress) != has(self.vm)",message="Exactly one of MACAddress and VM is required"
+type Reservation struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv4
+	IPAddress string `json:"ipAddress"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// VM is the namespaced name of the VM, e.g., default/vm-1.
+	// +optional
+	// +kubebuilder:validation:Optional
+	VM string `json:"vm,omitempty"`
+}
+
+type Route struct {
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv4
+	Gateway string `json:"gateway"`
+}
+
+type BootConfig struct {
+	// NextServer is the server to fetch the boot file from. The DHCP server
+	// itself is used if it's not set.
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format=ipv4
+	NextServer string `json:"nextServer,omitempty"`
+
+	// Filename is the boot file for legacy BIOS clients, and for UEFI clients
+	// if UEFIFilename is not set.
+	// +optional
+	// +kubebuilder:validation:Optional
+	Filename string `json:"filename,omitempty"`
+
+	// UEFIFilename is the boot file for UEFI clients.
+	// +optional
+	// +kubebuilder:validation:Optional
+	UEFIFilename string `json:"uefiFilename,omitempty"`
+
+	// IPXEFilename is the boot file for clients identifying themselves as
+	// iPXE, e.g., an iPXE script to break the chainloading loop.
+	// +optional
+	// +kubebuilder:validation:Optional
+	IPXEFilename string `json:"ipxeFilename,omitempty"`
+}
+
+type DHCPOptionType string
+
+const (
+	DHCPOptionTypeIP     DHCPOptionType = "ip"
+	DHCPOptionTypeIPList DHCPOptionType = "ip-list"
+	DHCPOptionTypeString DHCPOptionType = "string"
+	DHCPOptionTypeUint8  DHCPOptionType = "uint8"
+	DHCPOptionTypeUint16 DHCPOptionType = "uint16"
+	DHCPOptionTypeUint32 DHCPOptionType = "uint32"
+	DHCPOptionTypeBool   DHCPOptionType = "bool"
+	DHCPOptionTypeHex    DHCPOptionType = "hex"
+)
+
+type DHCPOption struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=254
+	Code int `json:"code"`
+
+	// Type is how Value is encoded into the option, one of ip, ip-list,
+	// string, uint8, uint16, uint32, bool and hex. The addresses of an ip-list
+	// are separated by commas.
+	// +kubebuilder:validation:Required
+	Type DHCPOptionType `json:"type"`
+
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+// Pool is the range of allocatable IP addresses. It may be changed on the fly,
+// e.g., widened once it fills up, as long as none of the allocated IP addresses
+// falls out of it or gets excluded.
 type Pool struct {
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Format=ipv4
-	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Start is immutable"
 	Start string `json:"start"`
 
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Format=ipv4
-	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="End is immutable"
 	End string `json:"end"`
 
 	// +optional
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Format=ipv4
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Ranges are the ranges of IP addresses the pool has besides the one from
+	// Start to End, for the allocatable space split around infrastructure
+	// blocks. None of the ranges may overlap.
+	// +optional
+	// +kubebuilder:validation:Optional
+	Ranges []IPRange `json:"ranges,omitempty"`
+}
+
+// IPRange is the range of IP addresses from Start to End inclusive.
+type IPRange struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv4
+	Start string `json:"start"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv4
+	End string `json:"end"`
+}
+
+// IPv6Config turns the pool into a dual-stack one. The addresses are handed
+// out by the DHCPv6 server of the agent as non-temporary addresses (IA_NA).
+type IPv6Config struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Prefix is immutable"
+	Prefix string `json:"prefix"`
+
+	// +kubebuilder:validation:Required
+	Pool IPv6Pool `json:"pool"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format=ipv6
+	// +kubebuilder:validation:MaxItems=3
+	DNS []string `json:"dns,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	DomainSearch []string `json:"domainSearch,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	LeaseTime *int `json:"leaseTime,omitempty"`
+
+	// RouterAdvertisement makes the agent send Router Advertisements on the
+	// network, for guests to learn the prefix and the default route.
+	// +optional
+	// +kubebuilder:validation:Optional
+	RouterAdvertisement *RouterAdvertisementConfig `json:"routerAdvertisement,omitempty"`
+}
+
+type RAMode string
+
+const (
+	// RAModeStateful has guests get their addresses from DHCPv6
+	RAModeStateful RAMode = "stateful"
+	// RAModeStateless has guests configure their addresses with SLAAC and
+	// get the other configuration, e.g., DNS servers, from DHCPv6
+	RAModeStateless RAMode = "stateless"
+	// RAModeSLAAC has guests configure everything from the Router
+	// Advertisements alone
+	RAModeSLAAC RAMode = "slaac"
+)
+
+type RouterAdvertisementConfig struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=stateful;stateless;slaac
+	Mode RAMode `json:"mode"`
+
+	// Interval is the maximum number of seconds between unsolicited Router
+	// Advertisements.
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=4
+	// +kubebuilder:validation:Maximum=1800
+	Interval *int `json:"interval,omitempty"`
+
+	// RouterLifetime is the number of seconds guests use the agent as their
+	// default router. It defaults to zero, i.e., the agent is not a default
+	// router.
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=9000
+	RouterLifetime *int `json:"routerLifetime,omitempty"`
+}
+
+type IPv6Pool struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv6
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Start is immutable"
+	Start string `json:"start"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format=ipv6
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="End is immutable"
+	End string `json:"end"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format=ipv6
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="Exclude is immutable"
 	Exclude []string `json:"exclude,omitempty"`
 }
@@ -117,10 +399,21 @@ type IPPoolStatus struct {
 	// +kubebuilder:validation:Optional
 	IPv4 *IPv4Status `json:"ipv4,omitempty"`
 
+	// +optional
+	// +kubebuilder:validation:Optional
+	IPv6 *IPv6Status `json:"ipv6,omitempty"`
+
+	// AgentPodRef refers to the Deployment running the agent of the IPPool.
 	// +optional
 	// +kubebuilder:validation:Optional
 	AgentPodRef *PodReference `json:"agentPodRef,omitempty"`
 
+	// Agent reports the replicas of the agent. It's filled in by the
+	// controller.
+	// +optional
+	// +kubebuilder:validation:Optional
+	Agent *AgentStatus `json:"agent,omitempty"`
+
 	// +optional
 	// +kubebuilder:validation:Optional
 	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
@@ -130,6 +423,92 @@ type IPv4Status struct {
 	Allocated map[string]string `json:"allocated,omitempty"`
 	Used      int               `json:"used"`
 	Available int               `json:"available"`
+
+	// Declined records the IP addresses declined by DHCP clients, keyed by
+	// IP address with the declining MAC address as value. It's filled in by
+	// the agent and drained by the controller once the addresses have been
+	// quarantined.
+	Declined map[string]string `json:"declined,omitempty"`
+
+	// Leases records what the agent has seen of the DHCP clients, keyed by
+	// MAC address. It's filled in by the agent and pruned by the controller
+	// once the MAC addresses are no longer allocated.
+	Leases map[string]LeaseActivity `json:"leases,omitempty"`
+
+	// Bound is the number of leases currently bound by DHCP clients.
+	Bound int `json:"bound"`
+
+	// PendingReclaims records the IP addresses to be reclaimed according to
+	// the reclaim policy, keyed by IP address. It's maintained by the
+	// controller, and an entry is dropped once the network interface becomes
+	// active again or the IP address is reclaimed.
+	PendingReclaims map[string]PendingReclaim `json:"pendingReclaims,omitempty"`
+
+	// LastAllocated is the IP address most recently allocated by the
+	// allocation strategy, for it to pick up where it left off after a
+	// restart.
+	LastAllocated string `json:"lastAllocated,omitempty"`
+}
+
+type ReclaimReason string
+
+const (
+	// ReclaimReasonInactive means the network interface has no bound DHCP
+	// lease
+	ReclaimReasonInactive ReclaimReason = "Inactive"
+	// ReclaimReasonStopped means the VM is stopped
+	ReclaimReasonStopped ReclaimReason = "Stopped"
+)
+
+// PendingReclaim is an IP address to be reclaimed.
+type PendingReclaim struct {
+	MACAddress string        `json:"macAddress"`
+	Reason     ReclaimReason `json:"reason"`
+
+	// ReclaimAt is when the IP address is reclaimed unless the network
+	// interface becomes active again before then.
+	ReclaimAt metav1.Time `json:"reclaimAt"`
+}
+
+// LeaseActivity is what the agent has seen of the DHCP client of a lease.
+type LeaseActivity struct {
+	// State is one of allocated, offered, bound, expired and released.
+	State string `json:"state,omitempty"`
+
+	// FirstSeen is when the agent first received a message from the client.
+	FirstSeen *metav1.Time `json:"firstSeen,omitempty"`
+
+	// LastAck is when the agent last acknowledged the lease to the client.
+	LastAck *metav1.Time `json:"lastAck,omitempty"`
+
+	// Hostname is the host name sent by the client.
+	Hostname string `json:"hostname,omitempty"`
+
+	// VendorClass is the vendor class identifier sent by the client.
+	VendorClass string `json:"vendorClass,omitempty"`
+}
+
+// IPv6Status has no count of available addresses, as IPv6 ranges easily
+// outgrow any integer.
+type IPv6Status struct {
+	Allocated map[string]string `json:"allocated,omitempty"`
+	Used      int               `json:"used"`
+
+	// Declined records the IPv6 addresses declined by DHCPv6 clients, the same
+	// way as for IPv4.
+	Declined map[string]string `json:"declined,omitempty"`
+}
+
+// AgentStatus is how many replicas of the agent are ready and which one of
+// them answers DHCP requests.
+type AgentStatus struct {
+	Replicas      int32 `json:"replicas"`
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// Leader is the name of the agent pod holding the lease of the IPPool. It's
+	// only set if the agent runs with leader election, i.e., more than one
+	// replica.
+	Leader string `json:"leader,omitempty"`
 }
 
 type PodReference struct {
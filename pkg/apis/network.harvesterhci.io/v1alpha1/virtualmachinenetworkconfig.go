@@ -9,6 +9,7 @@ import (
 const (
 	AllocatedState NetworkConfigState = "Allocated"
 	PendingState   NetworkConfigState = "Pending"
+	ReclaimedState NetworkConfigState = "Reclaimed"
 )
 
 var (
@@ -64,6 +65,17 @@ type NetworkConfig struct {
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Format=ipv4
 	IPAddress *string `json:"ipAddress,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format=ipv6
+	IPv6Address *string `json:"ipv6Address,omitempty"`
+
+	// CustomOptions take precedence over the custom options of the IPPool
+	// for this network interface only.
+	// +optional
+	// +kubebuilder:validation:Optional
+	CustomOptions []DHCPOption `json:"customOptions,omitempty"`
 }
 
 type VirtualMachineNetworkConfigStatus struct {
@@ -75,8 +87,15 @@ type VirtualMachineNetworkConfigStatus struct {
 }
 
 type NetworkConfigStatus struct {
-	AllocatedIPAddress string             `json:"allocatedIPAddress,omitempty"`
-	MACAddress         string             `json:"macAddress,omitempty"`
-	NetworkName        string             `json:"networkName,omitempty"`
-	State              NetworkConfigState `json:"state,omitempty"`
+	AllocatedIPAddress   string             `json:"allocatedIPAddress,omitempty"`
+	AllocatedIPv6Address string             `json:"allocatedIPv6Address,omitempty"`
+	MACAddress           string             `json:"macAddress,omitempty"`
+	NetworkName          string             `json:"networkName,omitempty"`
+	State                NetworkConfigState `json:"state,omitempty"`
+
+	// ReclaimedAt is when the IP addresses were reclaimed. They're allocated
+	// again once the VM is started after that.
+	// +optional
+	// +kubebuilder:validation:Optional
+	ReclaimedAt *metav1.Time `json:"reclaimedAt,omitempty"`
 }
@@ -23,9 +23,58 @@ package v1alpha1
 
 import (
 	genericcondition "github.com/rancher/wrangler/pkg/genericcondition"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AgentStatus) DeepCopyInto(out *AgentStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AgentStatus.
+func (in *AgentStatus) DeepCopy() *AgentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AgentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootConfig) DeepCopyInto(out *BootConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootConfig.
+func (in *BootConfig) DeepCopy() *BootConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BootConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DHCPOption) DeepCopyInto(out *DHCPOption) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DHCPOption.
+func (in *DHCPOption) DeepCopy() *DHCPOption {
+	if in == nil {
+		return nil
+	}
+	out := new(DHCPOption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPPool) DeepCopyInto(out *IPPool) {
 	*out = *in
@@ -91,11 +140,21 @@ func (in *IPPoolList) DeepCopyObject() runtime.Object {
 func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
 	*out = *in
 	in.IPv4Config.DeepCopyInto(&out.IPv4Config)
+	if in.IPv6Config != nil {
+		in, out := &in.IPv6Config, &out.IPv6Config
+		*out = new(IPv6Config)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Paused != nil {
 		in, out := &in.Paused, &out.Paused
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Reclaim != nil {
+		in, out := &in.Reclaim, &out.Reclaim
+		*out = new(ReclaimPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -118,11 +177,21 @@ func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
 		*out = new(IPv4Status)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IPv6 != nil {
+		in, out := &in.IPv6, &out.IPv6
+		*out = new(IPv6Status)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AgentPodRef != nil {
 		in, out := &in.AgentPodRef, &out.AgentPodRef
 		*out = new(PodReference)
 		**out = **in
 	}
+	if in.Agent != nil {
+		in, out := &in.Agent, &out.Agent
+		*out = new(AgentStatus)
+		**out = **in
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]genericcondition.GenericCondition, len(*in))
@@ -141,6 +210,22 @@ func (in *IPPoolStatus) DeepCopy() *IPPoolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPRange) DeepCopyInto(out *IPRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPRange.
+func (in *IPRange) DeepCopy() *IPRange {
+	if in == nil {
+		return nil
+	}
+	out := new(IPRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPv4Config) DeepCopyInto(out *IPv4Config) {
 	*out = *in
@@ -170,6 +255,41 @@ func (in *IPv4Config) DeepCopyInto(out *IPv4Config) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.QuarantineDuration != nil {
+		in, out := &in.QuarantineDuration, &out.QuarantineDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]Reservation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]Route, len(*in))
+		copy(*out, *in)
+	}
+	if in.Boot != nil {
+		in, out := &in.Boot, &out.Boot
+		*out = new(BootConfig)
+		**out = **in
+	}
+	if in.CustomOptions != nil {
+		in, out := &in.CustomOptions, &out.CustomOptions
+		*out = make([]DHCPOption, len(*in))
+		copy(*out, *in)
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int)
+		**out = **in
+	}
+	if in.DisableHostname != nil {
+		in, out := &in.DisableHostname, &out.DisableHostname
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -193,6 +313,27 @@ func (in *IPv4Status) DeepCopyInto(out *IPv4Status) {
 			(*out)[key] = val
 		}
 	}
+	if in.Declined != nil {
+		in, out := &in.Declined, &out.Declined
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Leases != nil {
+		in, out := &in.Leases, &out.Leases
+		*out = make(map[string]LeaseActivity, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.PendingReclaims != nil {
+		in, out := &in.PendingReclaims, &out.PendingReclaims
+		*out = make(map[string]PendingReclaim, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	return
 }
 
@@ -206,6 +347,118 @@ func (in *IPv4Status) DeepCopy() *IPv4Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6Config) DeepCopyInto(out *IPv6Config) {
+	*out = *in
+	in.Pool.DeepCopyInto(&out.Pool)
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DomainSearch != nil {
+		in, out := &in.DomainSearch, &out.DomainSearch
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LeaseTime != nil {
+		in, out := &in.LeaseTime, &out.LeaseTime
+		*out = new(int)
+		**out = **in
+	}
+	if in.RouterAdvertisement != nil {
+		in, out := &in.RouterAdvertisement, &out.RouterAdvertisement
+		*out = new(RouterAdvertisementConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6Config.
+func (in *IPv6Config) DeepCopy() *IPv6Config {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6Config)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6Pool) DeepCopyInto(out *IPv6Pool) {
+	*out = *in
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6Pool.
+func (in *IPv6Pool) DeepCopy() *IPv6Pool {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6Pool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPv6Status) DeepCopyInto(out *IPv6Status) {
+	*out = *in
+	if in.Allocated != nil {
+		in, out := &in.Allocated, &out.Allocated
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Declined != nil {
+		in, out := &in.Declined, &out.Declined
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPv6Status.
+func (in *IPv6Status) DeepCopy() *IPv6Status {
+	if in == nil {
+		return nil
+	}
+	out := new(IPv6Status)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaseActivity) DeepCopyInto(out *LeaseActivity) {
+	*out = *in
+	if in.FirstSeen != nil {
+		in, out := &in.FirstSeen, &out.FirstSeen
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAck != nil {
+		in, out := &in.LastAck, &out.LastAck
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaseActivity.
+func (in *LeaseActivity) DeepCopy() *LeaseActivity {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaseActivity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
 	*out = *in
@@ -214,6 +467,16 @@ func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.IPv6Address != nil {
+		in, out := &in.IPv6Address, &out.IPv6Address
+		*out = new(string)
+		**out = **in
+	}
+	if in.CustomOptions != nil {
+		in, out := &in.CustomOptions, &out.CustomOptions
+		*out = make([]DHCPOption, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -230,6 +493,10 @@ func (in *NetworkConfig) DeepCopy() *NetworkConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkConfigStatus) DeepCopyInto(out *NetworkConfigStatus) {
 	*out = *in
+	if in.ReclaimedAt != nil {
+		in, out := &in.ReclaimedAt, &out.ReclaimedAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -243,6 +510,23 @@ func (in *NetworkConfigStatus) DeepCopy() *NetworkConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingReclaim) DeepCopyInto(out *PendingReclaim) {
+	*out = *in
+	in.ReclaimAt.DeepCopyInto(&out.ReclaimAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingReclaim.
+func (in *PendingReclaim) DeepCopy() *PendingReclaim {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingReclaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodReference) DeepCopyInto(out *PodReference) {
 	*out = *in
@@ -267,6 +551,11 @@ func (in *Pool) DeepCopyInto(out *Pool) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Ranges != nil {
+		in, out := &in.Ranges, &out.Ranges
+		*out = make([]IPRange, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -280,6 +569,90 @@ func (in *Pool) DeepCopy() *Pool {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReclaimPolicy) DeepCopyInto(out *ReclaimPolicy) {
+	*out = *in
+	if in.InactivePeriod != nil {
+		in, out := &in.InactivePeriod, &out.InactivePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.StoppedGracePeriod != nil {
+		in, out := &in.StoppedGracePeriod, &out.StoppedGracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReclaimPolicy.
+func (in *ReclaimPolicy) DeepCopy() *ReclaimPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ReclaimPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Reservation) DeepCopyInto(out *Reservation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Reservation.
+func (in *Reservation) DeepCopy() *Reservation {
+	if in == nil {
+		return nil
+	}
+	out := new(Reservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Route) DeepCopyInto(out *Route) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Route.
+func (in *Route) DeepCopy() *Route {
+	if in == nil {
+		return nil
+	}
+	out := new(Route)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterAdvertisementConfig) DeepCopyInto(out *RouterAdvertisementConfig) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(int)
+		**out = **in
+	}
+	if in.RouterLifetime != nil {
+		in, out := &in.RouterLifetime, &out.RouterLifetime
+		*out = new(int)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterAdvertisementConfig.
+func (in *RouterAdvertisementConfig) DeepCopy() *RouterAdvertisementConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterAdvertisementConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineNetworkConfig) DeepCopyInto(out *VirtualMachineNetworkConfig) {
 	*out = *in
@@ -375,7 +748,9 @@ func (in *VirtualMachineNetworkConfigStatus) DeepCopyInto(out *VirtualMachineNet
 	if in.NetworkConfigs != nil {
 		in, out := &in.NetworkConfigs, &out.NetworkConfigs
 		*out = make([]NetworkConfigStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
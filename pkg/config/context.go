@@ -3,17 +3,22 @@ package config
 import (
 	"context"
 	"fmt"
+	"os"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/rancher/lasso/pkg/controller"
+	ctlapps "github.com/rancher/wrangler/pkg/generated/controllers/apps"
+	ctlcoordination "github.com/rancher/wrangler/pkg/generated/controllers/coordination.k8s.io"
 	"github.com/rancher/wrangler/pkg/generic"
 	"github.com/rancher/wrangler/pkg/schemes"
 	"github.com/rancher/wrangler/pkg/start"
 	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
@@ -70,15 +75,43 @@ type ControllerOptions struct {
 	AgentNamespace          string
 	AgentImage              *Image
 	AgentServiceAccountName string
+	AgentPodTemplate        *corev1.PodTemplateSpec
+	AgentReplicas           int32
+	AgentRolloutStrategy    appsv1.DeploymentStrategyType
 	NoDHCP                  bool
 }
 
+// LoadPodTemplate reads the pod template, in YAML or JSON, the agent pods
+// are merged with from path.
+func LoadPodTemplate(path string) (*corev1.PodTemplateSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	template := new(corev1.PodTemplateSpec)
+	if err := utilyaml.NewYAMLOrJSONDecoder(f, 4096).Decode(template); err != nil {
+		return nil, fmt.Errorf("pod template %s is malformed: %w", path, err)
+	}
+
+	return template, nil
+}
+
 type AgentOptions struct {
 	DryRun         bool
 	Nic            string
 	KubeConfigPath string
 	KubeContext    string
 	IPPoolRef      types.NamespacedName
+
+	// With LeaderElection, the agent only serves DHCP once it holds the
+	// lease of the IPPool in Namespace, and takes ServerIP, in CIDR
+	// notation, on Nic in the meantime. Name identifies the agent.
+	LeaderElection bool
+	Name           string
+	Namespace      string
+	ServerIP       string
 }
 
 type HTTPServerOptions struct {
@@ -96,9 +129,11 @@ type Management struct {
 
 	HarvesterNetworkFactory *ctlnetwork.Factory
 
-	CniFactory      *ctlcni.Factory
-	CoreFactory     *ctlcore.Factory
-	KubeVirtFactory *ctlkubevirt.Factory
+	AppsFactory         *ctlapps.Factory
+	CniFactory          *ctlcni.Factory
+	CoordinationFactory *ctlcoordination.Factory
+	CoreFactory         *ctlcore.Factory
+	KubeVirtFactory     *ctlkubevirt.Factory
 
 	ClientSet *kubernetes.Clientset
 
@@ -176,6 +211,24 @@ func SetupManagement(ctx context.Context, restConfig *rest.Config, options *Cont
 	management.CniFactory = cni
 	management.starters = append(management.starters, cni)
 
+	apps, err := ctlapps.NewFactoryFromConfigWithOptions(restConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	management.AppsFactory = apps
+	management.starters = append(management.starters, apps)
+
+	// The leases of the agents are all in the agent namespace, so those of
+	// the other namespaces, e.g., the node heartbeats, are not watched
+	coordination, err := ctlcoordination.NewFactoryFromConfigWithOptions(restConfig, &generic.FactoryOptions{
+		Namespace: options.AgentNamespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	management.CoordinationFactory = coordination
+	management.starters = append(management.starters, coordination)
+
 	kubevirt, err := ctlkubevirt.NewFactoryFromConfigWithOptions(restConfig, opts)
 	if err != nil {
 		return nil, err
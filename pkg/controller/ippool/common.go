@@ -3,15 +3,19 @@ package ippool
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"net"
 	"time"
 
 	cniv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	"github.com/rancher/wrangler/pkg/kv"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 
 	"github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io"
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
@@ -26,6 +30,8 @@ func prepareAgentPod(
 	clusterNetwork string,
 	agentServiceAccountName string,
 	agentImage *config.Image,
+	agentPodTemplate *corev1.PodTemplateSpec,
+	leaderElection bool,
 ) (*corev1.Pod, error) {
 	name := util.SafeAgentConcatName(ipPool.Namespace, ipPool.Name)
 
@@ -56,7 +62,24 @@ func prepareAgentPod(
 		args = append(args, "--dry-run")
 	}
 
-	return &corev1.Pod{
+	// With leader election, the server IP address is left to the leader,
+	// which is the only replica serving DHCP
+	ipAddrScript := fmt.Sprintf(setIPAddrScript, ipPool.Spec.IPv4Config.ServerIP, prefixLength)
+	if leaderElection {
+		ipAddrScript = flushIPAddrScript
+		args = append(args,
+			"--leader-election",
+			"--server-ip",
+			fmt.Sprintf("%s/%d", ipPool.Spec.IPv4Config.ServerIP, prefixLength),
+		)
+	}
+
+	ipPoolPodTemplate, err := util.ParseAgentPodTemplateAnnotation(ipPool.Annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Annotations: map[string]string{
 				multusNetworksAnnotationKey: string(networksStr),
@@ -98,7 +121,7 @@ func prepareAgentPod(
 					Command: []string{
 						"/bin/sh",
 						"-c",
-						fmt.Sprintf(setIPAddrScript, ipPool.Spec.IPv4Config.ServerIP, prefixLength),
+						ipAddrScript,
 					},
 					SecurityContext: &corev1.SecurityContext{
 						RunAsUser:  &runAsUserID,
@@ -118,8 +141,20 @@ func prepareAgentPod(
 					Args:  args,
 					Env: []corev1.EnvVar{
 						{
-							Name:  "VM_DHCP_AGENT_NAME",
-							Value: name,
+							Name: "VM_DHCP_AGENT_NAME",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{
+									FieldPath: "metadata.name",
+								},
+							},
+						},
+						{
+							Name: "POD_NAMESPACE",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{
+									FieldPath: "metadata.namespace",
+								},
+							},
 						},
 					},
 					SecurityContext: &corev1.SecurityContext{
@@ -150,9 +185,183 @@ func prepareAgentPod(
 				},
 			},
 		},
+	}
+
+	// The template of the controller goes first so that the one of the
+	// IPPool takes precedence
+	for _, template := range []*corev1.PodTemplateSpec{agentPodTemplate, ipPoolPodTemplate} {
+		if template == nil {
+			continue
+		}
+		if pod, err = mergePodTemplate(pod, template); err != nil {
+			return nil, err
+		}
+	}
+
+	return pod, nil
+}
+
+// prepareAgentDeployment returns the Deployment running agentReplicas agent
+// pods for ipPool. The pods are identical to what prepareAgentPod returns but
+// for their names. The Deployment is rolled out with agentRolloutStrategy, or
+// one fit for agentReplicas if it's empty. The pods elect a leader to hold
+// the server IP address if there's more than one replica, or if old and new
+// pods may run side by side during a RollingUpdate even with a single replica.
+// The Deployment is annotated with a hash of its pod template so that changes
+// to it can be told apart. It's owned by ipPool as long as both are in the
+// same namespace, as owner references across namespaces are not allowed.
+func prepareAgentDeployment(
+	ipPool *networkv1.IPPool,
+	noDHCP bool,
+	agentNamespace string,
+	clusterNetwork string,
+	agentServiceAccountName string,
+	agentImage *config.Image,
+	agentPodTemplate *corev1.PodTemplateSpec,
+	agentReplicas int32,
+	agentRolloutStrategy appsv1.DeploymentStrategyType,
+) (*appsv1.Deployment, error) {
+	// A single agent pod takes the server IP address on its own, so the old
+	// one has to go before the new one comes up
+	strategy := appsv1.DeploymentStrategy{
+		Type: appsv1.RecreateDeploymentStrategyType,
+	}
+	if agentReplicas > 1 {
+		strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+	if agentRolloutStrategy != "" {
+		strategy.Type = agentRolloutStrategy
+	}
+
+	leaderElection := agentReplicas > 1 || strategy.Type == appsv1.RollingUpdateDeploymentStrategyType
+	pod, err := prepareAgentPod(ipPool, noDHCP, agentNamespace, clusterNetwork, agentServiceAccountName, agentImage, agentPodTemplate, leaderElection)
+	if err != nil {
+		return nil, err
+	}
+
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: pod.Annotations,
+			Labels:      pod.Labels,
+		},
+		Spec: pod.Spec,
+	}
+	templateHash, err := hashPodTemplate(&template)
+	if err != nil {
+		return nil, err
+	}
+
+	var ownerReferences []metav1.OwnerReference
+	if ipPool.Namespace == agentNamespace {
+		isController := true
+		ownerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: networkv1.SchemeGroupVersion.String(),
+				Kind:       "IPPool",
+				Name:       ipPool.Name,
+				UID:        ipPool.UID,
+				Controller: &isController,
+			},
+		}
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				agentTemplateHashAnnotationKey: templateHash,
+			},
+			Labels:          pod.Labels,
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			OwnerReferences: ownerReferences,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &agentReplicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					vmDHCPControllerLabelKey: "agent",
+					ipPoolNamespaceLabelKey:  ipPool.Namespace,
+					ipPoolNameLabelKey:       ipPool.Name,
+				},
+			},
+			Strategy: strategy,
+			Template: template,
+		},
 	}, nil
 }
 
+// hashPodTemplate returns a hash of template, which is compared against the
+// one of a running Deployment instead of the pod template itself, as the
+// latter is filled with defaults by the API server.
+func hashPodTemplate(template *corev1.PodTemplateSpec) (string, error) {
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	h.Write(templateBytes)
+	return fmt.Sprintf("%08x", h.Sum32()), nil
+}
+
+// mergePodTemplate merges template into pod the way kubectl applies a
+// strategic merge patch, e.g., containers are merged by their names. The name
+// and namespace of pod are kept.
+func mergePodTemplate(pod *corev1.Pod, template *corev1.PodTemplateSpec) (*corev1.Pod, error) {
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	template = template.DeepCopy()
+	template.Name = ""
+	template.Namespace = ""
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields like containers are marshaled as null even if they're not set,
+	// which would mean deleting them in a patch
+	var patch map[string]interface{}
+	if err := json.Unmarshal(templateBytes, &patch); err != nil {
+		return nil, err
+	}
+	patchBytes, err := json.Marshal(pruneNulls(patch))
+	if err != nil {
+		return nil, err
+	}
+
+	mergedBytes, err := strategicpatch.StrategicMergePatch(podBytes, patchBytes, corev1.Pod{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge pod template: %w", err)
+	}
+
+	merged := new(corev1.Pod)
+	if err := json.Unmarshal(mergedBytes, merged); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+func pruneNulls(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			if elem == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = pruneNulls(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = pruneNulls(elem)
+		}
+	}
+	return val
+}
+
 func setRegisteredCondition(ipPool *networkv1.IPPool, status corev1.ConditionStatus, reason, message string) {
 	networkv1.Registered.SetStatus(ipPool, string(status))
 	networkv1.Registered.Reason(ipPool, reason)
@@ -211,6 +420,44 @@ func (b *IPPoolBuilder) Paused() *IPPoolBuilder {
 	return b
 }
 
+func (b *IPPoolBuilder) ServedBy(servedBy string) *IPPoolBuilder {
+	b.ipPool.Spec.ServedBy = servedBy
+	return b
+}
+
+func (b *IPPoolBuilder) AllocationStrategy(strategy networkv1.AllocationStrategy) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.AllocationStrategy = strategy
+	return b
+}
+
+func (b *IPPoolBuilder) QuarantineDuration(quarantineDuration *metav1.Duration) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.QuarantineDuration = quarantineDuration
+	return b
+}
+
+func (b *IPPoolBuilder) Reclaim(inactivePeriod, stoppedGracePeriod *metav1.Duration) *IPPoolBuilder {
+	b.ipPool.Spec.Reclaim = &networkv1.ReclaimPolicy{
+		InactivePeriod:     inactivePeriod,
+		StoppedGracePeriod: stoppedGracePeriod,
+	}
+	return b
+}
+
+func (b *IPPoolBuilder) PendingReclaim(ipAddress, macAddress string, reason networkv1.ReclaimReason, reclaimAt metav1.Time) *IPPoolBuilder {
+	if b.ipPool.Status.IPv4 == nil {
+		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
+	}
+	if b.ipPool.Status.IPv4.PendingReclaims == nil {
+		b.ipPool.Status.IPv4.PendingReclaims = make(map[string]networkv1.PendingReclaim, 1)
+	}
+	b.ipPool.Status.IPv4.PendingReclaims[ipAddress] = networkv1.PendingReclaim{
+		MACAddress: macAddress,
+		Reason:     reason,
+		ReclaimAt:  reclaimAt,
+	}
+	return b
+}
+
 func (b *IPPoolBuilder) UnPaused() *IPPoolBuilder {
 	paused := false
 	b.ipPool.Spec.Paused = &paused
@@ -238,11 +485,83 @@ func (b *IPPoolBuilder) PoolRange(start, end string) *IPPoolBuilder {
 	return b
 }
 
+func (b *IPPoolBuilder) Range(start, end string) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.Pool.Ranges = append(b.ipPool.Spec.IPv4Config.Pool.Ranges, networkv1.IPRange{
+		Start: start,
+		End:   end,
+	})
+	return b
+}
+
 func (b *IPPoolBuilder) Exclude(ipAddressList ...string) *IPPoolBuilder {
 	b.ipPool.Spec.IPv4Config.Pool.Exclude = append(b.ipPool.Spec.IPv4Config.Pool.Exclude, ipAddressList...)
 	return b
 }
 
+func (b *IPPoolBuilder) Reservation(ipAddress, macAddress, vm string) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.Reservations = append(b.ipPool.Spec.IPv4Config.Reservations, networkv1.Reservation{
+		IPAddress:  ipAddress,
+		MACAddress: macAddress,
+		VM:         vm,
+	})
+	return b
+}
+
+func (b *IPPoolBuilder) Route(destination, gateway string) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.Routes = append(b.ipPool.Spec.IPv4Config.Routes, networkv1.Route{
+		Destination: destination,
+		Gateway:     gateway,
+	})
+	return b
+}
+
+func (b *IPPoolBuilder) CustomOption(code int, optionType networkv1.DHCPOptionType, value string) *IPPoolBuilder {
+	b.ipPool.Spec.IPv4Config.CustomOptions = append(b.ipPool.Spec.IPv4Config.CustomOptions, networkv1.DHCPOption{
+		Code:  code,
+		Type:  optionType,
+		Value: value,
+	})
+	return b
+}
+
+func (b *IPPoolBuilder) IPv6Config(prefix, start, end string) *IPPoolBuilder {
+	if b.ipPool.Spec.IPv6Config == nil {
+		b.ipPool.Spec.IPv6Config = new(networkv1.IPv6Config)
+	}
+	b.ipPool.Spec.IPv6Config.Prefix = prefix
+	b.ipPool.Spec.IPv6Config.Pool.Start = start
+	b.ipPool.Spec.IPv6Config.Pool.End = end
+	return b
+}
+
+func (b *IPPoolBuilder) IPv6Exclude(ipAddressList ...string) *IPPoolBuilder {
+	if b.ipPool.Spec.IPv6Config == nil {
+		b.ipPool.Spec.IPv6Config = new(networkv1.IPv6Config)
+	}
+	b.ipPool.Spec.IPv6Config.Pool.Exclude = append(b.ipPool.Spec.IPv6Config.Pool.Exclude, ipAddressList...)
+	return b
+}
+
+func (b *IPPoolBuilder) IPv6DNS(dnsServers ...string) *IPPoolBuilder {
+	if b.ipPool.Spec.IPv6Config == nil {
+		b.ipPool.Spec.IPv6Config = new(networkv1.IPv6Config)
+	}
+	b.ipPool.Spec.IPv6Config.DNS = append(b.ipPool.Spec.IPv6Config.DNS, dnsServers...)
+	return b
+}
+
+func (b *IPPoolBuilder) IPv6RouterAdvertisement(mode networkv1.RAMode, interval, routerLifetime *int) *IPPoolBuilder {
+	if b.ipPool.Spec.IPv6Config == nil {
+		b.ipPool.Spec.IPv6Config = new(networkv1.IPv6Config)
+	}
+	b.ipPool.Spec.IPv6Config.RouterAdvertisement = &networkv1.RouterAdvertisementConfig{
+		Mode:           mode,
+		Interval:       interval,
+		RouterLifetime: routerLifetime,
+	}
+	return b
+}
+
 func (b *IPPoolBuilder) AgentPodRef(namespace, name, image, uid string) *IPPoolBuilder {
 	if b.ipPool.Status.AgentPodRef == nil {
 		b.ipPool.Status.AgentPodRef = new(networkv1.PodReference)
@@ -265,6 +584,55 @@ func (b *IPPoolBuilder) Allocated(ipAddress, macAddress string) *IPPoolBuilder {
 	return b
 }
 
+func (b *IPPoolBuilder) LastAllocated(ipAddress string) *IPPoolBuilder {
+	if b.ipPool.Status.IPv4 == nil {
+		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
+	}
+	b.ipPool.Status.IPv4.LastAllocated = ipAddress
+	return b
+}
+
+func (b *IPPoolBuilder) AllocatedIPv6(ipAddress, macAddress string) *IPPoolBuilder {
+	if b.ipPool.Status.IPv6 == nil {
+		b.ipPool.Status.IPv6 = new(networkv1.IPv6Status)
+	}
+	if b.ipPool.Status.IPv6.Allocated == nil {
+		b.ipPool.Status.IPv6.Allocated = make(map[string]string, 2)
+	}
+	b.ipPool.Status.IPv6.Allocated[ipAddress] = macAddress
+	return b
+}
+
+func (b *IPPoolBuilder) UsedIPv6(count int) *IPPoolBuilder {
+	if b.ipPool.Status.IPv6 == nil {
+		b.ipPool.Status.IPv6 = new(networkv1.IPv6Status)
+	}
+	b.ipPool.Status.IPv6.Used = count
+	return b
+}
+
+func (b *IPPoolBuilder) Declined(ipAddress, macAddress string) *IPPoolBuilder {
+	if b.ipPool.Status.IPv4 == nil {
+		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
+	}
+	if b.ipPool.Status.IPv4.Declined == nil {
+		b.ipPool.Status.IPv4.Declined = make(map[string]string, 1)
+	}
+	b.ipPool.Status.IPv4.Declined[ipAddress] = macAddress
+	return b
+}
+
+func (b *IPPoolBuilder) DeclinedIPv6(ipAddress, macAddress string) *IPPoolBuilder {
+	if b.ipPool.Status.IPv6 == nil {
+		b.ipPool.Status.IPv6 = new(networkv1.IPv6Status)
+	}
+	if b.ipPool.Status.IPv6.Declined == nil {
+		b.ipPool.Status.IPv6.Declined = make(map[string]string, 1)
+	}
+	b.ipPool.Status.IPv6.Declined[ipAddress] = macAddress
+	return b
+}
+
 func (b *IPPoolBuilder) Available(count int) *IPPoolBuilder {
 	if b.ipPool.Status.IPv4 == nil {
 		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
@@ -281,6 +649,25 @@ func (b *IPPoolBuilder) Used(count int) *IPPoolBuilder {
 	return b
 }
 
+func (b *IPPoolBuilder) Lease(macAddress, state string) *IPPoolBuilder {
+	if b.ipPool.Status.IPv4 == nil {
+		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
+	}
+	if b.ipPool.Status.IPv4.Leases == nil {
+		b.ipPool.Status.IPv4.Leases = make(map[string]networkv1.LeaseActivity, 1)
+	}
+	b.ipPool.Status.IPv4.Leases[macAddress] = networkv1.LeaseActivity{State: state}
+	return b
+}
+
+func (b *IPPoolBuilder) Bound(count int) *IPPoolBuilder {
+	if b.ipPool.Status.IPv4 == nil {
+		b.ipPool.Status.IPv4 = new(networkv1.IPv4Status)
+	}
+	b.ipPool.Status.IPv4.Bound = count
+	return b
+}
+
 func (b *IPPoolBuilder) RegisteredCondition(status corev1.ConditionStatus, reason, message string) *IPPoolBuilder {
 	setRegisteredCondition(b.ipPool, status, reason, message)
 	return b
@@ -326,6 +713,15 @@ func (b *ipPoolStatusBuilder) AgentPodRef(namespace, name, image, uid string) *i
 	return b
 }
 
+func (b *ipPoolStatusBuilder) Agent(replicas, readyReplicas int32, leader string) *ipPoolStatusBuilder {
+	b.ipPoolStatus.Agent = &networkv1.AgentStatus{
+		Replicas:      replicas,
+		ReadyReplicas: readyReplicas,
+		Leader:        leader,
+	}
+	return b
+}
+
 func (b *ipPoolStatusBuilder) RegisteredCondition(status corev1.ConditionStatus, reason, message string) *ipPoolStatusBuilder {
 	networkv1.Registered.SetStatus(&b.ipPoolStatus, string(status))
 	networkv1.Registered.Reason(&b.ipPoolStatus, reason)
@@ -358,13 +754,13 @@ func (b *ipPoolStatusBuilder) Build() networkv1.IPPoolStatus {
 	return b.ipPoolStatus
 }
 
-type podBuilder struct {
-	pod *corev1.Pod
+type deploymentBuilder struct {
+	deployment *appsv1.Deployment
 }
 
-func newPodBuilder(namespace, name string) *podBuilder {
-	return &podBuilder{
-		pod: &corev1.Pod{
+func newDeploymentBuilder(namespace, name string) *deploymentBuilder {
+	return &deploymentBuilder{
+		deployment: &appsv1.Deployment{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: namespace,
 				Name:      name,
@@ -373,37 +769,66 @@ func newPodBuilder(namespace, name string) *podBuilder {
 	}
 }
 
-func (b *podBuilder) Container(name, repository, tag string) *podBuilder {
+func (b *deploymentBuilder) Container(name, repository, tag string) *deploymentBuilder {
 	container := corev1.Container{
 		Name:  name,
 		Image: repository + ":" + tag,
 	}
-	b.pod.Spec.Containers = append(b.pod.Spec.Containers, container)
+	b.deployment.Spec.Template.Spec.Containers = append(b.deployment.Spec.Template.Spec.Containers, container)
 	return b
 }
 
-func (b *podBuilder) PodReady(ready corev1.ConditionStatus) *podBuilder {
-	var found bool
-	if b.pod.Status.Conditions == nil {
-		b.pod.Status.Conditions = make([]corev1.PodCondition, 0, 1)
+func (b *deploymentBuilder) Label(key, value string) *deploymentBuilder {
+	if b.deployment.Labels == nil {
+		b.deployment.Labels = make(map[string]string)
 	}
-	for i := range b.pod.Status.Conditions {
-		if b.pod.Status.Conditions[i].Type == corev1.PodReady {
-			b.pod.Status.Conditions[i].Status = corev1.ConditionTrue
-			break
-		}
-	}
-	if !found {
-		b.pod.Status.Conditions = append(b.pod.Status.Conditions, corev1.PodCondition{
-			Type:   corev1.PodReady,
-			Status: corev1.ConditionTrue,
-		})
+	b.deployment.Labels[key] = value
+	return b
+}
+
+func (b *deploymentBuilder) Replicas(replicas int32) *deploymentBuilder {
+	b.deployment.Spec.Replicas = &replicas
+	return b
+}
+
+func (b *deploymentBuilder) Strategy(strategyType appsv1.DeploymentStrategyType) *deploymentBuilder {
+	b.deployment.Spec.Strategy.Type = strategyType
+	return b
+}
+
+func (b *deploymentBuilder) ReadyReplicas(readyReplicas int32) *deploymentBuilder {
+	b.deployment.Status.ReadyReplicas = readyReplicas
+	return b
+}
+
+func (b *deploymentBuilder) Build() *appsv1.Deployment {
+	return b.deployment
+}
+
+type leaseBuilder struct {
+	lease *coordinationv1.Lease
+}
+
+func newLeaseBuilder(namespace, name string) *leaseBuilder {
+	return &leaseBuilder{
+		lease: &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+		},
 	}
+}
+
+func (b *leaseBuilder) Holder(holderIdentity string, renewTime time.Time, leaseDurationSeconds int32) *leaseBuilder {
+	b.lease.Spec.HolderIdentity = &holderIdentity
+	b.lease.Spec.RenewTime = &metav1.MicroTime{Time: renewTime}
+	b.lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
 	return b
 }
 
-func (b *podBuilder) Build() *corev1.Pod {
-	return b.pod
+func (b *leaseBuilder) Build() *coordinationv1.Lease {
+	return b.lease
 }
 
 type NetworkAttachmentDefinitionBuilder struct {
@@ -429,6 +854,11 @@ func (b *NetworkAttachmentDefinitionBuilder) Label(key, value string) *NetworkAt
 	return b
 }
 
+func (b *NetworkAttachmentDefinitionBuilder) Config(config string) *NetworkAttachmentDefinitionBuilder {
+	b.nad.Spec.Config = config
+	return b
+}
+
 func (b *NetworkAttachmentDefinitionBuilder) Build() *cniv1.NetworkAttachmentDefinition {
 	return b.nad
 }
@@ -0,0 +1,279 @@
+package ippool
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/config"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+func TestPrepareAgentPod(t *testing.T) {
+	type input struct {
+		ipPool           *networkv1.IPPool
+		agentPodTemplate *corev1.PodTemplateSpec
+		leaderElection   bool
+	}
+
+	type output struct {
+		modify func(pod *corev1.Pod)
+		err    error
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "no pod template",
+			given: input{
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				modify: func(pod *corev1.Pod) {},
+			},
+		},
+		{
+			name: "leader election",
+			given: input{
+				ipPool:         newTestIPPoolBuilder().Build(),
+				leaderElection: true,
+			},
+			expected: output{
+				modify: func(pod *corev1.Pod) {
+					pod.Spec.InitContainers[0].Command[2] = flushIPAddrScript
+					pod.Spec.Containers[0].Args = append(pod.Spec.Containers[0].Args,
+						"--leader-election",
+						"--server-ip",
+						testServerIP1+"/24",
+					)
+				},
+			},
+		},
+		{
+			name: "pod template of the controller",
+			given: input{
+				ipPool: newTestIPPoolBuilder().Build(),
+				agentPodTemplate: &corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "you-cant-rename-me",
+					},
+					Spec: corev1.PodSpec{
+						PriorityClassName: "system-node-critical",
+						Tolerations: []corev1.Toleration{
+							{
+								Key:      "network",
+								Operator: corev1.TolerationOpExists,
+								Effect:   corev1.TaintEffectNoSchedule,
+							},
+						},
+						InitContainers: []corev1.Container{
+							{
+								Name:            "ip-setter",
+								Image:           "registry.local/busybox:1.36",
+								ImagePullPolicy: corev1.PullNever,
+							},
+						},
+						Containers: []corev1.Container{
+							{
+								Name: testContainerName,
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceCPU: resource.MustParse("10m"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: output{
+				modify: func(pod *corev1.Pod) {
+					pod.Spec.PriorityClassName = "system-node-critical"
+					pod.Spec.Tolerations = []corev1.Toleration{
+						{
+							Key:      "network",
+							Operator: corev1.TolerationOpExists,
+							Effect:   corev1.TaintEffectNoSchedule,
+						},
+					}
+					pod.Spec.InitContainers[0].Image = "registry.local/busybox:1.36"
+					pod.Spec.InitContainers[0].ImagePullPolicy = corev1.PullNever
+					pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+						corev1.ResourceCPU: resource.MustParse("10m"),
+					}
+				},
+			},
+		},
+		{
+			name: "pod template of the ippool taking precedence",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					Annotation(util.AgentPodTemplateAnnotationKey, `{"spec":{"nodeSelector":{"rack":"r2"},"containers":[{"name":"agent","resources":{"requests":{"cpu":"20m"}}}]}}`).Build(),
+				agentPodTemplate: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						NodeSelector: map[string]string{
+							"zone": "z1",
+						},
+						Containers: []corev1.Container{
+							{
+								Name: testContainerName,
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceCPU:    resource.MustParse("10m"),
+										corev1.ResourceMemory: resource.MustParse("32Mi"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: output{
+				modify: func(pod *corev1.Pod) {
+					pod.Spec.NodeSelector = map[string]string{
+						"rack": "r2",
+						"zone": "z1",
+					}
+					pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("20m"),
+						corev1.ResourceMemory: resource.MustParse("32Mi"),
+					}
+				},
+			},
+		},
+		{
+			name: "pod template of the ippool setting a forbidden field",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					Annotation(util.AgentPodTemplateAnnotationKey, `{"spec":{"hostNetwork":true}}`).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("annotation %s may only set the node selector, tolerations, priority class and container resources", util.AgentPodTemplateAnnotationKey),
+			},
+		},
+	}
+
+	image := &config.Image{
+		Repository: testImageRepository,
+		Tag:        testImageTag,
+	}
+
+	for _, tc := range testCases {
+		tc.given.ipPool.Spec.NetworkName = testNetworkName
+		tc.given.ipPool.Spec.IPv4Config.ServerIP = testServerIP1
+		tc.given.ipPool.Spec.IPv4Config.CIDR = testCIDR
+
+		pod, err := prepareAgentPod(tc.given.ipPool, false, testPodNamespace, testClusterNetwork, testServiceAccountName, image, tc.given.agentPodTemplate, tc.given.leaderElection)
+
+		if tc.expected.err != nil {
+			assert.Equal(t, tc.expected.err, err, tc.name)
+			continue
+		}
+		assert.Nil(t, err, tc.name)
+
+		expectedPod, _ := prepareAgentPod(
+			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+				ServerIP(testServerIP1).
+				CIDR(testCIDR).
+				NetworkName(testNetworkName).Build(),
+			false,
+			testPodNamespace,
+			testClusterNetwork,
+			testServiceAccountName,
+			image,
+			nil,
+			false,
+		)
+		tc.expected.modify(expectedPod)
+
+		assert.Equal(t, expectedPod, pod, tc.name)
+	}
+}
+
+func TestPrepareAgentDeployment(t *testing.T) {
+	type input struct {
+		agentReplicas        int32
+		agentRolloutStrategy appsv1.DeploymentStrategyType
+	}
+
+	type output struct {
+		strategy       appsv1.DeploymentStrategyType
+		leaderElection bool
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "single replica",
+			given: input{
+				agentReplicas: 1,
+			},
+			expected: output{
+				strategy: appsv1.RecreateDeploymentStrategyType,
+			},
+		},
+		{
+			name: "more than one replica",
+			given: input{
+				agentReplicas: 3,
+			},
+			expected: output{
+				strategy:       appsv1.RollingUpdateDeploymentStrategyType,
+				leaderElection: true,
+			},
+		},
+		{
+			name: "single replica rolled out with rolling update",
+			given: input{
+				agentReplicas:        1,
+				agentRolloutStrategy: appsv1.RollingUpdateDeploymentStrategyType,
+			},
+			expected: output{
+				strategy:       appsv1.RollingUpdateDeploymentStrategyType,
+				leaderElection: true,
+			},
+		},
+		{
+			name: "more than one replica rolled out with recreate",
+			given: input{
+				agentReplicas:        3,
+				agentRolloutStrategy: appsv1.RecreateDeploymentStrategyType,
+			},
+			expected: output{
+				strategy:       appsv1.RecreateDeploymentStrategyType,
+				leaderElection: true,
+			},
+		},
+	}
+
+	image := &config.Image{
+		Repository: testImageRepository,
+		Tag:        testImageTag,
+	}
+
+	for _, tc := range testCases {
+		ipPool := NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			NetworkName(testNetworkName).Build()
+
+		deployment, err := prepareAgentDeployment(ipPool, false, testPodNamespace, testClusterNetwork, testServiceAccountName, image, nil, tc.given.agentReplicas, tc.given.agentRolloutStrategy)
+		assert.Nil(t, err, tc.name)
+
+		assert.Equal(t, tc.expected.strategy, deployment.Spec.Strategy.Type, tc.name)
+		assert.Equal(t, tc.expected.leaderElection, slices.Contains(deployment.Spec.Template.Spec.Containers[0].Args, "--leader-election"), tc.name)
+	}
+}
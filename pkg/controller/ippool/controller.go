@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
+	ctlappsv1 "github.com/rancher/wrangler/pkg/generated/controllers/apps/v1"
+	ctlcoordinationv1 "github.com/rancher/wrangler/pkg/generated/controllers/coordination.k8s.io/v1"
 	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/rancher/wrangler/pkg/relatedresource"
 	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	"github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io"
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
@@ -20,6 +26,7 @@ import (
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
 	ctlcorev1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/core/v1"
 	ctlcniv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/k8s.cni.cncf.io/v1"
+	ctlkubevirtv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/kubevirt.io/v1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/ipam"
 	"github.com/harvester/vm-dhcp-controller/pkg/metrics"
@@ -31,6 +38,7 @@ const (
 
 	multusNetworksAnnotationKey         = "k8s.v1.cni.cncf.io/networks"
 	holdIPPoolAgentUpgradeAnnotationKey = "network.harvesterhci.io/hold-ippool-agent-upgrade"
+	agentTemplateHashAnnotationKey      = network.GroupName + "/agent-template-hash"
 
 	ipPoolNamespaceLabelKey  = network.GroupName + "/ippool-namespace"
 	ipPoolNameLabelKey       = network.GroupName + "/ippool-name"
@@ -43,6 +51,12 @@ set -ex
 
 ip address flush dev eth1
 ip address add %s/%d dev eth1
+`
+	flushIPAddrScript = `
+#!/usr/bin/env sh
+set -ex
+
+ip address flush dev eth1
 `
 )
 
@@ -61,6 +75,9 @@ type Handler struct {
 	agentNamespace          string
 	agentImage              *config.Image
 	agentServiceAccountName string
+	agentPodTemplate        *corev1.PodTemplateSpec
+	agentReplicas           int32
+	agentRolloutStrategy    appsv1.DeploymentStrategyType
 	noAgent                 bool
 	noDHCP                  bool
 
@@ -71,20 +88,31 @@ type Handler struct {
 	ippoolController ctlnetworkv1.IPPoolController
 	ippoolClient     ctlnetworkv1.IPPoolClient
 	ippoolCache      ctlnetworkv1.IPPoolCache
+	deploymentClient ctlappsv1.DeploymentClient
+	deploymentCache  ctlappsv1.DeploymentCache
 	podClient        ctlcorev1.PodClient
-	podCache         ctlcorev1.PodCache
+	leaseCache       ctlcoordinationv1.LeaseCache
 	nadCache         ctlcniv1.NetworkAttachmentDefinitionCache
+	vmnetcfgCache    ctlnetworkv1.VirtualMachineNetworkConfigCache
+	vmCache          ctlkubevirtv1.VirtualMachineCache
 }
 
 func Register(ctx context.Context, management *config.Management) error {
 	ippools := management.HarvesterNetworkFactory.Network().V1alpha1().IPPool()
+	deployments := management.AppsFactory.Apps().V1().Deployment()
 	pods := management.CoreFactory.Core().V1().Pod()
+	leases := management.CoordinationFactory.Coordination().V1().Lease()
 	nads := management.CniFactory.K8s().V1().NetworkAttachmentDefinition()
+	vmnetcfgs := management.HarvesterNetworkFactory.Network().V1alpha1().VirtualMachineNetworkConfig()
+	vms := management.KubeVirtFactory.Kubevirt().V1().VirtualMachine()
 
 	handler := &Handler{
 		agentNamespace:          management.Options.AgentNamespace,
 		agentImage:              management.Options.AgentImage,
 		agentServiceAccountName: management.Options.AgentServiceAccountName,
+		agentPodTemplate:        management.Options.AgentPodTemplate,
+		agentReplicas:           management.Options.AgentReplicas,
+		agentRolloutStrategy:    management.Options.AgentRolloutStrategy,
 		noAgent:                 management.Options.NoAgent,
 		noDHCP:                  management.Options.NoDHCP,
 
@@ -95,9 +123,13 @@ func Register(ctx context.Context, management *config.Management) error {
 		ippoolController: ippools,
 		ippoolClient:     ippools,
 		ippoolCache:      ippools.Cache(),
+		deploymentClient: deployments,
+		deploymentCache:  deployments.Cache(),
 		podClient:        pods,
-		podCache:         pods.Cache(),
+		leaseCache:       leases.Cache(),
 		nadCache:         nads.Cache(),
+		vmnetcfgCache:    vmnetcfgs.Cache(),
+		vmCache:          vms.Cache(),
 	}
 
 	ctlnetworkv1.RegisterIPPoolStatusHandler(
@@ -127,22 +159,75 @@ func Register(ctx context.Context, management *config.Management) error {
 		sets := labels.Set{
 			"network.harvesterhci.io/vm-dhcp-controller": "agent",
 		}
-		pods, err := handler.podCache.List(namespace, sets.AsSelector())
+		deployments, err := handler.deploymentCache.List(namespace, sets.AsSelector())
 		if err != nil {
 			return nil, err
 		}
-		for _, pod := range pods {
+		for _, deployment := range deployments {
 			key := relatedresource.Key{
-				Namespace: pod.Labels[ipPoolNamespaceLabelKey],
-				Name:      pod.Labels[ipPoolNameLabelKey],
+				Namespace: deployment.Labels[ipPoolNamespaceLabelKey],
+				Name:      deployment.Labels[ipPoolNameLabelKey],
 			}
 			keys = append(keys, key)
 		}
 		return keys, nil
-	}, ippools, pods)
+	}, ippools, deployments)
+
+	// Keep the leader of the agent up to date. The leases get renewed every
+	// few seconds, so the IPPool is only enqueued when the holder changes.
+	relatedresource.Watch(ctx, "ippool-lease-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		lease, ok := obj.(*coordinationv1.Lease)
+		if !ok {
+			return nil, nil
+		}
+		deployment, err := handler.deploymentCache.Get(namespace, name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if deployment.Labels[vmDHCPControllerLabelKey] != "agent" {
+			return nil, nil
+		}
+		ipPool, err := handler.ippoolCache.Get(deployment.Labels[ipPoolNamespaceLabelKey], deployment.Labels[ipPoolNameLabelKey])
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if ipPool.Status.Agent != nil && ipPool.Status.Agent.Leader == leaseHolder(lease) {
+			return nil, nil
+		}
+		return []relatedresource.Key{relatedresource.NewKey(ipPool.Namespace, ipPool.Name)}, nil
+	}, ippools, leases)
+
+	// Keep the pending reclaims up to date with the VMs getting stopped or
+	// started
+	relatedresource.Watch(ctx, "ippool-vm-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		vm, ok := obj.(*kubevirtv1.VirtualMachine)
+		if !ok {
+			return nil, nil
+		}
+		vmNetCfg, err := handler.vmnetcfgCache.Get(vm.Namespace, vm.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		var keys []relatedresource.Key
+		for _, nc := range vmNetCfg.Spec.NetworkConfigs {
+			ipPoolNamespace, ipPoolName := kv.RSplit(nc.NetworkName, "/")
+			keys = append(keys, relatedresource.NewKey(ipPoolNamespace, ipPoolName))
+		}
+		return keys, nil
+	}, ippools, vms)
 
 	ippools.OnChange(ctx, controllerName, handler.OnChange)
 	ippools.OnRemove(ctx, controllerName, handler.OnRemove)
+	deployments.OnChange(ctx, controllerName, handler.OnAgentChange)
 
 	return nil
 }
@@ -188,6 +273,58 @@ func (h *Handler) OnChange(key string, ipPool *networkv1.IPPool) (*networkv1.IPP
 		ipv4Status = new(networkv1.IPv4Status)
 	}
 
+	now := time.Now()
+
+	// Quarantine the IP addresses declined by DHCP clients
+	for ip, mac := range ipv4Status.Declined {
+		if ipv4Status.Allocated[ip] == mac {
+			mark := util.NewQuarantinedMark(now)
+			until, _ := quarantinedUntil(ipPool, mark)
+			if err := h.quarantine(ipPool.Spec.NetworkName, ip, mac, until); err != nil {
+				return ipPool, err
+			}
+			ipv4Status.Allocated[ip] = mark
+			logrus.Infof("(ippool.OnChange) ip %s declined by %s was quarantined until %s in ipam %s", ip, mac, until.Format(time.RFC3339), ipPool.Spec.NetworkName)
+		} else {
+			logrus.Warningf("(ippool.OnChange) ignore declined ip %s which is not allocated to %s", ip, mac)
+		}
+		delete(ipv4Status.Declined, ip)
+	}
+	// For DeepEqual
+	if len(ipv4Status.Declined) == 0 {
+		ipv4Status.Declined = nil
+	}
+
+	// Forget the quarantined IP addresses taken out of the pool
+	for ip, val := range ipv4Status.Allocated {
+		if _, ok := quarantinedUntil(ipPool, val); !ok {
+			continue
+		}
+		if !util.IsIPInPool(ip, ipPool.Spec.IPv4Config.Pool) || util.IsIPExcluded(ip, ipPool.Spec.IPv4Config.Pool) {
+			delete(ipv4Status.Allocated, ip)
+			logrus.Infof("(ippool.OnChange) quarantined ip %s was taken out of ipam %s", ip, ipPool.Spec.NetworkName)
+		}
+	}
+
+	// Release the IP addresses whose quarantine periods are over, and come
+	// back for the rest when the next one is
+	next, err := h.expireQuarantined(ipPool, ipv4Status, now)
+	if err != nil {
+		return ipPool, err
+	}
+	if next > 0 {
+		h.ippoolController.EnqueueAfter(ipPool.Namespace, ipPool.Name, next)
+	}
+
+	if err := h.setAllocationStrategy(ipPool); err != nil {
+		return nil, err
+	}
+	lastAllocated, err := h.ipAllocator.GetLastAllocated(ipPool.Spec.NetworkName)
+	if err != nil {
+		return nil, err
+	}
+	ipv4Status.LastAllocated = lastAllocated
+
 	used, err := h.ipAllocator.GetUsed(ipPool.Spec.NetworkName)
 	if err != nil {
 		return nil, err
@@ -217,10 +354,17 @@ func (h *Handler) OnChange(key string, ipPool *networkv1.IPPool) (*networkv1.IPP
 	if allocated == nil {
 		allocated = make(map[string]string)
 	}
-	if util.IsIPInBetweenOf(ipPool.Spec.IPv4Config.ServerIP, ipPool.Spec.IPv4Config.Pool.Start, ipPool.Spec.IPv4Config.Pool.End) {
+	// Mark the reserved and excluded IP addresses afresh for the pool may
+	// have been changed
+	for ip, val := range allocated {
+		if val == util.ReservedMark || val == util.ExcludedMark {
+			delete(allocated, ip)
+		}
+	}
+	if util.IsIPInPool(ipPool.Spec.IPv4Config.ServerIP, ipPool.Spec.IPv4Config.Pool) {
 		allocated[ipPool.Spec.IPv4Config.ServerIP] = util.ReservedMark
 	}
-	if util.IsIPInBetweenOf(ipPool.Spec.IPv4Config.Router, ipPool.Spec.IPv4Config.Pool.Start, ipPool.Spec.IPv4Config.Pool.End) {
+	if util.IsIPInPool(ipPool.Spec.IPv4Config.Router, ipPool.Spec.IPv4Config.Pool) {
 		allocated[ipPool.Spec.IPv4Config.Router] = util.ReservedMark
 	}
 	for _, eIP := range ipPool.Spec.IPv4Config.Pool.Exclude {
@@ -232,8 +376,47 @@ func (h *Handler) OnChange(key string, ipPool *networkv1.IPPool) (*networkv1.IPP
 	}
 	ipv4Status.Allocated = allocated
 
+	// Drop the lease activities of the clients no longer having IP addresses
+	// allocated, and count the clients holding their leases
+	allocatedMACs := make(map[string]bool, len(allocated))
+	for _, mac := range allocated {
+		allocatedMACs[mac] = true
+	}
+	bound := 0
+	for mac, activity := range ipv4Status.Leases {
+		if !allocatedMACs[mac] {
+			delete(ipv4Status.Leases, mac)
+			continue
+		}
+		if activity.State == util.LeaseStateBound {
+			bound++
+		}
+	}
+	// For DeepEqual
+	if len(ipv4Status.Leases) == 0 {
+		ipv4Status.Leases = nil
+	}
+	ipv4Status.Bound = bound
+
+	if err := h.updatePendingReclaims(ipPool, ipv4Status, now); err != nil {
+		return nil, err
+	}
+
 	ipPoolCpy.Status.IPv4 = ipv4Status
 
+	if ipPool.Spec.IPv6Config != nil {
+		ipv6Status, next, err := h.getIPv6Status(ipPool, now)
+		if err != nil {
+			return nil, err
+		}
+		if next > 0 {
+			h.ippoolController.EnqueueAfter(ipPool.Namespace, ipPool.Name, next)
+		}
+		ipPoolCpy.Status.IPv6 = ipv6Status
+	} else {
+		ipPoolCpy.Status.IPv6 = nil
+	}
+
 	if !reflect.DeepEqual(ipPoolCpy, ipPool) {
 		logrus.Infof("(ippool.OnChange) update ippool %s/%s", ipPool.Namespace, ipPool.Name)
 		ipPoolCpy.Status.LastUpdate = metav1.Now()
@@ -250,10 +433,6 @@ func (h *Handler) OnRemove(key string, ipPool *networkv1.IPPool) (*networkv1.IPP
 
 	logrus.Debugf("(ippool.OnRemove) ippool configuration %s/%s has been removed", ipPool.Namespace, ipPool.Name)
 
-	if h.noAgent {
-		return ipPool, nil
-	}
-
 	if err := h.cleanup(ipPool); err != nil {
 		return ipPool, err
 	}
@@ -261,8 +440,8 @@ func (h *Handler) OnRemove(key string, ipPool *networkv1.IPPool) (*networkv1.IPP
 	return ipPool, nil
 }
 
-// DeployAgent reconciles ipPool and ensures there's an agent pod for it. The
-// returned status reports whether an agent pod is registered.
+// DeployAgent reconciles ipPool and ensures there's an agent deployment for
+// it. The returned status reports whether an agent deployment is registered.
 func (h *Handler) DeployAgent(ipPool *networkv1.IPPool, status networkv1.IPPoolStatus) (networkv1.IPPoolStatus, error) {
 	logrus.Debugf("(ippool.DeployAgent) deploy agent for ippool %s/%s", ipPool.Namespace, ipPool.Name)
 
@@ -274,6 +453,11 @@ func (h *Handler) DeployAgent(ipPool *networkv1.IPPool, status networkv1.IPPoolS
 		return status, nil
 	}
 
+	// IPPools served through DHCP relay agents have no agents of their own
+	if ipPool.Spec.ServedBy != "" {
+		return h.removeAgent(ipPool, status)
+	}
+
 	nadNamespace, nadName := kv.RSplit(ipPool.Spec.NetworkName, "/")
 	nad, err := h.nadCache.Get(nadNamespace, nadName)
 	if err != nil {
@@ -289,56 +473,131 @@ func (h *Handler) DeployAgent(ipPool *networkv1.IPPool, status networkv1.IPPoolS
 		return status, fmt.Errorf("could not find clusternetwork for nad %s", ipPool.Spec.NetworkName)
 	}
 
-	if ipPool.Status.AgentPodRef != nil {
-		status.AgentPodRef.Image = h.getAgentImage(ipPool)
-		pod, err := h.podCache.Get(ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name)
-		if err != nil {
-			if !apierrors.IsNotFound(err) {
-				return status, err
-			}
+	agent, err := prepareAgentDeployment(ipPool, h.noDHCP, h.agentNamespace, clusterNetwork, h.agentServiceAccountName, h.agentImage, h.agentPodTemplate, h.agentReplicas, h.agentRolloutStrategy)
+	if err != nil {
+		return status, err
+	}
 
-			logrus.Warningf("(ippool.DeployAgent) agent pod %s missing, redeploying", ipPool.Status.AgentPodRef.Name)
-		} else {
-			if pod.DeletionTimestamp != nil {
-				return status, fmt.Errorf("agent pod %s marked for deletion", ipPool.Status.AgentPodRef.Name)
-			}
+	agentDeployment, err := h.deploymentCache.Get(agent.Namespace, agent.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return status, err
+		}
 
-			if pod.GetUID() != ipPool.Status.AgentPodRef.UID {
-				return status, fmt.Errorf("agent pod %s uid mismatch", ipPool.Status.AgentPodRef.Name)
-			}
+		// The agent of ipPool might still be a bare pod from before agents
+		// were deployed as Deployments, which must give up the server IP
+		if err := h.removeLegacyAgentPod(status.AgentPodRef); err != nil {
+			return status, err
+		}
 
-			return status, nil
+		agentDeployment, err = h.deploymentClient.Create(agent)
+		if err != nil {
+			return status, err
 		}
-	}
 
-	agent, err := prepareAgentPod(ipPool, h.noDHCP, h.agentNamespace, clusterNetwork, h.agentServiceAccountName, h.agentImage)
-	if err != nil {
-		return status, err
+		logrus.Infof("(ippool.DeployAgent) agent for ippool %s/%s has been deployed", ipPool.Namespace, ipPool.Name)
+	} else {
+		if agentDeployment.DeletionTimestamp != nil {
+			return status, fmt.Errorf("agent deployment %s marked for deletion", agentDeployment.Name)
+		}
+
+		agentDeployment, err = h.updateAgentDeployment(ipPool, agentDeployment, agent)
+		if err != nil {
+			return status, err
+		}
 	}
 
 	if status.AgentPodRef == nil {
 		status.AgentPodRef = new(networkv1.PodReference)
 	}
 
-	status.AgentPodRef.Image = h.agentImage.String()
+	status.AgentPodRef.Namespace = agentDeployment.Namespace
+	status.AgentPodRef.Name = agentDeployment.Name
+	status.AgentPodRef.UID = agentDeployment.GetUID()
 
-	agentPod, err := h.podClient.Create(agent)
-	if err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			return status, nil
-		}
-		return status, err
+	// The image of a paused agent deployment is yet to be rolled out
+	if !agentDeployment.Spec.Paused || status.AgentPodRef.Image == "" {
+		status.AgentPodRef.Image = agentImageOf(agentDeployment)
 	}
 
-	logrus.Infof("(ippool.DeployAgent) agent for ippool %s/%s has been deployed", ipPool.Namespace, ipPool.Name)
+	return status, nil
+}
+
+// removeAgent removes the agent deployment of ipPool, if any, e.g., once
+// ipPool is served by the agent of another IPPool.
+func (h *Handler) removeAgent(ipPool *networkv1.IPPool, status networkv1.IPPoolStatus) (networkv1.IPPoolStatus, error) {
+	if status.AgentPodRef == nil {
+		return status, nil
+	}
+
+	logrus.Infof("(ippool.removeAgent) remove the agent %s/%s of ippool %s/%s served by %s", status.AgentPodRef.Namespace, status.AgentPodRef.Name, ipPool.Namespace, ipPool.Name, ipPool.Spec.ServedBy)
+	if err := h.deploymentClient.Delete(status.AgentPodRef.Namespace, status.AgentPodRef.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return status, err
+	}
+	if err := h.removeLegacyAgentPod(status.AgentPodRef); err != nil {
+		return status, err
+	}
 
-	status.AgentPodRef.Namespace = agentPod.Namespace
-	status.AgentPodRef.Name = agentPod.Name
-	status.AgentPodRef.UID = agentPod.GetUID()
+	status.AgentPodRef = nil
+	status.Agent = nil
 
 	return status, nil
 }
 
+// removeLegacyAgentPod removes the bare agent pod referred to by agentPodRef,
+// if any. Agents used to be deployed as bare pods, named the same as the agent
+// deployments are, and they're left behind by upgrades otherwise.
+func (h *Handler) removeLegacyAgentPod(agentPodRef *networkv1.PodReference) error {
+	if agentPodRef == nil {
+		return nil
+	}
+
+	if err := h.podClient.Delete(agentPodRef.Namespace, agentPodRef.Name, &metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	logrus.Infof("(ippool.removeLegacyAgentPod) legacy agent pod %s/%s has been removed", agentPodRef.Namespace, agentPodRef.Name)
+
+	return nil
+}
+
+// updateAgentDeployment updates agentDeployment in place to match agent, the
+// desired one, leaving it to the Deployment to roll out the changes. The
+// rollout is paused for as long as ipPool holds back agent upgrades, in which
+// case changes to the number of replicas are held back as well, since they
+// come along with changes to the pod template.
+func (h *Handler) updateAgentDeployment(ipPool *networkv1.IPPool, agentDeployment, agent *appsv1.Deployment) (*appsv1.Deployment, error) {
+	_, paused := ipPool.Annotations[holdIPPoolAgentUpgradeAnnotationKey]
+
+	agentDeploymentCpy := agentDeployment.DeepCopy()
+	agentDeploymentCpy.Spec.Paused = paused
+	if !paused {
+		agentDeploymentCpy.Spec.Replicas = agent.Spec.Replicas
+	}
+	agentDeploymentCpy.Spec.Strategy = agent.Spec.Strategy
+	if agentDeployment.Annotations[agentTemplateHashAnnotationKey] != agent.Annotations[agentTemplateHashAnnotationKey] {
+		if agentDeploymentCpy.Annotations == nil {
+			agentDeploymentCpy.Annotations = make(map[string]string)
+		}
+		agentDeploymentCpy.Annotations[agentTemplateHashAnnotationKey] = agent.Annotations[agentTemplateHashAnnotationKey]
+		agentDeploymentCpy.Spec.Template = agent.Spec.Template
+	}
+
+	if agentDeploymentCpy.Spec.Paused == agentDeployment.Spec.Paused &&
+		replicasOf(agentDeploymentCpy) == replicasOf(agentDeployment) &&
+		agentDeploymentCpy.Spec.Strategy.Type == agentDeployment.Spec.Strategy.Type &&
+		agentDeploymentCpy.Annotations[agentTemplateHashAnnotationKey] == agentDeployment.Annotations[agentTemplateHashAnnotationKey] {
+		return agentDeployment, nil
+	}
+
+	logrus.Infof("(ippool.updateAgentDeployment) update agent deployment %s/%s for ippool %s/%s", agentDeployment.Namespace, agentDeployment.Name, ipPool.Namespace, ipPool.Name)
+
+	return h.deploymentClient.Update(agentDeploymentCpy)
+}
+
 // BuildCache reconciles ipPool and initializes the IPAM and MAC caches for it.
 // The source information comes from both ipPool's spec and status. Since
 // IPPool objects are deemed source of truths, BuildCache honors the state and
@@ -351,8 +610,9 @@ func (h *Handler) BuildCache(ipPool *networkv1.IPPool, status networkv1.IPPoolSt
 		return status, fmt.Errorf("ippool %s/%s was administratively disabled", ipPool.Namespace, ipPool.Name)
 	}
 
+	// Apply the changes of the pool to the up-and-running caches
 	if networkv1.CacheReady.IsTrue(ipPool) {
-		return status, nil
+		return status, h.updateCache(ipPool)
 	}
 
 	logrus.Infof("(ippool.BuildCache) initialize ipam for ippool %s/%s", ipPool.Namespace, ipPool.Name)
@@ -361,10 +621,15 @@ func (h *Handler) BuildCache(ipPool *networkv1.IPPool, status networkv1.IPPoolSt
 		ipPool.Spec.IPv4Config.CIDR,
 		ipPool.Spec.IPv4Config.Pool.Start,
 		ipPool.Spec.IPv4Config.Pool.End,
+		ipamRanges(ipPool.Spec.IPv4Config.Pool)...,
 	); err != nil {
 		return status, err
 	}
 
+	if err := h.setAllocationStrategy(ipPool); err != nil {
+		return status, err
+	}
+
 	logrus.Infof("(ippool.BuildCache) initialize mac cache for ippool %s/%s", ipPool.Namespace, ipPool.Name)
 	if err := h.cacheAllocator.NewMACSet(ipPool.Spec.NetworkName); err != nil {
 		return status, err
@@ -396,6 +661,17 @@ func (h *Handler) BuildCache(ipPool *networkv1.IPPool, status networkv1.IPPoolSt
 			if mac == util.ExcludedMark || mac == util.ReservedMark {
 				continue
 			}
+			if until, ok := quarantinedUntil(ipPool, mac); ok {
+				// The bare marks of earlier versions are stamped later on
+				if !until.IsZero() && !time.Now().Before(until) {
+					continue
+				}
+				if err := h.ipAllocator.QuarantineIPUntil(ipPool.Spec.NetworkName, ip, until); err != nil {
+					return status, err
+				}
+				logrus.Infof("(ippool.BuildCache) previously quarantined ip %s was re-quarantined in ipam %s", ip, ipPool.Spec.NetworkName)
+				continue
+			}
 			if _, err := h.ipAllocator.AllocateIP(ipPool.Spec.NetworkName, ip); err != nil {
 				return status, err
 			}
@@ -404,6 +680,21 @@ func (h *Handler) BuildCache(ipPool *networkv1.IPPool, status networkv1.IPPoolSt
 			}
 			logrus.Infof("(ippool.BuildCache) previously allocated ip %s was re-allocated in ipam %s", ip, ipPool.Spec.NetworkName)
 		}
+
+		if lastAllocated := ipPool.Status.IPv4.LastAllocated; lastAllocated != "" {
+			if err := h.ipAllocator.SetLastAllocated(ipPool.Spec.NetworkName, lastAllocated); err != nil {
+				return status, err
+			}
+			logrus.Infof("(ippool.BuildCache) last allocated ip %s was restored in ipam %s", lastAllocated, ipPool.Spec.NetworkName)
+		}
+	}
+
+	if err := h.syncReservations(ipPool); err != nil {
+		return status, err
+	}
+
+	if err := h.buildIPv6Cache(ipPool); err != nil {
+		return status, err
 	}
 
 	logrus.Infof("(ippool.BuildCache) ipam and mac cache %s for ippool %s/%s has been updated", ipPool.Spec.NetworkName, ipPool.Namespace, ipPool.Name)
@@ -411,10 +702,264 @@ func (h *Handler) BuildCache(ipPool *networkv1.IPPool, status networkv1.IPPoolSt
 	return status, nil
 }
 
-// MonitorAgent reconciles ipPool and keeps an eye on the agent pod. If the
-// running agent pod does not match to the one record in ipPool's status,
-// MonitorAgent tries to delete it. The returned status reports whether the
-// agent pod is ready.
+// updateCache applies the changes of the pool range and the excluded IP
+// addresses of ipPool to its IPAM without rebuilding it, so that the pool can
+// be widened or have IP addresses excluded or included again on the fly. The
+// same goes for the IPv6 configuration, which may also be added or removed.
+func (h *Handler) updateCache(ipPool *networkv1.IPPool) error {
+	// The IPAM is yet to be rebuilt, e.g., after a restart
+	if !h.ipAllocator.IsNetworkInitialized(ipPool.Spec.NetworkName) {
+		return nil
+	}
+
+	if err := h.ipAllocator.UpdateIPSubnet(
+		ipPool.Spec.NetworkName,
+		ipPool.Spec.IPv4Config.Pool.Start,
+		ipPool.Spec.IPv4Config.Pool.End,
+		ipamRanges(ipPool.Spec.IPv4Config.Pool)...,
+	); err != nil {
+		return err
+	}
+
+	toRevoke := map[string]bool{
+		ipPool.Spec.IPv4Config.ServerIP: true,
+	}
+	if ipPool.Spec.IPv4Config.Router != "" {
+		toRevoke[ipPool.Spec.IPv4Config.Router] = true
+	}
+	for _, eIP := range ipPool.Spec.IPv4Config.Pool.Exclude {
+		toRevoke[eIP] = true
+	}
+
+	revoked, err := h.ipAllocator.ListRevoked(ipPool.Spec.NetworkName)
+	if err != nil {
+		return err
+	}
+	isRevoked := make(map[string]bool, len(revoked))
+	for _, ip := range revoked {
+		isRevoked[ip] = true
+		if toRevoke[ip] {
+			continue
+		}
+		if err := h.ipAllocator.UnrevokeIP(ipPool.Spec.NetworkName, ip); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.updateCache) no longer excluded ip %s was returned to ipam %s", ip, ipPool.Spec.NetworkName)
+	}
+
+	for ip := range toRevoke {
+		if isRevoked[ip] || !util.IsIPInPool(ip, ipPool.Spec.IPv4Config.Pool) {
+			continue
+		}
+		if err := h.ipAllocator.RevokeIP(ipPool.Spec.NetworkName, ip); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.updateCache) ip %s was revoked in ipam %s", ip, ipPool.Spec.NetworkName)
+	}
+
+	if err := h.syncReservations(ipPool); err != nil {
+		return err
+	}
+
+	return h.updateIPv6Cache(ipPool)
+}
+
+// updateIPv6Cache initializes, updates or removes the IPv6 IPAM of ipPool as
+// its IPv6 configuration is added, changed or removed.
+func (h *Handler) updateIPv6Cache(ipPool *networkv1.IPPool) error {
+	ipv6Config := ipPool.Spec.IPv6Config
+	isInitialized := h.ipAllocator.IsIPv6NetworkInitialized(ipPool.Spec.NetworkName)
+
+	if ipv6Config == nil {
+		if isInitialized {
+			h.ipAllocator.DeleteIPv6Subnet(ipPool.Spec.NetworkName)
+			logrus.Infof("(ippool.updateIPv6Cache) ipv6 ipam for ippool %s/%s has been removed", ipPool.Namespace, ipPool.Name)
+		}
+		return nil
+	}
+
+	if !isInitialized {
+		return h.buildIPv6Cache(ipPool)
+	}
+
+	return h.ipAllocator.UpdateIPv6Subnet(
+		ipPool.Spec.NetworkName,
+		ipv6Config.Prefix,
+		ipv6Config.Pool.Start,
+		ipv6Config.Pool.End,
+		ipv6Config.Pool.Exclude,
+	)
+}
+
+// syncReservations makes the IPAM hold the IP addresses of the reservations of
+// ipPool for their owners, and no others.
+func (h *Handler) syncReservations(ipPool *networkv1.IPPool) error {
+	toReserve := make(map[string]string, len(ipPool.Spec.IPv4Config.Reservations))
+	for _, r := range ipPool.Spec.IPv4Config.Reservations {
+		toReserve[r.IPAddress] = util.ReservationOwner(r)
+	}
+
+	reserved, err := h.ipAllocator.ListReserved(ipPool.Spec.NetworkName)
+	if err != nil {
+		return err
+	}
+	for ip := range reserved {
+		if _, ok := toReserve[ip]; ok {
+			continue
+		}
+		if err := h.ipAllocator.UnreserveIP(ipPool.Spec.NetworkName, ip); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.syncReservations) ip %s is no longer reserved in ipam %s", ip, ipPool.Spec.NetworkName)
+	}
+
+	for ip, owner := range toReserve {
+		if reserved[ip] == owner {
+			continue
+		}
+		if err := h.ipAllocator.ReserveIP(ipPool.Spec.NetworkName, ip, owner); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.syncReservations) ip %s was reserved for %s in ipam %s", ip, owner, ipPool.Spec.NetworkName)
+	}
+
+	return nil
+}
+
+// ipamRanges returns the ranges of pool besides the one from the start to the
+// end IP address for the IPAM.
+func ipamRanges(pool networkv1.Pool) []ipam.IPRange {
+	ranges := make([]ipam.IPRange, 0, len(pool.Ranges))
+	for _, r := range pool.Ranges {
+		ranges = append(ranges, ipam.IPRange{Start: r.Start, End: r.End})
+	}
+	return ranges
+}
+
+// getIPv6Status returns the IPv6 status of the dual-stack IPPool based on
+// the up-to-date IPv6 IPAM, and how long it is until the quarantine period of
+// the next declined IPv6 address is over.
+func (h *Handler) getIPv6Status(ipPool *networkv1.IPPool, now time.Time) (*networkv1.IPv6Status, time.Duration, error) {
+	ipv6Status := ipPool.Status.IPv6.DeepCopy()
+	if ipv6Status == nil {
+		ipv6Status = new(networkv1.IPv6Status)
+	}
+
+	allocated := ipv6Status.Allocated
+	if allocated == nil {
+		allocated = make(map[string]string)
+	}
+
+	// Quarantine the IPv6 addresses declined by DHCPv6 clients
+	for ip, mac := range ipv6Status.Declined {
+		if allocated[ip] == mac {
+			if err := h.ipAllocator.QuarantineIPv6(ipPool.Spec.NetworkName, ip); err != nil {
+				return nil, 0, err
+			}
+			allocated[ip] = util.NewQuarantinedMark(now)
+			logrus.Infof("(ippool.getIPv6Status) ip %s declined by %s was quarantined in ipv6 ipam %s", ip, mac, ipPool.Spec.NetworkName)
+		} else {
+			logrus.Warningf("(ippool.getIPv6Status) ignore declined ip %s which is not allocated to %s", ip, mac)
+		}
+	}
+	ipv6Status.Declined = nil
+
+	// Forget the quarantined IPv6 addresses taken out of the pool
+	for ip, val := range allocated {
+		if _, ok := util.ParseQuarantinedMark(val); !ok {
+			continue
+		}
+		if quarantined, err := h.ipAllocator.IsIPv6Quarantined(ipPool.Spec.NetworkName, ip); err != nil {
+			return nil, 0, err
+		} else if !quarantined {
+			delete(allocated, ip)
+			logrus.Infof("(ippool.getIPv6Status) quarantined ip %s was taken out of ipv6 ipam %s", ip, ipPool.Spec.NetworkName)
+		}
+	}
+
+	// Release the IPv6 addresses whose quarantine periods are over
+	next, err := h.expireQuarantinedIPv6(ipPool, allocated, now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	used, err := h.ipAllocator.GetIPv6Used(ipPool.Spec.NetworkName)
+	if err != nil {
+		return nil, 0, err
+	}
+	ipv6Status.Used = used
+
+	for _, eIP := range ipPool.Spec.IPv6Config.Pool.Exclude {
+		allocated[eIP] = util.ExcludedMark
+	}
+	// For DeepEqual
+	if len(allocated) == 0 {
+		allocated = nil
+	}
+	ipv6Status.Allocated = allocated
+
+	return ipv6Status, next, nil
+}
+
+// buildIPv6Cache initializes the IPv6 IPAM of dual-stack IPPools and
+// re-allocates the IPv6 addresses recorded in the IPPool status.
+func (h *Handler) buildIPv6Cache(ipPool *networkv1.IPPool) error {
+	ipv6Config := ipPool.Spec.IPv6Config
+	if ipv6Config == nil {
+		return nil
+	}
+
+	logrus.Infof("(ippool.buildIPv6Cache) initialize ipv6 ipam for ippool %s/%s", ipPool.Namespace, ipPool.Name)
+	if err := h.ipAllocator.NewIPv6Subnet(
+		ipPool.Spec.NetworkName,
+		ipv6Config.Prefix,
+		ipv6Config.Pool.Start,
+		ipv6Config.Pool.End,
+	); err != nil {
+		return err
+	}
+
+	// Revoke excluded IPv6 addresses in IPAM
+	for _, eIP := range ipv6Config.Pool.Exclude {
+		if err := h.ipAllocator.RevokeIPv6(ipPool.Spec.NetworkName, eIP); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.buildIPv6Cache) excluded ip %s was revoked in ipv6 ipam %s", eIP, ipPool.Spec.NetworkName)
+	}
+
+	if ipPool.Status.IPv6 == nil {
+		return nil
+	}
+
+	for ip, mac := range ipPool.Status.IPv6.Allocated {
+		if mac == util.ExcludedMark {
+			continue
+		}
+		if declinedAt, ok := util.ParseQuarantinedMark(mac); ok {
+			if !declinedAt.IsZero() && !time.Now().Before(declinedAt.Add(declineQuarantineDuration(ipPool))) {
+				continue
+			}
+			// The ones no longer in the pool are forgotten later on
+			if err := h.ipAllocator.QuarantineIPv6(ipPool.Spec.NetworkName, ip); err != nil {
+				logrus.Warningf("(ippool.buildIPv6Cache) skip quarantined ip %s: %v", ip, err)
+				continue
+			}
+			logrus.Infof("(ippool.buildIPv6Cache) previously quarantined ip %s was re-quarantined in ipv6 ipam %s", ip, ipPool.Spec.NetworkName)
+			continue
+		}
+		if _, err := h.ipAllocator.AllocateIPv6(ipPool.Spec.NetworkName, ip); err != nil {
+			return err
+		}
+		logrus.Infof("(ippool.buildIPv6Cache) previously allocated ip %s was re-allocated in ipv6 ipam %s", ip, ipPool.Spec.NetworkName)
+	}
+
+	return nil
+}
+
+// MonitorAgent reconciles ipPool and keeps an eye on the agent deployment.
+// Restarting and upgrading the agent pods is left to the Deployment. The
+// returned status reports how many agent replicas are ready and which one of
+// them is the leader.
 func (h *Handler) MonitorAgent(ipPool *networkv1.IPPool, status networkv1.IPPoolStatus) (networkv1.IPPoolStatus, error) {
 	logrus.Debugf("(ippool.MonitorAgent) monitor agent for ippool %s/%s", ipPool.Namespace, ipPool.Name)
 
@@ -422,7 +967,7 @@ func (h *Handler) MonitorAgent(ipPool *networkv1.IPPool, status networkv1.IPPool
 		return status, fmt.Errorf("ippool %s/%s was administratively disabled", ipPool.Namespace, ipPool.Name)
 	}
 
-	if h.noAgent {
+	if h.noAgent || ipPool.Spec.ServedBy != "" {
 		return status, nil
 	}
 
@@ -430,58 +975,159 @@ func (h *Handler) MonitorAgent(ipPool *networkv1.IPPool, status networkv1.IPPool
 		return status, fmt.Errorf("agent for ippool %s/%s is not deployed", ipPool.Namespace, ipPool.Name)
 	}
 
-	agentPod, err := h.podCache.Get(ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name)
+	agentDeployment, err := h.deploymentCache.Get(ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name)
 	if err != nil {
 		return status, err
 	}
 
-	if agentPod.GetUID() != ipPool.Status.AgentPodRef.UID || agentPod.Spec.Containers[0].Image != ipPool.Status.AgentPodRef.Image {
-		if agentPod.DeletionTimestamp != nil {
-			return status, fmt.Errorf("agent pod %s marked for deletion", agentPod.Name)
-		}
+	if agentDeployment.Status.ReadyReplicas == 0 {
+		return status, fmt.Errorf("agent deployment %s has no ready replicas", agentDeployment.Name)
+	}
+
+	status.Agent = &networkv1.AgentStatus{
+		Replicas:      replicasOf(agentDeployment),
+		ReadyReplicas: agentDeployment.Status.ReadyReplicas,
+	}
 
-		if err := h.podClient.Delete(agentPod.Namespace, agentPod.Name, &metav1.DeleteOptions{}); err != nil {
+	// Agents elect a leader with more than one replica, or when they're
+	// rolled out with RollingUpdate
+	if status.Agent.Replicas > 1 || agentDeployment.Spec.Strategy.Type == appsv1.RollingUpdateDeploymentStrategyType {
+		leader, err := h.getAgentLeader(agentDeployment)
+		if err != nil {
 			return status, err
 		}
+		status.Agent.Leader = leader
+	}
+
+	return status, nil
+}
+
+// getAgentLeader returns the name of the agent pod holding the lease of
+// agentDeployment, which must not have expired.
+func (h *Handler) getAgentLeader(agentDeployment *appsv1.Deployment) (string, error) {
+	lease, err := h.leaseCache.Get(agentDeployment.Namespace, agentDeployment.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("agent deployment %s has no leader", agentDeployment.Name)
+		}
+		return "", err
+	}
 
-		return status, fmt.Errorf("agent pod %s obsolete and purged", agentPod.Name)
+	leader := leaseHolder(lease)
+	if leader == "" {
+		return "", fmt.Errorf("agent deployment %s has no leader", agentDeployment.Name)
 	}
 
-	if !isPodReady(agentPod) {
-		return status, fmt.Errorf("agent pod %s not ready", agentPod.Name)
+	if lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil {
+		expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if time.Now().After(expiry) {
+			return "", fmt.Errorf("leader %s of agent deployment %s has not renewed its lease", leader, agentDeployment.Name)
+		}
 	}
 
-	return status, nil
+	return leader, nil
+}
+
+func leaseHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func replicasOf(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
 }
 
-func isPodReady(pod *corev1.Pod) bool {
-	for _, c := range pod.Status.Conditions {
-		if c.Type == corev1.PodReady {
-			return c.Status == corev1.ConditionTrue
+// agentImageOf returns the image of the agent container of deployment.
+func agentImageOf(deployment *appsv1.Deployment) string {
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == "agent" {
+			return container.Image
 		}
 	}
-	return false
+	return ""
 }
 
-func (h *Handler) getAgentImage(ipPool *networkv1.IPPool) string {
-	_, ok := ipPool.Annotations[holdIPPoolAgentUpgradeAnnotationKey]
-	if ok {
-		return ipPool.Status.AgentPodRef.Image
+// setAllocationStrategy applies the allocation strategy of ipPool to its
+// IPAM.
+func (h *Handler) setAllocationStrategy(ipPool *networkv1.IPPool) error {
+	strategy, err := ipam.NewAllocationStrategy(string(ipPool.Spec.IPv4Config.AllocationStrategy))
+	if err != nil {
+		return err
 	}
-	return h.agentImage.String()
+	return h.ipAllocator.SetAllocationStrategy(ipPool.Spec.NetworkName, strategy)
 }
 
-func (h *Handler) cleanup(ipPool *networkv1.IPPool) error {
-	if ipPool.Status.AgentPodRef == nil {
-		return nil
+// quarantine takes ipAddress, which was declined by macAddress, out of
+// circulation until the given time. The MAC address is dropped from the cache
+// so that the corresponding vmnetcfg gets a new IP address allocated.
+func (h *Handler) quarantine(networkName, ipAddress, macAddress string, until time.Time) error {
+	isAllocated, err := h.ipAllocator.IsAllocated(networkName, ipAddress)
+	if err != nil {
+		return err
+	}
+	if isAllocated {
+		if err := h.ipAllocator.DeallocateIP(networkName, ipAddress); err != nil {
+			return err
+		}
 	}
 
-	logrus.Infof("(ippool.cleanup) remove the backing agent %s/%s for ippool %s/%s", ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name, ipPool.Namespace, ipPool.Name)
-	if err := h.podClient.Delete(ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+	if err := h.cacheAllocator.DeleteMAC(networkName, macAddress); err != nil {
 		return err
 	}
 
+	return h.ipAllocator.QuarantineIPUntil(networkName, ipAddress, until)
+}
+
+// OnAgentChange removes the agent deployments whose IPPools are gone. Agent
+// deployments are owned by their IPPools only if they're in the same
+// namespace, so the rest would otherwise be left behind whenever the IPPools
+// are removed without cleanup, e.g., in no-agent mode.
+func (h *Handler) OnAgentChange(key string, deployment *appsv1.Deployment) (*appsv1.Deployment, error) {
+	if deployment == nil || deployment.DeletionTimestamp != nil {
+		return deployment, nil
+	}
+
+	if deployment.Labels[vmDHCPControllerLabelKey] != "agent" {
+		return deployment, nil
+	}
+
+	ipPoolNamespace, ipPoolName := deployment.Labels[ipPoolNamespaceLabelKey], deployment.Labels[ipPoolNameLabelKey]
+	if ipPoolNamespace == "" || ipPoolName == "" {
+		return deployment, nil
+	}
+
+	if _, err := h.ippoolCache.Get(ipPoolNamespace, ipPoolName); err == nil || !apierrors.IsNotFound(err) {
+		return deployment, err
+	}
+
+	logrus.Infof("(ippool.OnAgentChange) remove the agent %s of removed ippool %s/%s", key, ipPoolNamespace, ipPoolName)
+	if err := h.deploymentClient.Delete(deployment.Namespace, deployment.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return deployment, err
+	}
+
+	return nil, nil
+}
+
+// cleanup removes the agent deployment of ipPool, which is not garbage
+// collected unless it's owned by ipPool, and drops ipPool from the caches.
+func (h *Handler) cleanup(ipPool *networkv1.IPPool) error {
+	if !h.noAgent && ipPool.Status.AgentPodRef != nil {
+		logrus.Infof("(ippool.cleanup) remove the backing agent %s/%s for ippool %s/%s", ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name, ipPool.Namespace, ipPool.Name)
+		if err := h.deploymentClient.Delete(ipPool.Status.AgentPodRef.Namespace, ipPool.Status.AgentPodRef.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := h.removeLegacyAgentPod(ipPool.Status.AgentPodRef); err != nil {
+			return err
+		}
+	}
+
 	h.ipAllocator.DeleteIPSubnet(ipPool.Spec.NetworkName)
+	h.ipAllocator.DeleteIPv6Subnet(ipPool.Spec.NetworkName)
 	h.cacheAllocator.DeleteMACSet(ipPool.Spec.NetworkName)
 	h.metricsAllocator.DeleteIPPool(
 		ipPool.Namespace+"/"+ipPool.Name,
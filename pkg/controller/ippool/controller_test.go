@@ -3,13 +3,17 @@ package ippool
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/cache"
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
 	"github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/fake"
@@ -47,6 +51,7 @@ const (
 	testImage              = testImageRepository + ":" + testImageTag
 	testImageNew           = testImageRepository + ":" + testImageTagNew
 	testContainerName      = "agent"
+	testLeaderName         = testPodName + "-5d8f7c9b4-x2x7q"
 
 	testExcludedIP1 = "192.168.0.150"
 	testExcludedIP2 = "192.168.0.187"
@@ -75,8 +80,8 @@ func newTestIPPoolBuilder() *IPPoolBuilder {
 	return NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName)
 }
 
-func newTestPodBuilder() *podBuilder {
-	return newPodBuilder(testPodNamespace, testPodName)
+func newTestDeploymentBuilder() *deploymentBuilder {
+	return newDeploymentBuilder(testPodNamespace, testPodName)
 }
 
 func newTestIPPoolStatusBuilder() *ipPoolStatusBuilder {
@@ -170,6 +175,58 @@ func TestHandler_OnChange(t *testing.T) {
 		assert.Equal(t, expectedIPPool, ipPool)
 	})
 
+	t.Run("pool range and excluded ips changed", func(t *testing.T) {
+		key := testIPPoolNamespace + "/" + testIPPoolName
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, "192.168.0.254").
+			Revoke(testNetworkName, testExcludedIP2).
+			Allocate(testNetworkName, testAllocatedIP1).
+			Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, "192.168.0.254").
+			Exclude(testExcludedIP2).
+			NetworkName(testNetworkName).
+			Allocated(testExcludedIP1, util.ExcludedMark).
+			Allocated(testExcludedIP2, util.QuarantinedMark).
+			Allocated(testAllocatedIP1, testMAC1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, "192.168.0.254").
+			Exclude(testExcludedIP2).
+			NetworkName(testNetworkName).
+			Allocated(testExcludedIP2, util.ExcludedMark).
+			Allocated(testAllocatedIP1, testMAC1).
+			Available(152).
+			Used(1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").
+			StoppedCondition(corev1.ConditionFalse, "", "").Build()
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Add(givenIPPool)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		handler := Handler{
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+		}
+
+		ipPool, err := handler.OnChange(key, givenIPPool)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedIPPool.Status)
+		SanitizeStatus(&ipPool.Status)
+
+		assert.Equal(t, expectedIPPool, ipPool)
+	})
+
 	t.Run("pause ippool", func(t *testing.T) {
 		key := testIPPoolNamespace + "/" + testIPPoolName
 		givenIPAllocator := newTestIPAllocatorBuilder().
@@ -178,7 +235,7 @@ func TestHandler_OnChange(t *testing.T) {
 			NetworkName(testNetworkName).
 			Paused().
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		givenPod := newTestPodBuilder().Build()
+		givenDeployment := newTestDeploymentBuilder().Build()
 
 		expectedIPAllocator := newTestIPAllocatorBuilder().Build()
 		expectedIPPool := newTestIPPoolBuilder().
@@ -193,7 +250,7 @@ func TestHandler_OnChange(t *testing.T) {
 		}
 
 		k8sclientset := k8sfake.NewSimpleClientset()
-		err = k8sclientset.Tracker().Add(givenPod)
+		err = k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
@@ -206,6 +263,7 @@ func TestHandler_OnChange(t *testing.T) {
 			cacheAllocator:   cache.New(),
 			metricsAllocator: metrics.New(),
 			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			deploymentClient: fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
 			podClient:        fakeclient.PodClient(k8sclientset.CoreV1().Pods),
 		}
 
@@ -219,8 +277,8 @@ func TestHandler_OnChange(t *testing.T) {
 
 		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
 
-		_, err = handler.podClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
-		assert.Equal(t, fmt.Sprintf("pods \"%s\" not found", testPodName), err.Error())
+		_, err = handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Equal(t, fmt.Sprintf("deployments.apps \"%s\" not found", testPodName), err.Error())
 	})
 
 	t.Run("resume ippool", func(t *testing.T) {
@@ -266,6 +324,293 @@ func TestHandler_OnChange(t *testing.T) {
 
 		assert.Equal(t, expectedIPPool, ipPool)
 	})
+
+	t.Run("quarantine declined ip", func(t *testing.T) {
+		key := testIPPoolNamespace + "/" + testIPPoolName
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP1, testAllocatedIP2).
+			Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC1, testAllocatedIP1).
+			Add(testNetworkName, testMAC2, testAllocatedIP2).
+			Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).
+			Allocated(testAllocatedIP2, testMAC2).
+			Declined(testAllocatedIP1, testMAC1).
+			Declined(testAllocatedIP2, testMAC1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC2, testAllocatedIP2).
+			Build()
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP2, testMAC2).
+			Available(98).
+			Used(1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").
+			StoppedCondition(corev1.ConditionFalse, "", "").Build()
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Add(givenIPPool)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ippoolController := &fakeclient.IPPoolController{}
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolController: ippoolController,
+		}
+
+		ipPool, err := handler.OnChange(key, givenIPPool)
+		assert.Nil(t, err)
+
+		// The declined ip is quarantined for the default period since the
+		// decline
+		declinedAt, ok := util.ParseQuarantinedMark(ipPool.Status.IPv4.Allocated[testAllocatedIP1])
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now(), declinedAt, time.Minute)
+		expectedIPPool.Status.IPv4.Allocated[testAllocatedIP1] = util.NewQuarantinedMark(declinedAt)
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP2).
+			QuarantineUntil(testNetworkName, declinedAt.Add(time.Hour), testAllocatedIP1).
+			Build()
+
+		SanitizeStatus(&expectedIPPool.Status)
+		SanitizeStatus(&ipPool.Status)
+
+		assert.Equal(t, expectedIPPool, ipPool)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+		assert.Contains(t, ippoolController.EnqueuedAfter, key)
+		assert.LessOrEqual(t, ippoolController.EnqueuedAfter[key], time.Hour)
+	})
+
+	t.Run("quarantine declined ipv6 address", func(t *testing.T) {
+		key := testIPPoolNamespace + "/" + testIPPoolName
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP1).
+			IPv6Subnet(testNetworkName, "fd00:48::/64", "fd00:48::10", "fd00:48::20").
+			AllocateIPv6(testNetworkName, "fd00:48::10", "fd00:48::11").
+			Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC1, testAllocatedIP1).
+			Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			IPv6Config("fd00:48::/64", "fd00:48::10", "fd00:48::20").
+			Allocated(testAllocatedIP1, testMAC1).
+			AllocatedIPv6("fd00:48::10", testMAC1).
+			AllocatedIPv6("fd00:48::11", testMAC2).
+			DeclinedIPv6("fd00:48::10", testMAC1).
+			DeclinedIPv6("fd00:48::11", testMAC1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP1).
+			IPv6Subnet(testNetworkName, "fd00:48::/64", "fd00:48::10", "fd00:48::20").
+			AllocateIPv6(testNetworkName, "fd00:48::11").
+			QuarantineIPv6(testNetworkName, "fd00:48::10").
+			Build()
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			IPv6Config("fd00:48::/64", "fd00:48::10", "fd00:48::20").
+			Allocated(testAllocatedIP1, testMAC1).
+			AllocatedIPv6("fd00:48::11", testMAC2).
+			UsedIPv6(1).
+			Available(99).
+			Used(1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").
+			StoppedCondition(corev1.ConditionFalse, "", "").Build()
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Add(givenIPPool)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ippoolController := &fakeclient.IPPoolController{}
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolController: ippoolController,
+		}
+
+		ipPool, err := handler.OnChange(key, givenIPPool)
+		assert.Nil(t, err)
+
+		declinedAt, ok := util.ParseQuarantinedMark(ipPool.Status.IPv6.Allocated["fd00:48::10"])
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now(), declinedAt, time.Minute)
+		expectedIPPool.Status.IPv6.Allocated["fd00:48::10"] = util.NewQuarantinedMark(declinedAt)
+
+		SanitizeStatus(&expectedIPPool.Status)
+		SanitizeStatus(&ipPool.Status)
+
+		assert.Equal(t, expectedIPPool, ipPool)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Contains(t, ippoolController.EnqueuedAfter, key)
+	})
+
+	t.Run("aggregate lease activities", func(t *testing.T) {
+		key := testIPPoolNamespace + "/" + testIPPoolName
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP1).
+			Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC1, testAllocatedIP1).
+			Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).
+			Lease(testMAC1, "bound").
+			Lease(testMAC2, "bound").
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).
+			Lease(testMAC1, "bound").
+			Bound(1).
+			Available(99).
+			Used(1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").
+			StoppedCondition(corev1.ConditionFalse, "", "").Build()
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Add(givenIPPool)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+		}
+
+		ipPool, err := handler.OnChange(key, givenIPPool)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedIPPool.Status)
+		SanitizeStatus(&ipPool.Status)
+
+		assert.Equal(t, expectedIPPool, ipPool)
+	})
+}
+
+func TestHandler_OnRemove(t *testing.T) {
+	t.Run("ippool in no-agent mode removed", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			NetworkName(testNetworkName).
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).Build()
+
+		handler := Handler{
+			noAgent:          true,
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+		}
+
+		_, err := handler.OnRemove(testKey, givenIPPool)
+		assert.Nil(t, err)
+		assert.False(t, handler.ipAllocator.IsNetworkInitialized(testNetworkName))
+		_, err = handler.cacheAllocator.ListAll(testNetworkName)
+		assert.Equal(t, fmt.Sprintf("network %s does not exist", testNetworkName), err.Error())
+	})
+}
+
+func TestHandler_OnAgentChange(t *testing.T) {
+	tests := []struct {
+		name            string
+		givenDeployment *appsv1.Deployment
+		expectedRemoved bool
+	}{
+		{
+			name: "agent of existing ippool",
+			givenDeployment: newDeploymentBuilder(testPodNamespace, testPodName).
+				Label(vmDHCPControllerLabelKey, "agent").
+				Label(ipPoolNamespaceLabelKey, testIPPoolNamespace).
+				Label(ipPoolNameLabelKey, testIPPoolName).Build(),
+		},
+		{
+			name: "agent of removed ippool",
+			givenDeployment: newDeploymentBuilder(testPodNamespace, testPodName).
+				Label(vmDHCPControllerLabelKey, "agent").
+				Label(ipPoolNamespaceLabelKey, testIPPoolNamespace).
+				Label(ipPoolNameLabelKey, "removed").Build(),
+			expectedRemoved: true,
+		},
+		{
+			name: "deployment other than agent",
+			givenDeployment: newDeploymentBuilder(testPodNamespace, testPodName).
+				Label(ipPoolNamespaceLabelKey, testIPPoolNamespace).
+				Label(ipPoolNameLabelKey, "removed").Build(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			givenIPPool := newTestIPPoolBuilder().Build()
+
+			clientset := fake.NewSimpleClientset(givenIPPool)
+			k8sclientset := k8sfake.NewSimpleClientset(tc.givenDeployment)
+
+			handler := Handler{
+				ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+				deploymentClient: fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			}
+
+			_, err := handler.OnAgentChange(testPodNamespace+"/"+testPodName, tc.givenDeployment)
+			assert.Nil(t, err)
+
+			_, err = handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+			assert.Equal(t, tc.expectedRemoved, apierrors.IsNotFound(err))
+		})
+	}
 }
 
 func TestHandler_DeployAgent(t *testing.T) {
@@ -279,7 +624,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 
 		expectedStatus := newTestIPPoolStatusBuilder().
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		expectedPod, _ := prepareAgentPod(
+		expectedDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -292,6 +637,9 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		nadGVR := schema.GroupVersionResource{
@@ -313,18 +661,102 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Tag:        testImageTag,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+		}
+
+		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedStatus, status)
+
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedDeployment, deployment)
+	})
+
+	t.Run("ippool served by another ippool", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			NetworkName(testNetworkName).
+			ServedBy("default/transit").
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).Build()
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment)
+
+		handler := Handler{
+			agentNamespace:   testPodNamespace,
+			agentReplicas:    1,
+			deploymentClient: fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:  fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+			podClient:        fakeclient.PodClient(k8sclientset.CoreV1().Pods),
+		}
+
+		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, newTestIPPoolStatusBuilder().Build(), status)
+
+		_, err = handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("ippool with a legacy agent pod upgraded", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			NetworkName(testNetworkName).
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
+			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
+		givenPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testPodNamespace,
+				Name:      testPodName,
+			},
+		}
+
+		expectedStatus := newTestIPPoolStatusBuilder().
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+
+		nadGVR := schema.GroupVersionResource{
+			Group:    "k8s.cni.cncf.io",
+			Version:  "v1",
+			Resource: "network-attachment-definitions",
+		}
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Create(nadGVR, givenNAD, givenNAD.Namespace)
+		assert.Nil(t, err, "mock resource should add into fake controller tracker")
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenPod)
+
+		handler := Handler{
+			agentNamespace: testPodNamespace,
+			agentImage: &config.Image{
+				Repository: testImageRepository,
+				Tag:        testImageTag,
+			},
+			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
+			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
 		}
 
 		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
 
-		pod, err := handler.podClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		_, err = handler.podClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+
+		_, err = handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
 		assert.Nil(t, err)
-		assert.Equal(t, expectedPod, pod)
 	})
 
 	t.Run("ippool paused", func(t *testing.T) {
@@ -368,7 +800,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("network-attachment-definitions.k8s.cni.cncf.io \"%s\" not found", "you-cant-find-me"), err.Error())
 	})
 
-	t.Run("agent pod already exists", func(t *testing.T) {
+	t.Run("agent deployment already exists", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().
 			ServerIP(testServerIP1).
 			CIDR(testCIDR).
@@ -376,7 +808,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
 		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
 			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
-		givenPod, _ := prepareAgentPod(
+		givenDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -389,11 +821,14 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		expectedStatus := newTestIPPoolStatusBuilder().
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		expectedPod, _ := prepareAgentPod(
+		expectedDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -406,6 +841,9 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		nadGVR := schema.GroupVersionResource{
@@ -419,7 +857,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		k8sclientset := k8sfake.NewSimpleClientset()
-		err = k8sclientset.Tracker().Add(givenPod)
+		err = k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
@@ -429,18 +867,19 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Tag:        testImageTag,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
-			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
 
-		pod, err := handler.podClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
 		assert.Nil(t, err)
-		assert.Equal(t, expectedPod, pod)
+		assert.Equal(t, expectedDeployment, deployment)
 	})
 
 	t.Run("very long name ippool created", func(t *testing.T) {
@@ -453,7 +892,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 
 		expectedStatus := newTestIPPoolStatusBuilder().
 			AgentPodRef(testPodNamespace, testPodNameLong, testImage, "").Build()
-		expectedPod, _ := prepareAgentPod(
+		expectedDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolNameLong).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -466,6 +905,9 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		nadGVR := schema.GroupVersionResource{
@@ -487,21 +929,22 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Tag:        testImageTag,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
-			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
 
-		pod, err := handler.podClient.Get(testPodNamespace, testPodNameLong, metav1.GetOptions{})
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodNameLong, metav1.GetOptions{})
 		assert.Nil(t, err)
-		assert.Equal(t, expectedPod, pod)
+		assert.Equal(t, expectedDeployment, deployment)
 	})
 
-	t.Run("agent pod upgrade (from main to dev)", func(t *testing.T) {
+	t.Run("agent deployment upgrade (from main to dev)", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().
 			ServerIP(testServerIP1).
 			CIDR(testCIDR).
@@ -509,7 +952,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
 		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
 			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
-		givenPod, _ := prepareAgentPod(
+		givenDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -522,10 +965,30 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		expectedStatus := newTestIPPoolStatusBuilder().
 			AgentPodRef(testPodNamespace, testPodName, testImageNew, "").Build()
+		expectedDeployment, _ := prepareAgentDeployment(
+			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+				ServerIP(testServerIP1).
+				CIDR(testCIDR).
+				NetworkName(testNetworkName).Build(),
+			false,
+			testPodNamespace,
+			testClusterNetwork,
+			testServiceAccountName,
+			&config.Image{
+				Repository: testImageRepository,
+				Tag:        testImageTagNew,
+			},
+			nil,
+			1,
+			"",
+		)
 
 		nadGVR := schema.GroupVersionResource{
 			Group:    "k8s.cni.cncf.io",
@@ -538,7 +1001,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		k8sclientset := k8sfake.NewSimpleClientset()
-		err = k8sclientset.Tracker().Add(givenPod)
+		err = k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
@@ -548,17 +1011,22 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Tag:        testImageTagNew,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
-			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
+
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedDeployment, deployment)
 	})
 
-	t.Run("agent pod upgrade held back", func(t *testing.T) {
+	t.Run("agent deployment upgrade held back", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().
 			Annotation(holdIPPoolAgentUpgradeAnnotationKey, "true").
 			ServerIP(testServerIP1).
@@ -567,7 +1035,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
 		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
 			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
-		givenPod, _ := prepareAgentPod(
+		givenDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -580,10 +1048,32 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
 		)
 
 		expectedStatus := newTestIPPoolStatusBuilder().
 			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		expectedDeployment, _ := prepareAgentDeployment(
+			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+				Annotation(holdIPPoolAgentUpgradeAnnotationKey, "true").
+				ServerIP(testServerIP1).
+				CIDR(testCIDR).
+				NetworkName(testNetworkName).Build(),
+			false,
+			testPodNamespace,
+			testClusterNetwork,
+			testServiceAccountName,
+			&config.Image{
+				Repository: testImageRepository,
+				Tag:        testImageTagNew,
+			},
+			nil,
+			1,
+			"",
+		)
+		expectedDeployment.Spec.Paused = true
 
 		nadGVR := schema.GroupVersionResource{
 			Group:    "k8s.cni.cncf.io",
@@ -596,7 +1086,7 @@ func TestHandler_DeployAgent(t *testing.T) {
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		k8sclientset := k8sfake.NewSimpleClientset()
-		err = k8sclientset.Tracker().Add(givenPod)
+		err = k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
@@ -606,25 +1096,30 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Tag:        testImageTagNew,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
-			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
+
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedDeployment, deployment)
 	})
 
-	t.Run("existing agent pod uid mismatch", func(t *testing.T) {
+	t.Run("agent deployment scaled out", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().
 			ServerIP(testServerIP1).
 			CIDR(testCIDR).
 			NetworkName(testNetworkName).
-			AgentPodRef(testPodNamespace, testPodName, testImage, testUID).Build()
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
 		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
 			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
-		givenPod, _ := prepareAgentPod(
+		givenDeployment, _ := prepareAgentDeployment(
 			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
 				ServerIP(testServerIP1).
 				CIDR(testCIDR).
@@ -637,6 +1132,29 @@ func TestHandler_DeployAgent(t *testing.T) {
 				Repository: testImageRepository,
 				Tag:        testImageTag,
 			},
+			nil,
+			1,
+			"",
+		)
+
+		expectedStatus := newTestIPPoolStatusBuilder().
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		expectedDeployment, _ := prepareAgentDeployment(
+			NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+				ServerIP(testServerIP1).
+				CIDR(testCIDR).
+				NetworkName(testNetworkName).Build(),
+			false,
+			testPodNamespace,
+			testClusterNetwork,
+			testServiceAccountName,
+			&config.Image{
+				Repository: testImageRepository,
+				Tag:        testImageTag,
+			},
+			nil,
+			2,
+			appsv1.RecreateDeploymentStrategyType,
 		)
 
 		nadGVR := schema.GroupVersionResource{
@@ -650,23 +1168,82 @@ func TestHandler_DeployAgent(t *testing.T) {
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		k8sclientset := k8sfake.NewSimpleClientset()
-		err = k8sclientset.Tracker().Add(givenPod)
+		err = k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
 			agentNamespace: testPodNamespace,
 			agentImage: &config.Image{
 				Repository: testImageRepository,
-				Tag:        testImageTagNew,
+				Tag:        testImageTag,
 			},
 			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           2,
+			agentRolloutStrategy:    appsv1.RecreateDeploymentStrategyType,
 			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
-			podClient:               fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:                fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+		}
+
+		status, err := handler.DeployAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedStatus, status)
+
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedDeployment, deployment)
+	})
+
+	t.Run("ippool in the agent namespace", func(t *testing.T) {
+		givenIPPool := NewIPPoolBuilder(testPodNamespace, testIPPoolName).
+			ServerIP(testServerIP1).
+			CIDR(testCIDR).
+			NetworkName(testNetworkName).Build()
+		givenIPPool.UID = testUID
+		givenNAD := newTestNetworkAttachmentDefinitionBuilder().
+			Label(clusterNetworkLabelKey, testClusterNetwork).Build()
+
+		expectedOwnerReferences := []metav1.OwnerReference{
+			{
+				APIVersion: networkv1.SchemeGroupVersion.String(),
+				Kind:       "IPPool",
+				Name:       testIPPoolName,
+				UID:        testUID,
+				Controller: &[]bool{true}[0],
+			},
+		}
+
+		nadGVR := schema.GroupVersionResource{
+			Group:    "k8s.cni.cncf.io",
+			Version:  "v1",
+			Resource: "network-attachment-definitions",
+		}
+
+		clientset := fake.NewSimpleClientset()
+		err := clientset.Tracker().Create(nadGVR, givenNAD, givenNAD.Namespace)
+		assert.Nil(t, err, "mock resource should add into fake controller tracker")
+
+		k8sclientset := k8sfake.NewSimpleClientset()
+
+		handler := Handler{
+			agentNamespace: testPodNamespace,
+			agentImage: &config.Image{
+				Repository: testImageRepository,
+				Tag:        testImageTag,
+			},
+			agentServiceAccountName: testServiceAccountName,
+			agentReplicas:           1,
+			nadCache:                fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
+			deploymentClient:        fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:         fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		_, err = handler.DeployAgent(givenIPPool, givenIPPool.Status)
-		assert.Equal(t, fmt.Sprintf("agent pod %s uid mismatch", testPodName), err.Error())
+		assert.Nil(t, err)
+
+		deployment, err := handler.deploymentClient.Get(testPodNamespace, util.SafeAgentConcatName(testPodNamespace, testIPPoolName), metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, expectedOwnerReferences, deployment.OwnerReferences)
 	})
 }
 
@@ -713,13 +1290,123 @@ func TestHandler_BuildCache(t *testing.T) {
 		expectedStatus := newTestIPPoolStatusBuilder().
 			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
 
-		handler := Handler{}
+		handler := Handler{
+			ipAllocator: newTestIPAllocatorBuilder().Build(),
+		}
 
 		status, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
 		assert.Equal(t, expectedStatus, status)
 	})
 
+	t.Run("cache is already ready but pool range widened and excluded ips changed", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP2, testExcludedIP1).
+			Allocate(testNetworkName, testAllocatedIP1).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			ServerIP(testServerIP2).
+			PoolRange(testStartIP, "192.168.0.254").
+			Exclude(testExcludedIP2, testExcludedIP4).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, "192.168.0.254").
+			Revoke(testNetworkName, testServerIP2, testExcludedIP2, testExcludedIP4).
+			Allocate(testNetworkName, testAllocatedIP1).Build()
+
+		handler := Handler{
+			ipAllocator: givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+	})
+
+	t.Run("cache is already ready but reservations changed", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP2).
+			Reserve(testNetworkName, testAllocatedIP1, testMAC1).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			ServerIP(testServerIP2).
+			PoolRange(testStartIP, testEndIP).
+			Reservation(testAllocatedIP2, "22:33:44:55:66:77", "").
+			Reservation(testAllocatedIP1, "", "default/vm-1").
+			NetworkName(testNetworkName).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP2).
+			Reserve(testNetworkName, testAllocatedIP2, testMAC2).
+			Reserve(testNetworkName, testAllocatedIP1, "default/vm-1").Build()
+
+		handler := Handler{
+			ipAllocator: givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+	})
+
+	t.Run("cache is already ready but ipv6 config added", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP1).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			ServerIP(testServerIP1).
+			PoolRange(testStartIP, testEndIP).
+			IPv6Config("fd00:48::/64", "fd00:48::10", "fd00:48::20").
+			NetworkName(testNetworkName).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP1).
+			IPv6Subnet(testNetworkName, "fd00:48::/64", "fd00:48::10", "fd00:48::20").Build()
+
+		handler := Handler{
+			ipAllocator: givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+	})
+
+	t.Run("cache is already ready but ipv6 config removed", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP1).
+			IPv6Subnet(testNetworkName, "fd00:48::/64", "fd00:48::10", "fd00:48::20").Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			ServerIP(testServerIP1).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Revoke(testNetworkName, testServerIP1).Build()
+
+		handler := Handler{
+			ipAllocator: givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+	})
+
 	t.Run("ippool with excluded ips", func(t *testing.T) {
 		givenIPAllocator := newTestIPAllocatorBuilder().Build()
 		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
@@ -778,61 +1465,312 @@ func TestHandler_BuildCache(t *testing.T) {
 		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
 		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
 	})
+
+	t.Run("rebuild caches with reservations", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			Reservation(testAllocatedIP1, "11:22:33:44:55:66", "").
+			Reservation(testAllocatedIP2, "", "default/vm-1").
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testAllocatedIP1).
+			Reserve(testNetworkName, testAllocatedIP1, testMAC1).
+			Reserve(testNetworkName, testAllocatedIP2, "default/vm-1").Build()
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC1, testAllocatedIP1).Build()
+
+		handler := Handler{
+			cacheAllocator: givenCacheAllocator,
+			ipAllocator:    givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+	})
+
+	t.Run("rebuild caches with quarantined ip", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, util.QuarantinedMark).
+			Allocated(testAllocatedIP2, testMAC2).Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Quarantine(testNetworkName, testAllocatedIP1).
+			Allocate(testNetworkName, testAllocatedIP2).Build()
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMAC2, testAllocatedIP2).Build()
+
+		handler := Handler{
+			cacheAllocator: givenCacheAllocator,
+			ipAllocator:    givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+	})
+
+	t.Run("rebuild caches with last allocated ip", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			AllocationStrategy(networkv1.AllocationStrategyRoundRobin).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, testMAC1).
+			LastAllocated(testAllocatedIP1).Build()
+
+		handler := Handler{
+			cacheAllocator: givenCacheAllocator,
+			ipAllocator:    givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+
+		lastAllocated, err := handler.ipAllocator.GetLastAllocated(testNetworkName)
+		assert.Nil(t, err)
+		assert.Equal(t, testAllocatedIP1, lastAllocated)
+	})
+
+	t.Run("rebuild caches with multiple pool ranges", func(t *testing.T) {
+		givenIPAllocator := newTestIPAllocatorBuilder().Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			PoolRange("192.168.0.10", "192.168.0.19").
+			Range("192.168.0.100", "192.168.0.109").
+			NetworkName(testNetworkName).
+			Allocated("192.168.0.105", testMAC1).Build()
+
+		handler := Handler{
+			cacheAllocator: givenCacheAllocator,
+			ipAllocator:    givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+
+		available, err := handler.ipAllocator.GetAvailable(testNetworkName)
+		assert.Nil(t, err)
+		assert.Equal(t, 19, available)
+		isAllocated, err := handler.ipAllocator.IsAllocated(testNetworkName, "192.168.0.105")
+		assert.Nil(t, err)
+		assert.True(t, isAllocated)
+	})
+
+	t.Run("rebuild caches with released ips", func(t *testing.T) {
+		releasedAt := time.Now().UTC().Truncate(time.Second)
+		givenIPAllocator := newTestIPAllocatorBuilder().Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().Build()
+		givenIPPool := newTestIPPoolBuilder().
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			QuarantineDuration(&metav1.Duration{Duration: time.Hour}).
+			NetworkName(testNetworkName).
+			Allocated(testAllocatedIP1, util.NewReleasedMark(releasedAt.Add(-time.Minute))).
+			Allocated(testAllocatedIP2, util.NewReleasedMark(releasedAt.Add(-2*time.Hour))).Build()
+
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			QuarantineUntil(testNetworkName, releasedAt.Add(-time.Minute).Add(time.Hour), testAllocatedIP1).Build()
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).Build()
+
+		handler := Handler{
+			cacheAllocator: givenCacheAllocator,
+			ipAllocator:    givenIPAllocator,
+		}
+
+		_, err := handler.BuildCache(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+	})
 }
 
 func TestHandler_MonitorAgent(t *testing.T) {
-	t.Run("agent pod not found", func(t *testing.T) {
+	t.Run("agent deployment not found", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		givenPod := newPodBuilder("default", "nginx").Build()
+		givenDeployment := newDeploymentBuilder("default", "nginx").Build()
 
 		k8sclientset := k8sfake.NewSimpleClientset()
 
-		err := k8sclientset.Tracker().Add(givenPod)
+		err := k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
-			podCache: fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			agentReplicas:   1,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		_, err = handler.MonitorAgent(givenIPPool, givenIPPool.Status)
-		assert.Equal(t, fmt.Sprintf("pods \"%s\" not found", testPodName), err.Error())
+		assert.Equal(t, fmt.Sprintf("deployments.apps \"%s\" not found", testPodName), err.Error())
+	})
+
+	t.Run("ippool served by another ippool", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().
+			ServedBy("default/transit").Build()
+
+		handler := Handler{
+			agentReplicas: 1,
+		}
+
+		status, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, givenIPPool.Status, status)
 	})
 
-	t.Run("agent pod unready", func(t *testing.T) {
+	t.Run("agent deployment unready", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		givenPod := newTestPodBuilder().
-			Container(testContainerName, testImageRepository, testImageTag).Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).
+			Replicas(1).Build()
 
 		k8sclientset := k8sfake.NewSimpleClientset()
 
-		err := k8sclientset.Tracker().Add(givenPod)
+		err := k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
-			podCache: fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			agentReplicas:   1,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
 		_, err = handler.MonitorAgent(givenIPPool, givenIPPool.Status)
-		assert.Equal(t, fmt.Sprintf("agent pod %s not ready", testPodName), err.Error())
+		assert.Equal(t, fmt.Sprintf("agent deployment %s has no ready replicas", testPodName), err.Error())
 	})
 
-	t.Run("agent pod ready", func(t *testing.T) {
+	t.Run("agent deployment ready", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
-		givenPod := newTestPodBuilder().
+		givenDeployment := newTestDeploymentBuilder().
 			Container(testContainerName, testImageRepository, testImageTag).
-			PodReady(corev1.ConditionTrue).Build()
+			Replicas(1).
+			ReadyReplicas(1).Build()
+
+		expectedStatus := newTestIPPoolStatusBuilder().
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").
+			Agent(1, 1, "").Build()
 
 		k8sclientset := k8sfake.NewSimpleClientset()
 
-		err := k8sclientset.Tracker().Add(givenPod)
+		err := k8sclientset.Tracker().Add(givenDeployment)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
 
 		handler := Handler{
-			podCache: fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			agentReplicas:   1,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
-		_, err = handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		status, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedStatus, status)
+	})
+
+	t.Run("highly available agent deployment with leader", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).
+			Replicas(3).
+			ReadyReplicas(2).Build()
+		givenLease := newLeaseBuilder(testPodNamespace, testPodName).
+			Holder(testLeaderName, time.Now(), 10).Build()
+
+		expectedStatus := newTestIPPoolStatusBuilder().
+			AgentPodRef(testPodNamespace, testPodName, testImage, "").
+			Agent(3, 2, testLeaderName).Build()
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment, givenLease)
+
+		handler := Handler{
+			agentReplicas:   3,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+			leaseCache:      fakeclient.LeaseCache(k8sclientset.CoordinationV1().Leases),
+		}
+
+		status, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
 		assert.Nil(t, err)
+		assert.Equal(t, expectedStatus, status)
+	})
+
+	t.Run("highly available agent deployment without leader", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).
+			Replicas(3).
+			ReadyReplicas(3).Build()
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment)
+
+		handler := Handler{
+			agentReplicas:   3,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+			leaseCache:      fakeclient.LeaseCache(k8sclientset.CoordinationV1().Leases),
+		}
+
+		_, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Equal(t, fmt.Sprintf("agent deployment %s has no leader", testPodName), err.Error())
+	})
+
+	t.Run("single replica agent deployment rolled out with rolling update without leader", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).
+			Replicas(1).
+			Strategy(appsv1.RollingUpdateDeploymentStrategyType).
+			ReadyReplicas(1).Build()
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment)
+
+		handler := Handler{
+			agentReplicas:   1,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+			leaseCache:      fakeclient.LeaseCache(k8sclientset.CoordinationV1().Leases),
+		}
+
+		_, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Equal(t, fmt.Sprintf("agent deployment %s has no leader", testPodName), err.Error())
+	})
+
+	t.Run("highly available agent deployment with expired lease", func(t *testing.T) {
+		givenIPPool := newTestIPPoolBuilder().AgentPodRef(testPodNamespace, testPodName, testImage, "").Build()
+		givenDeployment := newTestDeploymentBuilder().
+			Container(testContainerName, testImageRepository, testImageTag).
+			Replicas(3).
+			ReadyReplicas(2).Build()
+		givenLease := newLeaseBuilder(testPodNamespace, testPodName).
+			Holder(testLeaderName, time.Now().Add(-time.Minute), 10).Build()
+
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment, givenLease)
+
+		handler := Handler{
+			agentReplicas:   3,
+			deploymentCache: fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
+			leaseCache:      fakeclient.LeaseCache(k8sclientset.CoordinationV1().Leases),
+		}
+
+		_, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Equal(t, fmt.Sprintf("leader %s of agent deployment %s has not renewed its lease", testLeaderName, testPodName), err.Error())
 	})
 
 	t.Run("ippool paused", func(t *testing.T) {
@@ -864,27 +1802,31 @@ func TestHandler_MonitorAgent(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("agent for ippool %s is not deployed", testIPPoolNamespace+"/"+testIPPoolName), err.Error())
 	})
 
-	t.Run("outdated agent pod", func(t *testing.T) {
+	t.Run("outdated agent deployment left to the rollout", func(t *testing.T) {
 		givenIPPool := newTestIPPoolBuilder().
 			AgentPodRef(testPodNamespace, testPodName, testImageNew, "").Build()
-		givenPod := newTestPodBuilder().
+		givenDeployment := newTestDeploymentBuilder().
 			Container(testContainerName, testImageRepository, testImageTag).
-			PodReady(corev1.ConditionTrue).Build()
+			Replicas(1).
+			ReadyReplicas(1).Build()
 
-		k8sclientset := k8sfake.NewSimpleClientset()
+		expectedStatus := newTestIPPoolStatusBuilder().
+			AgentPodRef(testPodNamespace, testPodName, testImageNew, "").
+			Agent(1, 1, "").Build()
 
-		err := k8sclientset.Tracker().Add(givenPod)
-		assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		k8sclientset := k8sfake.NewSimpleClientset(givenDeployment)
 
 		handler := Handler{
-			podClient: fakeclient.PodClient(k8sclientset.CoreV1().Pods),
-			podCache:  fakeclient.PodCache(k8sclientset.CoreV1().Pods),
+			agentReplicas:    1,
+			deploymentClient: fakeclient.DeploymentClient(k8sclientset.AppsV1().Deployments),
+			deploymentCache:  fakeclient.DeploymentCache(k8sclientset.AppsV1().Deployments),
 		}
 
-		_, err = handler.MonitorAgent(givenIPPool, givenIPPool.Status)
-		assert.Equal(t, fmt.Sprintf("agent pod %s obsolete and purged", testPodName), err.Error())
+		status, err := handler.MonitorAgent(givenIPPool, givenIPPool.Status)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedStatus, status)
 
-		_, err = handler.podClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
-		assert.Equal(t, fmt.Sprintf("pods \"%s\" not found", testPodName), err.Error())
+		_, err = handler.deploymentClient.Get(testPodNamespace, testPodName, metav1.GetOptions{})
+		assert.Nil(t, err)
 	})
 }
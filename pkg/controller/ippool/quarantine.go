@@ -0,0 +1,120 @@
+package ippool
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+// defaultDeclineQuarantineDuration is how long an IP address declined by a
+// DHCP client is kept out of circulation at least. It bounds the quarantine
+// so that a client declining whatever it's offered can't drain the pool.
+const defaultDeclineQuarantineDuration = time.Hour
+
+// quarantineDuration returns how long the IP addresses released from ipPool
+// are kept out of circulation, which is zero if they aren't.
+func quarantineDuration(ipPool *networkv1.IPPool) time.Duration {
+	if ipPool.Spec.IPv4Config.QuarantineDuration == nil {
+		return 0
+	}
+	return ipPool.Spec.IPv4Config.QuarantineDuration.Duration
+}
+
+// declineQuarantineDuration returns how long the IP addresses declined by DHCP
+// clients of ipPool are kept out of circulation, which is the quarantine
+// duration of the IPPool if it's longer than the default.
+func declineQuarantineDuration(ipPool *networkv1.IPPool) time.Duration {
+	return max(quarantineDuration(ipPool), defaultDeclineQuarantineDuration)
+}
+
+// quarantinedUntil returns when the quarantine period of the IP address marked
+// with val is over, and whether val is a released or quarantined mark at all.
+func quarantinedUntil(ipPool *networkv1.IPPool, val string) (time.Time, bool) {
+	if releasedAt, ok := util.ParseReleasedMark(val); ok {
+		return releasedAt.Add(quarantineDuration(ipPool)), true
+	}
+	if declinedAt, ok := util.ParseQuarantinedMark(val); ok {
+		if declinedAt.IsZero() {
+			return time.Time{}, true
+		}
+		return declinedAt.Add(declineQuarantineDuration(ipPool)), true
+	}
+	return time.Time{}, false
+}
+
+// expireQuarantined keeps the released and declined IP addresses quarantined
+// in the IPAM according to the up-to-date quarantine duration of the IPPool,
+// and drops the ones whose quarantine periods are over from the status. The
+// declined IP addresses marked by earlier versions, which carry no decline
+// time, are stamped with now. It returns how long it is until the next
+// quarantine period is over, or zero if there's none left.
+func (h *Handler) expireQuarantined(ipPool *networkv1.IPPool, ipv4Status *networkv1.IPv4Status, now time.Time) (time.Duration, error) {
+	var next time.Duration
+	for ip, val := range ipv4Status.Allocated {
+		if val == util.QuarantinedMark {
+			val = util.NewQuarantinedMark(now)
+			ipv4Status.Allocated[ip] = val
+		}
+		until, ok := quarantinedUntil(ipPool, val)
+		if !ok {
+			continue
+		}
+
+		isAllocated, err := h.ipAllocator.IsAllocated(ipPool.Spec.NetworkName, ip)
+		if err != nil {
+			return 0, err
+		}
+		if !isAllocated {
+			if err := h.ipAllocator.QuarantineIPUntil(ipPool.Spec.NetworkName, ip, until); err != nil {
+				return 0, err
+			}
+		}
+
+		wait := until.Sub(now)
+		if wait <= 0 {
+			delete(ipv4Status.Allocated, ip)
+			logrus.Infof("(ippool.expireQuarantined) quarantine of ip %s is over in ipam %s", ip, ipPool.Spec.NetworkName)
+			continue
+		}
+		if next == 0 || wait < next {
+			next = wait
+		}
+	}
+
+	return next, nil
+}
+
+// expireQuarantinedIPv6 releases the IPv6 addresses declined by DHCPv6 clients
+// whose quarantine periods are over, the same way as expireQuarantined does
+// for IPv4 ones.
+func (h *Handler) expireQuarantinedIPv6(ipPool *networkv1.IPPool, allocated map[string]string, now time.Time) (time.Duration, error) {
+	var next time.Duration
+	for ip, val := range allocated {
+		if val == util.QuarantinedMark {
+			val = util.NewQuarantinedMark(now)
+			allocated[ip] = val
+		}
+		declinedAt, ok := util.ParseQuarantinedMark(val)
+		if !ok {
+			continue
+		}
+
+		wait := declinedAt.Add(declineQuarantineDuration(ipPool)).Sub(now)
+		if wait <= 0 {
+			if err := h.ipAllocator.UnquarantineIPv6(ipPool.Spec.NetworkName, ip); err != nil {
+				return 0, err
+			}
+			delete(allocated, ip)
+			logrus.Infof("(ippool.expireQuarantinedIPv6) quarantine of ip %s is over in ipv6 ipam %s", ip, ipPool.Spec.NetworkName)
+			continue
+		}
+		if next == 0 || wait < next {
+			next = wait
+		}
+	}
+
+	return next, nil
+}
@@ -0,0 +1,177 @@
+package ippool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+func TestHandler_expireQuarantined(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name                string
+		quarantineDuration  *metav1.Duration
+		givenMark           string
+		expectedAllocated   map[string]string
+		expectedNext        time.Duration
+		expectedQuarantined bool
+	}{
+		{
+			name:               "quarantine period not over",
+			quarantineDuration: &metav1.Duration{Duration: time.Hour},
+			givenMark:          util.NewReleasedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP1: util.NewReleasedMark(now.Add(-time.Minute)),
+				testAllocatedIP2: testMAC2,
+			},
+			expectedNext:        59 * time.Minute,
+			expectedQuarantined: true,
+		},
+		{
+			name:               "quarantine period over",
+			quarantineDuration: &metav1.Duration{Duration: time.Minute},
+			givenMark:          util.NewReleasedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP2: testMAC2,
+			},
+		},
+		{
+			name:      "quarantine disabled since released",
+			givenMark: util.NewReleasedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP2: testMAC2,
+			},
+		},
+		{
+			name:      "declined ip quarantined by default",
+			givenMark: util.NewQuarantinedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP1: util.NewQuarantinedMark(now.Add(-time.Minute)),
+				testAllocatedIP2: testMAC2,
+			},
+			expectedNext:        59 * time.Minute,
+			expectedQuarantined: true,
+		},
+		{
+			name:               "declined ip quarantined for longer quarantine duration",
+			quarantineDuration: &metav1.Duration{Duration: 2 * time.Hour},
+			givenMark:          util.NewQuarantinedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP1: util.NewQuarantinedMark(now.Add(-time.Minute)),
+				testAllocatedIP2: testMAC2,
+			},
+			expectedNext:        119 * time.Minute,
+			expectedQuarantined: true,
+		},
+		{
+			name:               "declined ip quarantine period over",
+			quarantineDuration: &metav1.Duration{Duration: time.Minute},
+			givenMark:          util.NewQuarantinedMark(now.Add(-time.Hour)),
+			expectedAllocated: map[string]string{
+				testAllocatedIP2: testMAC2,
+			},
+		},
+		{
+			name:      "declined ip marked without decline time",
+			givenMark: util.QuarantinedMark,
+			expectedAllocated: map[string]string{
+				testAllocatedIP1: util.NewQuarantinedMark(now),
+				testAllocatedIP2: testMAC2,
+			},
+			expectedNext:        time.Hour,
+			expectedQuarantined: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			givenIPPool := newTestIPPoolBuilder().
+				CIDR(testCIDR).
+				PoolRange(testStartIP, testEndIP).
+				QuarantineDuration(tc.quarantineDuration).
+				NetworkName(testNetworkName).
+				Allocated(testAllocatedIP1, tc.givenMark).
+				Allocated(testAllocatedIP2, testMAC2).Build()
+			givenIPAllocator := newTestIPAllocatorBuilder().
+				IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+				Allocate(testNetworkName, testAllocatedIP2).Build()
+
+			handler := Handler{
+				ipAllocator: givenIPAllocator,
+			}
+
+			ipv4Status := givenIPPool.Status.IPv4.DeepCopy()
+			next, err := handler.expireQuarantined(givenIPPool, ipv4Status, now)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedNext, next)
+			assert.Equal(t, tc.expectedAllocated, ipv4Status.Allocated)
+
+			quarantined, err := handler.ipAllocator.IsQuarantined(testNetworkName, testAllocatedIP1)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedQuarantined, quarantined)
+		})
+	}
+}
+
+func TestHandler_expireQuarantinedIPv6(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name                string
+		givenMark           string
+		expectedAllocated   map[string]string
+		expectedNext        time.Duration
+		expectedQuarantined bool
+	}{
+		{
+			name:      "quarantine period not over",
+			givenMark: util.NewQuarantinedMark(now.Add(-time.Minute)),
+			expectedAllocated: map[string]string{
+				"fd00:48::10": util.NewQuarantinedMark(now.Add(-time.Minute)),
+				"fd00:48::11": testMAC2,
+			},
+			expectedNext:        59 * time.Minute,
+			expectedQuarantined: true,
+		},
+		{
+			name:      "quarantine period over",
+			givenMark: util.NewQuarantinedMark(now.Add(-time.Hour)),
+			expectedAllocated: map[string]string{
+				"fd00:48::11": testMAC2,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			givenIPPool := newTestIPPoolBuilder().
+				NetworkName(testNetworkName).
+				IPv6Config("fd00:48::/64", "fd00:48::10", "fd00:48::20").
+				AllocatedIPv6("fd00:48::10", tc.givenMark).
+				AllocatedIPv6("fd00:48::11", testMAC2).Build()
+			givenIPAllocator := newTestIPAllocatorBuilder().
+				IPv6Subnet(testNetworkName, "fd00:48::/64", "fd00:48::10", "fd00:48::20").
+				AllocateIPv6(testNetworkName, "fd00:48::11").
+				QuarantineIPv6(testNetworkName, "fd00:48::10").Build()
+
+			handler := Handler{
+				ipAllocator: givenIPAllocator,
+			}
+
+			allocated := givenIPPool.Status.IPv6.DeepCopy().Allocated
+			next, err := handler.expireQuarantinedIPv6(givenIPPool, allocated, now)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedNext, next)
+			assert.Equal(t, tc.expectedAllocated, allocated)
+
+			quarantined, err := handler.ipAllocator.IsIPv6Quarantined(testNetworkName, "fd00:48::10")
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedQuarantined, quarantined)
+		})
+	}
+}
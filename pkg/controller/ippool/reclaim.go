@@ -0,0 +1,130 @@
+package ippool
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+// updatePendingReclaims works out the IP addresses to be reclaimed according
+// to the reclaim policy of the IPPool. The reclaims themselves are carried out
+// by the vmnetcfg controller once they're due.
+func (h *Handler) updatePendingReclaims(ipPool *networkv1.IPPool, ipv4Status *networkv1.IPv4Status, now time.Time) error {
+	policy := ipPool.Spec.Reclaim
+	if policy == nil || (policy.InactivePeriod == nil && policy.StoppedGracePeriod == nil) {
+		ipv4Status.PendingReclaims = nil
+		return nil
+	}
+
+	vmnetcfgGetter := util.VmnetcfgGetter{VmnetcfgCache: h.vmnetcfgCache}
+	vmNetCfgs, err := vmnetcfgGetter.WhoUseIPPool(ipPool)
+	if err != nil {
+		return err
+	}
+
+	pendingReclaims := make(map[string]networkv1.PendingReclaim)
+	for _, vmNetCfg := range vmNetCfgs {
+		stopped, ready, err := h.getVMState(vmNetCfg.Namespace, vmNetCfg.Spec.VMName)
+		if err != nil {
+			return err
+		}
+
+		for _, ncStatus := range vmNetCfg.Status.NetworkConfigs {
+			ip := ncStatus.AllocatedIPAddress
+			if ncStatus.NetworkName != ipPool.Spec.NetworkName || ip == "" || ipv4Status.Allocated[ip] != ncStatus.MACAddress {
+				continue
+			}
+
+			var previous *networkv1.PendingReclaim
+			if pendingReclaim, ok := ipv4Status.PendingReclaims[ip]; ok {
+				previous = &pendingReclaim
+			}
+
+			pendingReclaim := nextPendingReclaim(policy, ncStatus.MACAddress, stopped, ready, ipv4Status.Leases[ncStatus.MACAddress], previous, now)
+			if pendingReclaim != nil {
+				pendingReclaims[ip] = *pendingReclaim
+			}
+		}
+	}
+
+	// For DeepEqual
+	if len(pendingReclaims) == 0 {
+		pendingReclaims = nil
+	}
+	ipv4Status.PendingReclaims = pendingReclaims
+
+	return nil
+}
+
+// nextPendingReclaim returns the pending reclaim of the IP address allocated
+// to macAddress, or nil if the network interface is in use. A stopped VM
+// takes precedence over an inactive network interface. The network interfaces
+// of ready VMs are never inactive, as a reclaimed network interface gets an IP
+// address again only once its VM becomes ready, and not when its guest asks
+// for one. If it's unknown since when the network interface is not in use, the
+// time is counted from when it was first found so, i.e., the previous pending
+// reclaim, or else now.
+func nextPendingReclaim(policy *networkv1.ReclaimPolicy, macAddress string, stopped, ready bool, activity networkv1.LeaseActivity, previous *networkv1.PendingReclaim, now time.Time) *networkv1.PendingReclaim {
+	var reason networkv1.ReclaimReason
+	var period time.Duration
+	var since *metav1.Time
+
+	switch {
+	case stopped && policy.StoppedGracePeriod != nil:
+		reason = networkv1.ReclaimReasonStopped
+		period = policy.StoppedGracePeriod.Duration
+	case policy.InactivePeriod != nil && !ready && activity.State != util.LeaseStateBound:
+		reason = networkv1.ReclaimReasonInactive
+		period = policy.InactivePeriod.Duration
+		if activity.LastAck != nil {
+			since = activity.LastAck
+		} else if activity.FirstSeen != nil {
+			since = activity.FirstSeen
+		}
+	default:
+		return nil
+	}
+
+	if since == nil {
+		if previous != nil && previous.MACAddress == macAddress && previous.Reason == reason {
+			return previous
+		}
+		// Made the same as it comes back from the apiserver, for it to be
+		// compared
+		nowCpy := metav1.NewTime(now).Rfc3339Copy()
+		since = &nowCpy
+	}
+
+	return &networkv1.PendingReclaim{
+		MACAddress: macAddress,
+		Reason:     reason,
+		ReclaimAt:  metav1.NewTime(since.Add(period)),
+	}
+}
+
+// getVMState tells if the VM is stopped, and if it's ready.
+func (h *Handler) getVMState(namespace, name string) (stopped, ready bool, err error) {
+	vm, err := h.vmCache.Get(namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return vm.Status.PrintableStatus == kubevirtv1.VirtualMachineStatusStopped, isVMReady(vm), nil
+}
+
+func isVMReady(vm *kubevirtv1.VirtualMachine) bool {
+	for _, condition := range vm.Status.Conditions {
+		if condition.Type == kubevirtv1.VirtualMachineReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
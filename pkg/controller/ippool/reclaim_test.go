@@ -0,0 +1,118 @@
+package ippool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+func TestNextPendingReclaim(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastAck := metav1.NewTime(now.Add(-time.Hour))
+	earlier := metav1.NewTime(now.Add(-24 * time.Hour))
+
+	policy := &networkv1.ReclaimPolicy{
+		InactivePeriod:     &metav1.Duration{Duration: 72 * time.Hour},
+		StoppedGracePeriod: &metav1.Duration{Duration: 24 * time.Hour},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *networkv1.ReclaimPolicy
+		stopped  bool
+		ready    bool
+		activity networkv1.LeaseActivity
+		previous *networkv1.PendingReclaim
+		expected *networkv1.PendingReclaim
+	}{
+		{
+			name:     "bound lease",
+			policy:   policy,
+			activity: networkv1.LeaseActivity{State: "bound", LastAck: &lastAck},
+		},
+		{
+			name:     "expired lease",
+			policy:   policy,
+			activity: networkv1.LeaseActivity{State: "expired", LastAck: &lastAck},
+			expected: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(lastAck.Add(72 * time.Hour)),
+			},
+		},
+		{
+			name:   "never seen",
+			policy: policy,
+			expected: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(now.Add(72 * time.Hour)),
+			},
+		},
+		{
+			name:   "never seen on a ready vm",
+			policy: policy,
+			ready:  true,
+			previous: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(earlier.Add(72 * time.Hour)),
+			},
+		},
+		{
+			name:     "expired lease on a ready vm",
+			policy:   policy,
+			ready:    true,
+			activity: networkv1.LeaseActivity{State: "expired", LastAck: &lastAck},
+		},
+		{
+			name:   "never seen since earlier",
+			policy: policy,
+			previous: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(earlier.Add(72 * time.Hour)),
+			},
+			expected: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(earlier.Add(72 * time.Hour)),
+			},
+		},
+		{
+			name:     "stopped vm",
+			policy:   policy,
+			stopped:  true,
+			activity: networkv1.LeaseActivity{State: "bound", LastAck: &lastAck},
+			previous: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonInactive,
+				ReclaimAt:  metav1.NewTime(earlier.Add(72 * time.Hour)),
+			},
+			expected: &networkv1.PendingReclaim{
+				MACAddress: testMAC1,
+				Reason:     networkv1.ReclaimReasonStopped,
+				ReclaimAt:  metav1.NewTime(now.Add(24 * time.Hour)),
+			},
+		},
+		{
+			name: "stopped vm without grace period",
+			policy: &networkv1.ReclaimPolicy{
+				InactivePeriod: &metav1.Duration{Duration: 72 * time.Hour},
+			},
+			stopped:  true,
+			activity: networkv1.LeaseActivity{State: "bound", LastAck: &lastAck},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := nextPendingReclaim(tc.policy, testMAC1, tc.stopped, tc.ready, tc.activity, tc.previous, now)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
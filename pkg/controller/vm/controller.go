@@ -14,6 +14,7 @@ import (
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
 	ctlkubevirtv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/kubevirt.io/v1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
 )
 
 const (
@@ -84,6 +85,21 @@ func (h *Handler) OnChange(key string, vm *kubevirtv1.VirtualMachine) (*kubevirt
 		}
 	}
 
+	// Designate IP addresses asked for by the annotation
+	ipAddresses, err := util.ParseIPAddressesAnnotation(vm.Annotations)
+	if err != nil {
+		return vm, err
+	}
+	for name, ipAddress := range ipAddresses {
+		nc, ok := ncm[name]
+		if !ok {
+			continue
+		}
+		ip := ipAddress
+		nc.IPAddress = &ip
+		ncm[name] = nc
+	}
+
 	vmNetCfg := prepareVmNetCfg(vm, ncm)
 
 	oldVmNetCfg, err := h.vmnetcfgCache.Get(vm.Namespace, vm.Name)
@@ -118,9 +134,12 @@ func prepareVmNetCfg(vm *kubevirtv1.VirtualMachine, ncm map[string]networkv1.Net
 		vmLabelKey: vm.Name,
 	}
 
+	// Keep the network configs in the order of the network interfaces
 	ncs := make([]networkv1.NetworkConfig, 0, len(ncm))
-	for _, nc := range ncm {
-		ncs = append(ncs, nc)
+	for _, nic := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+		if nc, ok := ncm[nic.Name]; ok {
+			ncs = append(ncs, nc)
+		}
 	}
 
 	return &networkv1.VirtualMachineNetworkConfig{
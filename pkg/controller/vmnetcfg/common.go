@@ -19,12 +19,12 @@ func setDisabledCondition(vmNetCfg *networkv1.VirtualMachineNetworkConfig, statu
 	networkv1.Disabled.Message(vmNetCfg, message)
 }
 
-type vmNetCfgBuilder struct {
+type VmNetCfgBuilder struct {
 	vmNetCfg *networkv1.VirtualMachineNetworkConfig
 }
 
-func newVmNetCfgBuilder(namespace, name string) *vmNetCfgBuilder {
-	return &vmNetCfgBuilder{
+func NewVmNetCfgBuilder(namespace, name string) *VmNetCfgBuilder {
+	return &VmNetCfgBuilder{
 		vmNetCfg: &networkv1.VirtualMachineNetworkConfig{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: namespace,
@@ -34,17 +34,22 @@ func newVmNetCfgBuilder(namespace, name string) *vmNetCfgBuilder {
 	}
 }
 
-func (b *vmNetCfgBuilder) Paused() *vmNetCfgBuilder {
+func (b *VmNetCfgBuilder) VMName(vmName string) *VmNetCfgBuilder {
+	b.vmNetCfg.Spec.VMName = vmName
+	return b
+}
+
+func (b *VmNetCfgBuilder) Paused() *VmNetCfgBuilder {
 	b.vmNetCfg.Spec.Paused = func(b bool) *bool { return &b }(true)
 	return b
 }
 
-func (b *vmNetCfgBuilder) UnPaused() *vmNetCfgBuilder {
+func (b *VmNetCfgBuilder) UnPaused() *VmNetCfgBuilder {
 	b.vmNetCfg.Spec.Paused = func(b bool) *bool { return &b }(false)
 	return b
 }
 
-func (b *vmNetCfgBuilder) WithNetworkConfig(ipAddress, macAddress, networkName string) *vmNetCfgBuilder {
+func (b *VmNetCfgBuilder) WithNetworkConfig(ipAddress, macAddress, networkName string) *VmNetCfgBuilder {
 	var ip *string
 	if ipAddress != "" {
 		ip = &ipAddress
@@ -58,7 +63,18 @@ func (b *vmNetCfgBuilder) WithNetworkConfig(ipAddress, macAddress, networkName s
 	return b
 }
 
-func (b *vmNetCfgBuilder) WithNetworkConfigStatus(ipAddress, macAddress, networkName string, state networkv1.NetworkConfigState) *vmNetCfgBuilder {
+// CustomOption adds a custom option to the network config added last.
+func (b *VmNetCfgBuilder) CustomOption(code int, optionType networkv1.DHCPOptionType, value string) *VmNetCfgBuilder {
+	nc := &b.vmNetCfg.Spec.NetworkConfigs[len(b.vmNetCfg.Spec.NetworkConfigs)-1]
+	nc.CustomOptions = append(nc.CustomOptions, networkv1.DHCPOption{
+		Code:  code,
+		Type:  optionType,
+		Value: value,
+	})
+	return b
+}
+
+func (b *VmNetCfgBuilder) WithNetworkConfigStatus(ipAddress, macAddress, networkName string, state networkv1.NetworkConfigState) *VmNetCfgBuilder {
 	ncStatus := networkv1.NetworkConfigStatus{
 		AllocatedIPAddress: ipAddress,
 		MACAddress:         macAddress,
@@ -69,17 +85,17 @@ func (b *vmNetCfgBuilder) WithNetworkConfigStatus(ipAddress, macAddress, network
 	return b
 }
 
-func (b *vmNetCfgBuilder) AllocatedCondition(status corev1.ConditionStatus, reason, message string) *vmNetCfgBuilder {
+func (b *VmNetCfgBuilder) AllocatedCondition(status corev1.ConditionStatus, reason, message string) *VmNetCfgBuilder {
 	setAllocatedCondition(b.vmNetCfg, status, reason, message)
 	return b
 }
 
-func (b *vmNetCfgBuilder) DisabledCondition(status corev1.ConditionStatus, reason, message string) *vmNetCfgBuilder {
+func (b *VmNetCfgBuilder) DisabledCondition(status corev1.ConditionStatus, reason, message string) *VmNetCfgBuilder {
 	setDisabledCondition(b.vmNetCfg, status, reason, message)
 	return b
 }
 
-func (b *vmNetCfgBuilder) Build() *networkv1.VirtualMachineNetworkConfig {
+func (b *VmNetCfgBuilder) Build() *networkv1.VirtualMachineNetworkConfig {
 	return b.vmNetCfg
 }
 
@@ -104,11 +120,23 @@ func (b *vmNetCfgStatusBuilder) WithNetworkConfigStatus(ipAddress, macAddress, n
 	return b
 }
 
+func (b *vmNetCfgStatusBuilder) AllocatedIPv6Address(macAddress, ipv6Address string) *vmNetCfgStatusBuilder {
+	for i := range b.vmNetCfgStatus.NetworkConfigs {
+		if b.vmNetCfgStatus.NetworkConfigs[i].MACAddress == macAddress {
+			b.vmNetCfgStatus.NetworkConfigs[i].AllocatedIPv6Address = ipv6Address
+		}
+	}
+	return b
+}
+
 func (b *vmNetCfgStatusBuilder) Build() networkv1.VirtualMachineNetworkConfigStatus {
 	return b.vmNetCfgStatus
 }
 
 func SanitizeStatus(status *networkv1.VirtualMachineNetworkConfigStatus) {
+	for i := range status.NetworkConfigs {
+		status.NetworkConfigs[i].ReclaimedAt = nil
+	}
 	for i := range status.Conditions {
 		status.Conditions[i].LastTransitionTime = ""
 		status.Conditions[i].LastUpdateTime = ""
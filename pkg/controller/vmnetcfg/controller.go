@@ -5,18 +5,28 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/rancher/wrangler/pkg/kv"
+	"github.com/rancher/wrangler/pkg/relatedresource"
 	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/cache"
 	"github.com/harvester/vm-dhcp-controller/pkg/config"
+	ctlkubevirtv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/kubevirt.io/v1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
 	"github.com/harvester/vm-dhcp-controller/pkg/ipam"
 	"github.com/harvester/vm-dhcp-controller/pkg/metrics"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
 )
 
 const controllerName = "vm-dhcp-vmnetcfg-controller"
@@ -32,11 +42,15 @@ type Handler struct {
 	ippoolController   ctlnetworkv1.IPPoolController
 	ippoolClient       ctlnetworkv1.IPPoolClient
 	ippoolCache        ctlnetworkv1.IPPoolCache
+	vmCache            ctlkubevirtv1.VirtualMachineCache
+
+	recorder record.EventRecorder
 }
 
 func Register(ctx context.Context, management *config.Management) error {
 	vmnetcfgs := management.HarvesterNetworkFactory.Network().V1alpha1().VirtualMachineNetworkConfig()
 	ippools := management.HarvesterNetworkFactory.Network().V1alpha1().IPPool()
+	vms := management.KubeVirtFactory.Kubevirt().V1().VirtualMachine()
 
 	handler := &Handler{
 		cacheAllocator:   management.CacheAllocator,
@@ -49,6 +63,9 @@ func Register(ctx context.Context, management *config.Management) error {
 		ippoolController:   ippools,
 		ippoolClient:       ippools,
 		ippoolCache:        ippools.Cache(),
+		vmCache:            vms.Cache(),
+
+		recorder: management.NewRecorder(controllerName, "", ""),
 	}
 
 	ctlnetworkv1.RegisterVirtualMachineNetworkConfigStatusHandler(
@@ -59,6 +76,66 @@ func Register(ctx context.Context, management *config.Management) error {
 		handler.Allocate,
 	)
 
+	vmnetcfgs.Cache().AddIndexer(indexer.VmNetCfgByNetworkIndex, indexer.VmNetCfgByNetwork)
+
+	// Re-allocate for vmnetcfgs holding IP addresses which were quarantined,
+	// and reclaim the ones pending reclaim
+	relatedresource.Watch(ctx, "vmnetcfg-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		ipPool, ok := obj.(*networkv1.IPPool)
+		if !ok || (ipPool.Status.IPv4 == nil && ipPool.Status.IPv6 == nil) {
+			return nil, nil
+		}
+		vmnetcfgGetter := util.VmnetcfgGetter{VmnetcfgCache: handler.vmnetcfgCache}
+		vmNetCfgs, err := vmnetcfgGetter.WhoUseIPPool(ipPool)
+		if err != nil {
+			return nil, err
+		}
+		var keys []relatedresource.Key
+		for _, vmNetCfg := range vmNetCfgs {
+			for _, ncStatus := range vmNetCfg.Status.NetworkConfigs {
+				if ipPool.Status.IPv6 != nil && ncStatus.AllocatedIPv6Address != "" &&
+					isQuarantined(ipPool.Status.IPv6.Allocated[ncStatus.AllocatedIPv6Address]) {
+					keys = append(keys, relatedresource.NewKey(vmNetCfg.Namespace, vmNetCfg.Name))
+					break
+				}
+				if ipPool.Status.IPv4 == nil {
+					continue
+				}
+				if isQuarantined(ipPool.Status.IPv4.Allocated[ncStatus.AllocatedIPAddress]) {
+					keys = append(keys, relatedresource.NewKey(vmNetCfg.Namespace, vmNetCfg.Name))
+					break
+				}
+				if pendingReclaim, ok := ipPool.Status.IPv4.PendingReclaims[ncStatus.AllocatedIPAddress]; ok && pendingReclaim.MACAddress == ncStatus.MACAddress {
+					keys = append(keys, relatedresource.NewKey(vmNetCfg.Namespace, vmNetCfg.Name))
+					break
+				}
+			}
+		}
+		return keys, nil
+	}, vmnetcfgs, ippools)
+
+	// Re-allocate for vmnetcfgs whose IP addresses were reclaimed once the VMs
+	// are started again
+	relatedresource.Watch(ctx, "vmnetcfg-vm-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		vm, ok := obj.(*kubevirtv1.VirtualMachine)
+		if !ok {
+			return nil, nil
+		}
+		vmNetCfg, err := handler.vmnetcfgCache.Get(vm.Namespace, vm.Name)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, ncStatus := range vmNetCfg.Status.NetworkConfigs {
+			if ncStatus.State == networkv1.ReclaimedState {
+				return []relatedresource.Key{relatedresource.NewKey(vmNetCfg.Namespace, vmNetCfg.Name)}, nil
+			}
+		}
+		return nil, nil
+	}, vmnetcfgs, vms)
+
 	vmnetcfgs.OnChange(ctx, controllerName, handler.OnChange)
 	vmnetcfgs.OnRemove(ctx, controllerName, handler.OnRemove)
 
@@ -115,6 +192,26 @@ func (h *Handler) Allocate(vmNetCfg *networkv1.VirtualMachineNetworkConfig, stat
 			return status, fmt.Errorf("ippool %s/%s is not ready", ipPoolNamespace, ipPoolName)
 		}
 
+		if oldNcStatus := findNetworkConfigStatusByMACAddress(vmNetCfg.Status.NetworkConfigs, nc.MACAddress); oldNcStatus != nil {
+			reclaimed, err := h.reclaim(vmNetCfg, *oldNcStatus, ipPool)
+			if err != nil {
+				return status, err
+			}
+			if reclaimed != nil {
+				ncStatuses = append(ncStatuses, *reclaimed)
+
+				// Update VirtualMachineNetworkConfig metrics
+				h.metricsAllocator.UpdateVmNetCfgStatus(
+					fmt.Sprintf("%s/%s", vmNetCfg.Namespace, vmNetCfg.Name),
+					reclaimed.NetworkName,
+					reclaimed.MACAddress,
+					reclaimed.AllocatedIPAddress,
+					string(reclaimed.State),
+				)
+				continue
+			}
+		}
+
 		exists, err := h.cacheAllocator.HasMAC(nc.NetworkName, nc.MACAddress)
 		if err != nil {
 			return status, err
@@ -134,15 +231,27 @@ func (h *Handler) Allocate(vmNetCfg *networkv1.VirtualMachineNetworkConfig, stat
 				dIP = *nc.IPAddress
 			}
 
-			// Recover IP from status (resume from paused state)
+			// Recover IP from status (resume from paused state) unless it
+			// was quarantined in the meantime
 			if oIP, err := findIPAddressFromNetworkConfigStatusByMACAddress(vmNetCfg.Status.NetworkConfigs, nc.MACAddress); err == nil {
-				dIP = oIP
+				if quarantined, _ := h.ipAllocator.IsQuarantined(nc.NetworkName, oIP); !quarantined {
+					dIP = oIP
+				}
 			}
 
-			// Allocate new IP
-			ip, err = h.ipAllocator.AllocateIP(nc.NetworkName, dIP)
-			if err != nil {
-				return status, err
+			if reservation := findReservation(vmNetCfg, nc, ipPool); reservation != nil {
+				// Allocate reserved IP
+				ip, err = h.ipAllocator.AllocateReservedIP(nc.NetworkName, reservation.IPAddress, util.ReservationOwner(*reservation))
+				if err != nil {
+					return status, err
+				}
+				logrus.Infof("(vmnetcfg.Allocate) reserved ip %s was allocated to %s", ip, nc.MACAddress)
+			} else {
+				// Allocate new IP
+				ip, err = h.ipAllocator.AllocateIPWithMAC(nc.NetworkName, dIP, nc.MACAddress)
+				if err != nil {
+					return status, err
+				}
 			}
 
 			if err := h.cacheAllocator.AddMAC(nc.NetworkName, nc.MACAddress, ip); err != nil {
@@ -150,12 +259,18 @@ func (h *Handler) Allocate(vmNetCfg *networkv1.VirtualMachineNetworkConfig, stat
 			}
 		}
 
+		ip6, err := h.allocateIPv6(vmNetCfg, nc, ipPool)
+		if err != nil {
+			return status, err
+		}
+
 		// Prepare VirtualMachineNetworkConfig status
 		ncStatus := networkv1.NetworkConfigStatus{
-			AllocatedIPAddress: ip,
-			MACAddress:         nc.MACAddress,
-			NetworkName:        nc.NetworkName,
-			State:              networkv1.AllocatedState,
+			AllocatedIPAddress:   ip,
+			AllocatedIPv6Address: ip6,
+			MACAddress:           nc.MACAddress,
+			NetworkName:          nc.NetworkName,
+			State:                networkv1.AllocatedState,
 		}
 
 		ncStatuses = append(ncStatuses, ncStatus)
@@ -187,6 +302,18 @@ func (h *Handler) Allocate(vmNetCfg *networkv1.VirtualMachineNetworkConfig, stat
 		ipv4Status.Allocated = allocated
 		ipPoolCpy.Status.IPv4 = ipv4Status
 
+		if ip6 != "" {
+			ipv6Status := ipPoolCpy.Status.IPv6
+			if ipv6Status == nil {
+				ipv6Status = new(networkv1.IPv6Status)
+			}
+			if ipv6Status.Allocated == nil {
+				ipv6Status.Allocated = make(map[string]string)
+			}
+			ipv6Status.Allocated[ip6] = nc.MACAddress
+			ipPoolCpy.Status.IPv6 = ipv6Status
+		}
+
 		if !reflect.DeepEqual(ipPoolCpy, ipPool) {
 			logrus.Infof("(vmnetcfg.Allocate) update ippool %s/%s", ipPool.Namespace, ipPool.Name)
 			ipPoolCpy.Status.LastUpdate = metav1.Now()
@@ -201,6 +328,40 @@ func (h *Handler) Allocate(vmNetCfg *networkv1.VirtualMachineNetworkConfig, stat
 	return status, nil
 }
 
+// allocateIPv6 allocates an IPv6 address for the network interface if the
+// IPPool is a dual-stack one. The IPv6 address recorded in the status is kept
+// as long as it's still held by the same MAC address.
+func (h *Handler) allocateIPv6(vmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig, ipPool *networkv1.IPPool) (string, error) {
+	if ipPool.Spec.IPv6Config == nil {
+		return "", nil
+	}
+
+	var dIP string
+	if nc.IPv6Address != nil {
+		dIP = *nc.IPv6Address
+	}
+
+	if oIP := findIPv6AddressFromNetworkConfigStatusByMACAddress(vmNetCfg.Status.NetworkConfigs, nc.MACAddress); oIP != "" {
+		isAllocated, err := h.ipAllocator.IsIPv6Allocated(nc.NetworkName, oIP)
+		if err != nil {
+			return "", err
+		}
+		quarantined, err := h.ipAllocator.IsIPv6Quarantined(nc.NetworkName, oIP)
+		if err != nil {
+			return "", err
+		}
+		if !isAllocated && !quarantined {
+			// Recover IPv6 address from status (resume from paused state)
+			// unless it was quarantined in the meantime
+			dIP = oIP
+		} else if ipPool.Status.IPv6 != nil && ipPool.Status.IPv6.Allocated[oIP] == nc.MACAddress {
+			return oIP, nil
+		}
+	}
+
+	return h.ipAllocator.AllocateIPv6(nc.NetworkName, dIP)
+}
+
 func (h *Handler) OnRemove(key string, vmNetCfg *networkv1.VirtualMachineNetworkConfig) (*networkv1.VirtualMachineNetworkConfig, error) {
 	if vmNetCfg == nil {
 		return nil, nil
@@ -219,53 +380,111 @@ func (h *Handler) cleanup(vmNetCfg *networkv1.VirtualMachineNetworkConfig) error
 	h.metricsAllocator.DeleteVmNetCfgStatus(vmNetCfg.Namespace + "/" + vmNetCfg.Name)
 
 	for _, ncStatus := range vmNetCfg.Status.NetworkConfigs {
-		// Deallocate IP address from IPAM
-		isAllocated, err := h.ipAllocator.IsAllocated(ncStatus.NetworkName, ncStatus.AllocatedIPAddress)
-		if err != nil {
+		// The IP addresses were already released when they were reclaimed
+		if ncStatus.State == networkv1.ReclaimedState {
+			continue
+		}
+		if err := h.release(ncStatus); err != nil {
 			return err
 		}
-		if isAllocated {
-			if err := h.ipAllocator.DeallocateIP(ncStatus.NetworkName, ncStatus.AllocatedIPAddress); err != nil {
+	}
+	return nil
+}
+
+// release returns the IP addresses of the network interface to the free pool,
+// and removes them from the MAC cache and the IPPool status.
+func (h *Handler) release(ncStatus networkv1.NetworkConfigStatus) error {
+	ipPoolNamespace, ipPoolName := kv.RSplit(ncStatus.NetworkName, "/")
+	ipPool, err := h.ippoolCache.Get(ipPoolNamespace, ipPoolName)
+	if err != nil {
+		return err
+	}
+	releasedAt := time.Now()
+	var quarantineDuration time.Duration
+	if ipPool.Spec.IPv4Config.QuarantineDuration != nil {
+		quarantineDuration = ipPool.Spec.IPv4Config.QuarantineDuration.Duration
+	}
+
+	// Deallocate IP address from IPAM, and keep it from being allocated
+	// again until the quarantine period is over
+	isAllocated, err := h.ipAllocator.IsAllocated(ncStatus.NetworkName, ncStatus.AllocatedIPAddress)
+	if err != nil {
+		return err
+	}
+	if isAllocated {
+		if err := h.ipAllocator.DeallocateIP(ncStatus.NetworkName, ncStatus.AllocatedIPAddress); err != nil {
+			return err
+		}
+		if quarantineDuration > 0 {
+			if err := h.ipAllocator.QuarantineIPUntil(ncStatus.NetworkName, ncStatus.AllocatedIPAddress, releasedAt.Add(quarantineDuration)); err != nil {
 				return err
 			}
 		}
+	}
+
+	// Remove entry from cache
+	exists, err := h.cacheAllocator.HasMAC(ncStatus.NetworkName, ncStatus.MACAddress)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := h.cacheAllocator.DeleteMAC(ncStatus.NetworkName, ncStatus.MACAddress); err != nil {
+			return err
+		}
+	}
 
-		// Remove entry from cache
-		exists, err := h.cacheAllocator.HasMAC(ncStatus.NetworkName, ncStatus.MACAddress)
+	// Deallocate IPv6 address from IPAM
+	if ncStatus.AllocatedIPv6Address != "" && h.ipAllocator.IsIPv6NetworkInitialized(ncStatus.NetworkName) {
+		isAllocated, err := h.ipAllocator.IsIPv6Allocated(ncStatus.NetworkName, ncStatus.AllocatedIPv6Address)
 		if err != nil {
 			return err
 		}
-		if exists {
-			if err := h.cacheAllocator.DeleteMAC(ncStatus.NetworkName, ncStatus.MACAddress); err != nil {
+		if isAllocated {
+			if err := h.ipAllocator.DeallocateIPv6(ncStatus.NetworkName, ncStatus.AllocatedIPv6Address); err != nil {
 				return err
 			}
 		}
+	}
 
-		ipPoolNamespace, ipPoolName := kv.RSplit(ncStatus.NetworkName, "/")
-		if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-			ipPool, err := h.ippoolCache.Get(ipPoolNamespace, ipPoolName)
-			if err != nil {
-				return err
-			}
-
-			ipPoolCpy := ipPool.DeepCopy()
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		ipPool, err := h.ippoolCache.Get(ipPoolNamespace, ipPoolName)
+		if err != nil {
+			return err
+		}
 
-			// Remove record in IPPool status
-			delete(ipPoolCpy.Status.IPv4.Allocated, ncStatus.AllocatedIPAddress)
+		ipPoolCpy := ipPool.DeepCopy()
 
-			if !reflect.DeepEqual(ipPoolCpy, ipPool) {
-				logrus.Infof("(vmnetcfg.cleanup) update ippool %s/%s", ipPool.Namespace, ipPool.Name)
-				ipPoolCpy.Status.LastUpdate = metav1.Now()
-				_, err := h.ippoolClient.UpdateStatus(ipPoolCpy)
-				return err
+		// Remove record in IPPool status, or mark it released if it's to be
+		// quarantined, leaving the ones that are not held by this MAC
+		// address, e.g., quarantined ones, untouched
+		if ipPoolCpy.Status.IPv4.Allocated[ncStatus.AllocatedIPAddress] == ncStatus.MACAddress {
+			if quarantineDuration > 0 {
+				ipPoolCpy.Status.IPv4.Allocated[ncStatus.AllocatedIPAddress] = util.NewReleasedMark(releasedAt)
+			} else {
+				delete(ipPoolCpy.Status.IPv4.Allocated, ncStatus.AllocatedIPAddress)
+			}
+		}
+		if ipPoolCpy.Status.IPv6 != nil && ncStatus.AllocatedIPv6Address != "" &&
+			ipPoolCpy.Status.IPv6.Allocated[ncStatus.AllocatedIPv6Address] == ncStatus.MACAddress {
+			delete(ipPoolCpy.Status.IPv6.Allocated, ncStatus.AllocatedIPv6Address)
+		}
+		if pendingReclaim, ok := ipPoolCpy.Status.IPv4.PendingReclaims[ncStatus.AllocatedIPAddress]; ok &&
+			pendingReclaim.MACAddress == ncStatus.MACAddress {
+			delete(ipPoolCpy.Status.IPv4.PendingReclaims, ncStatus.AllocatedIPAddress)
+			if len(ipPoolCpy.Status.IPv4.PendingReclaims) == 0 {
+				ipPoolCpy.Status.IPv4.PendingReclaims = nil
 			}
+		}
 
-			return nil
-		}); err != nil {
+		if !reflect.DeepEqual(ipPoolCpy, ipPool) {
+			logrus.Infof("(vmnetcfg.release) update ippool %s/%s", ipPool.Namespace, ipPool.Name)
+			ipPoolCpy.Status.LastUpdate = metav1.Now()
+			_, err := h.ippoolClient.UpdateStatus(ipPoolCpy)
 			return err
 		}
-	}
-	return nil
+
+		return nil
+	})
 }
 
 func findIPAddressFromNetworkConfigStatusByMACAddress(ncStatuses []networkv1.NetworkConfigStatus, macAddress string) (ipAddress string, err error) {
@@ -277,8 +496,72 @@ func findIPAddressFromNetworkConfigStatusByMACAddress(ncStatuses []networkv1.Net
 	return net.IPv4zero.String(), fmt.Errorf("could not find allocated ip for mac %s", macAddress)
 }
 
+// findReservation returns the reservation of ipPool for the network interface
+// nc of the VM, if any. An IP address designated to nc takes precedence over
+// reservations, and a reservation by the MAC address of nc over one by the VM.
+// A reservation by the VM goes to the first network interface of the VM in the
+// network that has neither an IP address designated nor one reserved by its MAC
+// address.
+func findReservation(vmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig, ipPool *networkv1.IPPool) *networkv1.Reservation {
+	if nc.IPAddress != nil {
+		return nil
+	}
+
+	reservations := ipPool.Spec.IPv4Config.Reservations
+
+	isReservedByMAC := func(macAddress string) bool {
+		for _, r := range reservations {
+			if r.MACAddress != "" && strings.EqualFold(r.MACAddress, macAddress) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i, r := range reservations {
+		if r.MACAddress != "" && strings.EqualFold(r.MACAddress, nc.MACAddress) {
+			return &reservations[i]
+		}
+	}
+
+	vm := vmNetCfg.Namespace + "/" + vmNetCfg.Spec.VMName
+	for i, r := range reservations {
+		if r.VM != vm {
+			continue
+		}
+		for _, other := range vmNetCfg.Spec.NetworkConfigs {
+			if other.NetworkName != nc.NetworkName || other.IPAddress != nil || isReservedByMAC(other.MACAddress) {
+				continue
+			}
+			if other.MACAddress == nc.MACAddress {
+				return &reservations[i]
+			}
+			return nil
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func findIPv6AddressFromNetworkConfigStatusByMACAddress(ncStatuses []networkv1.NetworkConfigStatus, macAddress string) string {
+	for _, ncStatus := range ncStatuses {
+		if ncStatus.MACAddress == macAddress {
+			return ncStatus.AllocatedIPv6Address
+		}
+	}
+	return ""
+}
+
 func updateAllNetworkConfigState(ncStatuses []networkv1.NetworkConfigStatus) {
 	for i := range ncStatuses {
 		ncStatuses[i].State = networkv1.PendingState
 	}
 }
+
+// isQuarantined tells if val, the value of an IP address in the allocated
+// map of an IPPool, marks it as declined by a DHCP client.
+func isQuarantined(val string) bool {
+	_, quarantined := util.ParseQuarantinedMark(val)
+	return quarantined
+}
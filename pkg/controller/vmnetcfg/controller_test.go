@@ -3,10 +3,13 @@ package vmnetcfg
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	"github.com/harvester/vm-dhcp-controller/pkg/cache"
@@ -14,6 +17,7 @@ import (
 	"github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/fake"
 	"github.com/harvester/vm-dhcp-controller/pkg/ipam"
 	"github.com/harvester/vm-dhcp-controller/pkg/metrics"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
 	"github.com/harvester/vm-dhcp-controller/pkg/util/fakeclient"
 )
 
@@ -36,10 +40,16 @@ const (
 	testIPAddress2  = "192.168.0.177"
 	testMACAddress1 = "11:22:33:44:55:66"
 	testMACAddress2 = "22:33:44:55:66:77"
+
+	testPrefix       = "fd00:48::/64"
+	testIPv6StartIP  = "fd00:48::100"
+	testIPv6EndIP    = "fd00:48::1ff"
+	testIPv6Address1 = "fd00:48::100"
+	testIPv6Address2 = "fd00:48::101"
 )
 
-func newTestVmNetCfgBuilder() *vmNetCfgBuilder {
-	return newVmNetCfgBuilder(testVmNetCfgNamespace, testVmNetCfgName)
+func newTestVmNetCfgBuilder() *VmNetCfgBuilder {
+	return NewVmNetCfgBuilder(testVmNetCfgNamespace, testVmNetCfgName)
 }
 
 func newTestVmNetCfgStatusBuilder() *vmNetCfgStatusBuilder {
@@ -344,6 +354,245 @@ func TestHandler_Allocate(t *testing.T) {
 		assert.Equal(t, expectedIPPool, ipPool)
 	})
 
+	t.Run("reclaim ip due", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).
+			WithNetworkConfig(testIPAddress2, testMACAddress2, testNetworkName).
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).
+			WithNetworkConfigStatus(testIPAddress2, testMACAddress2, testNetworkName, networkv1.AllocatedState).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress1, testMACAddress1).
+			Allocated(testIPAddress2, testMACAddress2).
+			PendingReclaim(testIPAddress1, testMACAddress1, networkv1.ReclaimReasonInactive, metav1.NewTime(time.Now().Add(-time.Minute))).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress1, testIPAddress2).Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress1, testIPAddress1).
+			Add(testNetworkName, testMACAddress2, testIPAddress2).Build()
+
+		expectedStatus := newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus("", testMACAddress1, testNetworkName, networkv1.ReclaimedState).
+			WithNetworkConfigStatus(testIPAddress2, testMACAddress2, testNetworkName, networkv1.AllocatedState).Build()
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress2, testMACAddress2).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress2).Build()
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress2, testIPAddress2).Build()
+
+		clientset := fake.NewSimpleClientset(givenIPPool)
+		recorder := record.NewFakeRecorder(1)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+			vmCache:          fakeclient.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+			recorder:         recorder,
+		}
+
+		status, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		assert.NotNil(t, status.NetworkConfigs[0].ReclaimedAt)
+		SanitizeStatus(&expectedStatus)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+
+		ipPool, err := handler.ippoolClient.Get(testIPPoolNamespace, testIPPoolName, metav1.GetOptions{})
+		assert.Nil(t, err)
+
+		ippool.SanitizeStatus(&expectedIPPool.Status)
+		ippool.SanitizeStatus(&ipPool.Status)
+
+		assert.Equal(t, expectedIPPool, ipPool)
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+		assert.Equal(t, fmt.Sprintf("Normal Reclaiming Reclaiming ip %s from %s of vmnetcfg %s as it's Inactive", testIPAddress1, testMACAddress1, testKey), <-recorder.Events)
+	})
+
+	t.Run("reclaim ip due of a ready vm", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			VMName(testVmNetCfgName).
+			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress1, testMACAddress1).
+			PendingReclaim(testIPAddress1, testMACAddress1, networkv1.ReclaimReasonInactive, metav1.NewTime(time.Now().Add(-time.Minute))).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenVM := &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testVmNetCfgNamespace,
+				Name:      testVmNetCfgName,
+			},
+			Status: kubevirtv1.VirtualMachineStatus{
+				Conditions: []kubevirtv1.VirtualMachineCondition{
+					{
+						Type:   kubevirtv1.VirtualMachineReady,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+		}
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress1).Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress1, testIPAddress1).Build()
+
+		expectedStatus := newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).Build()
+
+		clientset := fake.NewSimpleClientset(givenIPPool, givenVM)
+		recorder := record.NewFakeRecorder(1)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+			vmCache:          fakeclient.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+			recorder:         recorder,
+		}
+
+		status, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedStatus)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+		allocated, err := handler.ipAllocator.IsAllocated(testNetworkName, testIPAddress1)
+		assert.Nil(t, err)
+		assert.True(t, allocated)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("reclaim ip due with quarantine duration", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			QuarantineDuration(&metav1.Duration{Duration: time.Hour}).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress1, testMACAddress1).
+			PendingReclaim(testIPAddress1, testMACAddress1, networkv1.ReclaimReasonInactive, metav1.NewTime(time.Now().Add(-time.Minute))).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress1).Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress1, testIPAddress1).Build()
+
+		clientset := fake.NewSimpleClientset(givenIPPool)
+		recorder := record.NewFakeRecorder(1)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+			vmCache:          fakeclient.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+			recorder:         recorder,
+		}
+
+		_, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		ipPool, err := handler.ippoolClient.Get(testIPPoolNamespace, testIPPoolName, metav1.GetOptions{})
+		assert.Nil(t, err)
+
+		// The released ip is marked with the release time, and kept from
+		// being allocated again
+		_, released := util.ParseReleasedMark(ipPool.Status.IPv4.Allocated[testIPAddress1])
+		assert.True(t, released)
+		quarantined, err := handler.ipAllocator.IsQuarantined(testNetworkName, testIPAddress1)
+		assert.Nil(t, err)
+		assert.True(t, quarantined)
+	})
+
+	t.Run("reserved ips", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			VMName("test-vm").
+			WithNetworkConfig("", testMACAddress1, testNetworkName).
+			WithNetworkConfig("", testMACAddress2, testNetworkName).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			Reservation(testIPAddress1, "22:33:44:55:66:77", "").
+			Reservation(testIPAddress2, "", testKey).
+			NetworkName(testNetworkName).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Reserve(testNetworkName, testIPAddress1, testMACAddress2).
+			Reserve(testNetworkName, testIPAddress2, testKey).Build()
+
+		// The VM reservation goes to the network interface not reserved by
+		// its MAC address
+		expectedStatus := newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus(testIPAddress2, testMACAddress1, testNetworkName, networkv1.AllocatedState).
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress2, testNetworkName, networkv1.AllocatedState).Build()
+		expectedCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress1, testIPAddress2).
+			Add(testNetworkName, testMACAddress2, testIPAddress1).Build()
+		expectedIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress2, testIPAddress1).
+			Reserve(testNetworkName, testIPAddress1, testMACAddress2).
+			Reserve(testNetworkName, testIPAddress2, testKey).Build()
+
+		clientset := fake.NewSimpleClientset(givenVmNetCfg, givenIPPool)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+		}
+
+		status, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedStatus)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+
+		assert.Equal(t, expectedIPAllocator, handler.ipAllocator)
+		assert.Equal(t, expectedCacheAllocator, handler.cacheAllocator)
+	})
+
 	t.Run("ippool cache not ready", func(t *testing.T) {
 		givenVmNetCfg := newTestVmNetCfgBuilder().
 			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).
@@ -365,4 +614,116 @@ func TestHandler_Allocate(t *testing.T) {
 		_, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
 		assert.NotNil(t, fmt.Sprintf("ippool %s/%s is not ready", testIPPoolNamespace, testIPPoolName), err)
 	})
+
+	t.Run("dual-stack ippool", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).
+			WithNetworkConfig(testIPAddress2, testMACAddress2, testNetworkName).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			IPv6Config(testPrefix, testIPv6StartIP, testIPv6EndIP).
+			NetworkName(testNetworkName).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			IPv6Subnet(testNetworkName, testPrefix, testIPv6StartIP, testIPv6EndIP).Build()
+
+		expectedStatus := newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).
+			WithNetworkConfigStatus(testIPAddress2, testMACAddress2, testNetworkName, networkv1.AllocatedState).
+			AllocatedIPv6Address(testMACAddress1, testIPv6Address1).
+			AllocatedIPv6Address(testMACAddress2, testIPv6Address2).Build()
+		expectedIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			IPv6Config(testPrefix, testIPv6StartIP, testIPv6EndIP).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress1, testMACAddress1).
+			Allocated(testIPAddress2, testMACAddress2).
+			AllocatedIPv6(testIPv6Address1, testMACAddress1).
+			AllocatedIPv6(testIPv6Address2, testMACAddress2).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+
+		clientset := fake.NewSimpleClientset(givenVmNetCfg, givenIPPool)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+		}
+
+		status, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedStatus)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+
+		ipPool, err := handler.ippoolClient.Get(testIPPoolNamespace, testIPPoolName, metav1.GetOptions{})
+		assert.Nil(t, err)
+
+		ippool.SanitizeStatus(&expectedIPPool.Status)
+		ippool.SanitizeStatus(&ipPool.Status)
+		assert.Equal(t, expectedIPPool, ipPool)
+
+		// The IPv6 addresses are kept on the next round
+		givenVmNetCfg.Status = status
+		status, err = handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+	})
+
+	t.Run("dual-stack ippool with quarantined ipv6 address", func(t *testing.T) {
+		givenVmNetCfg := newTestVmNetCfgBuilder().
+			WithNetworkConfig(testIPAddress1, testMACAddress1, testNetworkName).Build()
+		givenVmNetCfg.Status = newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).
+			AllocatedIPv6Address(testMACAddress1, testIPv6Address1).Build()
+		givenIPPool := newTestIPPoolBuilder().
+			ServerIP(testServerIP).
+			CIDR(testCIDR).
+			PoolRange(testStartIP, testEndIP).
+			IPv6Config(testPrefix, testIPv6StartIP, testIPv6EndIP).
+			NetworkName(testNetworkName).
+			Allocated(testIPAddress1, testMACAddress1).
+			AllocatedIPv6(testIPv6Address1, util.QuarantinedMark).
+			CacheReadyCondition(corev1.ConditionTrue, "", "").Build()
+		givenCacheAllocator := newTestCacheAllocatorBuilder().
+			MACSet(testNetworkName).
+			Add(testNetworkName, testMACAddress1, testIPAddress1).Build()
+		givenIPAllocator := newTestIPAllocatorBuilder().
+			IPSubnet(testNetworkName, testCIDR, testStartIP, testEndIP).
+			Allocate(testNetworkName, testIPAddress1).
+			IPv6Subnet(testNetworkName, testPrefix, testIPv6StartIP, testIPv6EndIP).
+			QuarantineIPv6(testNetworkName, testIPv6Address1).Build()
+
+		expectedStatus := newTestVmNetCfgStatusBuilder().
+			WithNetworkConfigStatus(testIPAddress1, testMACAddress1, testNetworkName, networkv1.AllocatedState).
+			AllocatedIPv6Address(testMACAddress1, testIPv6Address2).Build()
+
+		clientset := fake.NewSimpleClientset(givenVmNetCfg, givenIPPool)
+
+		handler := Handler{
+			cacheAllocator:   givenCacheAllocator,
+			ipAllocator:      givenIPAllocator,
+			metricsAllocator: metrics.New(),
+			ippoolClient:     fakeclient.IPPoolClient(clientset.NetworkV1alpha1().IPPools),
+			ippoolCache:      fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools),
+		}
+
+		status, err := handler.Allocate(givenVmNetCfg, givenVmNetCfg.Status)
+		assert.Nil(t, err)
+
+		SanitizeStatus(&expectedStatus)
+		SanitizeStatus(&status)
+		assert.Equal(t, expectedStatus, status)
+	})
 }
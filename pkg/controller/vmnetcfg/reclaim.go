@@ -0,0 +1,99 @@
+package vmnetcfg
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+const reclaimingReason = "Reclaiming"
+
+// reclaim carries out the pending reclaim of the IP address of the network
+// interface once it's due, and keeps the network interface reclaimed until
+// the VM is started again. It returns the status of the reclaimed network
+// interface, or nil if the network interface should have IP addresses
+// allocated as usual.
+func (h *Handler) reclaim(vmNetCfg *networkv1.VirtualMachineNetworkConfig, ncStatus networkv1.NetworkConfigStatus, ipPool *networkv1.IPPool) (*networkv1.NetworkConfigStatus, error) {
+	if ncStatus.State == networkv1.ReclaimedState {
+		started, err := h.isVMStartedSince(vmNetCfg.Namespace, vmNetCfg.Spec.VMName, ncStatus.ReclaimedAt)
+		if err != nil || started {
+			return nil, err
+		}
+		return &ncStatus, nil
+	}
+
+	if ipPool.Status.IPv4 == nil || ncStatus.AllocatedIPAddress == "" {
+		return nil, nil
+	}
+	pendingReclaim, ok := ipPool.Status.IPv4.PendingReclaims[ncStatus.AllocatedIPAddress]
+	if !ok || pendingReclaim.MACAddress != ncStatus.MACAddress {
+		return nil, nil
+	}
+	if wait := time.Until(pendingReclaim.ReclaimAt.Time); wait > 0 {
+		h.vmnetcfgController.EnqueueAfter(vmNetCfg.Namespace, vmNetCfg.Name, wait)
+		return nil, nil
+	}
+
+	// The pending reclaim might be stale. The network interface of a ready VM
+	// would get no IP address until the VM is started again, however soon its
+	// guest asks for one.
+	if pendingReclaim.Reason == networkv1.ReclaimReasonInactive {
+		ready, err := h.isVMStartedSince(vmNetCfg.Namespace, vmNetCfg.Spec.VMName, nil)
+		if err != nil || ready {
+			return nil, err
+		}
+	}
+
+	h.recorder.Eventf(ipPool, corev1.EventTypeNormal, reclaimingReason,
+		"Reclaiming ip %s from %s of vmnetcfg %s/%s as it's %s",
+		ncStatus.AllocatedIPAddress, ncStatus.MACAddress, vmNetCfg.Namespace, vmNetCfg.Name, pendingReclaim.Reason)
+
+	if err := h.release(ncStatus); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("(vmnetcfg.reclaim) ip %s of %s was reclaimed in ipam %s", ncStatus.AllocatedIPAddress, ncStatus.MACAddress, ncStatus.NetworkName)
+
+	reclaimedAt := metav1.Now()
+	return &networkv1.NetworkConfigStatus{
+		MACAddress:  ncStatus.MACAddress,
+		NetworkName: ncStatus.NetworkName,
+		State:       networkv1.ReclaimedState,
+		ReclaimedAt: &reclaimedAt,
+	}, nil
+}
+
+// isVMStartedSince tells if the VM has become ready after since, or if it's
+// ready at all if since is nil.
+func (h *Handler) isVMStartedSince(namespace, name string, since *metav1.Time) (bool, error) {
+	vm, err := h.vmCache.Get(namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, condition := range vm.Status.Conditions {
+		if condition.Type != kubevirtv1.VirtualMachineReady || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		return since == nil || condition.LastTransitionTime.After(since.Time), nil
+	}
+	return false, nil
+}
+
+func findNetworkConfigStatusByMACAddress(ncStatuses []networkv1.NetworkConfigStatus, macAddress string) *networkv1.NetworkConfigStatus {
+	for i := range ncStatuses {
+		if ncStatuses[i].MACAddress == macAddress {
+			return &ncStatuses[i]
+		}
+	}
+	return nil
+}
@@ -79,7 +79,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _chartCrdsNetworkHarvesterhciIo_ippoolsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x19\x5d\x6f\xe3\xc6\xf1\x5d\xbf\x62\x8a\x3e\x38\x01\x4c\x19\x87\x14\x45\x21\xe0\xd0\x3a\xb2\x9a\x08\x71\x1d\x43\xb2\xaf\x08\x8a\x3e\x8c\xb8\x23\x69\xe3\xe5\x2e\xb3\x1f\xb2\xdd\x5c\xfe\x7b\x31\x4b\xd2\xa2\x28\x92\xa6\x75\x77\x41\xf6\x49\x9a\xdd\x9d\xef\x99\x9d\x19\x26\x49\x32\xc2\x5c\x7e\x20\xeb\xa4\xd1\x13\xc0\x5c\xd2\x93\x27\xcd\xff\xdc\xf8\xe1\x6f\x6e\x2c\xcd\xc5\xee\xdd\xe8\x41\x6a\x31\x81\x69\x70\xde\x64\x0b\x72\x26\xd8\x94\xae\x68\x2d\xb5\xf4\xd2\xe8\x51\x46\x1e\x05\x7a\x9c\x8c\x00\x50\x6b\xe3\x91\xc1\x8e\xff\x02\xfc\xfa\xdb\x08\x40\x63\x46\x13\x90\x79\x6e\x8c\x72\x63\x4d\xfe\xd1\xd8\x87\xf1\x16\xed\x8e\x9c\x27\xbb\x4d\xe5\x58\x9a\x91\xcb\x29\xe5\x4b\x1b\x6b\x42\x3e\x81\xae\x63\x05\xba\x12\x7d\xc1\xda\xfc\xf6\xd6\x18\x15\x01\x4a\x3a\xff\x43\x0d\x78\x2d\x9d\x8f\x1b\xb9\x0a\x16\xd5\x0b\x17\x11\xe6\xb6\xc6\xfa\x9b\x3d\xb6\x84\x77\x55\xed\x67\x79\x4c\xea\x4d\x50\x68\xab\xcb\x23\x00\x97\x9a\x9c\x26\x10\xef\xe6\x98\x92\x18\x01\xec\x0a\x3d\x46\x5c\x09\xa0\x10\x51\x3d\xa8\x6e\xad\xd4\x9e\xec\xd4\xa8\x90\xe9\x17\x4a\x3f\x3b\xa3\x6f\xd1\x6f\x27\x30\x66\xc1\x2b\xad\x30\xc6\x78\xa2\xd2\xda\xcd\xec\xee\xdf\x3f\x2e\x7e\x28\x61\xfe\x99\xc9\x3a\x6f\xa5\xde\xb4\x20\xf2\xe8\x83\x1b\xcb\x7c\xf7\x97\x31\xee\x50\x2a\x5c\xa9\x43\x6c\x97\x1f\x2e\xe7\xd7\x97\xdf\x5e\xcf\x0e\xf0\x31\x7f\x1b\xb2\xfd\x08\x83\x8b\x52\xee\x71\xdd\x2f\x67\x57\x6f\x42\x93\x1a\x5d\xe8\xc4\xfd\xe7\xef\x5f\xfd\x63\xcc\x97\xde\xbf\x3f\x5b\xd0\x46\xb2\x79\x49\x9c\x7d\xfd\xdf\xf2\xe8\x01\x9d\xc5\xec\xbb\xf9\xf2\x6e\xb6\x68\x50\x7b\x45\x09\xed\xc4\xa6\x98\x6e\x69\x41\x28\x9e\x3b\x88\x4d\x2f\xa7\xdf\xcf\x16\xb3\xcb\xab\x9f\x3e\x9d\xd8\xe5\x86\xb4\xef\x23\x76\xf9\xdd\xec\xe6\x6e\x38\xb1\x2a\xd0\xc6\xa9\xa5\x18\x63\x77\x32\x23\xe7\x31\xcb\x9b\x58\x0f\xd0\x09\xf4\x85\x13\x14\xdb\xbb\x77\xa8\xf2\x2d\xbe\x2b\x5c\x3b\xdd\x52\x86\x93\xf2\xbc\xc9\x49\x5f\xde\xce\x3f\x7c\xb3\x3c\x00\x03\xe4\xd6\xe4\x64\xbd\xac\x02\xa5\x58\xb5\xdc\x51\x83\x02\x08\x72\xa9\x95\xb9\x8f\x49\xe5\x63\x72\xb0\x07\xc0\x04\x8a\x5b\x20\x38\x89\x90\x03\xbf\xa5\x2a\x7a\x48\x94\x3c\x81\x59\x83\xdf\x4a\x07\x96\x72\x4b\x8e\x74\x91\x56\x18\x8c\x1a\xcc\xea\x67\x4a\xfd\xb8\x81\x7a\x49\x96\xd1\x70\x5c\x07\x25\x20\x35\x7a\x47\xd6\x83\xa5\xd4\x6c\xb4\xfc\xdf\x0b\x6e\x07\xde\x44\xa2\x0a\x3d\x39\x1f\x1d\xd7\x6a\x54\xb0\x43\x15\xe8\x1c\x50\x8b\x06\xe6\x0c\x9f\xc1\x12\xd3\x84\xa0\x6b\xf8\xe2\x05\xd7\xe4\xe3\x5f\xc6\x12\x48\xbd\x36\x13\xd8\x7a\x9f\xbb\xc9\xc5\xc5\x46\xfa\x2a\xa3\xa6\x26\xcb\x82\x96\xfe\xf9\x22\x35\xda\x5b\xb9\x0a\xde\x58\x77\x21\x68\x47\xea\xc2\xc9\x4d\x82\x36\xdd\x4a\x4f\xa9\x0f\x96\x2e\x30\x97\x49\x14\x44\x47\xff\x1a\x67\xe2\xcf\xb6\xcc\xc1\xee\x80\xec\x91\xef\x14\x2b\x66\xc8\x37\x98\x87\x93\x27\x48\x07\x58\xa2\x2a\x44\xdc\x5b\x81\x41\xac\xba\xc5\x6c\x79\x07\x15\x27\x85\xa5\x0a\xa3\xec\x8f\x1e\xe9\xa5\xb2\x0f\x6b\x53\xea\x35\xd9\xe2\xde\xda\x9a\x2c\xe2\x24\x2d\x72\x23\xb5\x8f\x7f\x52\x25\x49\x7b\x70\x61\x95\x49\xcf\x6e\xf0\x4b\x20\xe7\xd9\x74\x4d\xb4\xd3\xf8\xea\xc0\x8a\x20\xe4\xec\xec\xa2\x79\x60\xae\x61\x8a\x19\xa9\x29\x3a\xfa\x9d\x6d\xc5\x56\x71\x09\x1b\x61\x90\xb5\xea\x6f\x69\xf3\x70\xa1\xde\xda\x46\xf5\x60\xee\x57\x7b\x9c\xf2\xe2\x14\x3e\x35\x7a\x2d\x37\xcd\x9d\xbe\x5b\xbc\x52\x29\x6c\x1b\xbc\x53\x86\xfd\x7a\x4a\x1e\xc2\x8a\xac\x26\x4f\x2e\xd9\xa1\x92\xa2\x5e\x1a\x34\x57\x02\x19\x39\x87\x1b\xce\xc2\xf3\xab\x05\x3b\xa1\xcc\xb2\xe0\x6b\x8f\x58\x73\xd9\xa0\x98\x03\x52\x6b\x78\xff\x1e\x8c\x12\x4b\x52\xeb\x96\xb3\xa2\x8b\xe6\xda\xd8\x0c\xfd\x24\xaa\xa7\xf5\x80\xf4\x94\x75\xdc\x1d\xa0\x80\x0c\x9f\xe6\x11\x01\x7c\xd3\xa3\x41\xb4\x16\x9f\xdb\xb8\x36\x19\x4a\xcd\x15\xc1\x49\xfa\x2f\xae\x2f\x89\x5d\xb4\x1d\xc1\xa7\x09\xd7\xcf\xbc\x22\x74\xc4\x0f\x54\x1f\xef\xf5\x8a\xe1\x70\x69\x9f\x7f\x09\x9e\xf7\x06\x69\x37\x77\xbf\x4c\x5c\xfc\xb5\x93\xee\x0f\x21\x5e\xd4\x4c\xc3\xf5\xf5\xaa\x1b\x0e\x12\x0e\xde\x1c\x72\x70\x18\x76\xb3\x22\xf5\xbf\x16\x75\xf0\x86\xc8\x8b\xa2\x3f\xa5\x2a\x88\x0e\x47\x18\x2c\x7e\xaf\xe1\x61\xa8\x7e\xfa\x0d\x5c\xac\x4f\xd3\x61\x21\xec\x97\xd0\xa3\xf3\x68\xfd\x1f\xde\x89\x96\xcc\xe5\xe7\x17\x9f\xdf\x7f\x69\xa9\x23\x88\x12\x8e\xaf\x8e\x9d\xa8\xb6\x9e\x68\x3f\x7a\x57\xf7\xeb\xe4\x07\xac\x8c\xa4\x8a\x69\x30\x3a\x25\x70\xd4\x4e\xa5\x52\xc3\xd9\x9f\xb6\xe8\xbe\x2a\x95\x30\x2e\xa3\xe6\x6b\xf8\xf8\x11\x18\xee\xea\xc0\xb3\x16\x44\xd6\x04\x4f\x1d\x4f\xf5\xab\xbe\xf1\xe5\xde\xf2\x45\x64\xeb\x73\xbe\xe6\x2e\x96\x91\xf3\xdb\x3f\x9c\xa8\xcb\x92\xb1\xcf\x27\x6c\xb7\xd7\x27\xb1\x30\x6b\x01\xe7\xd5\x28\xe4\x10\x5c\x29\xed\x68\xab\x37\x08\x86\xab\xa2\xd5\xe2\x43\xfc\xbf\xcd\xf7\x0b\x57\x3e\x74\xfd\x12\xd6\xf4\xfc\xda\xdc\xe4\x98\xa9\x0c\x9f\xae\x49\x6f\xb8\x7b\xfe\xeb\xb1\x33\xf4\x3a\xc2\x49\x92\xdf\xec\x99\x79\xcd\x07\x86\xd8\x3f\xc7\xe0\xda\x6c\x5f\x30\xbe\x32\x46\x11\xea\x83\xdd\x76\x7f\x49\xe0\x78\xba\x54\xc7\x74\xdc\x59\xc4\x59\xc5\xd0\xde\x02\x37\xa4\xfd\xad\x11\x0b\x5a\xbf\xb5\xb9\x90\x19\xeb\xed\x94\x30\xd5\xa7\x96\xc5\xba\x9a\xd9\x9d\x74\x3b\xc8\x8e\x17\xa8\xbf\xab\xae\xd6\xfd\xfc\xaa\x68\xae\x99\x0c\xf8\x2d\x7a\xd8\x1a\x25\x1c\x04\x2d\x7f\x09\x04\xf3\xab\x72\xa4\x70\x0e\x52\x73\xaa\xe7\x76\xfb\xfe\x7e\x7e\xe5\xc6\x00\xdf\x52\xca\x0e\x01\x8f\x5d\x39\x45\x18\x7d\xe6\xe1\xc7\x9b\xeb\x9f\x80\xcf\xc5\x7b\xe7\x45\x8f\xcd\x44\x35\xa0\x92\xc5\xf0\xa3\x90\x2f\xe2\x64\x0a\x25\x3f\x29\xe6\xdc\xc7\xba\x0e\xf4\x5c\xad\x6b\x0f\xa8\x05\x6c\x49\xe5\xdc\xc7\x3f\x10\xb8\x60\x4b\x49\x98\x5c\xdc\x8d\x2a\x06\x61\x80\xdb\xf2\x0d\x79\x48\x8d\x5e\xab\xb6\xce\x7c\x80\xce\x7b\xf2\xd3\x7e\xec\x76\x6c\x93\xce\x72\xf1\xb5\x52\x5d\xa1\xf3\x77\x16\xb5\x93\xd5\x88\xad\xab\xec\x39\x30\xf9\x35\x3a\x0f\x5e\x66\x54\x0c\x2f\x2a\xce\xc0\xbf\xa0\x22\x51\x4c\x3a\x8c\x26\x38\x18\x06\xb6\x28\xc4\x00\x6a\xe3\xb7\x64\xdb\x15\x36\xe0\x11\x63\x31\xee\xe3\x38\x64\xb0\x08\x77\x71\x22\xb6\x17\x43\xba\x9a\x1c\x8f\xe8\xba\xc6\x2b\x83\x79\xaa\xf2\xe4\x10\x66\xbe\x0f\x19\xea\xc4\x12\x0a\x4e\xa0\xd5\x55\x90\x5a\xc8\x14\xe3\x14\x4a\x90\x47\xa9\x1c\xe0\xca\x84\xae\xc2\x0a\x4a\x81\x5e\x8c\x70\x2a\xeb\x96\xd0\x35\xe7\x9c\x1d\x9c\xb3\x1a\x8b\xe3\x5c\x8b\x1c\xba\xc3\x99\x6b\x32\x74\xb2\x32\xdb\x72\x74\x07\x47\xcb\x78\xb4\x98\xa8\xd6\x98\x39\x8f\xae\x68\xd6\x70\x67\x03\x9d\xc3\x3f\x51\x39\x3a\x87\x7b\xfd\xa0\xcd\xe3\xe9\x7c\xc5\x03\x83\xf4\xc4\x29\xc7\xac\x21\x55\xc1\x71\xbd\xf0\xc2\xd7\x89\xa4\xfb\x3a\x84\xa4\x3b\xe2\x92\x88\xb7\x65\xa3\xb7\x30\xea\xee\x23\xb9\xee\x7c\xeb\x33\x88\x4a\x99\x94\x43\xab\x5d\x71\xf5\x6f\x49\xaf\xcd\x19\x06\x8e\x6d\x3a\x9b\x9e\x97\xef\x46\xa7\xcd\x6d\xda\x0b\x96\xd7\x6f\xf6\x15\xba\xcd\x4f\x59\xf5\xbd\xda\x57\xa9\x41\x22\xee\xd3\xe2\x31\xa5\xaa\x73\xe0\xdd\x84\x73\xe0\xf0\x8a\xb1\x95\xe2\x11\x30\xd6\xe0\x62\x02\xde\x86\x02\xb7\xf3\xc6\xc6\xc2\x71\x0f\x09\xab\x97\xf1\x7e\xc5\x61\x19\xe9\xf0\xeb\x6f\xa3\xff\x07\x00\x00\xff\xff\x51\x01\x92\x53\xb3\x1d\x00\x00")
+var _chartCrdsNetworkHarvesterhciIo_ippoolsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x3c\x7f\x6f\xdb\x38\x96\xff\xeb\x53\xbc\xc5\xfd\xd1\x29\x60\xbb\xd3\x9d\x4e\x30\x67\xa0\xb8\x73\x93\xcc\x8c\xb1\x69\x9a\x4b\xda\xde\x2c\x0e\x87\x03\x2d\x3d\xdb\xdc\x50\xa4\x86\xa4\x9c\x78\x77\xf6\xbb\x1f\x1e\x45\x59\x92\x2d\x4a\xb2\x93\x76\x76\xa2\x00\x6d\x24\xea\x91\x7c\xbf\x7f\x51\xe3\xf1\x38\x62\x19\xff\x8c\xda\x70\x25\xa7\xc0\x32\x8e\x8f\x16\x25\xfd\x65\x26\xf7\x3f\x98\x09\x57\xaf\x36\xaf\xa3\x7b\x2e\x93\x29\x9c\xe7\xc6\xaa\xf4\x16\x8d\xca\x75\x8c\x17\xb8\xe4\x92\x5b\xae\x64\x94\xa2\x65\x09\xb3\x6c\x1a\x01\x30\x29\x95\x65\x74\xdb\xd0\x9f\x00\xff\xf8\x67\x04\x20\x59\x8a\x53\xe0\x59\xa6\x94\x30\x13\x89\xf6\x41\xe9\xfb\xc9\x9a\xe9\x0d\x1a\x8b\x7a\x1d\xf3\x09\x57\x91\xc9\x30\xa6\x97\x56\x5a\xe5\xd9\x14\x42\xc3\x0a\x70\x1e\x7c\xb1\xb4\xf9\xcd\x8d\x52\xc2\xdd\x10\xdc\xd8\xbf\xd4\x6e\x5e\x71\x63\xdd\x83\x4c\xe4\x9a\x89\xdd\x2a\xdc\x3d\xb3\x56\xda\x5e\x57\xd0\xc6\xf4\x54\xd4\xfe\x6b\xdc\xff\x0d\x97\xab\x5c\x30\x5d\xbe\x1c\x01\x98\x58\x65\x38\x05\xf7\x6e\xc6\x62\x4c\x22\x80\x4d\x81\x47\xb7\xb2\x31\xb0\x24\x71\xe8\x61\xe2\x46\x73\x69\x51\x9f\x2b\x91\xa7\x25\x5a\xc6\xf0\x37\xa3\xe4\x0d\xb3\xeb\x29\x4c\x68\xe3\x25\x56\x08\xa2\x9b\xb4\xc4\xda\xf5\xe5\xc7\xff\xfe\x70\xfb\x17\x7f\xcf\x6e\x69\x5a\x63\x35\x97\xab\x16\x40\x96\xd9\xdc\x4c\x78\xb6\x79\x33\x61\x1b\xc6\x05\x5b\x88\x26\xb4\xd9\xe7\xd9\xfc\x6a\xf6\xee\xea\xb2\x01\x8f\xd6\xb7\x42\xdd\x0d\x30\x37\x98\x34\x60\x7d\xba\xbb\xbc\x38\x1e\xcc\x42\xe5\xb2\x09\xe7\xdd\x87\x4f\xd7\xc7\x01\x8a\x95\x2c\x90\x6b\xfe\xe7\x3f\xbe\xf9\xcf\x09\xbd\xf4\xf6\xed\x8b\x5b\x5c\x71\x62\x27\x4c\x5e\xbc\xfc\x5f\x3f\xb4\x31\xd1\xed\xe5\x4f\xf3\xbb\x8f\x97\xb7\x97\x17\xc7\x60\xb3\x7d\xb2\x73\x16\xaf\xf1\x16\x59\xb2\x0d\x4c\x76\x3e\x3b\xff\xf9\xf2\xf6\x72\x76\xf1\xd7\xa7\x4f\x36\x5b\xa1\xb4\x5d\x93\xcd\x7e\xba\xbc\xfe\x38\x7c\xb2\x52\x62\x27\xb1\x46\x27\xac\x1f\x79\x8a\xc6\xb2\x34\xdb\x87\xda\x00\x97\x30\x5b\x70\x53\x31\xe9\xe6\x35\x13\xd9\x9a\xbd\x76\xb7\x4c\xbc\xc6\xd4\xa9\x00\xfa\x4b\x65\x28\x67\x37\xf3\xcf\xdf\xdd\x35\x6e\x03\x64\x5a\x65\xa8\x2d\x2f\x25\xae\xb8\x6a\x4a\xa8\x76\x17\x20\x41\x13\x6b\x9e\xd1\x0a\xa7\xf0\xdb\xb8\xf1\x0c\x80\x26\x28\xde\x82\x84\xb4\x11\x1a\xb0\x6b\x2c\xc5\x10\x13\xbf\x26\x50\x4b\xb0\x6b\x6e\x40\x63\xa6\xd1\xa0\x2c\xf4\x13\xdd\x66\x12\xd4\xe2\x6f\x18\xdb\xc9\x1e\xe8\x3b\xd4\x04\x06\xcc\x5a\xe5\x22\x81\x58\xc9\x0d\x6a\x0b\x1a\x63\xb5\x92\xfc\xef\x3b\xd8\x06\xac\x72\x93\x0a\x66\xd1\x58\x27\x01\x5a\x32\x01\x1b\x26\x72\x1c\x01\x93\xc9\x1e\xe4\x94\x6d\x41\x23\xcd\x09\xb9\xac\xc1\x73\x2f\x98\xfd\x75\xbc\x57\x1a\x81\xcb\xa5\x9a\xc2\xda\xda\xcc\x4c\x5f\xbd\x5a\x71\x5b\xaa\xe6\x58\xa5\x69\x2e\xb9\xdd\xbe\x8a\x95\xb4\x9a\x2f\x72\xab\xb4\x79\x95\xe0\x06\xc5\x2b\xc3\x57\x63\xa6\xe3\x35\xb7\x18\xdb\x5c\xe3\x2b\x96\xf1\xb1\xdb\x88\xa4\xed\x9b\x49\x9a\xfc\x9b\xf6\xca\xbc\x64\xa6\x00\xef\x14\xbf\x4e\xd5\x1e\x41\x1e\xd2\xc2\xc0\x0d\x30\x0f\xaa\xc0\x49\x45\x05\xba\x45\xa8\xbb\xbd\xbc\xfb\x08\xe5\x4a\x0a\x4a\x15\x44\xa9\x86\x9a\x10\x7d\x08\x9b\x5c\x2e\x51\x17\xef\x2d\xb5\x4a\x1d\x39\x50\x26\x99\xe2\xd2\xba\x3f\x62\xc1\x51\x5a\x30\xf9\x22\xe5\x96\xd8\xe0\xd7\x1c\x8d\x25\xd2\xed\x83\x3d\x77\xe6\x0b\x16\x08\x79\x46\xcc\x9e\xec\x0f\x98\x4b\x38\x67\x29\x8a\x73\x66\xf0\x2b\xd3\x8a\xa8\x62\xc6\x44\x84\x41\xd4\xaa\x1b\xe5\xea\xa7\x18\x5c\xa0\xb7\xf6\xa0\xb4\xbc\x00\xdd\x72\x4a\x17\x19\x97\x73\x25\x97\x7c\xb5\xff\xa4\xeb\x2d\xba\x98\x10\x2a\x76\xb2\x77\x67\x35\xb3\xb8\xda\xb6\x8d\xea\x63\xab\xf2\x67\x76\x00\x0d\x12\x8c\x79\x82\x06\x1e\xd6\x3c\x5e\xc3\x52\x23\xc2\xfc\x86\x0c\xb1\x46\x63\x1c\x2b\x16\xef\x60\x02\x0f\x6b\x94\x01\xc0\x52\x49\xa4\xc1\x09\x1a\xbe\x92\x34\x7a\x02\x17\xb8\x64\xb9\x70\x3c\x03\x42\x3d\xa0\xb1\x63\x02\xbf\xcf\x02\x4e\x4e\x00\x65\x9e\xb6\xef\x6c\x5c\x7f\x39\x30\x42\x33\x99\xa8\x34\xf4\x90\x8c\xe7\x58\xab\x05\x6f\x5f\xfd\x18\x52\x16\x8f\xd7\xcc\xac\x5b\x1f\x07\x78\xa5\xbc\x16\x4a\xd9\xf6\x85\x77\x13\x96\xae\x25\x17\xe8\xcc\x42\xe0\xf9\x50\xb2\xd2\xf5\xa3\x87\x45\x54\x20\x01\xa6\x75\xb9\x09\x60\xa9\x34\x08\x5c\xb1\x78\x0b\xef\xe6\x1f\xee\xbc\x68\x1b\xa7\x68\xdd\xc3\x4f\x97\x3f\xce\xcb\xbb\x1d\x33\xf0\xa5\x1b\x59\x9f\x88\x04\xdf\xe0\x81\x25\x18\x8c\x3c\xfa\xe5\xd9\x23\x96\x30\x9f\x03\x11\xf3\x9b\x5f\x2e\xbb\x91\xe1\xb7\x0a\x3c\x21\x55\xb1\xdc\x7a\xa5\x9a\x1a\x14\x1b\x34\xc0\x3a\x91\x70\xf3\xcb\xe5\x08\x70\xb2\x9a\x10\xfe\x80\xdf\xfc\x72\x09\xc5\xca\x88\xcd\x17\x1a\xd9\x7d\xa1\x3f\xd7\x8c\x4b\xa1\x58\x42\xc0\x85\x52\xd9\x93\x70\x24\xf1\xd1\x16\xe6\xf5\x39\x30\x74\xbd\x83\x56\xe2\xc7\x14\x7f\x59\x05\x4b\xb4\xf1\x7a\x1f\x67\x5a\xa5\x13\xf8\xb8\x46\xb8\xf8\xf9\xfc\xc6\x0f\xee\x80\xcf\xad\x41\xb1\x24\xd8\xe4\xfe\x02\x5f\x02\xb7\x2f\x06\x30\xcb\x52\xe9\x94\x59\x0a\x18\x36\x6f\x9e\x82\xad\x1c\x97\xfc\x48\x8e\xda\x67\xec\x43\xa6\xa9\x0b\xc9\x13\x68\x19\x30\x26\xe5\x15\xf3\x24\x40\xe2\x5e\xc8\x8f\xe3\xfb\x7c\x81\x5a\xa2\x45\x33\xde\x30\xc1\x93\x7a\x48\xb9\xff\x33\x86\x14\x8d\x61\x2b\x72\xba\xe7\x17\xb7\xb4\x67\x9e\xa6\xb9\xad\x05\x3f\xfb\x97\xce\x05\x61\x9e\x48\xfb\xf6\x2d\x28\x91\xdc\xa1\x58\xb6\x8c\x8d\x5d\xcc\xfb\x21\xeb\x98\x9d\x5b\x4c\x03\x8f\x86\xe8\x4d\x80\x58\x25\x1d\xa4\x05\x48\xd9\x23\x4f\xf3\x74\x0a\x7f\xfe\x3e\xcc\x4a\x00\x29\x97\xc5\xb0\xd7\x1d\x83\x0e\xc3\xab\xb6\x1f\x37\xaa\x03\xca\x70\xf1\x04\xf8\xb8\xcd\x9c\x35\x5d\xab\x07\xf8\xec\x1c\x40\x6e\x00\x25\x6d\x3a\x21\x77\xb9\x70\x9f\x95\x03\x36\x02\x32\xbd\x6a\x09\x3c\x1b\x01\xcf\xc6\x14\xcb\x8f\x3a\xa1\x17\x3c\x34\x82\x9c\x4b\xfb\x43\xf1\xcf\xeb\xb3\xe2\xdf\xef\xfe\x3c\x22\xb9\x17\xce\x34\xac\xf1\xb1\x90\x7a\xef\x0c\xa0\xf1\xee\xbf\x9f\xa5\x73\x12\xa6\x49\xab\x64\x8c\x7c\x96\x04\x16\x5b\x20\x5f\x8e\x99\x49\x2f\x9e\x3b\x38\x9c\x7e\x9d\x3f\x3c\x7d\x1a\x14\x72\x66\xb9\xc6\x3d\xc7\xbc\xba\xc6\x8e\xbd\x82\x0f\x69\x86\xe0\x43\xb7\xbe\xc0\xd3\x1e\xd9\x2f\x07\x30\xad\xd9\xb6\xe5\x79\xc2\x0d\x49\xe7\xcf\xca\xd8\xb0\x66\x1b\xc6\x66\x17\x4d\x50\x60\xac\xca\x0c\xac\x99\x4c\xca\x00\xe3\xf3\x7b\x17\xcf\x96\xa1\x5a\x69\x32\x99\x53\x8d\x5c\xc3\x5a\x05\x19\x80\xde\x6b\xa7\x73\xb1\x3f\x62\x30\x64\x6d\xbe\x58\x12\xd2\x17\xbd\x96\xa1\x53\xa1\xf4\xb2\x44\xca\x1e\xe7\x0e\x00\x7c\x77\x0a\x5d\x54\xca\xb8\xbc\x0e\x92\xa4\x67\xfa\xe2\xf5\x3b\xa4\xb8\x73\xfa\x05\x36\xd7\xbd\x78\x81\xcc\x20\x65\x32\xa6\xd1\x29\xba\x2f\xb5\xf9\x34\xea\x54\xc0\x67\xdf\x7f\xff\xdd\xf7\x51\xa7\xf2\x3d\xfb\xe1\xa4\xb9\xa5\xcd\xbe\x04\xbe\x2a\x66\x78\x73\x02\x3e\x29\xd5\xf9\x14\xc9\xbc\x21\xed\xeb\x1d\x10\xcd\xe4\xca\xa9\x76\x1f\x04\x92\xc8\xd6\x82\x33\x34\x13\x98\x5b\x17\xce\x2f\x9c\xc3\x29\x57\x98\x80\x92\xee\xdd\xa5\xd8\x86\xac\x40\xe1\xbc\x3e\x90\xf7\xeb\xc6\xc7\x08\xdc\x79\x7a\xc2\x40\x9e\x8d\x80\x19\x10\x4a\xae\xe8\x5f\xe9\x6d\x0b\x41\xf4\x8b\xc0\xa4\xb1\x84\xc0\x1c\x4b\x26\x84\x01\x95\x5b\x7a\x9b\x5b\x50\x1a\x56\x68\x0d\xe0\x63\x2c\xf2\xe4\x30\x47\x30\xd4\xf2\xe3\x7e\x3a\xe5\x28\x2d\x31\x88\xfe\x50\x2e\xf2\x89\x13\x75\x72\xe1\xc0\x95\xf4\x71\x1b\x5d\x8e\x4b\x3a\x26\x1a\xc6\x77\x74\xdd\x3a\x48\xce\x74\xef\xd8\xcf\xd9\xfc\x3a\xc1\x1d\x77\x11\x97\xc3\x9a\x19\x58\xa0\x71\xd9\x03\xba\x49\xbc\x42\xc9\xa4\x8e\x19\xee\x2c\xd3\x96\x6c\xca\xa5\x4c\x46\x2e\xf6\xac\x71\x96\x63\x6f\x57\x18\x00\x93\x09\x6e\x81\xb9\xc8\x9d\xd2\x43\x9a\x19\xab\x73\x97\xe6\xe9\x80\xbe\x10\x2a\xbe\x37\x13\xb8\xae\x71\xad\xdf\x04\x09\x89\xda\xa0\x16\x2c\x9b\x9c\x4e\xb1\x06\x2a\xe7\x37\x0e\x5f\x07\xc2\xda\x40\x56\x0f\x3e\x9a\x18\x01\x2e\x63\x91\x1b\xbe\x09\x98\xcf\xa1\x42\x32\x40\x54\x8e\xe0\xe3\x23\x98\x95\x7e\x0d\x91\xf8\xeb\x4f\xdc\xe7\xd3\xd1\x35\x06\x3c\x48\x2d\xd7\xaf\x71\xb1\xf8\x8e\x11\xbd\x1e\xdc\x30\x91\xed\xc1\xd1\x20\xec\xf4\xe2\xa5\x1b\x23\x61\x5c\x74\x61\xa1\x67\xff\xbf\xe6\x4c\x33\x4a\x52\xe3\x45\xae\x5d\xf4\x39\x8d\x7a\xe5\x28\xa8\x92\xfe\xeb\x00\x5a\x19\x13\x15\xe6\x09\x34\x3a\xef\xa5\x6e\x90\x68\xc4\x3d\x66\xb6\x4b\x11\x2d\x90\x9c\xdc\xca\x9c\xb1\x15\xe3\x72\x04\x86\x3c\x5d\x66\x5d\xea\x9d\x89\x5d\x06\x3c\x66\xf2\x85\x85\x58\x09\xc1\x13\x84\x07\x6e\xd7\xc0\x02\x80\x25\x3e\x50\x30\x16\x12\xde\x86\x62\x48\x30\x16\x5c\x16\xa1\x91\xcb\xaa\xec\x3c\x6c\x8d\xd5\x0e\xf6\xd7\x1a\x80\xec\x76\xe0\xd4\xa9\xb7\xdd\x23\x32\xb8\xee\x6f\x09\x6b\x95\xeb\x5d\x0e\xc6\x15\x6f\x51\x4f\xa2\x13\x58\x8a\xca\x0a\x7a\xd3\x95\x54\x18\x46\xd7\xdb\x1a\x1c\x58\x2b\xd1\x74\x28\x4a\xc5\xed\x4c\x0c\x6d\x21\x63\xda\xf2\x98\x4a\xc9\x65\x75\x3b\x00\x97\xbc\x54\xbd\x64\x31\xc1\xd0\xf0\xf9\xbd\x19\x01\x6e\x50\xc2\x02\x97\x54\x11\xb2\x6b\xdc\x3a\xdb\xe6\xea\x77\x21\x17\xa4\xd3\x06\x0c\xdb\x5f\x63\x87\x6e\x83\x06\x98\xac\x6d\x72\x67\xf8\xfc\x7e\xaa\x95\x17\xfc\x45\xdb\x7f\x3f\x3b\x0f\x82\x2f\xc1\x28\x3d\x22\xc2\xfa\x70\x8d\x1b\x58\x71\xda\x2f\x97\xc6\x22\xab\xd9\xd7\x25\xd7\xc6\xb6\x4c\xa6\xda\xb2\x37\x9e\x15\x3c\x4c\xd9\x58\xa7\x93\x0f\x32\xfb\x52\xd5\xb7\x53\x25\xfd\x09\xf1\x05\x9b\x14\x9c\xcd\x3b\x12\xca\xef\x67\xe7\x25\x84\x49\x74\xba\xa9\xe3\xd9\xac\x00\x32\x8d\x9e\x6c\x6f\x7a\x75\x2a\xfd\xa6\x2c\x1e\x30\xe3\x20\x50\x9b\x40\xc9\xa3\x85\xdb\x0a\x02\x3b\x62\xec\xda\x27\x8a\x08\xdd\xcb\xcb\xe7\xf7\x3e\x2d\xdd\x01\x91\x60\xba\x9a\xcc\xab\x4d\x3a\x7e\x3d\x79\xda\xf2\xfb\x2c\xee\xb8\x22\x4d\x74\xb2\x35\x3d\x2e\xa9\xd9\x48\x6b\x5e\x3e\xb2\xd8\x8a\x6d\x99\x1e\x7b\x3f\x3b\xf7\xab\x71\xc9\xad\x02\x9f\xe5\x1e\x02\xd0\xca\x9c\xe7\x9a\x99\x6f\x28\xa5\x3d\xa9\x68\xff\x12\xfe\xf4\xb6\xba\xbf\x49\x5f\x46\xc7\xbb\x02\x5a\xe5\x36\x94\xd1\xef\xe5\xd8\x2f\x97\x0f\xbe\x75\xcb\x7a\xce\x8c\xb0\xdb\xa8\x99\x1e\xaf\x70\x87\x28\x80\x04\x8d\xe5\xb2\xc3\xdb\x18\x88\x2f\x2a\xc0\xaf\x98\xc5\x07\xb6\xfd\x3a\xaa\xa4\x5f\x80\x6a\x5b\x0b\x8e\xf1\x4b\x3e\x55\xc4\xba\x39\xd4\x69\x72\x3d\xbf\x99\x46\x27\xa1\xe2\xcb\xf1\xe8\x9d\x5f\xd8\xf3\x71\x69\x98\x1a\x63\x57\x95\x69\xb9\xed\xdb\xda\x9a\xd7\x78\x87\xb4\xe8\x28\x62\x0c\x47\x45\xab\xa8\x96\xcb\x2f\xf2\x39\x06\xdb\xa8\x5d\x20\xe2\xc5\x9f\x48\x6d\x79\x34\x4c\x9c\x68\xea\x97\xf0\xdb\x6f\x95\x36\xf3\xf7\x5e\xec\x81\xe0\xd9\xe6\x2c\xd4\xc4\xd0\xef\x19\xcd\x6f\xca\xb7\xc1\xe6\x5a\xd6\xf2\x08\xae\xa0\xc1\x20\xc9\x99\x18\x1b\xcb\xe2\x7b\x52\xd9\xfb\x95\x07\x72\xdc\x28\x3f\xdd\xaa\xab\x29\xcf\xb4\xd8\x3a\x88\xe4\x4b\x6f\xce\x3c\x0d\x4a\xdb\xc8\xa8\x15\xcc\xe7\xb3\xc6\x16\xd3\x4c\x69\xa6\xb7\x35\xe8\xdf\xcc\x67\xff\x77\x3d\x7b\x39\x89\x8e\x53\x40\x43\xf2\xd5\x67\xc7\x6b\xbd\x23\x52\x94\xa7\xe7\xab\xff\xa0\x09\xe7\x70\x7e\xf5\xf9\x92\x87\xed\x24\x1b\xb4\xf7\xe3\x95\xda\x9e\x44\x5f\xca\x64\x88\x4e\x1b\xae\xd7\x8e\x4f\x69\x86\xb7\xdf\xc9\x17\x83\xf1\xd3\xcd\x1f\xcf\x81\xc3\x22\x7f\xfb\x25\xf0\x38\x3c\x73\xf3\x7b\x32\x51\x91\x54\x7c\xf6\xed\xff\x0e\x19\xa5\x4c\xe3\x92\x3f\x4e\xa3\x93\xf0\x78\x1c\x0e\x6b\xf8\xbb\x71\xb3\x0e\x41\xe0\x50\xe4\x15\x26\x75\x96\x50\x47\x2c\x37\x98\xa2\x0c\x70\x51\xbf\x21\xa5\xeb\xf6\x10\x1c\xa4\xec\xde\x27\xe2\x0b\x73\x67\x50\x26\xa5\x83\xd0\x18\x69\x7c\x8d\x28\x00\xdb\x07\xfb\x23\x62\x66\x58\x95\x5d\x9f\x20\x90\x69\xf7\x9a\xa7\x89\x8b\xa2\xe8\x4f\x1f\x52\x52\x9b\x9d\xc5\xc9\x89\xaa\xd9\x65\x40\x36\x2c\xa0\xda\x87\x23\x86\xae\xb9\x87\x55\x06\xcc\xbe\x14\x09\x32\x4f\x17\x85\x4f\x60\x30\x56\x32\xa1\xea\x85\x7d\x40\x94\x90\x4b\xa3\x04\x8f\x39\x65\x03\x0b\x8c\x75\x80\x6f\xe2\xb2\x7d\xc3\xde\x48\xfb\xde\x92\x1f\xbe\xfd\x36\xea\xed\x40\x09\x07\x13\x7d\x26\x91\xae\xb4\xb3\x1f\x26\xdc\x5c\xb9\x13\x4f\x8b\xcb\x5c\x44\x81\x11\xe5\x10\x11\x8e\xe5\x1d\x18\xc1\x58\xfc\x14\xb5\xe7\x58\x48\x5f\xf1\x25\xda\xa0\x83\x70\x1c\x2f\xdc\x36\x20\xee\x52\x28\x07\x9c\xe0\xf9\x3c\x37\xd8\x74\x18\x5d\xb3\x43\xd4\x9b\x4f\xf1\x2b\x77\xf5\x58\x7f\xcb\x09\xcd\xdf\x51\xab\x11\xf0\x09\x4e\x46\x35\xb8\xbe\x71\x92\x95\x6f\x77\xc0\xf7\x70\xfb\x99\xec\xdf\xbf\x1d\xc2\x64\xdf\x3e\x81\xc9\xfa\xb4\x7f\x1a\xea\x99\xe9\x54\xf1\x61\xa8\xc1\xf8\xaa\xd0\x3f\xd1\x11\xd3\x78\x95\xd6\xde\xa2\x91\xb2\xc7\x2b\x94\x2b\x3a\x50\x72\xf6\x26\x3a\x8a\x6b\x87\x1b\x98\x9a\x71\xb9\xae\x16\xd3\x67\x61\x86\x58\x97\x8c\x51\x93\xe5\x34\x3a\xa6\xd7\x46\x63\x2c\x18\x6f\x51\x09\xfd\x82\x75\x5b\xbc\x5a\xd6\x5e\x0a\x79\x6a\xcb\xdf\x1f\xa4\x9b\x0b\xa6\xcf\x4d\xbb\xf8\x53\xff\xad\xb3\x38\x54\xdb\xa9\x8a\x31\x2e\x69\x1f\x33\x4a\xe2\x57\x55\x10\x92\x2c\x65\xd7\xa8\x5d\xad\xd7\xae\xa9\x9c\xc3\xdb\x14\x93\xdf\xe7\x90\x1e\xd4\x6e\x13\xc5\x25\x8b\x2d\xdf\xe0\x0d\x6a\xae\x5a\x90\x3d\x5c\x29\xcd\x1b\x90\xf6\x0a\x5a\x07\x38\x73\x5b\x5f\x29\x57\x16\xa0\x20\x97\x41\xfd\x24\xd8\xfe\x0f\xc5\xbe\x64\xa7\x0d\x96\x35\x0f\x3a\xba\x51\x4b\xd4\x73\x53\xa1\x64\x02\xd7\xfb\xb3\x99\x70\x45\x40\xd3\x39\x2a\xaa\xa9\xb8\x0a\x0a\x11\xb2\xc2\x2d\x51\xcd\x23\x88\xdb\xed\x24\x3a\x41\xf9\x53\xff\x59\x86\xc9\x4f\x9a\xc5\xcf\x80\xe3\xbb\x03\x68\x7b\x78\xfe\xfc\xde\x21\xd6\x58\xb6\x2d\xa7\xae\x55\x89\x60\x7e\x13\xaa\xb3\xd5\x99\x9c\x70\xdb\xc2\xe4\x84\x9f\x0a\xc9\xc7\x23\xa3\x43\x8d\xb9\xa4\x46\xf2\xae\x25\x3d\xd9\x8f\x97\x3b\xff\x6e\x47\x15\x81\x49\x27\x54\xfe\x0c\x28\x3c\xac\x95\x29\x2d\x96\x9b\xb9\x4d\xc2\xdc\x09\x22\xff\x02\x33\xf0\x80\x42\x90\x11\x7c\x61\x20\x45\x26\xad\x93\x68\x67\xc3\x92\x12\x57\x66\xe4\x21\x13\xb7\xb6\x40\xdc\x9d\x34\xd2\xc8\x5c\x27\x3a\x95\x64\x9d\xd9\xb4\x6b\xad\xf2\xd5\xba\x68\x42\xd7\x28\xd8\xb6\x78\xd0\xe2\x83\x05\x31\xdc\x6e\x6e\xc6\x70\x78\x90\xb4\x93\x1a\xe4\x30\xe5\x7b\x9a\x22\xac\x41\xdc\x2a\x4f\x21\x9a\x3b\xc2\x48\x27\xba\x94\xb6\xbe\xcb\x04\x33\xc1\x63\xb6\xd3\xb2\x0e\xb4\xc7\x38\x75\x70\x91\xaa\x93\x3e\x17\xd6\x02\xd0\x1d\x79\x53\x42\xa0\x3e\x56\x03\x0a\x64\x49\xa8\x56\xd1\xbf\x11\xba\xae\x1c\x84\x3a\xff\x35\x36\x01\x99\x4a\x5c\xb1\xb4\xec\x40\x2d\x14\x99\x1f\x52\xb0\x58\xb1\xd1\x00\x7c\x25\xc5\x96\x74\x7b\x59\x12\x2d\xa0\xea\x5c\x1a\xa7\x3e\xfd\x0e\x00\x05\xc6\xa4\x3f\x4a\xb7\x2c\x2d\xa4\x9e\x4e\x35\xca\x36\x1b\x4c\x97\xc7\xfa\x21\xce\x3a\x79\xad\x7c\x97\x25\xdb\x5b\x4f\xb6\x69\xd4\x99\x35\xa0\x06\xec\xe8\x14\xef\x4c\x7f\x51\xf8\x5d\x3e\x5a\x63\x7b\xad\xcf\x03\x8f\x3a\xf4\x9c\xa3\xdc\x8d\x4a\x6e\x71\x39\x8d\x3a\x99\x6d\x56\x8d\x04\x8d\x4b\x3a\x7d\xea\x1b\x96\x2f\x30\x13\x6a\x9b\x7a\x16\x90\x25\x53\x39\xd0\x07\x30\x61\x8f\xcd\x8e\x94\x0d\x9e\x92\x63\xd7\x81\xd8\x20\x73\x84\xfb\xb8\x07\xbc\xe8\xd4\xf7\x49\x6f\xe7\x3c\x79\x8a\x24\x7f\x9a\x5f\x90\x18\x33\xb7\x3b\xdf\x14\xe0\xba\x1c\x72\xc9\x7f\xcd\x11\xe6\x17\xc5\xb1\x55\x33\x2a\x3a\xda\x5c\x53\xf9\xa7\x4f\xf3\x0b\x33\x01\x78\x87\x31\x79\xac\xf0\x10\x12\xb6\x44\x51\xc3\xcd\x87\xeb\xab\xbf\x02\x8d\x73\xef\x51\xf4\x44\xbe\xb2\x6b\xa4\x60\x82\x53\x64\xa6\xfc\xfe\x1c\x4c\x9a\xc1\xaf\x27\x66\x19\xf5\x09\x86\xd4\x04\x31\x39\x15\x03\xdc\x01\x07\x91\x19\x97\x37\x01\x93\x17\x5a\xc0\x02\x4d\xe7\x9e\x12\x6d\x0c\x24\xca\xf9\x8c\x2b\xa4\x1e\x20\xb9\x14\xe1\xce\x91\x4e\x9c\x77\x70\x7b\x75\x7a\x7d\x1a\x0d\x4e\xb8\x76\x33\x24\x80\x60\xc6\x7e\xd4\x4c\x1a\x07\x39\x9c\x6e\xdf\x23\xf9\x15\x33\x16\x5c\xa4\x4c\xd2\xb0\x5b\x19\xd8\x1d\x28\x4c\x5c\x6b\x15\x29\x4b\x68\x9c\xa9\x3f\xfc\xa1\xaa\x4e\xe1\x52\xb4\x23\xac\x07\x65\xe5\x36\x3e\xb9\x53\xc5\x83\xb7\x40\x65\x23\x51\xdb\x06\x37\xb5\x7d\x3c\x30\x13\x3a\xa5\x3c\x78\x4d\x65\x20\x37\x64\x31\x3f\xe7\x29\x93\x63\xd2\x91\x94\x84\x2d\x63\x40\xe0\x32\xe1\x74\xa8\x57\xae\x20\x41\xcb\xb8\x30\xc0\x16\x2a\xb7\x51\x2b\x44\x8f\x87\x1a\x11\x4e\x5d\xba\x46\x66\x94\x1c\xb4\x72\x42\x63\x31\x7c\xd7\x46\xb4\x43\xe3\x0b\xb3\xbf\xa0\x93\x91\xd9\xe6\x48\x05\x56\x74\xe7\x86\x96\x9a\x7a\xb7\x98\xdd\xf9\xa7\x8f\x9a\x3e\x1e\xf0\x23\x13\x06\x47\xf0\x49\xde\x4b\xf5\x70\xfa\xba\xba\x4e\x73\x35\xf1\x44\x2a\x50\x2d\x81\x1a\x77\x29\xd3\xba\x5b\xd7\x89\x53\x87\x6d\x6d\x99\xa8\x6b\x95\xb8\xe0\xa9\xa4\x0e\xc5\xd3\x55\x89\xa1\x92\xfe\x34\x3a\x4e\xeb\xec\xe2\xf3\xb6\x87\xd0\xf8\xb6\x4b\xb7\xf2\xea\x45\x52\xcf\xb6\x00\x76\xdf\x71\x99\x9e\xe0\xee\x40\x11\x64\x4f\xa3\x5e\xe2\xbf\xa3\x71\x87\x49\x45\x9f\x19\x89\x73\xad\x51\x52\x0b\x52\x57\xd0\xbe\xd7\xff\x39\x39\x69\xc1\x65\x37\xe9\x57\xc0\xfc\x30\x17\xe1\xa2\x6c\x6f\xa5\xaf\x75\xe8\xa4\x25\x4d\x14\x6a\x80\x1d\xc1\x3d\x6e\xdd\xed\x00\xe8\x5a\x3e\x63\xd7\x2d\x59\x00\x23\x27\xa0\xd6\x55\x48\x05\x7f\xe7\x87\xb4\xc4\x47\x01\xd8\x55\xdc\x40\x1e\x40\xa2\x59\xb9\x42\xaf\x72\x7c\xf0\x54\x34\x59\xd8\x46\x83\xc2\x9a\x6d\x28\xed\x12\xfc\x42\x42\xd5\x1b\x9d\x4c\x4e\x61\x69\xd2\xb8\xb3\x6e\x09\x1b\x46\x9a\xab\x3a\xa0\x92\x79\x6b\x48\x4d\x95\x71\xd9\x9d\x82\x75\xab\xa4\x5b\x30\xa8\x6c\x7e\xa6\x82\xb4\xaa\xfb\xb2\xc4\xc8\x19\x0e\xee\x0e\x37\x64\x3c\xbe\x87\x3c\xa3\x2f\x48\xd0\xf7\x3e\x2c\x08\x5c\xd2\xd1\xa0\x25\xb0\x25\x69\xcd\x50\x47\xb5\xa6\x0f\xec\xe8\xc0\x91\xf1\x1e\x56\x2d\xa4\xf0\xe9\x12\xd1\xc0\xea\x15\x01\x9d\xf9\x54\x17\x21\xef\xc1\x67\x29\x3d\xdb\x50\x73\xac\xa1\x92\x52\x30\x93\x06\xbb\x36\x15\xcf\xf1\xa4\x31\x18\x45\xa7\x87\x5f\x47\x19\xa6\x77\x7d\x90\x47\x4d\xbe\x77\x88\x9d\x4d\x70\x8d\xbd\xfc\x58\xbe\x51\xec\x03\x65\x6d\x1f\x45\xcb\x30\x71\x01\xdf\x74\xb4\x47\xd2\x2f\xdb\xb9\x35\xbb\x2f\xc9\x14\x1b\x0b\xed\xa6\x1e\x92\x92\x2b\x36\x26\x87\xb3\x63\x6c\xaf\x4a\xa2\xdf\x75\xe7\xa9\xd8\x96\xdd\xef\xce\xbe\x7a\x09\x20\x00\x2e\xac\x02\xfa\x78\x4e\x37\xb7\x7b\xc7\xbd\x77\x97\x83\x56\xee\x04\x3b\xbe\x1f\xbc\x70\x27\xbf\xf1\x7d\x0b\xd1\x08\x12\xb0\x98\x3c\x1f\x81\xc9\xaa\x87\x6e\x55\x8e\xc5\xaa\xdf\x8b\x6a\xe4\xcf\x0c\x27\x19\x79\x80\xee\x5c\xbc\xf7\xf8\x76\xba\x69\x44\xaa\x84\xbe\x61\x46\xa7\xd7\x73\x99\x74\x9f\x7f\xc7\xc7\x8c\x3c\x2c\x17\xe4\x95\x47\x48\x9e\x4c\xc5\x0d\xca\x44\xe9\x73\xc1\x8c\x19\xbc\x9f\xcf\xd5\x3b\xa5\x1e\x2e\xc0\x40\x5c\xdc\x2b\xbe\x4f\xc2\x51\x77\x40\x84\x3a\xbf\x3e\x0f\x4f\xf6\x58\xa2\xc1\x86\x86\x54\x9a\xd9\x79\x00\x61\x4d\xb9\xaf\x11\xfb\x7d\x80\xfa\xd9\x81\xf6\xe4\xa7\x9f\x85\x68\x9c\xe9\xbc\xd5\x8a\x07\x60\xef\x6c\x7b\x6d\x12\x9f\xd7\x97\xca\x9d\xae\x41\x5d\xb1\xde\x24\x3a\x01\x83\x19\xba\x83\xf6\xbe\x96\xf6\xdc\x36\xea\xa6\x01\xdd\x27\x4d\x6a\x06\x9e\xbe\x53\xd3\x5b\xa3\x18\x66\x74\x9e\xf1\x2c\x44\x77\x6c\x7a\x04\x18\xb7\xad\x59\x47\x93\xd6\x50\xfe\x6d\x14\x3c\x67\xb6\xa1\x70\x03\x45\x35\xc8\x25\xf5\x48\xd4\x0b\xa0\x9d\xc0\x77\x75\x23\x58\x60\xac\x28\xdf\xe4\xca\x68\x64\x85\xe9\x44\x57\x55\x93\x92\x5f\x51\x2d\x77\x85\xa0\xbe\x96\xbd\x23\x7b\x70\x48\x41\xce\x8e\xc7\x1e\xab\x5f\x56\x05\x35\x45\xa1\xd2\x45\x4d\x12\xe2\x81\x4c\x00\x8b\x69\x20\x05\x14\x56\x05\x60\xdb\xf5\xee\x05\xc8\xa8\x7d\x68\x5b\xa9\xad\x1a\x70\xaf\x9f\xa8\xe5\xd6\xd6\xc3\x89\x00\xd4\x4a\x3d\xd1\xf7\xa5\x12\xca\x77\xa2\xb4\x7a\x4b\x6c\x96\x68\x57\xd7\xac\xa2\x8f\x83\xf2\x63\xc9\x46\x01\xe0\x0d\xe6\xf2\x59\x9d\x00\x27\x4f\xa2\x13\xc8\xd2\xde\x89\xd0\x1f\xc0\x86\x39\x6e\x5c\x85\xf2\x2d\xcf\xda\xe3\xea\x31\xd4\xbe\xba\x3a\x68\xed\xd4\xd9\x3e\x8d\x8e\x67\x31\xea\x69\xf7\x69\x29\x7f\x2a\x2e\x56\xb9\xf7\xe8\xcb\x65\x97\xd8\x45\xfa\xe0\x1a\x55\xe3\x37\x67\xe5\xa1\x74\x64\x86\x8b\x36\x13\xa7\x72\xbb\xd2\xea\x01\x98\xdc\x96\x38\x9b\x44\xc7\xe9\xe7\x9d\x81\x7a\xba\x71\xe9\x55\x19\x3d\x5c\xf1\x47\x49\x4e\x6c\xce\x2a\x5a\x1d\xa4\x27\x36\x67\x95\x73\x42\xc3\xcd\x7e\xa5\xb8\xfa\x79\xa0\xc2\xb4\x21\xad\x4c\xd4\x7e\xf3\x2f\x23\x49\xc7\x4a\x45\x95\x7c\x9f\x46\xc7\x9b\x9c\x20\xad\x5a\x67\x3c\xb8\xe9\x3a\x0e\x92\x29\x58\xed\x3f\xa7\x64\xac\xd2\x94\x76\xaf\xdd\xc9\x17\xbb\x6f\xb1\x96\x2b\x34\x96\xd9\xdc\x4c\xe1\x1f\xff\x8c\xfe\x7f\x00\x14\xc5\xbd\x6c\xa9\x5b\x00\x00")
 
 func chartCrdsNetworkHarvesterhciIo_ippoolsYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -94,12 +94,12 @@ func chartCrdsNetworkHarvesterhciIo_ippoolsYaml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "chart/crds/network.harvesterhci.io_ippools.yaml", size: 7603, mode: os.FileMode(420), modTime: time.Unix(1708939633, 0)}
+	info := bindataFileInfo{name: "chart/crds/network.harvesterhci.io_ippools.yaml", size: 23465, mode: os.FileMode(420), modTime: time.Unix(1792207250, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _chartCrdsNetworkHarvesterhciIo_virtualmachinenetworkconfigsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x57\x4d\x6f\x1b\x37\x13\xbe\xeb\x57\x0c\xf0\x1e\x92\x00\xde\x35\x8c\xb7\x68\x0b\x01\x46\x6b\x28\x69\x6b\xd4\x4e\x8d\xd8\xf1\xa5\xe8\x61\xb4\x1c\x49\x8c\xb9\xe4\x96\x33\x54\xec\xa6\xf9\xef\x05\xc9\x5d\x6b\xb5\x92\x6c\x45\x48\xcb\xdb\x92\xf3\xf1\xcc\xf7\x6c\x51\x14\x23\x6c\xf4\x2d\x79\xd6\xce\x8e\x01\x1b\x4d\xf7\x42\x36\x7e\x71\x79\xf7\x3d\x97\xda\x1d\x2f\x4f\x46\x77\xda\xaa\x31\x4c\x02\x8b\xab\xdf\x11\xbb\xe0\x2b\x7a\x4d\x33\x6d\xb5\x68\x67\x47\x35\x09\x2a\x14\x1c\x8f\x00\xd0\x5a\x27\x18\xaf\x39\x7e\x02\x7c\xfa\x3c\x02\xb0\x58\xd3\x18\x96\xda\x4b\x40\x53\x63\xb5\xd0\x96\x2c\xc9\x47\xe7\xef\x2a\x67\x67\x7a\xce\x65\xfb\x59\x2e\xd0\x2f\x89\x85\xfc\xa2\xd2\xa5\x76\x23\x6e\xa8\x8a\x92\xe6\xde\x85\x66\x0c\xbb\xc8\xb2\x8e\x56\x67\xc6\x7b\x9b\xd5\x5d\x66\x75\x6f\x33\xe3\x24\xa9\x4b\x54\x46\xb3\xfc\xfa\x1c\xe5\x85\x66\x49\xd4\x8d\x09\x1e\xcd\xd3\x46\x24\x42\x5e\x38\x2f\x6f\x57\x60\x0a\x58\xd6\x96\xa4\x9a\xcd\x07\x9f\x2d\xb9\xb6\xf3\x60\xd0\x3f\x29\x79\x04\xc0\x95\x6b\x68\x0c\x49\x70\x83\x15\xa9\x11\xc0\x32\x07\x2e\x29\x2a\x00\x95\x4a\xf1\x40\x73\xe5\xb5\x15\xf2\x13\x67\x42\x6d\x1f\x61\x7c\x60\x67\xaf\x50\x16\x63\x28\xa3\x53\xcb\x65\x1d\x85\xa5\xc7\x2e\x42\xb7\x97\x6f\xcf\x2e\xdf\xb4\x57\xf2\x10\x15\xb2\x78\x6d\xe7\x5b\x44\x08\x4a\xe0\xb2\x72\x36\x6b\xe5\xdf\x7f\x78\xf9\x63\x19\x79\x4e\x4f\x5f\x9c\x19\xe3\x2a\x14\x52\x2f\x5e\xfd\xd1\x52\xae\xe9\x39\xbb\xb8\xf8\x6d\x72\x76\xf3\xe6\xf5\x5e\xaa\xba\xfc\x2a\x2b\x4f\x29\xb5\x6e\x74\x4d\x2c\x58\x37\xeb\x42\x7f\x5e\x47\xae\x50\xb2\x75\x6d\xf6\x9d\xa0\x69\x16\x78\x92\xbd\x5e\x2d\xa8\xc6\x71\x4b\xef\x1a\xb2\x67\x57\xe7\xb7\xff\xbf\x5e\xbb\x06\x68\xbc\x6b\xc8\x8b\xee\x62\x99\x4f\xaf\x64\x7a\xb7\x00\x8a\xb8\xf2\xba\x91\x54\x4b\x7f\x17\x6b\x6f\x00\x51\x41\xe6\x02\x15\x6b\x87\x18\x64\x41\x5d\x0c\x49\xb5\x98\xc0\xcd\x40\x16\x9a\xc1\x53\xe3\x89\xc9\xe6\x6a\x8a\xd7\x68\xc1\x4d\x3f\x50\x25\xe5\x40\xf4\x35\xf9\x28\x26\xa6\x5e\x30\x0a\x2a\x67\x97\xe4\x05\x3c\x55\x6e\x6e\xf5\x5f\x8f\xb2\x19\xc4\x25\xa5\x06\x85\x58\x20\x65\x89\x45\x03\x4b\x34\x81\x8e\x00\xad\x1a\x48\xae\xf1\x01\x3c\x45\x9d\x10\x6c\x4f\x5e\x62\xe0\x21\x8e\x4b\xe7\x09\xb4\x9d\xb9\x31\x2c\x44\x1a\x1e\x1f\x1f\xcf\xb5\x74\x8d\xa4\x72\x75\x1d\xac\x96\x87\xe3\xca\x59\xf1\x7a\x1a\xc4\x79\x3e\x56\xb4\x24\x73\xcc\x7a\x5e\xa0\xaf\x16\x5a\xa8\x92\xe0\xe9\x18\x1b\x5d\x24\x43\x6c\xca\xae\xb2\x56\xff\xf3\x6d\xeb\xe1\x35\xb5\x1b\xb9\x93\x4f\xea\x01\x5f\x10\x9e\xd8\x09\x40\x33\x60\x2b\x2a\x9b\xb8\x8a\x42\xbc\x8a\xae\x7b\xf7\xe6\xfa\x06\x3a\x24\x39\x52\x39\x28\x2b\xd2\x0d\xbf\x74\xf1\x89\xde\xd4\x76\x46\x3e\xf3\xcd\xbc\xab\x93\x4c\xb2\xaa\x71\xda\x4a\xfa\xa8\x8c\x26\x2b\xc0\x61\x5a\x6b\x89\x69\xf0\x67\x20\x96\x18\xba\xa1\xd8\x49\x6a\xb6\x30\x25\x08\x4d\x4c\x76\x35\x24\x38\xb7\x30\xc1\x9a\xcc\x04\x99\xfe\xe3\x58\xc5\xa8\x70\x11\x83\xb0\x57\xb4\xfa\x23\x64\x48\x9c\xdd\xdb\x7b\xe8\x46\xc2\xea\x6c\xaf\xd3\x78\x6c\xbf\x99\x6f\xbc\x02\x68\xa1\x7a\xcb\xf5\x53\x22\x5b\xc6\xe6\x4c\x29\x4f\xbc\xe3\x19\x60\xe6\x7c\x8d\x32\x06\xdd\x2c\xbf\xd9\x41\xb2\xc3\x19\xab\x53\x63\xf5\x8c\x96\x1a\xef\x2f\xc8\xce\x63\x9f\x3c\xf9\xee\x50\x35\xad\x93\xe2\x38\xd8\x43\xcf\xb7\x07\x9a\x13\x33\x59\x7b\x52\xdb\x54\x14\x3d\x53\xb7\x3e\xf7\x20\x6e\x79\xdf\x91\x28\x8f\xd0\xcf\x53\x94\x61\x13\x78\x66\x44\xef\xf1\x61\xe3\xed\xbe\xb8\x0b\x53\xf2\x96\x84\xb8\x58\xa2\xd1\xaa\xbf\xd7\x0c\xd0\x13\x33\xce\xe3\x7c\xee\xe7\x5b\x2a\x78\x67\xcd\x43\xac\x51\x54\x8a\x86\xed\x35\xb9\x25\x98\x88\xdd\xa8\x6b\x32\xb3\x12\x8d\x79\x79\x7f\x04\xf7\xa0\x2d\x30\x99\xd9\xab\x01\x47\x83\x81\xb7\xb9\x30\x1b\x32\x75\xce\x10\xda\xc1\x6b\x1e\xf4\x9b\x3c\x4f\xc7\xf4\xc9\x68\x1e\xe4\x9b\xdb\xcb\x88\x23\x36\x59\x5d\xd7\x41\x70\x6a\xb6\xc5\x32\xfb\x23\xda\x0e\xa7\xa7\x9d\x5f\xd6\xe8\xb6\x27\x52\x01\x6b\x0b\x4d\xdf\x88\xcd\x0e\x92\x56\x92\x7d\x7b\xc8\x6a\xc7\xf9\x8a\xfd\xc3\x20\xcb\x8d\x47\xcb\xba\xdb\x68\x76\x95\xde\xda\xdc\xba\x40\x16\x10\x5d\x53\x9e\x15\x1d\x32\x90\x47\x51\xa4\xf2\x60\x71\x96\x60\x6d\xf5\xda\x3c\xe2\x00\xad\x93\x05\xf9\xe1\x74\x78\xa4\x78\xae\x75\x44\x33\xde\xa7\xe9\xb3\xb7\x09\x37\x69\x01\x59\x99\xa1\xb9\x67\xc7\x47\xe4\x5d\xd3\x6c\x6f\x4c\x5d\xc2\xed\x03\xe6\x97\x50\xa3\x2d\x3c\xa1\x8a\xe9\xd8\xb1\x82\xb6\x4a\x57\x98\x86\xbe\x22\x41\x6d\x18\x70\xea\xc2\x66\x73\xe9\xfb\xa1\x17\x84\x43\xa1\x7b\x42\x1e\xae\x95\x3b\x90\x47\x37\x66\xf2\x38\x6a\xd6\xd3\xe1\x05\x0f\x01\x1d\xec\xcc\x6d\xa5\xb2\x03\xd1\x75\x22\xcd\x0b\x6c\x0f\xcc\x51\x4a\x45\x37\x83\x1b\x1f\x97\xcc\x9f\xd0\x30\x1d\xc1\x7b\x7b\x67\xdd\xc7\xc3\x71\x25\x82\xbd\xfc\xf4\xd0\x24\xed\x95\x09\xf1\x7f\x71\x85\xeb\x40\xd5\x4f\x8f\xb1\x9d\x15\x57\x24\xb9\x5f\x3a\xbb\x76\xcf\xa7\x7f\x6d\xb1\xc1\xee\xdf\xed\xfc\xea\x99\xdd\xe3\x2b\xac\x2f\x5f\x63\x35\xd9\x2b\x85\x0f\xe5\x3e\x28\x3a\x5b\x99\x36\x2e\x39\xfe\x15\xa8\x31\x88\x0f\x39\x2f\x58\x9c\x4f\x83\x72\x75\x13\xa6\x8f\x3f\x3d\x9d\x01\x6d\x41\xc2\xa7\xcf\xa3\x7f\x02\x00\x00\xff\xff\xc5\x26\x68\xc2\xc0\x11\x00\x00")
+var _chartCrdsNetworkHarvesterhciIo_virtualmachinenetworkconfigsYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x58\x6f\x6f\xf3\xb6\x11\x7f\xaf\x4f\x71\xc0\x5e\x3c\x2d\x60\x29\x48\xfb\x2c\x2b\x04\x04\x5b\xe0\x3e\xdb\x82\x25\x4f\x83\x27\x69\xde\x0c\x7b\x71\x96\xce\x16\x1b\x8a\xd4\xc8\x93\x63\xaf\xeb\x77\x1f\x8e\x94\x63\xf9\x9f\xec\xb8\x6b\x45\x03\x86\xc8\xe3\xef\x8e\x77\xc7\xfb\xa3\x34\x4d\x13\x6c\xd4\x33\x39\xaf\xac\xc9\x01\x1b\x45\x0b\x26\x23\x6f\x3e\x7b\xf9\xce\x67\xca\x5e\xcc\x2f\x93\x17\x65\xca\x1c\xc6\xad\x67\x5b\x7f\x21\x6f\x5b\x57\xd0\xf7\x34\x55\x46\xb1\xb2\x26\xa9\x89\xb1\x44\xc6\x3c\x01\x40\x63\x2c\xa3\x4c\x7b\x79\x05\xf8\xf9\x97\x04\xc0\x60\x4d\x39\xcc\x95\xe3\x16\x75\x8d\x45\xa5\x0c\x19\xe2\x57\xeb\x5e\x0a\x6b\xa6\x6a\xe6\xb3\xee\x35\xab\xd0\xcd\xc9\x33\xb9\xaa\x50\x99\xb2\x89\x6f\xa8\x10\xa4\x99\xb3\x6d\x93\xc3\x21\xb2\xc8\xa3\xe3\x19\xe5\x7d\x8e\xec\xee\x23\xbb\xcf\x71\xe3\x38\xb0\x0b\x54\x5a\x79\xfe\xc7\x31\xca\x3b\xe5\x39\x50\x37\xba\x75\xa8\x87\x0f\x11\x08\x7d\x65\x1d\x7f\x5e\x0b\x93\xc2\xbc\x36\xc4\xc5\x74\xb6\xf5\xda\x91\x2b\x33\x6b\x35\xba\x41\xe4\x04\xc0\x17\xb6\xa1\x1c\x02\x70\x83\x05\x95\x09\xc0\x3c\x1a\x2e\x9c\x3a\x05\x2c\xcb\x60\x0f\xd4\x0f\x4e\x19\x26\x37\xb6\xba\xad\x57\x76\x48\xe1\x27\x6f\xcd\x03\x72\x95\x43\x26\x4a\xcd\xe6\xb5\x80\x05\x21\x56\x16\x7a\xbe\xff\x7c\x73\xff\xa9\x9b\xe2\xa5\x30\xf4\xec\x94\x99\xed\x81\x60\xe4\xd6\x67\x85\x35\x91\xab\xff\xe7\x9f\xbf\xfa\x4b\x26\x7b\xae\xaf\x3f\xdc\x68\x6d\x0b\x64\x2a\x3f\x7c\xfd\xaf\x8e\x72\x83\xcf\xcd\xdd\xdd\x0f\xe3\x9b\xa7\x4f\xdf\x9f\xc4\x6a\xe5\x5f\x59\xe1\x28\xb8\xd6\x93\xaa\xc9\x33\xd6\xcd\x26\xe8\xdf\x36\x25\x2f\x91\x29\x59\x2f\xcf\x2f\x51\x37\x15\x5e\x86\x29\x5f\x54\x54\x07\x87\x95\x37\xdb\x90\xb9\x79\xb8\x7d\xfe\xf6\x71\x63\x1a\xa0\x71\xb6\x21\xc7\x6a\x65\xcb\x38\x7a\x57\xa6\x37\x0b\x50\x92\x2f\x9c\x6a\x44\xc2\x1c\xfe\x9b\x6e\xac\x01\x08\x83\xb8\x0b\x4a\xb9\x3b\xe4\x81\x2b\x5a\xd9\x90\xca\x4e\x26\xb0\x53\xe0\x4a\x79\x70\xd4\x38\xf2\x64\xe2\x6d\x92\x69\x34\x60\x27\x3f\x51\xc1\xd9\x16\xf4\x23\x39\x81\x01\x5f\xd9\x56\x97\x50\x58\x33\x27\xc7\xe0\xa8\xb0\x33\xa3\xfe\xf3\x86\xed\x81\x6d\x60\xaa\x91\xc9\x33\x04\x2f\x31\xa8\x61\x8e\xba\xa5\x11\xa0\x29\xb7\x90\x6b\x5c\x82\x23\xe1\x09\xad\xe9\xe1\x85\x0d\x7e\x5b\x8e\x7b\xeb\x08\x94\x99\xda\x1c\x2a\xe6\xc6\xe7\x17\x17\x33\xc5\xab\x40\x52\xd8\xba\x6e\x8d\xe2\xe5\x45\x61\x0d\x3b\x35\x69\xd9\x3a\x7f\x51\xd2\x9c\xf4\x85\x57\xb3\x14\x5d\x51\x29\xa6\x82\x5b\x47\x17\xd8\xa8\x34\x1c\xc4\xc8\xf1\x7d\x56\x97\x7f\x70\x5d\xe8\xf1\x1b\x6c\x77\x7c\x27\xfe\x42\x0c\x78\x87\x79\x24\x12\x80\xf2\x80\x1d\x54\xd4\xc9\xda\x0a\x32\x25\xaa\xfb\xf2\xe9\xf1\x09\x56\x92\x44\x4b\x45\xa3\xac\x49\xfd\x21\xfb\x88\x36\x95\x99\x92\x8b\xfb\xa6\xce\xd6\xc1\x1c\x64\xca\xc6\x2a\xc3\xe1\xa5\xd0\x8a\x0c\x83\x6f\x27\xb5\x62\x71\x83\x7f\xb7\xe4\x59\x4c\xb7\x0d\x3b\x0e\xc1\x16\x26\x04\x6d\x23\xce\x5e\x6e\x13\xdc\x1a\x18\x63\x4d\x7a\x8c\x9e\x7e\x67\x5b\x89\x55\x7c\x2a\x46\x38\xc9\x5a\xfd\x14\xb2\x7e\x22\x71\x54\x6f\x6f\x61\x95\x12\x00\x86\xef\xa9\x8c\x2e\x8c\xc6\x60\xbe\xb3\x0a\xa0\x98\xea\x3d\xd3\x43\x90\x71\x14\x21\x1b\xfe\xd0\xf4\x52\xdd\xee\x33\xec\x72\xeb\x67\xdc\x07\x03\xc6\x17\x82\xc6\x51\x41\x25\x99\x82\xc0\xce\x83\xc7\x50\xc7\x13\x6c\x47\x17\x22\x05\xc1\xed\xc3\x83\xb5\xfa\x20\xf6\xd4\x76\xee\xd6\xa9\x22\xde\xfa\x29\x0a\xb0\xd1\xcb\x2c\xd9\xbb\xeb\xb0\x62\x4e\x53\x4f\xa7\x24\x5b\xd2\xd0\xba\x04\x98\x85\xaa\xdb\x3a\x87\x6f\xfe\xf8\x71\x98\x50\x99\x48\x78\x39\x48\x16\x5d\x46\x8e\x38\x23\x37\x40\x19\xe8\x06\x91\x4e\xb5\x5d\x1c\x4f\xcb\x86\x24\x7c\x54\xf6\x15\x9e\x43\xe4\x50\x1e\xc8\x14\xb6\xa4\x52\x34\x1e\xe3\x6e\xb4\xdc\x08\xac\x21\x09\xe8\xaa\x19\x81\x6a\x52\x29\x44\x46\x47\xf0\xe3\x8d\x19\x41\xab\x0c\x7f\x17\xff\x2e\xaf\xe2\xff\xb7\xdf\x8c\x60\x62\xad\x96\xf0\x0d\x15\x2d\x32\x78\xaa\x48\xaa\x01\x47\xde\x93\xef\x32\x47\xc7\xe7\x08\x1b\x74\x04\x9e\x1a\x74\x12\x4e\x60\xb2\x04\x09\x04\xb8\x13\xd0\x00\x4e\xb8\xd3\xdb\x23\x04\xd4\xfc\xd7\x23\x49\x44\x54\x8e\xb6\xa2\x7b\x7f\xa4\x20\x7a\x1f\x58\x16\x3e\x03\xcb\x41\xd2\x83\xeb\x07\xa2\x52\x7f\x44\x12\x74\x0e\x97\x7b\x29\x54\x73\x13\xcd\x73\xe8\x0c\x53\xeb\x6a\xe4\x1c\x54\x33\xff\x98\x9c\xa9\x2b\xd5\xcc\xaf\x4e\x67\x73\x75\x2e\x9b\x1a\x8b\x23\x5c\x6a\x5c\xdc\x91\x99\x49\x2d\x77\xf9\xa7\x73\xd9\x74\xd1\x4b\x4a\xd6\x13\xf8\x5c\x9d\xa9\xb5\x21\xdf\x4a\x7b\x47\xdd\xbb\xdc\x13\x31\x79\xa7\xdb\xd4\xb8\xb8\x0d\x01\x17\x3e\x26\xef\x71\xa6\x45\xfa\xd2\x4e\xc8\x19\x62\xf2\xe9\x1c\xb5\x2a\xfb\xbd\x57\xff\x49\xa1\x26\xef\x71\x26\x3d\x44\x3f\x27\x86\xa2\xc4\x1a\xbd\x94\x3a\x02\xcb\x92\xb6\x4b\x40\xf9\xb9\x56\x8b\xec\xba\x7c\x24\x3d\xcd\x50\xeb\xaf\x16\x23\x58\x80\x32\xe0\x49\x4f\xbf\xde\xda\xd1\x60\xeb\xf7\xa9\x30\x1e\x44\x42\x15\xa1\xd9\x5a\x8d\xcd\x48\x9e\xbc\xcf\xa6\x83\xd6\x3c\x4b\x37\xcf\xf7\x22\x87\x44\x72\x55\xd7\x2d\xe3\x44\xef\x0b\x14\x51\x1f\x72\x76\xb8\xbe\x5e\xe9\x25\x39\xee\x48\x29\x6c\x34\x5d\x83\x8e\x11\xdb\xa6\x3c\x39\x2d\xeb\xae\xfb\xb0\xff\x63\x8d\xa3\xd1\xf3\x93\x43\xe3\x03\xb2\x74\x5d\x79\x72\x42\xb2\xbc\x43\xcf\xc0\xaa\xa6\x90\xf3\xde\x24\x03\x7e\x83\xa2\x32\x16\xbf\x92\x07\x37\xda\xc3\xdd\xc1\x16\xd0\x58\xae\xc8\x65\xe7\x5d\xe9\x78\x8c\x1f\x43\x85\x7c\xf2\x11\x24\x83\xea\xde\x31\x94\x5f\x6b\x18\x5e\xd1\x1f\xaa\xb8\x4f\x96\x69\xe5\x70\xa7\x08\xf3\xf7\xb6\x46\x93\x3a\xc2\x52\xdc\x71\xe5\xab\xa0\x4c\xa9\x0a\x0c\x8d\x49\x49\x8c\x4a\x7b\xc0\x89\x6d\x77\x83\xcb\xea\x89\x07\x7a\x33\xc2\xb9\xa2\x3b\x42\xbf\xdd\xfa\x1e\x90\x5c\xd4\x18\xc9\x25\xd5\x6c\xba\xc3\x07\xbf\x2d\xd0\xd9\xca\xdc\x77\x55\x0e\x48\xf4\x18\x48\xc1\x4e\x37\x85\x79\x2b\xc9\x9e\x9c\x34\xc2\x7f\x45\xed\x69\x04\x3f\x9a\x17\x63\x5f\xcf\x97\x6b\xa8\xc8\xdc\xd4\x93\x54\x8f\x76\x0a\x85\x6e\xe5\xd3\xd7\x5a\xae\xec\xb7\x48\x63\x07\x6f\xdc\xc1\xc2\x68\x30\x77\x1d\xce\x4f\xbf\x59\xf3\x85\xab\xef\x4b\xb7\x0f\x47\x6a\x8f\xa3\x36\xea\x41\xcd\xaf\x7e\x2d\xd8\xf1\x5a\xe8\x28\x44\xaf\x88\x38\x1b\xc3\x51\xa1\x51\xd5\x54\xde\xf0\x49\xee\x37\xd0\xdd\x7c\x59\x43\x49\x52\x7c\xad\xc8\x74\x4d\x67\xaf\xc3\x78\x25\x47\x6b\xa6\xa1\xff\x58\x7e\x70\xb4\xd6\xed\x41\x78\x9c\xa1\x32\x60\xa5\xcb\x15\xd4\xe7\x7b\x61\xe2\x19\x9d\xb4\x1f\x38\x95\xbb\xc0\x15\xee\x7c\xf0\xda\xae\x5f\x25\xb6\xa7\x92\x71\xce\xd5\x98\x5c\x09\xca\xcf\xdb\x7d\xd6\xe5\xd8\xbb\x69\x67\xd2\xcb\x87\xa3\x32\x07\x76\x5d\x3f\xe2\xd9\x3a\x49\x1b\xbd\x99\x76\xf2\xf6\x5d\x6c\x75\x00\xcf\xc8\xad\xcf\xe1\xe7\x5f\x92\xff\x0d\x00\x2e\xf2\x17\xba\xe3\x17\x00\x00")
 
 func chartCrdsNetworkHarvesterhciIo_virtualmachinenetworkconfigsYamlBytes() ([]byte, error) {
 	return bindataRead(
@@ -114,7 +114,7 @@ func chartCrdsNetworkHarvesterhciIo_virtualmachinenetworkconfigsYaml() (*asset,
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "chart/crds/network.harvesterhci.io_virtualmachinenetworkconfigs.yaml", size: 4544, mode: os.FileMode(420), modTime: time.Unix(1708939633, 0)}
+	info := bindataFileInfo{name: "chart/crds/network.harvesterhci.io_virtualmachinenetworkconfigs.yaml", size: 6115, mode: os.FileMode(420), modTime: time.Unix(1792199433, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
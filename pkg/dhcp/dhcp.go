@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"sync"
 	"time"
@@ -12,19 +13,91 @@ import (
 
 	"github.com/insomniacslk/dhcp/dhcpv4"
 	"github.com/insomniacslk/dhcp/dhcpv4/server4"
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+	"github.com/insomniacslk/dhcp/iana"
 	"github.com/insomniacslk/dhcp/rfc1035label"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+)
+
+// OptionMSClasslessStaticRoute is the Microsoft flavor of the Classless Static
+// Route option, which is still needed by older Windows clients.
+const OptionMSClasslessStaticRoute = dhcpv4.GenericOptionCode(249)
+
+const (
+	// defaultLeaseTime is the lease time handed out when the IPPool does not
+	// specify one: 1 year
+	defaultLeaseTime = 31536000
+
+	// expirerInterval is how often bound leases are checked for expiry
+	expirerInterval = 10 * time.Second
+)
+
+// LeaseState is where a lease is in its life cycle as seen by the DHCP server.
+type LeaseState string
+
+const (
+	// LeaseStateAllocated means the IP address is allocated to the client,
+	// which hasn't asked for it yet.
+	LeaseStateAllocated LeaseState = "allocated"
+	// LeaseStateOffered means the lease was offered to the client.
+	LeaseStateOffered LeaseState = "offered"
+	// LeaseStateBound means the client holds the lease.
+	LeaseStateBound LeaseState = util.LeaseStateBound
+	// LeaseStateExpired means the client did not renew the lease in time.
+	LeaseStateExpired LeaseState = "expired"
+	// LeaseStateReleased means the client gave up the lease.
+	LeaseStateReleased LeaseState = "released"
 )
 
 type DHCPLease struct {
-	ServerIP     net.IP
-	ClientIP     net.IP
-	SubnetMask   net.IPMask
-	Router       net.IP
-	DNS          []net.IP
-	DomainName   string
-	DomainSearch []string
-	NTP          []net.IP
-	LeaseTime    int
+	ServerIP        net.IP
+	ClientIP        net.IP
+	SubnetMask      net.IPMask
+	Router          net.IP
+	DNS             []net.IP
+	DomainName      string
+	DomainSearch    []string
+	NTP             []net.IP
+	LeaseTime       int
+	Routes          dhcpv4.Routes
+	NextServer      net.IP
+	BootFile        string
+	UEFIBootFile    string
+	IPXEBootFile    string
+	CustomOptions   []dhcpv4.Option
+	MTU             int
+	DisableHostname bool
+	State           LeaseState
+	FirstSeenAt     time.Time
+	BoundAt         time.Time
+	RenewedAt       time.Time
+	LastAckAt       time.Time
+	ExpiresAt       time.Time
+	ReleasedAt      time.Time
+	DeclinedAt      time.Time
+	// ClientHostname and VendorClass are what the client sent about itself
+	ClientHostname string
+	VendorClass    string
+}
+
+// duration returns how long the lease is valid once bound or renewed.
+func (l *DHCPLease) duration() time.Duration {
+	if l.LeaseTime > 0 {
+		return time.Duration(l.LeaseTime) * time.Second
+	}
+	return defaultLeaseTime * time.Second
+}
+
+// onLink reports whether ip is within the subnet of the lease.
+func (l *DHCPLease) onLink(ip net.IP) bool {
+	subnet := net.IPNet{
+		IP:   l.ClientIP.Mask(l.SubnetMask),
+		Mask: l.SubnetMask,
+	}
+	return subnet.Contains(ip)
 }
 
 func (l *DHCPLease) String() string {
@@ -35,10 +108,46 @@ func (l *DHCPLease) String() string {
 	return string(b)
 }
 
+// DHCPClient holds the settings of a single client, which come along with its
+// VirtualMachineNetworkConfig rather than with the IPPool.
+type DHCPClient struct {
+	Hostname string
+	// CustomOptions take precedence over the custom options of the lease
+	CustomOptions []dhcpv4.Option
+}
+
+// DeclineFunc is called with the hardware address and the IP address of a
+// lease whenever the client declines the IP address, i.e., the client found
+// the IP address already in use on the network. Declines of the same client
+// within declineInterval are ignored.
+type DeclineFunc func(hwAddr string, ipAddr net.IP)
+
+// declineInterval is how long the declines of a client are ignored after the
+// one passed on to the DeclineFunc. Each passed-on decline gets the IP address
+// quarantined and another one handed out, so a client declining whatever it's
+// offered would otherwise drain the pool.
+const declineInterval = 10 * time.Minute
+
+// ActivityFunc is called with the hardware address and a copy of a lease
+// whenever the state of the lease changes. It must not block.
+type ActivityFunc func(hwAddr string, lease DHCPLease)
+
 type DHCPAllocator struct {
-	leases  map[string]DHCPLease
-	servers map[string]*server4.Server
-	mutex   sync.RWMutex
+	leases     map[string]DHCPLease
+	leases6    map[string]DHCPv6Lease
+	duids6     map[string]string
+	clients    map[string]DHCPClient
+	servers    map[string]*server4.Server
+	servers6   map[string]*server6.Server
+	serverDUID dhcpv6.DUID
+	// declines and declines6 hold when the clients last declined an IPv4
+	// and an IPv6 address, by hardware address. They outlive the leases,
+	// which are replaced once the declined IP addresses are.
+	declines   map[string]time.Time
+	declines6  map[string]time.Time
+	onDecline  DeclineFunc
+	onActivity ActivityFunc
+	mutex      sync.RWMutex
 }
 
 func New() *DHCPAllocator {
@@ -47,14 +156,43 @@ func New() *DHCPAllocator {
 
 func NewDHCPAllocator() *DHCPAllocator {
 	leases := make(map[string]DHCPLease)
+	leases6 := make(map[string]DHCPv6Lease)
+	duids6 := make(map[string]string)
+	clients := make(map[string]DHCPClient)
 	servers := make(map[string]*server4.Server)
+	servers6 := make(map[string]*server6.Server)
+	declines := make(map[string]time.Time)
+	declines6 := make(map[string]time.Time)
 
 	return &DHCPAllocator{
-		leases:  leases,
-		servers: servers,
+		leases:    leases,
+		leases6:   leases6,
+		duids6:    duids6,
+		clients:   clients,
+		servers:   servers,
+		servers6:  servers6,
+		declines:  declines,
+		declines6: declines6,
 	}
 }
 
+// OnDecline registers fn to be called when a DHCPDECLINE for a known lease is
+// received.
+func (a *DHCPAllocator) OnDecline(fn DeclineFunc) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.onDecline = fn
+}
+
+// OnActivity registers fn to be called when the state of a lease changes.
+func (a *DHCPAllocator) OnActivity(fn ActivityFunc) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.onActivity = fn
+}
+
 func (a *DHCPAllocator) AddLease(
 	hwAddr string,
 	serverIP string,
@@ -66,6 +204,11 @@ func (a *DHCPAllocator) AddLease(
 	domainSearch []string,
 	ntpServers []string,
 	leaseTime *int,
+	routes []networkv1.Route,
+	boot *networkv1.BootConfig,
+	customOptions []networkv1.DHCPOption,
+	mtu *int,
+	disableHostname *bool,
 ) (err error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
@@ -82,13 +225,95 @@ func (a *DHCPAllocator) AddLease(
 		return fmt.Errorf("lease for hwaddr %s already exists", hwAddr)
 	}
 
+	lease, err := newLease(serverIP, clientIP, cidr, routerIP, dnsServers, domainName, domainSearch, ntpServers, leaseTime, routes, boot, customOptions, mtu, disableHostname)
+	if err != nil {
+		return err
+	}
+
+	lease.State = LeaseStateAllocated
+
+	a.leases[hwAddr] = lease
+
+	logrus.Infof("(dhcp.AddLease) lease added for hardware address: %s", hwAddr)
+
+	return
+}
+
+// UpdateLease rebuilds the existing lease of hwAddr with the configuration in
+// place, e.g., after the DNS servers of the IPPool were changed. The state of
+// the lease and the activity of the client are kept, and the new
+// configuration is handed out from the next reply on.
+func (a *DHCPAllocator) UpdateLease(
+	hwAddr string,
+	serverIP string,
+	clientIP string,
+	cidr string,
+	routerIP string,
+	dnsServers []string,
+	domainName *string,
+	domainSearch []string,
+	ntpServers []string,
+	leaseTime *int,
+	routes []networkv1.Route,
+	boot *networkv1.BootConfig,
+	customOptions []networkv1.DHCPOption,
+	mtu *int,
+	disableHostname *bool,
+) (err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	old, exists := a.leases[hwAddr]
+	if !exists {
+		return fmt.Errorf("lease for hwaddr %s does not exists", hwAddr)
+	}
+
+	lease, err := newLease(serverIP, clientIP, cidr, routerIP, dnsServers, domainName, domainSearch, ntpServers, leaseTime, routes, boot, customOptions, mtu, disableHostname)
+	if err != nil {
+		return err
+	}
+
+	lease.State = old.State
+	lease.FirstSeenAt = old.FirstSeenAt
+	lease.BoundAt = old.BoundAt
+	lease.RenewedAt = old.RenewedAt
+	lease.LastAckAt = old.LastAckAt
+	lease.ExpiresAt = old.ExpiresAt
+	lease.ReleasedAt = old.ReleasedAt
+	lease.DeclinedAt = old.DeclinedAt
+	lease.ClientHostname = old.ClientHostname
+	lease.VendorClass = old.VendorClass
+
+	a.leases[hwAddr] = lease
+
+	logrus.Infof("(dhcp.UpdateLease) lease updated for hardware address: %s", hwAddr)
+
+	return
+}
+
+func newLease(
+	serverIP string,
+	clientIP string,
+	cidr string,
+	routerIP string,
+	dnsServers []string,
+	domainName *string,
+	domainSearch []string,
+	ntpServers []string,
+	leaseTime *int,
+	routes []networkv1.Route,
+	boot *networkv1.BootConfig,
+	customOptions []networkv1.DHCPOption,
+	mtu *int,
+	disableHostname *bool,
+) (DHCPLease, error) {
 	lease := DHCPLease{}
 	lease.ServerIP = net.ParseIP(serverIP)
 	lease.ClientIP = net.ParseIP(clientIP)
 
 	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return err
+		return DHCPLease{}, err
 	}
 	lease.SubnetMask = ipNet.Mask
 
@@ -111,7 +336,7 @@ func (a *DHCPAllocator) AddLease(
 		} else {
 			ntpServerIPs, err := net.LookupIP(ntpServer)
 			if err != nil {
-				logrus.Errorf("(dhcp.AddLease) cannot get any ip addresses from ntp domainname entry %s: %s", ntpServer, err)
+				logrus.Errorf("(dhcp.newLease) cannot get any ip addresses from ntp domainname entry %s: %s", ntpServer, err)
 			}
 			for _, ip := range ntpServerIPs {
 				if ip.To4() != nil {
@@ -127,11 +352,50 @@ func (a *DHCPAllocator) AddLease(
 		lease.LeaseTime = *leaseTime
 	}
 
-	a.leases[hwAddr] = lease
+	for _, route := range routes {
+		_, dest, err := net.ParseCIDR(route.Destination)
+		if err != nil || dest.IP.To4() == nil {
+			return DHCPLease{}, fmt.Errorf("route destination %s is not a valid ipv4 cidr", route.Destination)
+		}
+		gateway := net.ParseIP(route.Gateway).To4()
+		if gateway == nil {
+			return DHCPLease{}, fmt.Errorf("route gateway %s is not a valid ipv4 address", route.Gateway)
+		}
+		lease.Routes = append(lease.Routes, &dhcpv4.Route{
+			Dest:   dest,
+			Router: gateway,
+		})
+	}
 
-	logrus.Infof("(dhcp.AddLease) lease added for hardware address: %s", hwAddr)
+	if boot != nil {
+		if boot.NextServer != "" {
+			lease.NextServer = net.ParseIP(boot.NextServer).To4()
+			if lease.NextServer == nil {
+				return DHCPLease{}, fmt.Errorf("next server %s is not a valid ipv4 address", boot.NextServer)
+			}
+		}
+		lease.BootFile = boot.Filename
+		lease.UEFIBootFile = boot.UEFIFilename
+		lease.IPXEBootFile = boot.IPXEFilename
+	}
 
-	return
+	lease.CustomOptions, err = parseCustomOptions(customOptions)
+	if err != nil {
+		return DHCPLease{}, err
+	}
+
+	if mtu != nil {
+		if *mtu < 68 || *mtu > 65535 {
+			return DHCPLease{}, fmt.Errorf("mtu %d is out of range", *mtu)
+		}
+		lease.MTU = *mtu
+	}
+
+	if disableHostname != nil {
+		lease.DisableHostname = *disableHostname
+	}
+
+	return lease, nil
 }
 
 func (a *DHCPAllocator) checkLease(hwAddr string) bool {
@@ -162,13 +426,54 @@ func (a *DHCPAllocator) DeleteLease(hwAddr string) (err error) {
 	return
 }
 
+// SetClient sets the host name and the custom options of the client with
+// hwAddr. The custom options take precedence over the custom options of the
+// lease of the client.
+func (a *DHCPAllocator) SetClient(hwAddr string, hostname string, customOptions []networkv1.DHCPOption) error {
+	if _, err := net.ParseMAC(hwAddr); err != nil {
+		return fmt.Errorf("hwaddr %s is not valid", hwAddr)
+	}
+
+	opts, err := parseCustomOptions(customOptions)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.clients[hwAddr] = DHCPClient{
+		Hostname:      hostname,
+		CustomOptions: opts,
+	}
+
+	logrus.Infof("(dhcp.SetClient) client set for hardware address: %s", hwAddr)
+
+	return nil
+}
+
+func (a *DHCPAllocator) GetClient(hwAddr string) (client DHCPClient) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.clients[hwAddr]
+}
+
+func (a *DHCPAllocator) DeleteClient(hwAddr string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.clients, hwAddr)
+}
+
 func (a *DHCPAllocator) Usage() {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
 	for hwaddr, lease := range a.leases {
-		logrus.Infof("(dhcp.Usage) lease: hwaddr=%s, clientip=%s, netmask=%s, router=%s, dns=%+v, domain=%s, domainsearch=%+v, ntp=%+v, leasetime=%d",
+		logrus.Infof("(dhcp.Usage) lease: hwaddr=%s, state=%s, clientip=%s, netmask=%s, router=%s, dns=%+v, domain=%s, domainsearch=%+v, ntp=%+v, leasetime=%d, routes=%s",
 			hwaddr,
+			lease.State,
 			lease.ClientIP.String(),
 			lease.SubnetMask.String(),
 			lease.Router.String(),
@@ -177,14 +482,12 @@ func (a *DHCPAllocator) Usage() {
 			lease.DomainSearch,
 			lease.NTP,
 			lease.LeaseTime,
+			lease.Routes,
 		)
 	}
 }
 
 func (a *DHCPAllocator) dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv4.DHCPv4) {
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-
 	if m == nil {
 		logrus.Errorf("(dhcp.dhcpHandler) packet is nil!")
 		return
@@ -197,21 +500,251 @@ func (a *DHCPAllocator) dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv
 		return
 	}
 
-	reply, err := dhcpv4.NewReplyFromRequest(m)
-	if err != nil {
-		logrus.Errorf("(dhcp.dhcpHandler) NewReplyFromRequest failed: %v", err)
+	hwAddr := m.ClientHWAddr.String()
+	a.see(m, time.Now())
+	defer a.reportActivity(hwAddr)
+
+	var reply *dhcpv4.DHCPv4
+
+	switch messageType := m.MessageType(); messageType {
+	case dhcpv4.MessageTypeDiscover:
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPDISCOVER: %+v", m)
+		if reply = a.prepareReply(m, dhcpv4.MessageTypeOffer); reply == nil {
+			return
+		}
+		a.offer(hwAddr)
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPOFFER: %+v", reply)
+	case dhcpv4.MessageTypeRequest:
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPREQUEST: %+v", m)
+		switch verdict, serverIP, reason := a.verifyRequest(m); verdict {
+		case requestIgnore:
+			logrus.Debugf("(dhcp.dhcpHandler) ignore DHCPREQUEST from hwaddr %s: %s", m.ClientHWAddr.String(), reason)
+			return
+		case requestNAK:
+			logrus.Infof("(dhcp.dhcpHandler) reject DHCPREQUEST from hwaddr %s: %s", m.ClientHWAddr.String(), reason)
+			if reply = prepareNAK(m, serverIP, reason); reply == nil {
+				return
+			}
+			// Broadcast the DHCPNAK as the client may not hold a usable address
+			// (RFC 2131 section 4.1)
+			if !isRelayed(m) {
+				peer = &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpv4.ClientPort}
+			}
+			logrus.Debugf("(dhcp.dhcpHandler) DHCPNAK: %+v", reply)
+		default:
+			if reply = a.prepareReply(m, dhcpv4.MessageTypeAck); reply == nil {
+				return
+			}
+			a.bind(hwAddr, time.Now())
+			logrus.Debugf("(dhcp.dhcpHandler) DHCPACK: %+v", reply)
+		}
+	case dhcpv4.MessageTypeInform:
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPINFORM: %+v", m)
+		if reply = a.prepareReply(m, dhcpv4.MessageTypeAck); reply == nil {
+			return
+		}
+		// The client has already got an address; only the configuration
+		// parameters are returned (RFC 2131 section 4.3.5)
+		reply.ClientIPAddr = m.ClientIPAddr
+		reply.YourIPAddr = net.IPv4zero
+		reply.Options.Del(dhcpv4.OptionIPAddressLeaseTime)
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPACK: %+v", reply)
+	case dhcpv4.MessageTypeRelease:
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPRELEASE: %+v", m)
+		a.release(m)
+		return
+	case dhcpv4.MessageTypeDecline:
+		logrus.Debugf("(dhcp.dhcpHandler) DHCPDECLINE: %+v", m)
+		a.decline(m)
+		return
+	default:
+		logrus.Warnf("(dhcp.dhcpHandler) Unhandled message type for hwaddr [%s]: %v", m.ClientHWAddr.String(), messageType)
 		return
 	}
 
-	lease := a.leases[m.ClientHWAddr.String()]
+	// Replies to relayed requests go back to the server port of the relay
+	// agent (RFC 2131 section 4.1)
+	if isRelayed(m) {
+		peer = &net.UDPAddr{IP: m.GatewayIPAddr, Port: dhcpv4.ServerPort}
+	}
 
-	if lease.ClientIP == nil {
-		logrus.Warnf("(dhcp.dhcpHandler) NO LEASE FOUND: hwaddr=%s", m.ClientHWAddr.String())
+	if _, err := conn.WriteTo(toBytes(reply), peer); err != nil {
+		logrus.Errorf("(dhcp.dhcpHandler) Cannot reply to client: %v", err)
+	}
+}
 
-		return
+func isRelayed(m *dhcpv4.DHCPv4) bool {
+	return m.GatewayIPAddr != nil && !m.GatewayIPAddr.IsUnspecified()
+}
+
+// linkAddress returns the address identifying the link of the client sending
+// the relayed request m, which is the link selection sub-option of the relay
+// agent information (RFC 3527) if any, or else giaddr. It returns nil if m is
+// not relayed.
+func linkAddress(m *dhcpv4.DHCPv4) net.IP {
+	if !isRelayed(m) {
+		return nil
+	}
+
+	if rai := m.RelayAgentInfo(); rai != nil {
+		if linkSelection := rai.Get(dhcpv4.LinkSelectionSubOption); len(linkSelection) == net.IPv4len {
+			return net.IP(linkSelection)
+		}
+	}
+
+	return m.GatewayIPAddr
+}
+
+// lookupLease finds the lease of the client sending m. A request relayed from
+// a link outside the subnet of the lease is meant for another IPPool, in which
+// case no lease is returned.
+func (a *DHCPAllocator) lookupLease(m *dhcpv4.DHCPv4) (DHCPLease, bool) {
+	lease, exists := a.leases[m.ClientHWAddr.String()]
+	if !exists || lease.ClientIP == nil {
+		return DHCPLease{}, false
+	}
+
+	if link := linkAddress(m); link != nil && !lease.onLink(link) {
+		logrus.Debugf("(dhcp.lookupLease) lease of hwaddr %s is not on link %s", m.ClientHWAddr.String(), link)
+		return DHCPLease{}, false
 	}
 
-	logrus.Debugf("(dhcp.dhcpHandler) LEASE FOUND: hwaddr=%s, serverip=%s, clientip=%s, mask=%s, router=%s, dns=%+v, domainname=%s, domainsearch=%+v, ntp=%+v, leasetime=%d",
+	return lease, true
+}
+
+// toBytes serializes reply with the relay agent information option, if any, as
+// the last option (RFC 3046 section 2.2). Options are otherwise serialized in
+// the order of their codes.
+func toBytes(reply *dhcpv4.DHCPv4) []byte {
+	rai := reply.Options.Get(dhcpv4.OptionRelayAgentInformation)
+	if len(rai) == 0 || len(rai) > math.MaxUint8 {
+		return reply.ToBytes()
+	}
+
+	reply.Options.Del(dhcpv4.OptionRelayAgentInformation)
+	defer reply.UpdateOption(dhcpv4.OptGeneric(dhcpv4.OptionRelayAgentInformation, rai))
+
+	b := reply.ToBytes()
+	// The options start after the fixed-size header and the magic cookie
+	end := 240 + len(reply.Options.ToBytes())
+
+	option := append([]byte{dhcpv4.OptionRelayAgentInformation.Code(), uint8(len(rai))}, rai...)
+
+	return append(b[:end:end], append(option, b[end:]...)...)
+}
+
+type requestVerdict int
+
+const (
+	requestACK requestVerdict = iota
+	requestNAK
+	requestIgnore
+)
+
+// verifyRequest looks up the lease of the client sending the DHCPREQUEST m and
+// decides how to answer it. It also returns the server IP address of the lease
+// and the reason for the decision.
+func (a *DHCPAllocator) verifyRequest(m *dhcpv4.DHCPv4) (requestVerdict, net.IP, string) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	lease, exists := a.lookupLease(m)
+	if !exists {
+		return requestIgnore, nil, "no lease found"
+	}
+
+	verdict, reason := checkRequest(m, lease)
+
+	return verdict, lease.ServerIP, reason
+}
+
+// checkRequest validates the DHCPREQUEST m against lease according to the
+// client state the request is sent from (RFC 2131 section 4.3.2):
+//
+//   - SELECTING: the server identifier is set. Requests for other servers are
+//     ignored, and the requested IP address must match the lease.
+//   - INIT-REBOOT: no server identifier, the requested IP address is set and
+//     must match the lease.
+//   - RENEWING/REBINDING: no server identifier, no requested IP address, and
+//     ciaddr must match the lease.
+func checkRequest(m *dhcpv4.DHCPv4, lease DHCPLease) (requestVerdict, string) {
+	serverID := m.ServerIdentifier()
+	requestedIP := m.RequestedIPAddress()
+	clientIP := m.ClientIPAddr
+	hasClientIP := clientIP != nil && !clientIP.IsUnspecified()
+
+	switch {
+	case serverID != nil:
+		// SELECTING
+		if !serverID.Equal(lease.ServerIP) {
+			return requestIgnore, fmt.Sprintf("server %s selected instead", serverID)
+		}
+		if requestedIP == nil {
+			return requestNAK, "requested ip is missing"
+		}
+		if !requestedIP.Equal(lease.ClientIP) {
+			return requestNAK, fmt.Sprintf("requested ip %s does not match leased ip %s", requestedIP, lease.ClientIP)
+		}
+	case requestedIP != nil:
+		// INIT-REBOOT
+		if !requestedIP.Equal(lease.ClientIP) {
+			return requestNAK, fmt.Sprintf("requested ip %s does not match leased ip %s", requestedIP, lease.ClientIP)
+		}
+	case hasClientIP:
+		// RENEWING or REBINDING
+		if !clientIP.Equal(lease.ClientIP) {
+			return requestNAK, fmt.Sprintf("client ip %s does not match leased ip %s", clientIP, lease.ClientIP)
+		}
+	default:
+		return requestIgnore, "neither requested ip nor client ip is present"
+	}
+
+	return requestACK, ""
+}
+
+// prepareNAK builds a DHCPNAK for m on behalf of serverIP with message as the
+// error message to the client. The broadcast bit is always set, as the client
+// has no usable address, so that relay agents broadcast the DHCPNAK to the
+// client, too (RFC 2131 section 4.3.2).
+func prepareNAK(m *dhcpv4.DHCPv4, serverIP net.IP, message string) *dhcpv4.DHCPv4 {
+	reply, err := dhcpv4.NewReplyFromRequest(m,
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeNak),
+		dhcpv4.WithOption(dhcpv4.OptServerIdentifier(serverIP)),
+		dhcpv4.WithOption(dhcpv4.OptMessage(message)),
+	)
+	if err != nil {
+		logrus.Errorf("(dhcp.prepareNAK) NewReplyFromRequest failed: %v", err)
+		return nil
+	}
+
+	reply.ClientIPAddr = net.IPv4zero
+	reply.YourIPAddr = net.IPv4zero
+	reply.ServerIPAddr = net.IPv4zero
+	reply.SetBroadcast()
+
+	return reply
+}
+
+// prepareReply builds a reply of messageType for m out of the lease of the
+// requesting client. It returns nil if there's no lease for the client.
+func (a *DHCPAllocator) prepareReply(m *dhcpv4.DHCPv4, messageType dhcpv4.MessageType) *dhcpv4.DHCPv4 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	reply, err := dhcpv4.NewReplyFromRequest(m)
+	if err != nil {
+		logrus.Errorf("(dhcp.prepareReply) NewReplyFromRequest failed: %v", err)
+		return nil
+	}
+
+	lease, exists := a.lookupLease(m)
+	if !exists {
+		logrus.Warnf("(dhcp.prepareReply) NO LEASE FOUND: hwaddr=%s", m.ClientHWAddr.String())
+
+		return nil
+	}
+
+	logrus.Debugf("(dhcp.prepareReply) LEASE FOUND: hwaddr=%s, serverip=%s, clientip=%s, mask=%s, router=%s, dns=%+v, domainname=%s, domainsearch=%+v, ntp=%+v, leasetime=%d, routes=%s",
 		m.ClientHWAddr.String(),
 		lease.ServerIP.String(),
 		lease.ClientIP.String(),
@@ -222,6 +755,7 @@ func (a *DHCPAllocator) dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv
 		lease.DomainSearch,
 		lease.NTP,
 		lease.LeaseTime,
+		lease.Routes,
 	)
 
 	reply.ClientIPAddr = lease.ClientIP
@@ -232,6 +766,7 @@ func (a *DHCPAllocator) dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv
 	reply.Flags = m.Flags
 	reply.GatewayIPAddr = m.GatewayIPAddr
 
+	reply.UpdateOption(dhcpv4.OptMessageType(messageType))
 	reply.UpdateOption(dhcpv4.OptServerIdentifier(lease.ServerIP))
 	reply.UpdateOption(dhcpv4.OptSubnetMask(lease.SubnetMask))
 	reply.UpdateOption(dhcpv4.OptRouter(lease.Router))
@@ -255,30 +790,295 @@ func (a *DHCPAllocator) dhcpHandler(conn net.PacketConn, peer net.Addr, m *dhcpv
 		reply.UpdateOption(dhcpv4.OptNTPServers(lease.NTP...))
 	}
 
-	if lease.LeaseTime > 0 {
-		reply.UpdateOption(dhcpv4.OptIPAddressLeaseTime(time.Duration(lease.LeaseTime) * time.Second))
+	if len(lease.Routes) > 0 {
+		routes := append(dhcpv4.Routes{}, lease.Routes...)
+		// Clients honoring classless static routes ignore the router option
+		// (RFC 3442), so the default route has to be part of the routes
+		if lease.Router.To4() != nil && !lease.Router.IsUnspecified() {
+			routes = append(routes, &dhcpv4.Route{
+				Dest: &net.IPNet{
+					IP:   net.IPv4zero.To4(),
+					Mask: net.CIDRMask(0, 32),
+				},
+				Router: lease.Router.To4(),
+			})
+		}
+
+		reply.UpdateOption(dhcpv4.OptClasslessStaticRoute(routes...))
+		reply.UpdateOption(dhcpv4.OptGeneric(OptionMSClasslessStaticRoute, routes.ToBytes()))
+	}
+
+	if lease.NextServer != nil {
+		reply.ServerIPAddr = lease.NextServer
+		reply.UpdateOption(dhcpv4.OptTFTPServerName(lease.NextServer.String()))
+	}
+
+	if bootFile := selectBootFile(m, lease); bootFile != "" {
+		reply.BootFileName = bootFile
+		reply.UpdateOption(dhcpv4.OptBootFileName(bootFile))
+	}
+
+	if lease.MTU > 0 {
+		reply.UpdateOption(dhcpv4.Option{Code: dhcpv4.OptionInterfaceMTU, Value: dhcpv4.Uint16(lease.MTU)})
+	}
+
+	client := a.clients[m.ClientHWAddr.String()]
+
+	if client.Hostname != "" && !lease.DisableHostname {
+		reply.UpdateOption(dhcpv4.OptHostName(client.Hostname))
+	}
+
+	for _, opt := range lease.CustomOptions {
+		reply.UpdateOption(opt)
+	}
+
+	for _, opt := range client.CustomOptions {
+		reply.UpdateOption(opt)
+	}
+
+	reply.UpdateOption(dhcpv4.OptIPAddressLeaseTime(lease.duration()))
+
+	return reply
+}
+
+// see records what the client sending m tells about itself on its lease, if
+// any, at now.
+func (a *DHCPAllocator) see(m *dhcpv4.DHCPv4, now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	hwAddr := m.ClientHWAddr.String()
+
+	lease, exists := a.leases[hwAddr]
+	if !exists {
+		return
+	}
+
+	if lease.FirstSeenAt.IsZero() {
+		lease.FirstSeenAt = now
+	}
+	if hostname := m.HostName(); hostname != "" {
+		lease.ClientHostname = hostname
+	}
+	if vendorClass := m.ClassIdentifier(); vendorClass != "" {
+		lease.VendorClass = vendorClass
+	}
+
+	a.leases[hwAddr] = lease
+}
+
+// reportActivity passes the lease of hwAddr, if any, to the registered
+// ActivityFunc.
+func (a *DHCPAllocator) reportActivity(hwAddr string) {
+	a.mutex.RLock()
+	lease, exists := a.leases[hwAddr]
+	onActivity := a.onActivity
+	a.mutex.RUnlock()
+
+	if exists && onActivity != nil {
+		onActivity(hwAddr, lease)
+	}
+}
+
+// offer records that the lease of hwAddr was offered to the client. A bound
+// lease stays bound, as the client may still be using it.
+func (a *DHCPAllocator) offer(hwAddr string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	lease, exists := a.leases[hwAddr]
+	if !exists || lease.State == LeaseStateBound {
+		return
+	}
+
+	lease.State = LeaseStateOffered
+	a.leases[hwAddr] = lease
+}
+
+// bind records that the lease of hwAddr was acknowledged to the client at now.
+// Acknowledging a lease which is still bound renews it.
+func (a *DHCPAllocator) bind(hwAddr string, now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	lease, exists := a.leases[hwAddr]
+	if !exists {
+		return
+	}
+
+	if lease.State == LeaseStateBound && now.Before(lease.ExpiresAt) {
+		lease.RenewedAt = now
+		logrus.Debugf("(dhcp.bind) lease renewed by hardware address: %s", hwAddr)
 	} else {
-		// default lease time: 1 year
-		reply.UpdateOption(dhcpv4.OptIPAddressLeaseTime(31536000 * time.Second))
+		lease.BoundAt = now
+		lease.RenewedAt = time.Time{}
+		logrus.Infof("(dhcp.bind) lease bound by hardware address: %s", hwAddr)
 	}
+	lease.State = LeaseStateBound
+	lease.LastAckAt = now
+	lease.ExpiresAt = now.Add(lease.duration())
 
-	switch messageType := m.MessageType(); messageType {
-	case dhcpv4.MessageTypeDiscover:
-		logrus.Debugf("(dhcp.dhcpHandler) DHCPDISCOVER: %+v", m)
-		reply.UpdateOption(dhcpv4.OptMessageType(dhcpv4.MessageTypeOffer))
-		logrus.Debugf("(dhcp.dhcpHandler) DHCPOFFER: %+v", reply)
-	case dhcpv4.MessageTypeRequest:
-		logrus.Debugf("(dhcp.dhcpHandler) DHCPREQUEST: %+v", m)
-		reply.UpdateOption(dhcpv4.OptMessageType(dhcpv4.MessageTypeAck))
-		logrus.Debugf("(dhcp.dhcpHandler) DHCPACK: %+v", reply)
+	a.leases[hwAddr] = lease
+}
+
+// expire marks the bound leases not renewed before now as expired. It returns
+// the hardware addresses of the expired leases.
+func (a *DHCPAllocator) expire(now time.Time) []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var expired []string
+	for hwAddr, lease := range a.leases {
+		if lease.State != LeaseStateBound || now.Before(lease.ExpiresAt) {
+			continue
+		}
+		lease.State = LeaseStateExpired
+		a.leases[hwAddr] = lease
+		expired = append(expired, hwAddr)
+
+		logrus.Infof("(dhcp.expire) lease expired for hardware address: %s", hwAddr)
+	}
+
+	return expired
+}
+
+func (a *DHCPAllocator) runExpirer(ctx context.Context) {
+	ticker := time.NewTicker(expirerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, hwAddr := range a.expire(now) {
+				a.reportActivity(hwAddr)
+			}
+		}
+	}
+}
+
+// selectBootFile picks the boot file in lease for the client sending m. iPXE
+// clients, identified by the user class, get the iPXE boot file. The others
+// get the UEFI or the legacy BIOS boot file according to their architecture.
+func selectBootFile(m *dhcpv4.DHCPv4, lease DHCPLease) string {
+	if lease.IPXEBootFile != "" {
+		for _, userClass := range m.UserClass() {
+			if userClass == "iPXE" {
+				return lease.IPXEBootFile
+			}
+		}
+	}
+
+	if lease.UEFIBootFile != "" {
+		for _, arch := range m.ClientArch() {
+			if isUEFI(arch) {
+				return lease.UEFIBootFile
+			}
+		}
+	}
+
+	return lease.BootFile
+}
+
+func isUEFI(arch iana.Arch) bool {
+	switch arch {
+	case iana.EFI_IA32, iana.EFI_X86_64, iana.EFI_BC, iana.EFI_ARM32, iana.EFI_ARM64,
+		iana.EFI_X86_HTTP, iana.EFI_X86_64_HTTP, iana.EFI_BC_HTTP, iana.EFI_ARM32_HTTP, iana.EFI_ARM64_HTTP:
+		return true
 	default:
-		logrus.Warnf("(dhcp.dhcpHandler) Unhandled message type for hwaddr [%s]: %v", m.ClientHWAddr.String(), messageType)
+		return false
+	}
+}
+
+// release records the DHCPRELEASE m on the lease of the client. The lease
+// itself is kept since the IP address stays allocated to the client until the
+// controller says otherwise.
+func (a *DHCPAllocator) release(m *dhcpv4.DHCPv4) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	hwAddr := m.ClientHWAddr.String()
+
+	lease, exists := a.leases[hwAddr]
+	if !exists {
+		logrus.Warnf("(dhcp.release) NO LEASE FOUND: hwaddr=%s", hwAddr)
 		return
 	}
 
-	if _, err := conn.WriteTo(reply.ToBytes(), peer); err != nil {
-		logrus.Errorf("(dhcp.dhcpHandler) Cannot reply to client: %v", err)
+	if !lease.ClientIP.Equal(m.ClientIPAddr) {
+		logrus.Warnf("(dhcp.release) released ip %s does not match leased ip %s for hwaddr %s", m.ClientIPAddr, lease.ClientIP, hwAddr)
+		return
 	}
+
+	lease.State = LeaseStateReleased
+	lease.ReleasedAt = time.Now()
+	a.leases[hwAddr] = lease
+
+	logrus.Infof("(dhcp.release) lease released by hardware address: %s", hwAddr)
+}
+
+// decline records the DHCPDECLINE m on the lease of the client and notifies
+// the registered DeclineFunc, if any.
+func (a *DHCPAllocator) decline(m *dhcpv4.DHCPv4) {
+	hwAddr := m.ClientHWAddr.String()
+
+	a.mutex.Lock()
+
+	lease, exists := a.leases[hwAddr]
+	if !exists {
+		a.mutex.Unlock()
+		logrus.Warnf("(dhcp.decline) NO LEASE FOUND: hwaddr=%s", hwAddr)
+		return
+	}
+
+	if serverID := m.ServerIdentifier(); serverID != nil && !serverID.Equal(lease.ServerIP) {
+		a.mutex.Unlock()
+		logrus.Debugf("(dhcp.decline) ignore DHCPDECLINE for server %s from hwaddr %s", serverID, hwAddr)
+		return
+	}
+
+	if requestedIP := m.RequestedIPAddress(); !lease.ClientIP.Equal(requestedIP) {
+		a.mutex.Unlock()
+		logrus.Warnf("(dhcp.decline) declined ip %s does not match leased ip %s for hwaddr %s", requestedIP, lease.ClientIP, hwAddr)
+		return
+	}
+
+	now := time.Now()
+	if isDeclineThrottled(a.declines, hwAddr, now) {
+		a.mutex.Unlock()
+		logrus.Warnf("(dhcp.decline) ignore DHCPDECLINE of ip %s from hwaddr %s which declined within %s", lease.ClientIP, hwAddr, declineInterval)
+		return
+	}
+
+	lease.DeclinedAt = now
+	a.leases[hwAddr] = lease
+	onDecline := a.onDecline
+
+	a.mutex.Unlock()
+
+	logrus.Warnf("(dhcp.decline) lease declined by hardware address %s: ip %s is in use", hwAddr, lease.ClientIP)
+
+	if onDecline != nil {
+		onDecline(hwAddr, lease.ClientIP)
+	}
+}
+
+// isDeclineThrottled tells if the client with hwAddr already declined within
+// declineInterval before now, recording the decline at now otherwise. The
+// records older than that are dropped along the way. The caller must hold the
+// lock of the allocator.
+func isDeclineThrottled(declines map[string]time.Time, hwAddr string, now time.Time) bool {
+	for addr, declinedAt := range declines {
+		if now.Sub(declinedAt) >= declineInterval {
+			delete(declines, addr)
+		}
+	}
+	if _, exists := declines[hwAddr]; exists {
+		return true
+	}
+	declines[hwAddr] = now
+	return false
 }
 
 func (a *DHCPAllocator) Run(ctx context.Context, nic string) (err error) {
@@ -305,6 +1105,8 @@ func (a *DHCPAllocator) Run(ctx context.Context, nic string) (err error) {
 
 	a.servers[nic] = server
 
+	go a.runExpirer(ctx)
+
 	return nil
 }
 
@@ -321,6 +1123,12 @@ func (a *DHCPAllocator) DryRun(ctx context.Context, nic string) (err error) {
 func (a *DHCPAllocator) stop(nic string) (err error) {
 	logrus.Infof("(dhcp.Stop) stopping DHCP service on nic %s", nic)
 
+	if a.servers6[nic] != nil {
+		if err := a.servers6[nic].Close(); err != nil {
+			logrus.Errorf("(dhcp.Stop) cannot stop DHCPv6 service on nic %s: %v", nic, err)
+		}
+	}
+
 	if a.servers[nic] == nil {
 		return nil
 	}
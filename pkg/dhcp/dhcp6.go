@@ -0,0 +1,387 @@
+package dhcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/dhcpv6/server6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+// defaultLeaseTime6 is the valid lifetime of the addresses handed out when
+// the IPPool does not specify one, same as for DHCPv4.
+const defaultLeaseTime6 = defaultLeaseTime
+
+type DHCPv6Lease struct {
+	ClientIP     net.IP
+	DNS          []net.IP
+	DomainSearch []string
+	LeaseTime    int
+}
+
+func (l *DHCPv6Lease) String() string {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (a *DHCPAllocator) AddLease6(
+	hwAddr string,
+	clientIP string,
+	dnsServers []string,
+	domainSearch []string,
+	leaseTime *int,
+) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if hwAddr == "" {
+		return fmt.Errorf("hwaddr is empty")
+	}
+
+	if _, err := net.ParseMAC(hwAddr); err != nil {
+		return fmt.Errorf("hwaddr %s is not valid", hwAddr)
+	}
+
+	if _, exists := a.leases6[hwAddr]; exists {
+		return fmt.Errorf("ipv6 lease for hwaddr %s already exists", hwAddr)
+	}
+
+	lease := DHCPv6Lease{}
+
+	lease.ClientIP = net.ParseIP(clientIP)
+	if lease.ClientIP == nil || lease.ClientIP.To4() != nil {
+		return fmt.Errorf("client ip %s is not a valid ipv6 address", clientIP)
+	}
+
+	for _, dnsServer := range dnsServers {
+		dnsServerIP := net.ParseIP(dnsServer)
+		if dnsServerIP == nil || dnsServerIP.To4() != nil {
+			return fmt.Errorf("dns server %s is not a valid ipv6 address", dnsServer)
+		}
+		lease.DNS = append(lease.DNS, dnsServerIP)
+	}
+	lease.DomainSearch = domainSearch
+
+	if leaseTime != nil {
+		lease.LeaseTime = *leaseTime
+	}
+
+	a.leases6[hwAddr] = lease
+
+	logrus.Infof("(dhcp.AddLease6) ipv6 lease added for hardware address: %s", hwAddr)
+
+	return nil
+}
+
+func (a *DHCPAllocator) GetLease6(hwAddr string) (lease DHCPv6Lease) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.leases6[hwAddr]
+}
+
+func (a *DHCPAllocator) DeleteLease6(hwAddr string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, exists := a.leases6[hwAddr]; !exists {
+		return fmt.Errorf("ipv6 lease for hwaddr %s does not exists", hwAddr)
+	}
+
+	delete(a.leases6, hwAddr)
+	for duid, addr := range a.duids6 {
+		if addr == hwAddr {
+			delete(a.duids6, duid)
+		}
+	}
+
+	logrus.Infof("(dhcp.DeleteLease6) ipv6 lease deleted for hardware address: %s", hwAddr)
+
+	return nil
+}
+
+func (a *DHCPAllocator) dhcp6Handler(conn net.PacketConn, peer net.Addr, m dhcpv6.DHCPv6) {
+	if m == nil {
+		logrus.Errorf("(dhcp.dhcp6Handler) packet is nil!")
+		return
+	}
+
+	logrus.Tracef("(dhcp.dhcp6Handler) INCOMING PACKET=%s", m.Summary())
+
+	msg, err := m.GetInnerMessage()
+	if err != nil {
+		logrus.Errorf("(dhcp.dhcp6Handler) cannot get inner message: %v", err)
+		return
+	}
+
+	hwAddr, err := a.hwAddrOf6(m, msg, peer)
+	if err != nil {
+		logrus.Debugf("(dhcp.dhcp6Handler) ignore %s with unknown hwaddr: %v", msg.Type(), err)
+		return
+	}
+
+	reply := a.prepareReply6(hwAddr, msg)
+	if reply == nil {
+		return
+	}
+
+	var resp dhcpv6.DHCPv6 = reply
+	if m.IsRelay() {
+		resp, err = dhcpv6.NewRelayReplFromRelayForw(m.(*dhcpv6.RelayMessage), reply)
+		if err != nil {
+			logrus.Errorf("(dhcp.dhcp6Handler) cannot build relay reply: %v", err)
+			return
+		}
+	}
+
+	if _, err := conn.WriteTo(resp.ToBytes(), peer); err != nil {
+		logrus.Errorf("(dhcp.dhcp6Handler) Cannot reply to client: %v", err)
+	}
+}
+
+// hwAddrOf6 finds the hardware address of the client sending m through peer,
+// as a DHCPv6 message does not carry it on its own. It's taken from the relay
+// information or the DUID of the client, or else from the EUI-64 link-local
+// address of the client. Clients with DUID-EN or DUID-UUID are recognized by
+// their DUID once the hardware address has been seen.
+func (a *DHCPAllocator) hwAddrOf6(m dhcpv6.DHCPv6, msg *dhcpv6.Message, peer net.Addr) (string, error) {
+	var duid string
+	if clientID := msg.Options.ClientID(); clientID != nil {
+		duid = fmt.Sprintf("%x", clientID.ToBytes())
+	}
+
+	hwAddr, err := dhcpv6.ExtractMAC(m)
+	if err != nil && !m.IsRelay() {
+		if udpAddr, ok := peer.(*net.UDPAddr); ok {
+			hwAddr, err = dhcpv6.GetMacAddressFromEUI64(udpAddr.IP)
+		}
+	}
+	if err == nil {
+		if duid != "" {
+			a.mutex.Lock()
+			if _, exists := a.leases6[hwAddr.String()]; exists {
+				a.duids6[duid] = hwAddr.String()
+			}
+			a.mutex.Unlock()
+		}
+		return hwAddr.String(), nil
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if addr, exists := a.duids6[duid]; exists && duid != "" {
+		return addr, nil
+	}
+
+	return "", err
+}
+
+// prepareReply6 builds the answer to the DHCPv6 message msg from the client
+// with hwAddr. It returns nil if msg should not be answered.
+func (a *DHCPAllocator) prepareReply6(hwAddr string, msg *dhcpv6.Message) *dhcpv6.Message {
+	a.mutex.RLock()
+	lease, exists := a.leases6[hwAddr]
+	serverDUID := a.serverDUID
+	onDecline := a.onDecline
+	a.mutex.RUnlock()
+
+	if !exists {
+		logrus.Debugf("(dhcp.prepareReply6) NO IPV6 LEASE FOUND: hwaddr=%s", hwAddr)
+		return nil
+	}
+
+	if serverDUID == nil {
+		logrus.Errorf("(dhcp.prepareReply6) server duid is not set")
+		return nil
+	}
+
+	// Messages meant for other servers are silently discarded (RFC 8415
+	// section 16)
+	if serverID := msg.Options.ServerID(); serverID != nil && !serverID.Equal(serverDUID) {
+		logrus.Debugf("(dhcp.prepareReply6) ignore %s from hwaddr %s for another server", msg.Type(), hwAddr)
+		return nil
+	}
+
+	var (
+		reply *dhcpv6.Message
+		err   error
+	)
+
+	switch msg.Type() {
+	case dhcpv6.MessageTypeSolicit:
+		if msg.GetOneOption(dhcpv6.OptionRapidCommit) != nil {
+			reply, err = dhcpv6.NewReplyFromMessage(msg)
+		} else {
+			reply, err = dhcpv6.NewAdvertiseFromSolicit(msg)
+		}
+		if err == nil {
+			addIANA(reply, msg, lease)
+			addConfiguration(reply, lease)
+		}
+	case dhcpv6.MessageTypeRequest, dhcpv6.MessageTypeRenew, dhcpv6.MessageTypeRebind:
+		reply, err = dhcpv6.NewReplyFromMessage(msg)
+		if err == nil {
+			addIANA(reply, msg, lease)
+			addConfiguration(reply, lease)
+		}
+	case dhcpv6.MessageTypeConfirm:
+		reply, err = dhcpv6.NewReplyFromMessage(msg)
+		if err == nil {
+			reply.AddOption(confirmStatus(msg, lease))
+		}
+	case dhcpv6.MessageTypeInformationRequest:
+		reply, err = dhcpv6.NewReplyFromMessage(msg)
+		if err == nil {
+			addConfiguration(reply, lease)
+		}
+	case dhcpv6.MessageTypeRelease:
+		// The address stays reserved for the client, there is nothing to
+		// free up
+		reply, err = dhcpv6.NewReplyFromMessage(msg)
+		if err == nil {
+			reply.AddOption(&dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess})
+		}
+	case dhcpv6.MessageTypeDecline:
+		if isDeclined(msg, lease) {
+			a.mutex.Lock()
+			throttled := isDeclineThrottled(a.declines6, hwAddr, time.Now())
+			a.mutex.Unlock()
+			if throttled {
+				logrus.Warnf("(dhcp.prepareReply6) ignore decline of ip %s from hwaddr %s which declined within %s", lease.ClientIP, hwAddr, declineInterval)
+			} else {
+				logrus.Warnf("(dhcp.prepareReply6) ip %s was declined by hwaddr %s", lease.ClientIP, hwAddr)
+				if onDecline != nil {
+					onDecline(hwAddr, lease.ClientIP)
+				}
+			}
+		} else {
+			logrus.Warnf("(dhcp.prepareReply6) ignore decline of ips other than %s from hwaddr %s", lease.ClientIP, hwAddr)
+		}
+		reply = &dhcpv6.Message{
+			MessageType:   dhcpv6.MessageTypeReply,
+			TransactionID: msg.TransactionID,
+		}
+		if cid := msg.GetOneOption(dhcpv6.OptionClientID); cid != nil {
+			reply.AddOption(cid)
+		}
+		reply.AddOption(&dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess})
+	default:
+		logrus.Warnf("(dhcp.prepareReply6) Unhandled message type for hwaddr [%s]: %v", hwAddr, msg.Type())
+		return nil
+	}
+	if err != nil {
+		logrus.Errorf("(dhcp.prepareReply6) cannot prepare reply for %s from hwaddr %s: %v", msg.Type(), hwAddr, err)
+		return nil
+	}
+
+	reply.AddOption(dhcpv6.OptServerID(serverDUID))
+
+	return reply
+}
+
+// addIANA hands out the address of lease in the IA_NA requested by msg.
+func addIANA(reply, msg *dhcpv6.Message, lease DHCPv6Lease) {
+	iaNA := msg.Options.OneIANA()
+	if iaNA == nil {
+		return
+	}
+
+	leaseTime := lease.LeaseTime
+	if leaseTime <= 0 {
+		leaseTime = defaultLeaseTime6
+	}
+	lifetime := time.Duration(leaseTime) * time.Second
+
+	reply.AddOption(&dhcpv6.OptIANA{
+		IaId: iaNA.IaId,
+		// Renew at 0.5 and rebind at 0.8 of the lifetime (RFC 8415 section
+		// 21.4)
+		T1: lifetime / 2,
+		T2: lifetime * 4 / 5,
+		Options: dhcpv6.IdentityOptions{Options: dhcpv6.Options{
+			&dhcpv6.OptIAAddress{
+				IPv6Addr:          lease.ClientIP,
+				PreferredLifetime: lifetime,
+				ValidLifetime:     lifetime,
+			},
+		}},
+	})
+}
+
+// addConfiguration adds the configuration parameters of lease to reply.
+func addConfiguration(reply *dhcpv6.Message, lease DHCPv6Lease) {
+	if len(lease.DNS) > 0 {
+		reply.AddOption(dhcpv6.OptDNS(lease.DNS...))
+	}
+
+	if len(lease.DomainSearch) > 0 {
+		dhcpv6.WithDomainSearchList(lease.DomainSearch...)(reply)
+	}
+}
+
+// confirmStatus tells whether the addresses the client sending the CONFIRM
+// msg holds are still appropriate.
+func confirmStatus(msg *dhcpv6.Message, lease DHCPv6Lease) *dhcpv6.OptStatusCode {
+	for _, iaNA := range msg.Options.IANA() {
+		for _, addr := range iaNA.Options.Addresses() {
+			if !addr.IPv6Addr.Equal(lease.ClientIP) {
+				return &dhcpv6.OptStatusCode{StatusCode: iana.StatusNotOnLink}
+			}
+		}
+	}
+	return &dhcpv6.OptStatusCode{StatusCode: iana.StatusSuccess}
+}
+
+// isDeclined tells whether the client sending the DECLINE msg declines the
+// address of lease.
+func isDeclined(msg *dhcpv6.Message, lease DHCPv6Lease) bool {
+	for _, iaNA := range msg.Options.IANA() {
+		for _, addr := range iaNA.Options.Addresses() {
+			if addr.IPv6Addr.Equal(lease.ClientIP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Run6 starts the DHCPv6 server on nic, identified by the DUID-LL of nic.
+func (a *DHCPAllocator) Run6(ctx context.Context, nic string) (err error) {
+	logrus.Infof("(dhcp.Run6) starting DHCPv6 service on nic %s", nic)
+
+	iface, err := net.InterfaceByName(nic)
+	if err != nil {
+		return
+	}
+
+	server, err := server6.NewServer(nic, nil, a.dhcp6Handler)
+	if err != nil {
+		return
+	}
+
+	a.mutex.Lock()
+	a.serverDUID = &dhcpv6.DUIDLL{
+		HWType:        iana.HWTypeEthernet,
+		LinkLayerAddr: iface.HardwareAddr,
+	}
+	a.servers6[nic] = server
+	a.mutex.Unlock()
+
+	go func() {
+		if err := server.Serve(); err != nil {
+			logrus.Errorf("(dhcp.Run6) DHCPv6 server on nic %s exited with error: %v", nic, err)
+		}
+	}()
+
+	return nil
+}
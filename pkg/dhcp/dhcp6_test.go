@@ -0,0 +1,350 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv6"
+	"github.com/insomniacslk/dhcp/iana"
+)
+
+func TestAddLease6(t *testing.T) {
+	td := New()
+
+	testLeases := []struct {
+		hwAddr     string
+		clientIP   string
+		dnsServers []string
+		want       error
+	}{
+		{
+			hwAddr:     "52:54:00:00:00:01",
+			clientIP:   "fd00:48::100",
+			dnsServers: []string{"2606:4700:4700::1111"},
+			want:       nil,
+		},
+		{
+			hwAddr:   "52:54:00:00:00:01",
+			clientIP: "fd00:48::101",
+			want:     fmt.Errorf("ipv6 lease for hwaddr 52:54:00:00:00:01 already exists"),
+		},
+		{
+			hwAddr:   "52:54:00:00:00:02",
+			clientIP: "192.168.0.100",
+			want:     fmt.Errorf("client ip 192.168.0.100 is not a valid ipv6 address"),
+		},
+		{
+			hwAddr:     "52:54:00:00:00:02",
+			clientIP:   "fd00:48::102",
+			dnsServers: []string{"1.1.1.1"},
+			want:       fmt.Errorf("dns server 1.1.1.1 is not a valid ipv6 address"),
+		},
+	}
+
+	for _, tc := range testLeases {
+		got := td.AddLease6(tc.hwAddr, tc.clientIP, tc.dnsServers, nil, nil)
+		if got == nil || tc.want == nil {
+			if got != tc.want {
+				t.Errorf("got %v, wanted %v", got, tc.want)
+			}
+		} else if got.Error() != tc.want.Error() {
+			t.Errorf("got %q, wanted %q", got, tc.want)
+		}
+	}
+
+	if err := td.DeleteLease6("52:54:00:00:00:01"); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if lease := td.GetLease6("52:54:00:00:00:01"); lease.ClientIP != nil {
+		t.Errorf("got %s, wanted no lease", lease.ClientIP)
+	}
+}
+
+func TestPrepareReply6(t *testing.T) {
+	hwAddr, _ := net.ParseMAC("52:54:00:00:00:01")
+	serverDUID := &dhcpv6.DUIDLL{
+		HWType:        iana.HWTypeEthernet,
+		LinkLayerAddr: net.HardwareAddr{0x52, 0x54, 0x00, 0xff, 0xff, 0xff},
+	}
+	otherDUID := &dhcpv6.DUIDLL{
+		HWType:        iana.HWTypeEthernet,
+		LinkLayerAddr: net.HardwareAddr{0x52, 0x54, 0x00, 0xee, 0xee, 0xee},
+	}
+	leaseTime := 3600
+
+	td := New()
+	td.serverDUID = serverDUID
+	if err := td.AddLease6(hwAddr.String(), "fd00:48::100", []string{"2606:4700:4700::1111"}, []string{"aibao.moe"}, &leaseTime); err != nil {
+		t.Fatal(err)
+	}
+
+	var declined []string
+	td.OnDecline(func(hwAddr string, ipAddr net.IP) {
+		declined = append(declined, hwAddr+"/"+ipAddr.String())
+	})
+
+	solicit, err := dhcpv6.NewSolicit(hwAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		msg         func() *dhcpv6.Message
+		wantType    dhcpv6.MessageType
+		wantAddress bool
+		wantStatus  *iana.StatusCode
+		wantNoReply bool
+		// wantRapidCommit is whether the reply must carry the rapid commit
+		// option (RFC 8415 section 18.3.1)
+		wantRapidCommit bool
+		wantDeclined    []string
+	}{
+		{
+			name:        "solicit",
+			msg:         func() *dhcpv6.Message { return solicit },
+			wantType:    dhcpv6.MessageTypeAdvertise,
+			wantAddress: true,
+		},
+		{
+			name: "solicit with rapid commit",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithRapidCommit)
+				return m
+			},
+			wantType:        dhcpv6.MessageTypeReply,
+			wantAddress:     true,
+			wantRapidCommit: true,
+		},
+		{
+			name: "solicit from unknown client",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(net.HardwareAddr{0x52, 0x54, 0x00, 0x00, 0x00, 0x02})
+				return m
+			},
+			wantNoReply: true,
+		},
+		{
+			name: "request",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithServerID(serverDUID))
+				m.MessageType = dhcpv6.MessageTypeRequest
+				return m
+			},
+			wantType:    dhcpv6.MessageTypeReply,
+			wantAddress: true,
+		},
+		{
+			name: "request for another server",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithServerID(otherDUID))
+				m.MessageType = dhcpv6.MessageTypeRequest
+				return m
+			},
+			wantNoReply: true,
+		},
+		{
+			name: "confirm with appropriate address",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00:48::100")}))
+				m.MessageType = dhcpv6.MessageTypeConfirm
+				return m
+			},
+			wantType:   dhcpv6.MessageTypeReply,
+			wantStatus: func(c iana.StatusCode) *iana.StatusCode { return &c }(iana.StatusSuccess),
+		},
+		{
+			name: "confirm with inappropriate address",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00:49::100")}))
+				m.MessageType = dhcpv6.MessageTypeConfirm
+				return m
+			},
+			wantType:   dhcpv6.MessageTypeReply,
+			wantStatus: func(c iana.StatusCode) *iana.StatusCode { return &c }(iana.StatusNotOnLink),
+		},
+		{
+			name: "decline of another address",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithServerID(serverDUID), dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00:48::101")}))
+				m.MessageType = dhcpv6.MessageTypeDecline
+				return m
+			},
+			wantType:   dhcpv6.MessageTypeReply,
+			wantStatus: func(c iana.StatusCode) *iana.StatusCode { return &c }(iana.StatusSuccess),
+		},
+		{
+			name: "decline",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithServerID(serverDUID), dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00:48::100")}))
+				m.MessageType = dhcpv6.MessageTypeDecline
+				return m
+			},
+			wantType:     dhcpv6.MessageTypeReply,
+			wantStatus:   func(c iana.StatusCode) *iana.StatusCode { return &c }(iana.StatusSuccess),
+			wantDeclined: []string{"52:54:00:00:00:01/fd00:48::100"},
+		},
+		{
+			name: "decline again",
+			msg: func() *dhcpv6.Message {
+				m, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithServerID(serverDUID), dhcpv6.WithIANA(dhcpv6.OptIAAddress{IPv6Addr: net.ParseIP("fd00:48::100")}))
+				m.MessageType = dhcpv6.MessageTypeDecline
+				return m
+			},
+			wantType:   dhcpv6.MessageTypeReply,
+			wantStatus: func(c iana.StatusCode) *iana.StatusCode { return &c }(iana.StatusSuccess),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			declined = nil
+			msg := tc.msg()
+			mac, err := dhcpv6.ExtractMAC(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reply := td.prepareReply6(mac.String(), msg)
+			if tc.wantNoReply {
+				if reply != nil {
+					t.Errorf("got %s, wanted no reply", reply.Summary())
+				}
+				return
+			}
+			if reply == nil {
+				t.Fatalf("got no reply, wanted %s", tc.wantType)
+			}
+
+			if reply.Type() != tc.wantType {
+				t.Errorf("got type %s, wanted %s", reply.Type(), tc.wantType)
+			}
+			if reply.TransactionID != msg.TransactionID {
+				t.Errorf("got transaction id %s, wanted %s", reply.TransactionID, msg.TransactionID)
+			}
+			if serverID := reply.Options.ServerID(); serverID == nil || !serverID.Equal(serverDUID) {
+				t.Errorf("got server id %v, wanted %v", serverID, serverDUID)
+			}
+
+			if tc.wantAddress {
+				iaNA := reply.Options.OneIANA()
+				if iaNA == nil {
+					t.Fatalf("got no ia_na")
+				}
+				if iaNA.IaId != msg.Options.OneIANA().IaId {
+					t.Errorf("got iaid %v, wanted %v", iaNA.IaId, msg.Options.OneIANA().IaId)
+				}
+				if iaNA.T1 != 30*time.Minute || iaNA.T2 != 48*time.Minute {
+					t.Errorf("got t1 %s and t2 %s, wanted 30m0s and 48m0s", iaNA.T1, iaNA.T2)
+				}
+				addr := iaNA.Options.OneAddress()
+				if addr == nil || !addr.IPv6Addr.Equal(net.ParseIP("fd00:48::100")) {
+					t.Errorf("got address %v, wanted fd00:48::100", addr)
+				} else if addr.ValidLifetime != time.Hour {
+					t.Errorf("got valid lifetime %s, wanted 1h0m0s", addr.ValidLifetime)
+				}
+				if dns := reply.Options.DNS(); len(dns) != 1 || !dns[0].Equal(net.ParseIP("2606:4700:4700::1111")) {
+					t.Errorf("got dns %v, wanted [2606:4700:4700::1111]", dns)
+				}
+				if labels := reply.Options.DomainSearchList(); labels == nil || len(labels.Labels) != 1 || labels.Labels[0] != "aibao.moe" {
+					t.Errorf("got domain search list %v, wanted [aibao.moe]", labels)
+				}
+			}
+
+			if tc.wantStatus != nil {
+				status := reply.Options.Status()
+				if status == nil || status.StatusCode != *tc.wantStatus {
+					t.Errorf("got status %v, wanted %s", status, *tc.wantStatus)
+				}
+			}
+
+			if rapidCommit := reply.GetOneOption(dhcpv6.OptionRapidCommit) != nil; rapidCommit != tc.wantRapidCommit {
+				t.Errorf("got rapid commit %t, wanted %t", rapidCommit, tc.wantRapidCommit)
+			}
+
+			if fmt.Sprint(declined) != fmt.Sprint(tc.wantDeclined) {
+				t.Errorf("got declined %v, wanted %v", declined, tc.wantDeclined)
+			}
+		})
+	}
+}
+
+func TestHwAddrOf6(t *testing.T) {
+	hwAddr, _ := net.ParseMAC("52:54:00:00:00:01")
+	duidEN := &dhcpv6.DUIDEN{EnterpriseNumber: 32473, EnterpriseIdentifier: []byte{0x01, 0x02}}
+	duidUUID := &dhcpv6.DUIDUUID{UUID: [16]byte{0x01}}
+	eui64Peer := &net.UDPAddr{IP: net.ParseIP("fe80::5054:ff:fe00:1"), Port: dhcpv6.DefaultClientPort}
+	stablePeer := &net.UDPAddr{IP: net.ParseIP("fe80::1234:5678:9abc:def0"), Port: dhcpv6.DefaultClientPort}
+
+	td := New()
+	if err := td.AddLease6(hwAddr.String(), "fd00:48::100", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		duid       dhcpv6.DUID
+		peer       net.Addr
+		wantHwAddr string
+		wantErr    bool
+	}{
+		{
+			name:       "duid-ll",
+			duid:       &dhcpv6.DUIDLL{HWType: iana.HWTypeEthernet, LinkLayerAddr: hwAddr},
+			peer:       stablePeer,
+			wantHwAddr: hwAddr.String(),
+		},
+		{
+			name:    "duid-uuid from stable privacy address",
+			duid:    duidUUID,
+			peer:    stablePeer,
+			wantErr: true,
+		},
+		{
+			name:       "duid-en from eui-64 address",
+			duid:       duidEN,
+			peer:       eui64Peer,
+			wantHwAddr: hwAddr.String(),
+		},
+		{
+			name:       "known duid-en from stable privacy address",
+			duid:       duidEN,
+			peer:       stablePeer,
+			wantHwAddr: hwAddr.String(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithClientID(tc.duid))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := td.hwAddrOf6(msg, msg, tc.peer)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("got %s, wanted error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.wantHwAddr {
+				t.Errorf("got %s, wanted %s", got, tc.wantHwAddr)
+			}
+		})
+	}
+
+	// The DUID is forgotten along with the lease
+	if err := td.DeleteLease6(hwAddr.String()); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := dhcpv6.NewSolicit(hwAddr, dhcpv6.WithClientID(duidEN))
+	if got, err := td.hwAddrOf6(msg, msg, stablePeer); err == nil {
+		t.Errorf("got %s, wanted error", got)
+	}
+}
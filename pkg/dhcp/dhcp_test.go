@@ -3,24 +3,36 @@ package dhcp
 import (
 	"fmt"
 	"net"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+	"github.com/insomniacslk/dhcp/iana"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 )
 
 func TestDHCP(t *testing.T) {
 	td := New()
 
 	testLeases := []struct {
-		hwAddr       string
-		serverIP     string
-		clientIP     string
-		cidr         string
-		routerIP     string
-		dnsServers   []string
-		domainName   *string
-		domainSearch []string
-		ntpServers   []string
-		leaseTime    *int
-		want         error
+		hwAddr          string
+		serverIP        string
+		clientIP        string
+		cidr            string
+		routerIP        string
+		dnsServers      []string
+		domainName      *string
+		domainSearch    []string
+		ntpServers      []string
+		leaseTime       *int
+		routes          []networkv1.Route
+		boot            *networkv1.BootConfig
+		customOptions   []networkv1.DHCPOption
+		mtu             *int
+		disableHostname *bool
+		want            error
 	}{
 		{
 			hwAddr:       "aa:bb:cc:dd:ee:ff",
@@ -120,6 +132,57 @@ func TestDHCP(t *testing.T) {
 			ntpServers: []string{"xxxx"},
 			want:       nil,
 		},
+		{
+			hwAddr:   "00:01:02:03:04:06",
+			serverIP: "192.168.0.2",
+			clientIP: "192.168.0.12",
+			cidr:     "192.168.0.0/24",
+			routerIP: "192.168.0.1",
+			routes: []networkv1.Route{
+				{Destination: "10.10.0.0/16", Gateway: "192.168.0.3"},
+			},
+			want: nil,
+		},
+		{
+			hwAddr:   "00:01:02:03:04:07",
+			serverIP: "192.168.0.2",
+			clientIP: "192.168.0.13",
+			cidr:     "192.168.0.0/24",
+			routerIP: "192.168.0.1",
+			routes: []networkv1.Route{
+				{Destination: "10.10.0.0/36", Gateway: "192.168.0.3"},
+			},
+			want: fmt.Errorf("route destination 10.10.0.0/36 is not a valid ipv4 cidr"),
+		},
+		{
+			hwAddr:   "00:01:02:03:04:08",
+			serverIP: "192.168.0.2",
+			clientIP: "192.168.0.14",
+			cidr:     "192.168.0.0/24",
+			routerIP: "192.168.0.1",
+			routes: []networkv1.Route{
+				{Destination: "10.10.0.0/16", Gateway: "fd00::1"},
+			},
+			want: fmt.Errorf("route gateway fd00::1 is not a valid ipv4 address"),
+		},
+		{
+			hwAddr:   "00:01:02:03:04:09",
+			serverIP: "192.168.0.2",
+			clientIP: "192.168.0.15",
+			cidr:     "192.168.0.0/24",
+			routerIP: "192.168.0.1",
+			mtu:      func(i int) *int { return &i }(1450),
+			want:     nil,
+		},
+		{
+			hwAddr:   "00:01:02:03:04:0a",
+			serverIP: "192.168.0.2",
+			clientIP: "192.168.0.16",
+			cidr:     "192.168.0.0/24",
+			routerIP: "192.168.0.1",
+			mtu:      func(i int) *int { return &i }(40),
+			want:     fmt.Errorf("mtu 40 is out of range"),
+		},
 	}
 
 	// AddLease function tests
@@ -135,6 +198,11 @@ func TestDHCP(t *testing.T) {
 			testLeases[i].domainSearch,
 			testLeases[i].ntpServers,
 			testLeases[i].leaseTime,
+			testLeases[i].routes,
+			testLeases[i].boot,
+			testLeases[i].customOptions,
+			testLeases[i].mtu,
+			testLeases[i].disableHostname,
 		); got != testLeases[i].want {
 			if got == nil || testLeases[i].want == nil {
 				t.Errorf("got %q, wanted %q", got, testLeases[i].want)
@@ -179,3 +247,844 @@ func TestDHCP(t *testing.T) {
 		}
 	}
 }
+
+func TestReleaseAndDecline(t *testing.T) {
+	td := New()
+
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+
+	var declined []string
+	td.OnDecline(func(hwAddr string, ipAddr net.IP) {
+		declined = append(declined, hwAddr+"/"+ipAddr.String())
+	})
+
+	testDeclines := []struct {
+		name        string
+		hwAddr      net.HardwareAddr
+		serverID    string
+		requestedIP string
+		want        []string
+	}{
+		{
+			name:        "unknown-client",
+			hwAddr:      net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.10",
+			want:        nil,
+		},
+		{
+			name:        "other-server",
+			hwAddr:      hwAddr,
+			serverID:    "192.168.0.3",
+			requestedIP: "192.168.0.10",
+			want:        nil,
+		},
+		{
+			name:        "mismatched-ip",
+			hwAddr:      hwAddr,
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.11",
+			want:        nil,
+		},
+		{
+			name:        "ok",
+			hwAddr:      hwAddr,
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.10",
+			want:        []string{"aa:bb:cc:dd:ee:ff/192.168.0.10"},
+		},
+		{
+			name:        "declined-again",
+			hwAddr:      hwAddr,
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.10",
+			want:        nil,
+		},
+	}
+
+	// decline function tests
+	for _, tc := range testDeclines {
+		declined = nil
+		m, err := dhcpv4.New(
+			dhcpv4.WithHwAddr(tc.hwAddr),
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeDecline),
+			dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.ParseIP(tc.serverID))),
+			dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP(tc.requestedIP))),
+		)
+		if err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+		td.decline(m)
+		if fmt.Sprint(declined) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, wanted %v", tc.name, declined, tc.want)
+		}
+	}
+	if td.GetLease("aa:bb:cc:dd:ee:ff").DeclinedAt.IsZero() {
+		t.Errorf("got zero DeclinedAt, wanted non-zero")
+	}
+
+	// release function tests
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeRelease),
+		dhcpv4.WithClientIP(net.ParseIP("192.168.0.11")),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	td.release(m)
+	if !td.GetLease("aa:bb:cc:dd:ee:ff").ReleasedAt.IsZero() {
+		t.Errorf("got non-zero ReleasedAt for mismatched ip, wanted zero")
+	}
+	m.ClientIPAddr = net.ParseIP("192.168.0.10")
+	td.release(m)
+	if td.GetLease("aa:bb:cc:dd:ee:ff").ReleasedAt.IsZero() {
+		t.Errorf("got zero ReleasedAt, wanted non-zero")
+	}
+	if got := td.GetLease("aa:bb:cc:dd:ee:ff").State; got != LeaseStateReleased {
+		t.Errorf("got state %s, wanted %s", got, LeaseStateReleased)
+	}
+}
+
+func TestLeaseState(t *testing.T) {
+	td := New()
+
+	leaseTime := 300
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, &leaseTime, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testSteps := []struct {
+		name          string
+		step          func()
+		wantState     LeaseState
+		wantBoundAt   time.Time
+		wantRenewedAt time.Time
+		wantExpiresAt time.Time
+	}{
+		{
+			name:      "allocated",
+			step:      func() {},
+			wantState: LeaseStateAllocated,
+		},
+		{
+			name:      "offered",
+			step:      func() { td.offer("aa:bb:cc:dd:ee:ff") },
+			wantState: LeaseStateOffered,
+		},
+		{
+			name:          "bound",
+			step:          func() { td.bind("aa:bb:cc:dd:ee:ff", t0) },
+			wantState:     LeaseStateBound,
+			wantBoundAt:   t0,
+			wantExpiresAt: t0.Add(300 * time.Second),
+		},
+		{
+			name:          "offered-while-bound",
+			step:          func() { td.offer("aa:bb:cc:dd:ee:ff") },
+			wantState:     LeaseStateBound,
+			wantBoundAt:   t0,
+			wantExpiresAt: t0.Add(300 * time.Second),
+		},
+		{
+			name:          "renewed",
+			step:          func() { td.bind("aa:bb:cc:dd:ee:ff", t0.Add(150*time.Second)) },
+			wantState:     LeaseStateBound,
+			wantBoundAt:   t0,
+			wantRenewedAt: t0.Add(150 * time.Second),
+			wantExpiresAt: t0.Add(450 * time.Second),
+		},
+		{
+			name:          "not-expired-yet",
+			step:          func() { td.expire(t0.Add(449 * time.Second)) },
+			wantState:     LeaseStateBound,
+			wantBoundAt:   t0,
+			wantRenewedAt: t0.Add(150 * time.Second),
+			wantExpiresAt: t0.Add(450 * time.Second),
+		},
+		{
+			name:          "expired",
+			step:          func() { td.expire(t0.Add(450 * time.Second)) },
+			wantState:     LeaseStateExpired,
+			wantBoundAt:   t0,
+			wantRenewedAt: t0.Add(150 * time.Second),
+			wantExpiresAt: t0.Add(450 * time.Second),
+		},
+		{
+			name:          "bound-again",
+			step:          func() { td.bind("aa:bb:cc:dd:ee:ff", t0.Add(600*time.Second)) },
+			wantState:     LeaseStateBound,
+			wantBoundAt:   t0.Add(600 * time.Second),
+			wantExpiresAt: t0.Add(900 * time.Second),
+		},
+	}
+
+	for _, tc := range testSteps {
+		tc.step()
+		lease := td.GetLease("aa:bb:cc:dd:ee:ff")
+		if lease.State != tc.wantState {
+			t.Errorf("%s: got state %s, wanted %s", tc.name, lease.State, tc.wantState)
+		}
+		if !lease.BoundAt.Equal(tc.wantBoundAt) {
+			t.Errorf("%s: got bound at %s, wanted %s", tc.name, lease.BoundAt, tc.wantBoundAt)
+		}
+		if !lease.RenewedAt.Equal(tc.wantRenewedAt) {
+			t.Errorf("%s: got renewed at %s, wanted %s", tc.name, lease.RenewedAt, tc.wantRenewedAt)
+		}
+		if !lease.ExpiresAt.Equal(tc.wantExpiresAt) {
+			t.Errorf("%s: got expires at %s, wanted %s", tc.name, lease.ExpiresAt, tc.wantExpiresAt)
+		}
+	}
+
+	if got := td.expire(t0.Add(900 * time.Second)); fmt.Sprint(got) != "[aa:bb:cc:dd:ee:ff]" {
+		t.Errorf("got expired %v, wanted [aa:bb:cc:dd:ee:ff]", got)
+	}
+
+	leases, err := td.ListAll("")
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if !strings.Contains(leases["aa:bb:cc:dd:ee:ff"], `"State":"expired"`) {
+		t.Errorf("got %s, wanted expired state", leases["aa:bb:cc:dd:ee:ff"])
+	}
+}
+
+func TestUpdateLease(t *testing.T) {
+	td := New()
+
+	leaseTime := 300
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", []string{"8.8.8.8"}, nil, nil, nil, &leaseTime, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	td.bind("aa:bb:cc:dd:ee:ff", t0)
+
+	newLeaseTime := 600
+	domainName := "example.com"
+	if err := td.UpdateLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", []string{"1.1.1.1", "1.0.0.1"}, &domainName, nil, nil, &newLeaseTime, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	lease := td.GetLease("aa:bb:cc:dd:ee:ff")
+	if len(lease.DNS) != 2 || !lease.DNS[0].Equal(net.ParseIP("1.1.1.1")) || !lease.DNS[1].Equal(net.ParseIP("1.0.0.1")) {
+		t.Errorf("got dns %v, wanted [1.1.1.1 1.0.0.1]", lease.DNS)
+	}
+	if lease.DomainName != domainName {
+		t.Errorf("got domain name %q, wanted %q", lease.DomainName, domainName)
+	}
+	if lease.LeaseTime != newLeaseTime {
+		t.Errorf("got lease time %d, wanted %d", lease.LeaseTime, newLeaseTime)
+	}
+
+	// The state of the lease is kept
+	if lease.State != LeaseStateBound {
+		t.Errorf("got state %s, wanted %s", lease.State, LeaseStateBound)
+	}
+	if !lease.BoundAt.Equal(t0) || !lease.ExpiresAt.Equal(t0.Add(300*time.Second)) {
+		t.Errorf("got bound at %s and expires at %s, wanted %s and %s", lease.BoundAt, lease.ExpiresAt, t0, t0.Add(300*time.Second))
+	}
+
+	if err := td.UpdateLease("00:01:02:03:04:05", "192.168.0.2", "192.168.0.11", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err == nil {
+		t.Errorf("got nil, wanted error")
+	} else if err.Error() != "lease for hwaddr 00:01:02:03:04:05 does not exists" {
+		t.Errorf("got %q", err)
+	}
+}
+
+func TestLeaseActivity(t *testing.T) {
+	td := New()
+
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	var reported []DHCPLease
+	td.OnActivity(func(hwAddr string, lease DHCPLease) {
+		if hwAddr != "aa:bb:cc:dd:ee:ff" {
+			t.Errorf("got activity of %s, wanted aa:bb:cc:dd:ee:ff", hwAddr)
+		}
+		reported = append(reported, lease)
+	})
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithOption(dhcpv4.OptHostName("test-vm")),
+		dhcpv4.WithOption(dhcpv4.OptClassIdentifier("PXEClient")),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	td.see(m, t0)
+	td.see(m, t0.Add(time.Minute))
+	td.reportActivity("aa:bb:cc:dd:ee:ff")
+	// Clients without leases are not reported
+	td.reportActivity("11:22:33:44:55:66")
+
+	if len(reported) != 1 {
+		t.Fatalf("got %d activities reported, wanted 1", len(reported))
+	}
+	lease := reported[0]
+	if !lease.FirstSeenAt.Equal(t0) {
+		t.Errorf("got first seen at %s, wanted %s", lease.FirstSeenAt, t0)
+	}
+	if lease.ClientHostname != "test-vm" {
+		t.Errorf("got hostname %q, wanted %q", lease.ClientHostname, "test-vm")
+	}
+	if lease.VendorClass != "PXEClient" {
+		t.Errorf("got vendor class %q, wanted %q", lease.VendorClass, "PXEClient")
+	}
+	if lease.State != LeaseStateAllocated {
+		t.Errorf("got state %s, wanted %s", lease.State, LeaseStateAllocated)
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+	lease := DHCPLease{
+		ServerIP: net.ParseIP("192.168.0.2"),
+		ClientIP: net.ParseIP("192.168.0.10"),
+	}
+
+	testRequests := []struct {
+		name        string
+		serverID    string
+		requestedIP string
+		clientIP    string
+		want        requestVerdict
+	}{
+		{
+			name:        "selecting-ok",
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.10",
+			want:        requestACK,
+		},
+		{
+			name:        "selecting-other-server",
+			serverID:    "192.168.0.3",
+			requestedIP: "192.168.0.10",
+			want:        requestIgnore,
+		},
+		{
+			name:        "selecting-mismatched-ip",
+			serverID:    "192.168.0.2",
+			requestedIP: "192.168.0.11",
+			want:        requestNAK,
+		},
+		{
+			name:     "selecting-missing-ip",
+			serverID: "192.168.0.2",
+			want:     requestNAK,
+		},
+		{
+			name:        "init-reboot-ok",
+			requestedIP: "192.168.0.10",
+			want:        requestACK,
+		},
+		{
+			name:        "init-reboot-mismatched-ip",
+			requestedIP: "192.168.0.11",
+			want:        requestNAK,
+		},
+		{
+			name:     "renewing-ok",
+			clientIP: "192.168.0.10",
+			want:     requestACK,
+		},
+		{
+			name:     "renewing-mismatched-ip",
+			clientIP: "192.168.0.11",
+			want:     requestNAK,
+		},
+		{
+			name: "malformed",
+			want: requestIgnore,
+		},
+	}
+
+	// checkRequest function tests
+	for _, tc := range testRequests {
+		modifiers := []dhcpv4.Modifier{
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeRequest),
+		}
+		if tc.serverID != "" {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptServerIdentifier(net.ParseIP(tc.serverID))))
+		}
+		if tc.requestedIP != "" {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP(tc.requestedIP))))
+		}
+		if tc.clientIP != "" {
+			modifiers = append(modifiers, dhcpv4.WithClientIP(net.ParseIP(tc.clientIP)))
+		}
+		m, err := dhcpv4.New(modifiers...)
+		if err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+		if got, reason := checkRequest(m, lease); got != tc.want {
+			t.Errorf("%s: got %d (%s), wanted %d", tc.name, got, reason, tc.want)
+		}
+	}
+}
+
+func TestPrepareNAK(t *testing.T) {
+	m, err := dhcpv4.New(
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeRequest),
+		dhcpv4.WithClientIP(net.ParseIP("192.168.0.11")),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	reply := prepareNAK(m, net.ParseIP("192.168.0.2"), "client ip 192.168.0.11 does not match leased ip 192.168.0.10")
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPNAK")
+	}
+	if got := reply.MessageType(); got != dhcpv4.MessageTypeNak {
+		t.Errorf("got %s, wanted %s", got, dhcpv4.MessageTypeNak)
+	}
+	if got := reply.ServerIdentifier(); !got.Equal(net.ParseIP("192.168.0.2")) {
+		t.Errorf("got %s, wanted 192.168.0.2", got)
+	}
+	if !reply.ClientIPAddr.IsUnspecified() || !reply.YourIPAddr.IsUnspecified() {
+		t.Errorf("got ciaddr %s and yiaddr %s, wanted both unspecified", reply.ClientIPAddr, reply.YourIPAddr)
+	}
+	if !reply.IsBroadcast() {
+		t.Errorf("got unicast, wanted broadcast")
+	}
+	if got := reply.Message(); got != "client ip 192.168.0.11 does not match leased ip 192.168.0.10" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestPrepareNAKRelayed(t *testing.T) {
+	m, err := dhcpv4.New(
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeRequest),
+		dhcpv4.WithGatewayIP(net.ParseIP("10.0.0.1")),
+		dhcpv4.WithOption(dhcpv4.OptRequestedIPAddress(net.ParseIP("192.168.0.11"))),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	reply := prepareNAK(m, net.ParseIP("192.168.0.2"), "requested ip 192.168.0.11 does not match leased ip 192.168.0.10")
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPNAK")
+	}
+	if got := reply.GatewayIPAddr; !got.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("got giaddr %s, wanted 10.0.0.1", got)
+	}
+	if !reply.YourIPAddr.IsUnspecified() {
+		t.Errorf("got yiaddr %s, wanted unspecified", reply.YourIPAddr)
+	}
+	if !reply.IsBroadcast() {
+		t.Errorf("got unicast, wanted broadcast")
+	}
+}
+
+func TestPrepareReplyWithRoutes(t *testing.T) {
+	td := New()
+
+	routes := []networkv1.Route{
+		{Destination: "10.10.0.0/16", Gateway: "192.168.0.3"},
+	}
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, routes, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	reply := td.prepareReply(m, dhcpv4.MessageTypeOffer)
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPOFFER")
+	}
+
+	wanted := "route to 10.10.0.0/16 via 192.168.0.3; route to 0.0.0.0/0 via 192.168.0.1"
+	if got := dhcpv4.Routes(reply.ClasslessStaticRoute()).String(); got != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	var msRoutes dhcpv4.Routes
+	if err := msRoutes.FromBytes(reply.Options.Get(OptionMSClasslessStaticRoute)); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if got := msRoutes.String(); got != wanted {
+		t.Errorf("got %q, wanted %q", got, wanted)
+	}
+
+	// The lease itself must not carry the default route
+	if got := len(td.GetLease("aa:bb:cc:dd:ee:ff").Routes); got != 1 {
+		t.Errorf("got %d routes, wanted 1", got)
+	}
+}
+
+func TestSelectBootFile(t *testing.T) {
+	lease := DHCPLease{
+		BootFile:     "pxelinux.0",
+		UEFIBootFile: "ipxe.efi",
+		IPXEBootFile: "http://192.168.0.2/boot.ipxe",
+	}
+
+	testBootFiles := []struct {
+		name      string
+		lease     DHCPLease
+		arch      []iana.Arch
+		userClass string
+		want      string
+	}{
+		{
+			name:  "legacy-bios",
+			lease: lease,
+			arch:  []iana.Arch{iana.INTEL_X86PC},
+			want:  "pxelinux.0",
+		},
+		{
+			name:  "no-arch",
+			lease: lease,
+			want:  "pxelinux.0",
+		},
+		{
+			name:  "uefi",
+			lease: lease,
+			arch:  []iana.Arch{iana.EFI_X86_64},
+			want:  "ipxe.efi",
+		},
+		{
+			name:  "uefi-without-uefi-boot-file",
+			lease: DHCPLease{BootFile: "pxelinux.0"},
+			arch:  []iana.Arch{iana.EFI_X86_64},
+			want:  "pxelinux.0",
+		},
+		{
+			name:      "ipxe",
+			lease:     lease,
+			arch:      []iana.Arch{iana.EFI_X86_64},
+			userClass: "iPXE",
+			want:      "http://192.168.0.2/boot.ipxe",
+		},
+		{
+			name:      "ipxe-without-ipxe-boot-file",
+			lease:     DHCPLease{BootFile: "pxelinux.0", UEFIBootFile: "ipxe.efi"},
+			arch:      []iana.Arch{iana.EFI_X86_64},
+			userClass: "iPXE",
+			want:      "ipxe.efi",
+		},
+	}
+
+	// selectBootFile function tests
+	for _, tc := range testBootFiles {
+		modifiers := []dhcpv4.Modifier{
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+		}
+		if len(tc.arch) > 0 {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptClientArch(tc.arch...)))
+		}
+		if tc.userClass != "" {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptUserClass(tc.userClass)))
+		}
+		m, err := dhcpv4.New(modifiers...)
+		if err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+		if got := selectBootFile(m, tc.lease); got != tc.want {
+			t.Errorf("%s: got %q, wanted %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPrepareReplyWithBoot(t *testing.T) {
+	td := New()
+
+	boot := &networkv1.BootConfig{
+		NextServer: "192.168.0.5",
+		Filename:   "pxelinux.0",
+	}
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, boot, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	reply := td.prepareReply(m, dhcpv4.MessageTypeOffer)
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPOFFER")
+	}
+	if !reply.ServerIPAddr.Equal(net.ParseIP("192.168.0.5")) {
+		t.Errorf("got siaddr %s, wanted 192.168.0.5", reply.ServerIPAddr)
+	}
+	if got := reply.TFTPServerName(); got != "192.168.0.5" {
+		t.Errorf("got option 66 %q, wanted 192.168.0.5", got)
+	}
+	if got := reply.BootFileNameOption(); got != "pxelinux.0" {
+		t.Errorf("got option 67 %q, wanted pxelinux.0", got)
+	}
+	if reply.BootFileName != "pxelinux.0" {
+		t.Errorf("got file %q, wanted pxelinux.0", reply.BootFileName)
+	}
+	if got := reply.ServerIdentifier(); !got.Equal(net.ParseIP("192.168.0.2")) {
+		t.Errorf("got server identifier %s, wanted 192.168.0.2", got)
+	}
+}
+
+func TestPrepareReplyWithCustomOptions(t *testing.T) {
+	td := New()
+
+	customOptions := []networkv1.DHCPOption{
+		{Code: 150, Type: networkv1.DHCPOptionTypeIP, Value: "192.168.0.5"},
+		{Code: 252, Type: networkv1.DHCPOptionTypeString, Value: "http://192.168.0.6/wpad.dat"},
+	}
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, customOptions, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	clientOptions := []networkv1.DHCPOption{
+		{Code: 150, Type: networkv1.DHCPOptionTypeIP, Value: "192.168.0.7"},
+	}
+	if err := td.SetClient("aa:bb:cc:dd:ee:ff", "", clientOptions); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	reply := td.prepareReply(m, dhcpv4.MessageTypeOffer)
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPOFFER")
+	}
+	if got := net.IP(reply.Options.Get(dhcpv4.GenericOptionCode(150))); !got.Equal(net.ParseIP("192.168.0.7")) {
+		t.Errorf("got option 150 %s, wanted 192.168.0.7", got)
+	}
+	if got := string(reply.Options.Get(dhcpv4.GenericOptionCode(252))); got != "http://192.168.0.6/wpad.dat" {
+		t.Errorf("got option 252 %q, wanted http://192.168.0.6/wpad.dat", got)
+	}
+
+	td.DeleteClient("aa:bb:cc:dd:ee:ff")
+
+	reply = td.prepareReply(m, dhcpv4.MessageTypeOffer)
+	if reply == nil {
+		t.Fatalf("got nil, wanted DHCPOFFER")
+	}
+	if got := net.IP(reply.Options.Get(dhcpv4.GenericOptionCode(150))); !got.Equal(net.ParseIP("192.168.0.5")) {
+		t.Errorf("got option 150 %s, wanted 192.168.0.5", got)
+	}
+}
+
+func TestPrepareReplyWithMTUAndHostname(t *testing.T) {
+	mtu := 1450
+	disabled := true
+
+	testReplies := []struct {
+		name            string
+		mtu             *int
+		disableHostname *bool
+		hostname        string
+		wantMTU         uint16
+		wantHostname    string
+	}{
+		{
+			name:         "mtu-and-hostname",
+			mtu:          &mtu,
+			hostname:     "test-vm",
+			wantMTU:      1450,
+			wantHostname: "test-vm",
+		},
+		{
+			name: "no-mtu-no-hostname",
+		},
+		{
+			name:            "hostname-disabled",
+			disableHostname: &disabled,
+			hostname:        "test-vm",
+		},
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	m, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	// prepareReply function tests
+	for _, tc := range testReplies {
+		td := New()
+		if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, nil, tc.mtu, tc.disableHostname); err != nil {
+			t.Fatalf("%s: %s", tc.name, err.Error())
+		}
+		if tc.hostname != "" {
+			if err := td.SetClient("aa:bb:cc:dd:ee:ff", tc.hostname, nil); err != nil {
+				t.Fatalf("%s: %s", tc.name, err.Error())
+			}
+		}
+
+		reply := td.prepareReply(m, dhcpv4.MessageTypeOffer)
+		if reply == nil {
+			t.Fatalf("%s: got nil, wanted DHCPOFFER", tc.name)
+		}
+
+		var gotMTU uint16
+		if data := reply.Options.Get(dhcpv4.OptionInterfaceMTU); data != nil {
+			if len(data) != 2 {
+				t.Errorf("%s: got option 26 of length %d, wanted 2", tc.name, len(data))
+				continue
+			}
+			gotMTU = uint16(data[0])<<8 | uint16(data[1])
+		}
+		if gotMTU != tc.wantMTU {
+			t.Errorf("%s: got mtu %d, wanted %d", tc.name, gotMTU, tc.wantMTU)
+		}
+		if got := reply.HostName(); got != tc.wantHostname {
+			t.Errorf("%s: got hostname %q, wanted %q", tc.name, got, tc.wantHostname)
+		}
+	}
+}
+
+func TestPrepareReplyRelayed(t *testing.T) {
+	td := New()
+	if err := td.AddLease("aa:bb:cc:dd:ee:ff", "192.168.0.2", "192.168.0.10", "192.168.0.0/24", "192.168.0.1", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	testReplies := []struct {
+		name          string
+		gatewayIP     net.IP
+		linkSelection net.IP
+		wantReply     bool
+	}{
+		{
+			name:      "not-relayed",
+			wantReply: true,
+		},
+		{
+			name:      "giaddr-on-link",
+			gatewayIP: net.IPv4(192, 168, 0, 1),
+			wantReply: true,
+		},
+		{
+			name:      "giaddr-off-link",
+			gatewayIP: net.IPv4(192, 168, 1, 1),
+		},
+		{
+			name:          "link-selection-on-link",
+			gatewayIP:     net.IPv4(10, 0, 0, 1),
+			linkSelection: net.IPv4(192, 168, 0, 0),
+			wantReply:     true,
+		},
+		{
+			name:          "link-selection-off-link",
+			gatewayIP:     net.IPv4(192, 168, 0, 1),
+			linkSelection: net.IPv4(192, 168, 1, 0),
+		},
+	}
+
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+
+	for _, tc := range testReplies {
+		modifiers := []dhcpv4.Modifier{
+			dhcpv4.WithHwAddr(hwAddr),
+			dhcpv4.WithMessageType(dhcpv4.MessageTypeDiscover),
+		}
+		if tc.gatewayIP != nil {
+			modifiers = append(modifiers, dhcpv4.WithGatewayIP(tc.gatewayIP))
+		}
+		if tc.linkSelection != nil {
+			modifiers = append(modifiers, dhcpv4.WithOption(dhcpv4.OptRelayAgentInfo(
+				dhcpv4.OptGeneric(dhcpv4.AgentCircuitIDSubOption, []byte("eth0")),
+				dhcpv4.OptGeneric(dhcpv4.LinkSelectionSubOption, tc.linkSelection.To4()),
+			)))
+		}
+		m, err := dhcpv4.New(modifiers...)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err.Error())
+		}
+
+		reply := td.prepareReply(m, dhcpv4.MessageTypeOffer)
+		if !tc.wantReply {
+			if reply != nil {
+				t.Errorf("%s: got %s, wanted nil", tc.name, reply.Summary())
+			}
+			continue
+		}
+		if reply == nil {
+			t.Fatalf("%s: got nil, wanted DHCPOFFER", tc.name)
+		}
+		if !reply.GatewayIPAddr.Equal(m.GatewayIPAddr) {
+			t.Errorf("%s: got giaddr %s, wanted %s", tc.name, reply.GatewayIPAddr, m.GatewayIPAddr)
+		}
+		if got, want := reply.Options.Get(dhcpv4.OptionRelayAgentInformation), m.Options.Get(dhcpv4.OptionRelayAgentInformation); string(got) != string(want) {
+			t.Errorf("%s: got relay agent information %v, wanted %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestToBytes(t *testing.T) {
+	hwAddr, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	reply, err := dhcpv4.New(
+		dhcpv4.WithHwAddr(hwAddr),
+		dhcpv4.WithMessageType(dhcpv4.MessageTypeOffer),
+		dhcpv4.WithOption(dhcpv4.OptGeneric(dhcpv4.GenericOptionCode(252), []byte("http://192.168.0.8/wpad.dat"))),
+		dhcpv4.WithOption(dhcpv4.OptRelayAgentInfo(
+			dhcpv4.OptGeneric(dhcpv4.AgentCircuitIDSubOption, []byte("eth0")),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	rai := reply.Options.Get(dhcpv4.OptionRelayAgentInformation)
+
+	b := toBytes(reply)
+
+	// Walk through the options to find the one before the end option
+	var last uint8
+	for i := 240; i < len(b) && b[i] != dhcpv4.OptionEnd.Code(); i += 2 + int(b[i+1]) {
+		last = b[i]
+	}
+	if last != dhcpv4.OptionRelayAgentInformation.Code() {
+		t.Errorf("got option %d as the last one, wanted 82", last)
+	}
+
+	parsed, err := dhcpv4.FromBytes(b)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if got := parsed.Options.Get(dhcpv4.OptionRelayAgentInformation); string(got) != string(rai) {
+		t.Errorf("got relay agent information %v, wanted %v", got, rai)
+	}
+	if got := parsed.Options.Get(dhcpv4.GenericOptionCode(252)); string(got) != "http://192.168.0.8/wpad.dat" {
+		t.Errorf("got option 252 %q, wanted %q", got, "http://192.168.0.8/wpad.dat")
+	}
+
+	// The reply itself is left intact
+	if got := reply.Options.Get(dhcpv4.OptionRelayAgentInformation); string(got) != string(rai) {
+		t.Errorf("got relay agent information %v after serialization, wanted %v", got, rai)
+	}
+}
@@ -0,0 +1,171 @@
+package dhcp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/insomniacslk/dhcp/dhcpv4"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+// managedOptions are the options set by the server itself, which custom
+// options must not override.
+var managedOptions = map[uint8]bool{
+	dhcpv4.OptionSubnetMask.Code():           true,
+	dhcpv4.OptionRouter.Code():               true,
+	dhcpv4.OptionHostName.Code():             true,
+	dhcpv4.OptionInterfaceMTU.Code():         true,
+	dhcpv4.OptionDomainNameServer.Code():     true,
+	dhcpv4.OptionDomainName.Code():           true,
+	dhcpv4.OptionNTPServers.Code():           true,
+	dhcpv4.OptionIPAddressLeaseTime.Code():   true,
+	dhcpv4.OptionDHCPMessageType.Code():      true,
+	dhcpv4.OptionServerIdentifier.Code():     true,
+	dhcpv4.OptionMessage.Code():              true,
+	dhcpv4.OptionTFTPServerName.Code():       true,
+	dhcpv4.OptionBootfileName.Code():         true,
+	dhcpv4.OptionDNSDomainSearchList.Code():  true,
+	dhcpv4.OptionClasslessStaticRoute.Code(): true,
+	OptionMSClasslessStaticRoute.Code():      true,
+}
+
+// legacyOptions are the managed options which custom options could set
+// before the server managed them. Custom options still setting them take
+// precedence over the server, so that they keep working after an upgrade.
+var legacyOptions = map[uint8]bool{
+	dhcpv4.OptionHostName.Code():     true,
+	dhcpv4.OptionInterfaceMTU.Code(): true,
+}
+
+func isLegacyOption(code int) bool {
+	return code > 0 && code < 255 && legacyOptions[uint8(code)]
+}
+
+// IsManagedOption tells whether the option with code is set by the server.
+func IsManagedOption(code int) bool {
+	return code > 0 && code < 255 && managedOptions[uint8(code)]
+}
+
+// ParseCustomOption encodes the value of the custom option according to its
+// type.
+func ParseCustomOption(option networkv1.DHCPOption) (dhcpv4.Option, error) {
+	if option.Code < 1 || option.Code > 254 {
+		return dhcpv4.Option{}, fmt.Errorf("custom option code %d is out of range", option.Code)
+	}
+
+	var value dhcpv4.OptionValue
+
+	switch option.Type {
+	case networkv1.DHCPOptionTypeIP:
+		ip := net.ParseIP(option.Value).To4()
+		if ip == nil {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid ipv4 address", option.Code, option.Value)
+		}
+		value = dhcpv4.IP(ip)
+	case networkv1.DHCPOptionTypeIPList:
+		var ips dhcpv4.IPs
+		for _, s := range strings.Split(option.Value, ",") {
+			ip := net.ParseIP(strings.TrimSpace(s)).To4()
+			if ip == nil {
+				return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid ipv4 address list", option.Code, option.Value)
+			}
+			ips = append(ips, ip)
+		}
+		value = ips
+	case networkv1.DHCPOptionTypeString:
+		value = dhcpv4.String(option.Value)
+	case networkv1.DHCPOptionTypeUint8:
+		u, err := strconv.ParseUint(option.Value, 10, 8)
+		if err != nil {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid uint8", option.Code, option.Value)
+		}
+		value = dhcpv4.OptionGeneric{Data: []byte{uint8(u)}}
+	case networkv1.DHCPOptionTypeUint16:
+		u, err := strconv.ParseUint(option.Value, 10, 16)
+		if err != nil {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid uint16", option.Code, option.Value)
+		}
+		value = dhcpv4.Uint16(u)
+	case networkv1.DHCPOptionTypeUint32:
+		u, err := strconv.ParseUint(option.Value, 10, 32)
+		if err != nil {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid uint32", option.Code, option.Value)
+		}
+		value = dhcpv4.OptionGeneric{Data: []byte{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}}
+	case networkv1.DHCPOptionTypeBool:
+		b, err := strconv.ParseBool(option.Value)
+		if err != nil {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid bool", option.Code, option.Value)
+		}
+		if b {
+			value = dhcpv4.OptionGeneric{Data: []byte{1}}
+		} else {
+			value = dhcpv4.OptionGeneric{Data: []byte{0}}
+		}
+	case networkv1.DHCPOptionTypeHex:
+		data, err := hex.DecodeString(strings.ReplaceAll(option.Value, ":", ""))
+		if err != nil || len(data) == 0 {
+			return dhcpv4.Option{}, fmt.Errorf("custom option %d value %s is not a valid hex string", option.Code, option.Value)
+		}
+		value = dhcpv4.OptionGeneric{Data: data}
+	default:
+		return dhcpv4.Option{}, fmt.Errorf("custom option %d type %s is unknown", option.Code, option.Type)
+	}
+
+	return dhcpv4.OptGeneric(dhcpv4.GenericOptionCode(option.Code), value.ToBytes()), nil
+}
+
+// CheckCustomOptions checks whether each of the custom options:
+//   - has a known type and a value of that type
+//   - has a code NOT managed by the DHCP server, unless it's a legacy option
+//     left as it was in oldOptions
+//   - has a code NOT used by other custom options
+func CheckCustomOptions(options, oldOptions []networkv1.DHCPOption) error {
+	codes := make(map[int]bool, len(options))
+	for _, option := range options {
+		if _, err := ParseCustomOption(option); err != nil {
+			return err
+		}
+
+		if IsManagedOption(option.Code) && !(isLegacyOption(option.Code) && containsOption(oldOptions, option)) {
+			return fmt.Errorf("custom option %d is managed by the server", option.Code)
+		}
+
+		if codes[option.Code] {
+			return fmt.Errorf("custom option %d is duplicated", option.Code)
+		}
+		codes[option.Code] = true
+	}
+
+	return nil
+}
+
+func containsOption(options []networkv1.DHCPOption, option networkv1.DHCPOption) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCustomOptions encodes the custom options and rejects those clashing
+// with the options set by the server, but for the legacy options.
+func parseCustomOptions(options []networkv1.DHCPOption) ([]dhcpv4.Option, error) {
+	var opts []dhcpv4.Option
+	for _, option := range options {
+		opt, err := ParseCustomOption(option)
+		if err != nil {
+			return nil, err
+		}
+		if IsManagedOption(option.Code) && !isLegacyOption(option.Code) {
+			return nil, fmt.Errorf("custom option %d is managed by the server", option.Code)
+		}
+		opts = append(opts, opt)
+	}
+	return opts, nil
+}
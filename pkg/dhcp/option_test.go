@@ -0,0 +1,134 @@
+package dhcp
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+func TestParseCustomOption(t *testing.T) {
+	testOptions := []struct {
+		name   string
+		option networkv1.DHCPOption
+		data   []byte
+		want   error
+	}{
+		{
+			name:   "ip",
+			option: networkv1.DHCPOption{Code: 150, Type: networkv1.DHCPOptionTypeIP, Value: "192.168.0.5"},
+			data:   []byte{192, 168, 0, 5},
+		},
+		{
+			name:   "ip-invalid",
+			option: networkv1.DHCPOption{Code: 150, Type: networkv1.DHCPOptionTypeIP, Value: "fd00::5"},
+			want:   fmt.Errorf("custom option 150 value fd00::5 is not a valid ipv4 address"),
+		},
+		{
+			name:   "ip-list",
+			option: networkv1.DHCPOption{Code: 44, Type: networkv1.DHCPOptionTypeIPList, Value: "192.168.0.5, 192.168.0.6"},
+			data:   []byte{192, 168, 0, 5, 192, 168, 0, 6},
+		},
+		{
+			name:   "ip-list-invalid",
+			option: networkv1.DHCPOption{Code: 44, Type: networkv1.DHCPOptionTypeIPList, Value: "192.168.0.5,"},
+			want:   fmt.Errorf("custom option 44 value 192.168.0.5, is not a valid ipv4 address list"),
+		},
+		{
+			name:   "string",
+			option: networkv1.DHCPOption{Code: 252, Type: networkv1.DHCPOptionTypeString, Value: "wpad"},
+			data:   []byte("wpad"),
+		},
+		{
+			name:   "uint8",
+			option: networkv1.DHCPOption{Code: 23, Type: networkv1.DHCPOptionTypeUint8, Value: "64"},
+			data:   []byte{64},
+		},
+		{
+			name:   "uint8-overflow",
+			option: networkv1.DHCPOption{Code: 23, Type: networkv1.DHCPOptionTypeUint8, Value: "256"},
+			want:   fmt.Errorf("custom option 23 value 256 is not a valid uint8"),
+		},
+		{
+			name:   "uint16",
+			option: networkv1.DHCPOption{Code: 22, Type: networkv1.DHCPOptionTypeUint16, Value: "1500"},
+			data:   []byte{0x05, 0xdc},
+		},
+		{
+			name:   "uint32",
+			option: networkv1.DHCPOption{Code: 2, Type: networkv1.DHCPOptionTypeUint32, Value: "3600"},
+			data:   []byte{0, 0, 0x0e, 0x10},
+		},
+		{
+			name:   "bool",
+			option: networkv1.DHCPOption{Code: 19, Type: networkv1.DHCPOptionTypeBool, Value: "true"},
+			data:   []byte{1},
+		},
+		{
+			name:   "bool-invalid",
+			option: networkv1.DHCPOption{Code: 19, Type: networkv1.DHCPOptionTypeBool, Value: "yes"},
+			want:   fmt.Errorf("custom option 19 value yes is not a valid bool"),
+		},
+		{
+			name:   "hex",
+			option: networkv1.DHCPOption{Code: 43, Type: networkv1.DHCPOptionTypeHex, Value: "01:04:c0:a8:00:05"},
+			data:   []byte{0x01, 0x04, 0xc0, 0xa8, 0x00, 0x05},
+		},
+		{
+			name:   "hex-invalid",
+			option: networkv1.DHCPOption{Code: 43, Type: networkv1.DHCPOptionTypeHex, Value: "0x01"},
+			want:   fmt.Errorf("custom option 43 value 0x01 is not a valid hex string"),
+		},
+		{
+			name:   "unknown-type",
+			option: networkv1.DHCPOption{Code: 150, Type: "ipv6", Value: "fd00::5"},
+			want:   fmt.Errorf("custom option 150 type ipv6 is unknown"),
+		},
+		{
+			name:   "code-out-of-range",
+			option: networkv1.DHCPOption{Code: 255, Type: networkv1.DHCPOptionTypeUint8, Value: "1"},
+			want:   fmt.Errorf("custom option code 255 is out of range"),
+		},
+	}
+
+	// ParseCustomOption function tests
+	for _, tc := range testOptions {
+		opt, got := ParseCustomOption(tc.option)
+		if got != nil && tc.want != nil {
+			if got.Error() != tc.want.Error() {
+				t.Errorf("%s: got %q, wanted %q", tc.name, got, tc.want)
+			}
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, wanted %v", tc.name, got, tc.want)
+			continue
+		}
+		if opt.Code.Code() != uint8(tc.option.Code) {
+			t.Errorf("%s: got code %d, wanted %d", tc.name, opt.Code.Code(), tc.option.Code)
+		}
+		if !bytes.Equal(opt.Value.ToBytes(), tc.data) {
+			t.Errorf("%s: got data %v, wanted %v", tc.name, opt.Value.ToBytes(), tc.data)
+		}
+	}
+}
+
+func TestParseCustomOptions(t *testing.T) {
+	if _, err := parseCustomOptions([]networkv1.DHCPOption{
+		{Code: 3, Type: networkv1.DHCPOptionTypeIP, Value: "192.168.0.1"},
+	}); err == nil || err.Error() != "custom option 3 is managed by the server" {
+		t.Errorf("got %v, wanted custom option 3 is managed by the server", err)
+	}
+
+	// Legacy options set before the server managed them are kept
+	opts, err := parseCustomOptions([]networkv1.DHCPOption{
+		{Code: 26, Type: networkv1.DHCPOptionTypeUint16, Value: "9000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 || opts[0].Code.Code() != 26 {
+		t.Errorf("got %v, wanted custom option 26", opts)
+	}
+}
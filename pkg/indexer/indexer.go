@@ -1,11 +1,18 @@
 package indexer
 
 import (
+	"strings"
+
+	"github.com/rancher/wrangler/pkg/kv"
+
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 )
 
 const (
-	VmNetCfgByNetworkIndex = "network.harvesterhci.io/vmnetcfg-by-network"
+	VmNetCfgByNetworkIndex    = "network.harvesterhci.io/vmnetcfg-by-network"
+	VmNetCfgByMACAddressIndex = "network.harvesterhci.io/vmnetcfg-by-mac-address"
+	IPPoolByNetworkIndex      = "network.harvesterhci.io/ippool-by-network"
+	IPPoolByServedByIndex     = "network.harvesterhci.io/ippool-by-served-by"
 )
 
 func VmNetCfgByNetwork(obj *networkv1.VirtualMachineNetworkConfig) ([]string, error) {
@@ -16,3 +23,47 @@ func VmNetCfgByNetwork(obj *networkv1.VirtualMachineNetworkConfig) ([]string, er
 	}
 	return networkNames, nil
 }
+
+// VmNetCfgByMACAddress indexes vmnetcfgs by the MAC addresses of their network
+// configs, keyed by MACAddressKey, as MAC addresses are unique per network.
+func VmNetCfgByMACAddress(obj *networkv1.VirtualMachineNetworkConfig) ([]string, error) {
+	ncs := obj.Spec.NetworkConfigs
+	keys := make([]string, 0, len(ncs))
+	for _, nc := range ncs {
+		keys = append(keys, MACAddressKey(nc.NetworkName, nc.MACAddress))
+	}
+	return keys, nil
+}
+
+// MACAddressKey returns the key of the MAC address in the network for
+// VmNetCfgByMACAddressIndex.
+func MACAddressKey(networkName, macAddress string) string {
+	return networkName + "/" + strings.ToLower(macAddress)
+}
+
+// NamespacedNetworkName returns the network name in the form of
+// namespace/name. A network name without a namespace refers to the
+// NetworkAttachmentDefinition in the default namespace, the same as it does
+// when the NetworkAttachmentDefinition is looked up.
+func NamespacedNetworkName(networkName string) string {
+	namespace, name := kv.RSplit(networkName, "/")
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace + "/" + name
+}
+
+// IPPoolByNetwork indexes IPPools by the namespaced names of their networks, so
+// that "net-1" and "default/net-1" are the same network.
+func IPPoolByNetwork(obj *networkv1.IPPool) ([]string, error) {
+	return []string{NamespacedNetworkName(obj.Spec.NetworkName)}, nil
+}
+
+// IPPoolByServedBy indexes IPPools served through DHCP relay agents by the
+// IPPool serving them.
+func IPPoolByServedBy(obj *networkv1.IPPool) ([]string, error) {
+	if obj.Spec.ServedBy == "" {
+		return nil, nil
+	}
+	return []string{obj.Spec.ServedBy}, nil
+}
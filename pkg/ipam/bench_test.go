@@ -0,0 +1,133 @@
+package ipam
+
+import (
+	"net/netip"
+	"testing"
+)
+
+const (
+	benchNetwork = "default/net-bench"
+	benchCIDR    = "172.16.0.0/16"
+	benchStartIP = "172.16.0.1"
+	benchEndIP   = "172.16.255.254"
+)
+
+// mapSubnet is the former IPv4 IPAM backend, which expands every IP address
+// of the range into a map, kept for comparison.
+type mapSubnet struct {
+	ips map[string]bool
+}
+
+func newMapSubnet(start, end string) *mapSubnet {
+	startAddr := netip.MustParseAddr(start)
+	endAddr := netip.MustParseAddr(end)
+	ips := make(map[string]bool)
+	for ip := startAddr; endAddr.Compare(ip.Prev()) > 0; ip = ip.Next() {
+		ips[ip.String()] = false
+	}
+	return &mapSubnet{ips: ips}
+}
+
+func (s *mapSubnet) allocate() (string, bool) {
+	for ip, isAllocated := range s.ips {
+		if !isAllocated {
+			s.ips[ip] = true
+			return ip, true
+		}
+	}
+	return "", false
+}
+
+func (s *mapSubnet) getAvailable() int {
+	var available int
+	for _, isAllocated := range s.ips {
+		if !isAllocated {
+			available++
+		}
+	}
+	return available
+}
+
+// newBenchIPAllocator returns an IPAllocator with the /16 range all but the
+// last 256 IP addresses allocated.
+func newBenchIPAllocator(b *testing.B) *IPAllocator {
+	ti := New()
+	if err := ti.NewIPSubnet(benchNetwork, benchCIDR, benchStartIP, benchEndIP); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < ti.ipam[benchNetwork].Size()-256; i++ {
+		if _, err := ti.AllocateIP(benchNetwork, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return ti
+}
+
+func newBenchMapSubnet() *mapSubnet {
+	s := newMapSubnet(benchStartIP, benchEndIP)
+	for i := 0; i < len(s.ips)-256; i++ {
+		s.allocate()
+	}
+	return s
+}
+
+func BenchmarkNewIPSubnet(b *testing.B) {
+	b.Run("bitmap", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := New().NewIPSubnet(benchNetwork, benchCIDR, benchStartIP, benchEndIP); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newMapSubnet(benchStartIP, benchEndIP)
+		}
+	})
+}
+
+func BenchmarkAllocateIP(b *testing.B) {
+	b.Run("bitmap", func(b *testing.B) {
+		ti := newBenchIPAllocator(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ip, err := ti.AllocateIP(benchNetwork, "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := ti.DeallocateIP(benchNetwork, ip); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		s := newBenchMapSubnet()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ip, ok := s.allocate()
+			if !ok {
+				b.Fatal("no more ip addresses left")
+			}
+			s.ips[ip] = false
+		}
+	})
+}
+
+func BenchmarkGetAvailable(b *testing.B) {
+	b.Run("bitmap", func(b *testing.B) {
+		ti := newBenchIPAllocator(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := ti.GetAvailable(benchNetwork); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		s := newBenchMapSubnet()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.getAvailable()
+		}
+	})
+}
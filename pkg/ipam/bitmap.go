@@ -0,0 +1,56 @@
+package ipam
+
+import "math/bits"
+
+// bitmap is a fixed-size set of indices, one bit per index.
+type bitmap []uint64
+
+func newBitmap(size int) bitmap {
+	return make(bitmap, (size+63)/64)
+}
+
+func (b bitmap) has(i int) bool {
+	return b[i/64]&(1<<(uint(i)%64)) != 0
+}
+
+func (b bitmap) set(i int) {
+	b[i/64] |= 1 << (uint(i) % 64)
+}
+
+func (b bitmap) clear(i int) {
+	b[i/64] &^= 1 << (uint(i) % 64)
+}
+
+// nextUnset returns the first index from i up to but not including to that is
+// set in none of the bitmaps, or false if there's none. It skips over 64
+// indices at a time where they're all set.
+func nextUnset(i, to int, bitmaps ...bitmap) (int, bool) {
+	for i < to {
+		w := i / 64
+		var word uint64
+		for _, b := range bitmaps {
+			word |= b[w]
+		}
+		// Ignore the indices before i
+		word |= (1 << (uint(i) % 64)) - 1
+		if word != ^uint64(0) {
+			j := w*64 + bits.TrailingZeros64(^word)
+			if j < to {
+				return j, true
+			}
+			return 0, false
+		}
+		i = (w + 1) * 64
+	}
+	return 0, false
+}
+
+// forEach calls fn with every index set, in ascending order.
+func (b bitmap) forEach(fn func(i int)) {
+	for w, word := range b {
+		for word != 0 {
+			fn(w*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
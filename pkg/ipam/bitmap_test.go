@@ -0,0 +1,33 @@
+package ipam
+
+import "testing"
+
+func TestNextUnset(t *testing.T) {
+	allocated := newBitmap(200)
+	revoked := newBitmap(200)
+	for i := 0; i < 130; i++ {
+		allocated.set(i)
+	}
+	revoked.set(130)
+	allocated.clear(70)
+
+	tests := []struct {
+		from   int
+		to     int
+		want   int
+		wantOK bool
+	}{
+		{from: 0, to: 200, want: 70, wantOK: true},
+		{from: 71, to: 200, want: 131, wantOK: true},
+		{from: 71, to: 131, wantOK: false},
+		{from: 199, to: 200, want: 199, wantOK: true},
+		{from: 200, to: 200, wantOK: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := nextUnset(tc.from, tc.to, allocated, revoked)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("nextUnset(%d, %d) = %d, %t, wanted %d, %t", tc.from, tc.to, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package ipam
 
+import "time"
+
 type IPAllocatorBuilder struct {
 	ipAllocator *IPAllocator
 }
@@ -29,6 +31,49 @@ func (b *IPAllocatorBuilder) Allocate(name string, ipAddressList ...string) *IPA
 	return b
 }
 
+func (b *IPAllocatorBuilder) Quarantine(name string, ipAddressList ...string) *IPAllocatorBuilder {
+	for _, ip := range ipAddressList {
+		_ = b.ipAllocator.QuarantineIP(name, ip)
+	}
+	return b
+}
+
+func (b *IPAllocatorBuilder) QuarantineUntil(name string, until time.Time, ipAddressList ...string) *IPAllocatorBuilder {
+	for _, ip := range ipAddressList {
+		_ = b.ipAllocator.QuarantineIPUntil(name, ip, until)
+	}
+	return b
+}
+
+func (b *IPAllocatorBuilder) Reserve(name, ipAddress, owner string) *IPAllocatorBuilder {
+	_ = b.ipAllocator.ReserveIP(name, ipAddress, owner)
+	return b
+}
+
+func (b *IPAllocatorBuilder) LastAllocated(name, ipAddress string) *IPAllocatorBuilder {
+	_ = b.ipAllocator.SetLastAllocated(name, ipAddress)
+	return b
+}
+
+func (b *IPAllocatorBuilder) IPv6Subnet(name, prefix, start, end string) *IPAllocatorBuilder {
+	_ = b.ipAllocator.NewIPv6Subnet(name, prefix, start, end)
+	return b
+}
+
+func (b *IPAllocatorBuilder) AllocateIPv6(name string, ipAddressList ...string) *IPAllocatorBuilder {
+	for _, ip := range ipAddressList {
+		_, _ = b.ipAllocator.AllocateIPv6(name, ip)
+	}
+	return b
+}
+
+func (b *IPAllocatorBuilder) QuarantineIPv6(name string, ipAddressList ...string) *IPAllocatorBuilder {
+	for _, ip := range ipAddressList {
+		_ = b.ipAllocator.QuarantineIPv6(name, ip)
+	}
+	return b
+}
+
 func (b *IPAllocatorBuilder) Build() *IPAllocator {
 	return b.ipAllocator
 }
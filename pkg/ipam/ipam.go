@@ -1,25 +1,152 @@
 package ipam
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"net/netip"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// IPRange is the range of IP addresses from Start to End inclusive.
+type IPRange struct {
+	Start string
+	End   string
+}
+
+// ipRange is an IP address range of an IPSubnet. Its IP addresses are indexed
+// from offset on.
+type ipRange struct {
+	// base is the start IP address as a number
+	base   uint32
+	offset int
+	size   int
+}
+
+// IPSubnet keeps track of the IP addresses of its ranges in bitmaps indexed
+// by their offsets from the start IP address of the lowest range, skipping
+// the gaps between the ranges, so that even large ranges take little memory
+// and the counters are kept up to date rather than counted.
 type IPSubnet struct {
 	ipNet     *net.IPNet
-	start     net.IP
-	end       net.IP
 	broadcast net.IP
-	ips       map[string]bool
+	// ranges are sorted by their start IP addresses
+	ranges []ipRange
+	size   int
+	// allocated has the bits of the allocated IP addresses set
+	allocated bitmap
+	// revoked has the bits of the IP addresses taken out of the range set,
+	// e.g., the excluded ones
+	revoked      bitmap
+	used         int
+	revokedCount int
+	// quarantined holds IP addresses that must not be handed out until the
+	// time they're mapped to, e.g., those just released. The ones mapped to
+	// the zero time, e.g., those declined by DHCP clients due to address
+	// conflicts, are quarantined until revoked.
+	quarantined map[int]time.Time
+	// reserved holds IP addresses that must not be handed out but to the
+	// owners they're mapped to, e.g., the MAC addresses of the network
+	// interfaces they're reserved for
+	reserved map[int]string
+	// strategy picks the IP address to be allocated when none is designated
+	strategy AllocationStrategy
+	// last is the index of the IP address most recently picked by the
+	// strategy, or -1 if there's none
+	last int
+}
+
+// index returns the index of ipAddress in the range, or false if it's not in
+// the range.
+func (s *IPSubnet) index(ipAddress string) (int, bool) {
+	ip, err := netip.ParseAddr(ipAddress)
+	if err != nil || !ip.Unmap().Is4() {
+		return 0, false
+	}
+	v := ipv4ToUint32(ip.Unmap())
+	for _, r := range s.ranges {
+		if v >= r.base && int(v-r.base) < r.size {
+			return r.offset + int(v-r.base), true
+		}
+	}
+	return 0, false
+}
+
+// lookup returns the index of ipAddress in the range, or false if it's not in
+// the range or revoked.
+func (s *IPSubnet) lookup(ipAddress string) (int, bool) {
+	i, ok := s.index(ipAddress)
+	if !ok || s.revoked.has(i) {
+		return 0, false
+	}
+	return i, true
+}
+
+func (s *IPSubnet) addr(i int) string {
+	for _, r := range s.ranges {
+		if i < r.offset+r.size {
+			return uint32ToIPv4(r.base + uint32(i-r.offset)).String()
+		}
+	}
+	return ""
+}
+
+func (s *IPSubnet) isQuarantined(i int) bool {
+	until, exists := s.quarantined[i]
+	return exists && (until.IsZero() || time.Now().Before(until))
+}
+
+func (s *IPSubnet) isReserved(i int) bool {
+	_, exists := s.reserved[i]
+	return exists
+}
+
+// Size returns the number of IP addresses of all the ranges.
+func (s *IPSubnet) Size() int {
+	return s.size
+}
+
+// NextFree returns the index of the first IP address from the i-th one on
+// that is neither allocated, revoked, quarantined, nor reserved, wrapping
+// around at the end, or false if there's none.
+func (s *IPSubnet) NextFree(i int) (int, bool) {
+	for _, r := range [][2]int{{i, s.size}, {0, i}} {
+		from, to := r[0], r[1]
+		for from < to {
+			j, ok := nextUnset(from, to, s.allocated, s.revoked)
+			if !ok {
+				break
+			}
+			if !s.isQuarantined(j) && !s.isReserved(j) {
+				return j, true
+			}
+			from = j + 1
+		}
+	}
+	return 0, false
+}
+
+func ipv4ToUint32(ip netip.Addr) uint32 {
+	b := ip.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func uint32ToIPv4(v uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return netip.AddrFrom4(b)
 }
 
 type IPAllocator struct {
-	ipam  map[string]IPSubnet
+	ipam  map[string]*IPSubnet
+	ipam6 map[string]*IPv6Subnet
 	mutex sync.RWMutex
 }
 
@@ -29,15 +156,98 @@ func New() *IPAllocator {
 
 func NewIPAllocator() *IPAllocator {
 	return &IPAllocator{
-		ipam: make(map[string]IPSubnet),
+		ipam:  make(map[string]*IPSubnet),
+		ipam6: make(map[string]*IPv6Subnet),
+	}
+}
+
+// NewIPSubnet initializes the IPAM of the network with the IP addresses from
+// start to end, and those of the other ranges if any. None of the ranges may
+// overlap.
+func (a *IPAllocator) NewIPSubnet(name, cidr, start, end string, ranges ...IPRange) error {
+	ipSubnet, err := newIPSubnet(cidr, start, end, ranges...)
+	if err != nil {
+		return err
+	}
+
+	a.ipam[name] = ipSubnet
+
+	return nil
+}
+
+// UpdateIPSubnet changes the ranges of the network on the fly, e.g., widens
+// them, carrying the allocated, revoked and quarantined IP addresses over.
+// It fails without changing anything if an allocated IP address is not within
+// the new ranges. The revoked and quarantined ones not within them are
+// forgotten.
+func (a *IPAllocator) UpdateIPSubnet(name, start, end string, ranges ...IPRange) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	newSubnet, err := newIPSubnet(subnet.ipNet.String(), start, end, ranges...)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(newSubnet.ranges, subnet.ranges) {
+		return nil
+	}
+
+	var orphaned []string
+	subnet.allocated.forEach(func(i int) {
+		ipAddress := subnet.addr(i)
+		j, ok := newSubnet.index(ipAddress)
+		if !ok {
+			orphaned = append(orphaned, ipAddress)
+			return
+		}
+		newSubnet.allocate(j)
+	})
+	if len(orphaned) > 0 {
+		return fmt.Errorf("allocated ip %s is not within the new ranges of network %s ipam", strings.Join(orphaned, ","), name)
+	}
+
+	subnet.revoked.forEach(func(i int) {
+		if j, ok := newSubnet.index(subnet.addr(i)); ok {
+			newSubnet.revoked.set(j)
+			newSubnet.revokedCount++
+		}
+	})
+
+	for i, until := range subnet.quarantined {
+		if j, ok := newSubnet.index(subnet.addr(i)); ok {
+			newSubnet.quarantined[j] = until
+		}
+	}
+
+	for i, owner := range subnet.reserved {
+		if j, ok := newSubnet.index(subnet.addr(i)); ok {
+			newSubnet.reserved[j] = owner
+		}
 	}
+
+	newSubnet.strategy = subnet.strategy
+	if subnet.last >= 0 {
+		if j, ok := newSubnet.index(subnet.addr(subnet.last)); ok {
+			newSubnet.last = j
+		}
+	}
+
+	a.ipam[name] = newSubnet
+
+	return nil
 }
 
-func (a *IPAllocator) NewIPSubnet(name, cidr, start, end string) error {
+func newIPSubnet(cidr, start, end string, ranges ...IPRange) (*IPSubnet, error) {
 	// Calculate the broadcast IP address
 	ip, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	ipv4 := ip.To4()
 	mask := ipNet.Mask
@@ -46,49 +256,78 @@ func (a *IPAllocator) NewIPSubnet(name, cidr, start, end string) error {
 		broadcast[i] = octet | ^mask[i]
 	}
 
+	ipSubnet := &IPSubnet{
+		ipNet:       ipNet,
+		broadcast:   broadcast,
+		quarantined: make(map[int]time.Time),
+		reserved:    make(map[int]string),
+		strategy:    lowestFreeStrategy{},
+		last:        -1,
+	}
+
+	for _, ipr := range append([]IPRange{{Start: start, End: end}}, ranges...) {
+		r, err := newIPRange(ipNet, broadcast, cidr, ipr.Start, ipr.End)
+		if err != nil {
+			return nil, err
+		}
+		ipSubnet.ranges = append(ipSubnet.ranges, r)
+	}
+
+	sort.Slice(ipSubnet.ranges, func(i, j int) bool {
+		return ipSubnet.ranges[i].base < ipSubnet.ranges[j].base
+	})
+	for i := range ipSubnet.ranges {
+		r := &ipSubnet.ranges[i]
+		if i > 0 {
+			prev := ipSubnet.ranges[i-1]
+			if uint64(prev.base)+uint64(prev.size) > uint64(r.base) {
+				return nil, fmt.Errorf("ip range %s-%s overlaps with ip range %s-%s",
+					uint32ToIPv4(r.base), uint32ToIPv4(r.base+uint32(r.size-1)),
+					uint32ToIPv4(prev.base), uint32ToIPv4(prev.base+uint32(prev.size-1)))
+			}
+		}
+		r.offset = ipSubnet.size
+		ipSubnet.size += r.size
+	}
+
+	ipSubnet.allocated = newBitmap(ipSubnet.size)
+	ipSubnet.revoked = newBitmap(ipSubnet.size)
+
+	return ipSubnet, nil
+}
+
+func newIPRange(ipNet *net.IPNet, broadcast net.IP, cidr, start, end string) (ipRange, error) {
 	startIP := net.ParseIP(start)
 	if !ipNet.Contains(startIP) {
-		return fmt.Errorf("start ip address %s is not within subnet %s range", start, cidr)
+		return ipRange{}, fmt.Errorf("start ip address %s is not within subnet %s range", start, cidr)
 	}
 	endIP := net.ParseIP(end)
 	if !ipNet.Contains(endIP) {
-		return fmt.Errorf("end ip address %s is not within subnet %s range", end, cidr)
+		return ipRange{}, fmt.Errorf("end ip address %s is not within subnet %s range", end, cidr)
 	}
 
 	startAddr, ok := netip.AddrFromSlice(startIP)
 	if !ok {
-		return fmt.Errorf("cannot convert ip address %s", start)
+		return ipRange{}, fmt.Errorf("cannot convert ip address %s", start)
 	}
 	endAddr, ok := netip.AddrFromSlice(endIP)
 	if !ok {
-		return fmt.Errorf("cannot convert ip address %s", end)
+		return ipRange{}, fmt.Errorf("cannot convert ip address %s", end)
 	}
 
 	if startAddr.Compare(endAddr) > 0 {
-		return fmt.Errorf("end ip address %s is less than start ip address %s", end, start)
+		return ipRange{}, fmt.Errorf("end ip address %s is less than start ip address %s", end, start)
 	}
 
 	if endIP.Equal(broadcast) {
-		return fmt.Errorf("end ip address %s equals broadcast ip address %s", end, broadcast.String())
-	}
-
-	// Expand the map of allocated IP addresses ranging from the start to end IP address
-	ips := make(map[string]bool)
-	for ip := startAddr; endAddr.Compare(ip.Prev()) > 0; ip = ip.Next() {
-		ips[ip.Unmap().String()] = false
-	}
-
-	ipSubnet := IPSubnet{
-		ipNet:     ipNet,
-		start:     startIP.To4(),
-		end:       endIP.To4(),
-		broadcast: broadcast,
-		ips:       ips,
+		return ipRange{}, fmt.Errorf("end ip address %s equals broadcast ip address %s", end, broadcast.String())
 	}
 
-	a.ipam[name] = ipSubnet
-
-	return nil
+	base := ipv4ToUint32(startAddr.Unmap())
+	return ipRange{
+		base: base,
+		size: int(ipv4ToUint32(endAddr.Unmap())-base) + 1,
+	}, nil
 }
 
 func (a *IPAllocator) DeleteIPSubnet(name string) {
@@ -100,12 +339,79 @@ func (a *IPAllocator) IsNetworkInitialized(name string) bool {
 	return exists
 }
 
+// SetAllocationStrategy changes the allocation strategy of the network, which
+// is LowestFree initially.
+func (a *IPAllocator) SetAllocationStrategy(name string, strategy AllocationStrategy) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	subnet.strategy = strategy
+
+	return nil
+}
+
+// GetLastAllocated returns the IP address most recently allocated by the
+// allocation strategy of the network, or the empty string if there's none.
+func (a *IPAllocator) GetLastAllocated(name string) (string, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return "", fmt.Errorf("network %s does not exist", name)
+	}
+
+	if subnet.last < 0 {
+		return "", nil
+	}
+	return subnet.addr(subnet.last), nil
+}
+
+// SetLastAllocated restores the IP address most recently allocated by the
+// allocation strategy of the network, e.g., after a restart, for the
+// round-robin strategy to pick up where it left off. It's forgotten if it's
+// no longer in the range.
+func (a *IPAllocator) SetLastAllocated(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+	if _, err := netip.ParseAddr(ipAddress); err != nil {
+		return err
+	}
+
+	subnet.last = -1
+	if i, ok := subnet.index(ipAddress); ok {
+		subnet.last = i
+	}
+
+	return nil
+}
+
 func (a *IPAllocator) AllocateIP(name string, ipAddress string) (string, error) {
+	return a.AllocateIPWithMAC(name, ipAddress, "")
+}
+
+// AllocateIPWithMAC allocates ipAddress, or the IP address picked by the
+// allocation strategy for macAddress if ipAddress is unspecified.
+func (a *IPAllocator) AllocateIPWithMAC(name, ipAddress, macAddress string) (string, error) {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
+	subnet, exists := a.ipam[name]
+	if !exists {
 		return "", fmt.Errorf("network %s does not exist", name)
 	}
 	if ipAddress == "" {
@@ -115,41 +421,52 @@ func (a *IPAllocator) AllocateIP(name string, ipAddress string) (string, error)
 	designatedIP := net.ParseIP(ipAddress)
 
 	if !designatedIP.IsUnspecified() {
-		ok := a.ipam[name].ipNet.Contains(designatedIP)
+		ok := subnet.ipNet.Contains(designatedIP)
 		if !ok {
-			subnetMask, _ := a.ipam[name].ipNet.Mask.Size()
+			subnetMask, _ := subnet.ipNet.Mask.Size()
 			return net.IPv4zero.String(), fmt.Errorf(
 				"designated ip %s is not in subnet %s/%d",
 				designatedIP.String(),
-				a.ipam[name].ipNet.IP.String(),
+				subnet.ipNet.IP.String(),
 				subnetMask,
 			)
 		}
 
-		if a.ipam[name].broadcast.Equal(designatedIP) {
-			return net.IPv4zero.String(), fmt.Errorf("designated ip %s equals broadcast ip address %s", designatedIP.String(), a.ipam[name].broadcast.String())
+		if subnet.broadcast.Equal(designatedIP) {
+			return net.IPv4zero.String(), fmt.Errorf("designated ip %s equals broadcast ip address %s", designatedIP.String(), subnet.broadcast.String())
 		}
-	}
 
-	for ip, isAllocated := range a.ipam[name].ips {
-		if !designatedIP.IsUnspecified() {
-			if ip == designatedIP.String() {
-				if isAllocated {
-					return net.IPv4zero.String(), fmt.Errorf("designated ip %s is already allocated", designatedIP.String())
-				} else {
-					a.ipam[name].ips[ip] = true
-					return ip, nil
-				}
-			}
-		} else {
-			if !isAllocated {
-				a.ipam[name].ips[ip] = true
-				return ip, nil
-			}
+		i, ok := subnet.lookup(designatedIP.String())
+		if !ok {
+			return net.IPv4zero.String(), fmt.Errorf("no more ip addresses left in network %s ipam", name)
+		}
+		if subnet.allocated.has(i) {
+			return net.IPv4zero.String(), fmt.Errorf("designated ip %s is already allocated", designatedIP.String())
 		}
+		if subnet.isQuarantined(i) {
+			return net.IPv4zero.String(), fmt.Errorf("designated ip %s is quarantined", designatedIP.String())
+		}
+		if owner, reserved := subnet.reserved[i]; reserved && owner != strings.ToLower(macAddress) {
+			return net.IPv4zero.String(), fmt.Errorf("designated ip %s is reserved for %s", designatedIP.String(), owner)
+		}
+		subnet.allocate(i)
+		return designatedIP.String(), nil
 	}
 
-	return net.IPv4zero.String(), fmt.Errorf("no more ip addresses left in network %s ipam", name)
+	i, ok := subnet.strategy.Pick(subnet, subnet.last, macAddress)
+	if !ok {
+		return net.IPv4zero.String(), fmt.Errorf("no more ip addresses left in network %s ipam", name)
+	}
+	subnet.allocate(i)
+	subnet.last = i
+
+	return subnet.addr(i), nil
+}
+
+func (s *IPSubnet) allocate(i int) {
+	s.allocated.set(i)
+	s.used++
+	delete(s.quarantined, i)
 }
 
 func (a *IPAllocator) DeallocateIP(name, ipAddress string) error {
@@ -157,97 +474,307 @@ func (a *IPAllocator) DeallocateIP(name, ipAddress string) error {
 	defer a.mutex.Unlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
+	subnet, exists := a.ipam[name]
+	if !exists {
 		return fmt.Errorf("network %s does not exist", name)
 	}
 	if ipAddress == "" {
 		return fmt.Errorf("designated ip is empty")
 	}
 
-	isAllocated, exists := a.ipam[name].ips[ipAddress]
-	if !exists {
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
 		return fmt.Errorf("to-be-deallocated ip %s was not found in network %s ipam", ipAddress, name)
 	}
-	if !isAllocated {
+	if !subnet.allocated.has(i) {
 		return fmt.Errorf("to-be-deallocated ip %s was not allocated", ipAddress)
 	}
 
-	a.ipam[name].ips[ipAddress] = false
+	subnet.allocated.clear(i)
+	subnet.used--
+
+	return nil
+}
+
+// QuarantineIP takes a deallocated IP address out of circulation. Quarantined
+// IP addresses are neither allocatable nor counted as available.
+func (a *IPAllocator) QuarantineIP(name, ipAddress string) error {
+	return a.QuarantineIPUntil(name, ipAddress, time.Time{})
+}
+
+// QuarantineIPUntil takes a deallocated IP address out of circulation until
+// the given time, or until revoked if it's the zero time.
+func (a *IPAllocator) QuarantineIPUntil(name, ipAddress string, until time.Time) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+	if ipAddress == "" {
+		return fmt.Errorf("designated ip is empty")
+	}
+
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
+		return fmt.Errorf("to-be-quarantined ip %s was not found in network %s ipam", ipAddress, name)
+	}
+	if subnet.allocated.has(i) {
+		return fmt.Errorf("to-be-quarantined ip %s is still allocated", ipAddress)
+	}
+
+	subnet.quarantined[i] = until
 
 	return nil
 }
 
+func (a *IPAllocator) IsQuarantined(name, ipAddress string) (bool, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return false, fmt.Errorf("network %s does not exist", name)
+	}
+
+	i, ok := subnet.lookup(ipAddress)
+	return ok && subnet.isQuarantined(i), nil
+}
+
+// RevokeIP takes an IP address out of the range for good, e.g., the server IP
+// address. IP addresses not in the range are ignored.
 func (a *IPAllocator) RevokeIP(name, ipAddress string) error {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
+	subnet, exists := a.ipam[name]
+	if !exists {
 		return fmt.Errorf("network %s does not exist", name)
 	}
 
-	delete(a.ipam[name].ips, ipAddress)
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
+		return nil
+	}
+	if subnet.allocated.has(i) {
+		subnet.allocated.clear(i)
+		subnet.used--
+	}
+	subnet.revoked.set(i)
+	subnet.revokedCount++
+	delete(subnet.quarantined, i)
+	delete(subnet.reserved, i)
 
 	return nil
 }
 
-func (a *IPAllocator) IsAllocated(name, ipAddress string) (bool, error) {
+// ReserveIP holds an IP address for the owner, e.g., the MAC address of a
+// network interface, so that it's allocated to nothing else. It may be
+// allocated already, e.g., to the owner.
+func (a *IPAllocator) ReserveIP(name, ipAddress, owner string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+	if owner == "" {
+		return fmt.Errorf("owner of ip %s is empty", ipAddress)
+	}
+
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
+		return fmt.Errorf("to-be-reserved ip %s was not found in network %s ipam", ipAddress, name)
+	}
+
+	subnet.reserved[i] = owner
+
+	return nil
+}
+
+// UnreserveIP releases the hold of the owner on an IP address. IP addresses
+// not reserved are ignored.
+func (a *IPAllocator) UnreserveIP(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	if i, ok := subnet.index(ipAddress); ok {
+		delete(subnet.reserved, i)
+	}
+
+	return nil
+}
+
+// ListReserved returns the reserved IP addresses of the network mapped to
+// their owners.
+func (a *IPAllocator) ListReserved(name string) (map[string]string, error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
-	var isAllocated bool
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return nil, fmt.Errorf("network %s does not exist", name)
+	}
+
+	ips := make(map[string]string, len(subnet.reserved))
+	for i, owner := range subnet.reserved {
+		ips[subnet.addr(i)] = owner
+	}
+
+	return ips, nil
+}
+
+// AllocateReservedIP allocates the IP address reserved for the owner, unless
+// it's quarantined, e.g., declined by a DHCP client.
+func (a *IPAllocator) AllocateReservedIP(name, ipAddress, owner string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
-		return isAllocated, fmt.Errorf("network %s does not exist", name)
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return "", fmt.Errorf("network %s does not exist", name)
+	}
+
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
+		return net.IPv4zero.String(), fmt.Errorf("reserved ip %s was not found in network %s ipam", ipAddress, name)
+	}
+	if reservedFor, reserved := subnet.reserved[i]; !reserved || reservedFor != owner {
+		return net.IPv4zero.String(), fmt.Errorf("ip %s is not reserved for %s", ipAddress, owner)
+	}
+	if subnet.allocated.has(i) {
+		return net.IPv4zero.String(), fmt.Errorf("reserved ip %s is already allocated", ipAddress)
+	}
+	if subnet.isQuarantined(i) {
+		return net.IPv4zero.String(), fmt.Errorf("reserved ip %s is quarantined", ipAddress)
 	}
+	subnet.allocate(i)
 
-	isAllocated, exists := a.ipam[name].ips[ipAddress]
+	return ipAddress, nil
+}
+
+// UnrevokeIP returns a revoked IP address to the range, e.g., one no longer
+// excluded. IP addresses not revoked are ignored.
+func (a *IPAllocator) UnrevokeIP(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam[name]
 	if !exists {
-		return isAllocated, fmt.Errorf("ip %s was not found in network %s ipam", ipAddress, name)
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	i, ok := subnet.index(ipAddress)
+	if !ok || !subnet.revoked.has(i) {
+		return nil
 	}
+	subnet.revoked.clear(i)
+	subnet.revokedCount--
 
-	return isAllocated, nil
+	return nil
 }
 
-func (a *IPAllocator) GetUsed(name string) (int, error) {
+// ListRevoked returns the revoked IP addresses of the network in ascending
+// order.
+func (a *IPAllocator) ListRevoked(name string) ([]string, error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
-	var used int
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return nil, fmt.Errorf("network %s does not exist", name)
+	}
+
+	ips := make([]string, 0, subnet.revokedCount)
+	subnet.revoked.forEach(func(i int) {
+		ips = append(ips, subnet.addr(i))
+	})
+
+	return ips, nil
+}
+
+func (a *IPAllocator) IsAllocated(name, ipAddress string) (bool, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
-		return used, fmt.Errorf("network %s does not exist", name)
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return false, fmt.Errorf("network %s does not exist", name)
 	}
 
-	for _, isAllocated := range a.ipam[name].ips {
-		if isAllocated {
-			used++
-		}
+	i, ok := subnet.lookup(ipAddress)
+	if !ok {
+		return false, fmt.Errorf("ip %s was not found in network %s ipam", ipAddress, name)
 	}
 
-	return used, nil
+	return subnet.allocated.has(i), nil
 }
 
-func (a *IPAllocator) GetAvailable(name string) (int, error) {
+func (a *IPAllocator) GetUsed(name string) (int, error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
-	var available int
+	// Sanity check
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return 0, fmt.Errorf("network %s does not exist", name)
+	}
+
+	return subnet.used, nil
+}
+
+func (a *IPAllocator) GetAvailable(name string) (int, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
-		return available, fmt.Errorf("network %s does not exist", name)
+	subnet, exists := a.ipam[name]
+	if !exists {
+		return 0, fmt.Errorf("network %s does not exist", name)
 	}
 
-	for _, isAllocated := range a.ipam[name].ips {
-		if !isAllocated {
-			available++
+	return subnet.size - subnet.revokedCount - subnet.used - subnet.countQuarantined() - subnet.countReserved(), nil
+}
+
+// countQuarantined returns the number of the IP addresses still quarantined,
+// which are never allocated nor revoked.
+func (s *IPSubnet) countQuarantined() int {
+	var quarantined int
+	for i := range s.quarantined {
+		if s.isQuarantined(i) {
+			quarantined++
 		}
 	}
+	return quarantined
+}
 
-	return available, nil
+// countReserved returns the number of the IP addresses reserved but neither
+// allocated nor quarantined yet, which are never revoked.
+func (s *IPSubnet) countReserved() int {
+	var reserved int
+	for i := range s.reserved {
+		if !s.allocated.has(i) && !s.isQuarantined(i) {
+			reserved++
+		}
+	}
+	return reserved
 }
 
 func (a *IPAllocator) GetUsage(name string) error {
@@ -255,33 +782,52 @@ func (a *IPAllocator) GetUsage(name string) error {
 	defer a.mutex.RUnlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
+	subnet, exists := a.ipam[name]
+	if !exists {
 		return fmt.Errorf("network %s does not exist", name)
 	}
 
-	logrus.Infof("ipam[%s] ipNet=%s/%s, start=%s, end=%s, broadcast=%s",
+	logrus.Infof("ipam[%s] ipNet=%s/%s, broadcast=%s",
 		name,
-		a.ipam[name].ipNet.IP.String(),
-		a.ipam[name].ipNet.Mask.String(),
-		a.ipam[name].start.String(),
-		a.ipam[name].end.String(),
-		a.ipam[name].broadcast.String(),
+		subnet.ipNet.IP.String(),
+		subnet.ipNet.Mask.String(),
+		subnet.broadcast.String(),
 	)
 
-	var used int
+	logrus.Infof("ipam[%s] ranges=", name)
+	for _, r := range subnet.ranges {
+		logrus.Infof("ipam[%s] - %s-%s", name, uint32ToIPv4(r.base), uint32ToIPv4(r.base+uint32(r.size-1)))
+	}
+
 	logrus.Infof("ipam[%s] allocatedIPs=", name)
-	for ip, isAllocated := range a.ipam[name].ips {
-		if isAllocated {
-			logrus.Infof("ipam[%s] - %s", name, ip)
-			used++
+	for i := 0; i < subnet.size; i++ {
+		if subnet.allocated.has(i) {
+			logrus.Infof("ipam[%s] - %s", name, subnet.addr(i))
+		}
+	}
+
+	logrus.Infof("ipam[%s] quarantinedIPs=", name)
+	for i := range subnet.quarantined {
+		if subnet.isQuarantined(i) {
+			logrus.Infof("ipam[%s] - %s", name, subnet.addr(i))
 		}
 	}
 
-	logrus.Infof("ipam[%s] total=%d, in-use=%d, available=%d",
+	logrus.Infof("ipam[%s] reservedIPs=", name)
+	for i, owner := range subnet.reserved {
+		logrus.Infof("ipam[%s] - %s (%s)", name, subnet.addr(i), owner)
+	}
+
+	total := subnet.size - subnet.revokedCount
+	quarantined := subnet.countQuarantined()
+	reserved := subnet.countReserved()
+	logrus.Infof("ipam[%s] total=%d, in-use=%d, quarantined=%d, reserved=%d, available=%d",
 		name,
-		len(a.ipam[name].ips),
-		used,
-		(len(a.ipam[name].ips) - used),
+		total,
+		subnet.used,
+		quarantined,
+		reserved,
+		(total - subnet.used - quarantined - reserved),
 	)
 
 	return nil
@@ -292,13 +838,16 @@ func (a *IPAllocator) ListAll(name string) (map[string]string, error) {
 	defer a.mutex.RUnlock()
 
 	// Sanity check
-	if _, exists := a.ipam[name]; !exists {
+	subnet, exists := a.ipam[name]
+	if !exists {
 		return nil, fmt.Errorf("network %s does not exist", name)
 	}
 
-	ips := make(map[string]string, len(a.ipam[name].ips))
-	for ip, isAllocated := range a.ipam[name].ips {
-		ips[ip] = strconv.FormatBool(isAllocated)
+	ips := make(map[string]string, subnet.size-subnet.revokedCount)
+	for i := 0; i < subnet.size; i++ {
+		if !subnet.revoked.has(i) {
+			ips[subnet.addr(i)] = strconv.FormatBool(subnet.allocated.has(i))
+		}
 	}
 
 	return ips, nil
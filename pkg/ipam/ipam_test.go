@@ -3,6 +3,7 @@ package ipam
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestIPAM(t *testing.T) {
@@ -293,3 +294,357 @@ func TestIPAM(t *testing.T) {
 		t.Errorf("got %q", got)
 	}
 }
+
+func TestQuarantineIP(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.10", "192.168.0.11").
+		Allocate("default/net-1", "192.168.0.10").
+		Build()
+
+	quarantineIPs := []struct {
+		subnetName string
+		ip         string
+		want       error
+	}{
+		{
+			subnetName: "default/not-existing-network-class",
+			ip:         "192.168.0.10",
+			want:       fmt.Errorf("network default/not-existing-network-class does not exist"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "",
+			want:       fmt.Errorf("designated ip is empty"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.100",
+			want:       fmt.Errorf("to-be-quarantined ip 192.168.0.100 was not found in network default/net-1 ipam"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.10",
+			want:       fmt.Errorf("to-be-quarantined ip 192.168.0.10 is still allocated"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.11",
+			want:       nil,
+		},
+	}
+
+	// QuarantineIP function tests
+	for i := 0; i < len(quarantineIPs); i++ {
+		if got := ti.QuarantineIP(
+			quarantineIPs[i].subnetName,
+			quarantineIPs[i].ip,
+		); got != quarantineIPs[i].want {
+			if got == nil || quarantineIPs[i].want == nil {
+				t.Errorf("got %q, wanted %q", got, quarantineIPs[i].want)
+			} else if got.Error() != quarantineIPs[i].want.Error() {
+				t.Errorf("got %q, wanted %q", got, quarantineIPs[i].want)
+			}
+		}
+	}
+
+	// Quarantined IP addresses are neither allocatable nor available
+	available, err := ti.GetAvailable("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if available != 0 {
+		t.Errorf("got %d, wanted 0", available)
+	}
+	if _, got := ti.AllocateIP("default/net-1", "192.168.0.11"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "designated ip 192.168.0.11 is quarantined" {
+		t.Errorf("got %q", got)
+	}
+	if _, got := ti.AllocateIP("default/net-1", ""); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "no more ip addresses left in network default/net-1 ipam" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestQuarantineIPUntil(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.10", "192.168.0.11").
+		Build()
+
+	if err := ti.QuarantineIPUntil("default/net-1", "192.168.0.10", time.Now().Add(-time.Minute)); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if err := ti.QuarantineIPUntil("default/net-1", "192.168.0.11", time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+
+	// IP addresses whose quarantine periods are over are allocatable again
+	if quarantined, _ := ti.IsQuarantined("default/net-1", "192.168.0.10"); quarantined {
+		t.Errorf("got quarantined, wanted not quarantined")
+	}
+	if quarantined, _ := ti.IsQuarantined("default/net-1", "192.168.0.11"); !quarantined {
+		t.Errorf("got not quarantined, wanted quarantined")
+	}
+	available, err := ti.GetAvailable("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if available != 1 {
+		t.Errorf("got %d, wanted 1", available)
+	}
+	if _, got := ti.AllocateIP("default/net-1", "192.168.0.11"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "designated ip 192.168.0.11 is quarantined" {
+		t.Errorf("got %q", got)
+	}
+	if ip, got := ti.AllocateIP("default/net-1", ""); got != nil {
+		t.Errorf("%s", got.Error())
+	} else if ip != "192.168.0.10" {
+		t.Errorf("got %s, wanted 192.168.0.10", ip)
+	}
+}
+
+func TestIPSubnetWithRanges(t *testing.T) {
+	ti := New()
+
+	if err := ti.NewIPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.10", "192.168.0.20",
+		IPRange{Start: "192.168.0.15", End: "192.168.0.30"},
+	); err == nil {
+		t.Errorf("got nil, wanted error")
+	} else if err.Error() != "ip range 192.168.0.15-192.168.0.30 overlaps with ip range 192.168.0.10-192.168.0.20" {
+		t.Errorf("got %q", err)
+	}
+
+	if err := ti.NewIPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.150", "192.168.0.151",
+		IPRange{Start: "192.168.0.10", End: "192.168.0.11"},
+	); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	available, err := ti.GetAvailable("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if available != 4 {
+		t.Errorf("got %d, wanted 4", available)
+	}
+
+	// IP addresses between the ranges are not in the pool
+	if _, err := ti.AllocateIP("default/net-1", "192.168.0.100"); err == nil {
+		t.Errorf("got nil, wanted error")
+	} else if err.Error() != "no more ip addresses left in network default/net-1 ipam" {
+		t.Errorf("got %q", err)
+	}
+
+	// All the ranges are allocated from in ascending order
+	for _, want := range []string{"192.168.0.10", "192.168.0.11", "192.168.0.150", "192.168.0.151"} {
+		if ip, err := ti.AllocateIP("default/net-1", ""); err != nil {
+			t.Errorf("%s", err.Error())
+		} else if ip != want {
+			t.Errorf("got %s, wanted %s", ip, want)
+		}
+	}
+
+	used, err := ti.GetUsed("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if used != 4 {
+		t.Errorf("got %d, wanted 4", used)
+	}
+	if _, err := ti.AllocateIP("default/net-1", ""); err == nil {
+		t.Errorf("got nil, wanted error")
+	}
+}
+
+func TestUpdateIPSubnet(t *testing.T) {
+	ti := New()
+
+	if err := ti.NewIPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.10", "192.168.0.13"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if _, err := ti.AllocateIP("default/net-1", "192.168.0.12"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if err := ti.RevokeIP("default/net-1", "192.168.0.13"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if err := ti.QuarantineIP("default/net-1", "192.168.0.11"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	// Shrinking the range past an allocated address changes nothing
+	if err := ti.UpdateIPSubnet("default/net-1", "192.168.0.10", "192.168.0.11"); err == nil {
+		t.Errorf("got nil, wanted error")
+	} else if err.Error() != "allocated ip 192.168.0.12 is not within the new ranges of network default/net-1 ipam" {
+		t.Errorf("got %q", err)
+	}
+
+	// Widening the range keeps the allocated, revoked and quarantined ones
+	if err := ti.UpdateIPSubnet("default/net-1", "192.168.0.11", "192.168.0.15",
+		IPRange{Start: "192.168.0.100", End: "192.168.0.101"},
+	); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	isAllocated, err := ti.IsAllocated("default/net-1", "192.168.0.12")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if !isAllocated {
+		t.Errorf("got %s unallocated, wanted allocated", "192.168.0.12")
+	}
+	isQuarantined, err := ti.IsQuarantined("default/net-1", "192.168.0.11")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if !isQuarantined {
+		t.Errorf("got %s unquarantined, wanted quarantined", "192.168.0.11")
+	}
+
+	// 192.168.0.11-15 and 192.168.0.100-101 less the allocated, revoked and
+	// quarantined ones
+	available, err := ti.GetAvailable("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if available != 4 {
+		t.Errorf("got %d, wanted 4", available)
+	}
+
+	revoked, err := ti.ListRevoked("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if len(revoked) != 1 || revoked[0] != "192.168.0.13" {
+		t.Errorf("got %v, wanted [192.168.0.13]", revoked)
+	}
+
+	if err := ti.UnrevokeIP("default/net-1", "192.168.0.13"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if ip, err := ti.AllocateIP("default/net-1", ""); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "192.168.0.13" {
+		t.Errorf("got %s, wanted 192.168.0.13", ip)
+	}
+}
+
+func TestReserveIP(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPSubnet("default/net-1", "192.168.0.0/24", "192.168.0.10", "192.168.0.12").
+		Revoke("default/net-1", "192.168.0.12").
+		Build()
+
+	reserveIPs := []struct {
+		subnetName string
+		ip         string
+		owner      string
+		want       error
+	}{
+		{
+			subnetName: "default/not-existing-network-class",
+			ip:         "192.168.0.10",
+			owner:      "11:22:33:44:55:66",
+			want:       fmt.Errorf("network default/not-existing-network-class does not exist"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.10",
+			owner:      "",
+			want:       fmt.Errorf("owner of ip 192.168.0.10 is empty"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.100",
+			owner:      "11:22:33:44:55:66",
+			want:       fmt.Errorf("to-be-reserved ip 192.168.0.100 was not found in network default/net-1 ipam"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.12",
+			owner:      "11:22:33:44:55:66",
+			want:       fmt.Errorf("to-be-reserved ip 192.168.0.12 was not found in network default/net-1 ipam"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "192.168.0.10",
+			owner:      "11:22:33:44:55:66",
+			want:       nil,
+		},
+	}
+
+	// ReserveIP function tests
+	for i := 0; i < len(reserveIPs); i++ {
+		if got := ti.ReserveIP(
+			reserveIPs[i].subnetName,
+			reserveIPs[i].ip,
+			reserveIPs[i].owner,
+		); got != reserveIPs[i].want {
+			if got == nil || reserveIPs[i].want == nil {
+				t.Errorf("got %q, wanted %q", got, reserveIPs[i].want)
+			} else if got.Error() != reserveIPs[i].want.Error() {
+				t.Errorf("got %q, wanted %q", got, reserveIPs[i].want)
+			}
+		}
+	}
+
+	// Reserved IP addresses are neither allocatable to others nor available
+	available, err := ti.GetAvailable("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if available != 1 {
+		t.Errorf("got %d, wanted 1", available)
+	}
+	if ip, err := ti.AllocateIP("default/net-1", ""); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "192.168.0.11" {
+		t.Errorf("got %s, wanted 192.168.0.11", ip)
+	}
+	if _, got := ti.AllocateIPWithMAC("default/net-1", "192.168.0.10", "22:33:44:55:66:77"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "designated ip 192.168.0.10 is reserved for 11:22:33:44:55:66" {
+		t.Errorf("got %q", got)
+	}
+	if _, got := ti.AllocateReservedIP("default/net-1", "192.168.0.10", "default/vm-1"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "ip 192.168.0.10 is not reserved for default/vm-1" {
+		t.Errorf("got %q", got)
+	}
+
+	// The owner gets the reserved IP address only once it's no longer
+	// quarantined
+	if err := ti.QuarantineIP("default/net-1", "192.168.0.10"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if _, got := ti.AllocateReservedIP("default/net-1", "192.168.0.10", "11:22:33:44:55:66"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "reserved ip 192.168.0.10 is quarantined" {
+		t.Errorf("got %q", got)
+	}
+	if err := ti.QuarantineIPUntil("default/net-1", "192.168.0.10", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if ip, err := ti.AllocateReservedIP("default/net-1", "192.168.0.10", "11:22:33:44:55:66"); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "192.168.0.10" {
+		t.Errorf("got %s, wanted 192.168.0.10", ip)
+	}
+
+	reserved, err := ti.ListReserved("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if len(reserved) != 1 || reserved["192.168.0.10"] != "11:22:33:44:55:66" {
+		t.Errorf("got %v, wanted map[192.168.0.10:11:22:33:44:55:66]", reserved)
+	}
+
+	if err := ti.UnreserveIP("default/net-1", "192.168.0.10"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if reserved, _ := ti.ListReserved("default/net-1"); len(reserved) != 0 {
+		t.Errorf("got %v, wanted none", reserved)
+	}
+}
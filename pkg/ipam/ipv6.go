@@ -0,0 +1,358 @@
+package ipam
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// IPv6Subnet keeps track of the allocated addresses only, since IPv6 ranges
+// are far too large to be kept in bitmaps like the IPv4 ones.
+type IPv6Subnet struct {
+	prefix    netip.Prefix
+	start     netip.Addr
+	end       netip.Addr
+	allocated map[netip.Addr]bool
+	revoked   map[netip.Addr]bool
+	// quarantined addresses were declined by DHCP clients and are kept out
+	// of circulation
+	quarantined map[netip.Addr]bool
+	// next is where the search for a free address starts from
+	next netip.Addr
+}
+
+func (s *IPv6Subnet) contains(ip netip.Addr) bool {
+	return s.start.Compare(ip) <= 0 && ip.Compare(s.end) <= 0
+}
+
+func parseIPv6(ipAddress string) (netip.Addr, error) {
+	ip, err := netip.ParseAddr(ipAddress)
+	if err != nil || !ip.Is6() || ip.Is4In6() {
+		return netip.Addr{}, fmt.Errorf("%s is not a valid ipv6 address", ipAddress)
+	}
+	return ip, nil
+}
+
+func newIPv6Subnet(prefix, start, end string) (*IPv6Subnet, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil || !p.Addr().Is6() {
+		return nil, fmt.Errorf("%s is not a valid ipv6 prefix", prefix)
+	}
+	p = p.Masked()
+
+	startIP, err := parseIPv6(start)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Contains(startIP) {
+		return nil, fmt.Errorf("start ip address %s is not within subnet %s range", start, prefix)
+	}
+	endIP, err := parseIPv6(end)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Contains(endIP) {
+		return nil, fmt.Errorf("end ip address %s is not within subnet %s range", end, prefix)
+	}
+	if startIP.Compare(endIP) > 0 {
+		return nil, fmt.Errorf("end ip address %s is less than start ip address %s", end, start)
+	}
+
+	return &IPv6Subnet{
+		prefix:      p,
+		start:       startIP,
+		end:         endIP,
+		allocated:   make(map[netip.Addr]bool),
+		revoked:     make(map[netip.Addr]bool),
+		quarantined: make(map[netip.Addr]bool),
+		next:        startIP,
+	}, nil
+}
+
+func (a *IPAllocator) NewIPv6Subnet(name, prefix, start, end string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	subnet, err := newIPv6Subnet(prefix, start, end)
+	if err != nil {
+		return err
+	}
+	a.ipam6[name] = subnet
+
+	return nil
+}
+
+// UpdateIPv6Subnet changes the prefix, range and excluded addresses of the
+// network on the fly, carrying the allocated IPv6 addresses over. It fails
+// without changing anything if an allocated address is not within the new
+// range or is excluded.
+func (a *IPAllocator) UpdateIPv6Subnet(name, prefix, start, end string, exclude []string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	newSubnet, err := newIPv6Subnet(prefix, start, end)
+	if err != nil {
+		return err
+	}
+
+	for _, ipAddress := range exclude {
+		ip, err := parseIPv6(ipAddress)
+		if err != nil {
+			return err
+		}
+		if newSubnet.contains(ip) {
+			newSubnet.revoked[ip] = true
+		}
+	}
+
+	var orphaned []string
+	for ip := range subnet.allocated {
+		if !newSubnet.contains(ip) || newSubnet.revoked[ip] {
+			orphaned = append(orphaned, ip.String())
+			continue
+		}
+		newSubnet.allocated[ip] = true
+	}
+	if len(orphaned) > 0 {
+		sort.Strings(orphaned)
+		return fmt.Errorf("allocated ip %s is not within the new range of network %s ipv6 ipam", strings.Join(orphaned, ","), name)
+	}
+
+	for ip := range subnet.quarantined {
+		if newSubnet.contains(ip) && !newSubnet.revoked[ip] {
+			newSubnet.quarantined[ip] = true
+		}
+	}
+
+	if newSubnet.contains(subnet.next) {
+		newSubnet.next = subnet.next
+	}
+	a.ipam6[name] = newSubnet
+
+	return nil
+}
+
+func (a *IPAllocator) DeleteIPv6Subnet(name string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.ipam6, name)
+}
+
+func (a *IPAllocator) IsIPv6NetworkInitialized(name string) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	_, exists := a.ipam6[name]
+	return exists
+}
+
+// AllocateIPv6 allocates the designated IPv6 address, or the next free one
+// if none is designated.
+func (a *IPAllocator) AllocateIPv6(name string, ipAddress string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return "", fmt.Errorf("network %s does not exist", name)
+	}
+
+	if ipAddress != "" {
+		designatedIP, err := parseIPv6(ipAddress)
+		if err != nil {
+			return "", err
+		}
+		if !subnet.contains(designatedIP) {
+			return "", fmt.Errorf("designated ip %s is not in range %s-%s", ipAddress, subnet.start, subnet.end)
+		}
+		if subnet.revoked[designatedIP] {
+			return "", fmt.Errorf("designated ip %s is excluded", ipAddress)
+		}
+		if subnet.allocated[designatedIP] {
+			return "", fmt.Errorf("designated ip %s is already allocated", ipAddress)
+		}
+		if subnet.quarantined[designatedIP] {
+			return "", fmt.Errorf("designated ip %s is quarantined", ipAddress)
+		}
+		subnet.allocated[designatedIP] = true
+		return designatedIP.String(), nil
+	}
+
+	// Among any len(allocated)+len(revoked)+len(quarantined)+1 consecutive
+	// addresses at least one is free, unless the whole range is exhausted
+	ip := subnet.next
+	for i := 0; i <= len(subnet.allocated)+len(subnet.revoked)+len(subnet.quarantined); i++ {
+		if !subnet.allocated[ip] && !subnet.revoked[ip] && !subnet.quarantined[ip] {
+			subnet.allocated[ip] = true
+			subnet.next = subnet.advance(ip)
+			return ip.String(), nil
+		}
+		ip = subnet.advance(ip)
+		if ip == subnet.next {
+			break
+		}
+	}
+
+	return "", fmt.Errorf("no more ip addresses left in network %s ipam", name)
+}
+
+// advance returns the address following ip, wrapping around at the end of
+// the range.
+func (s *IPv6Subnet) advance(ip netip.Addr) netip.Addr {
+	if ip == s.end {
+		return s.start
+	}
+	return ip.Next()
+}
+
+func (a *IPAllocator) DeallocateIPv6(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return err
+	}
+	if !subnet.allocated[ip] {
+		return fmt.Errorf("to-be-deallocated ip %s was not allocated", ipAddress)
+	}
+
+	delete(subnet.allocated, ip)
+
+	return nil
+}
+
+// RevokeIPv6 takes the IPv6 address out of the range, e.g., for the excluded
+// ones.
+func (a *IPAllocator) RevokeIPv6(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return err
+	}
+	if subnet.contains(ip) {
+		delete(subnet.allocated, ip)
+		subnet.revoked[ip] = true
+	}
+
+	return nil
+}
+
+// QuarantineIPv6 takes the IPv6 address declined by a DHCP client out of
+// circulation, deallocating it if needed.
+func (a *IPAllocator) QuarantineIPv6(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return err
+	}
+	if !subnet.contains(ip) || subnet.revoked[ip] {
+		return fmt.Errorf("to-be-quarantined ip %s was not found in network %s ipv6 ipam", ipAddress, name)
+	}
+
+	delete(subnet.allocated, ip)
+	subnet.quarantined[ip] = true
+
+	return nil
+}
+
+// UnquarantineIPv6 puts the quarantined IPv6 address back into circulation.
+func (a *IPAllocator) UnquarantineIPv6(name, ipAddress string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return err
+	}
+
+	delete(subnet.quarantined, ip)
+
+	return nil
+}
+
+func (a *IPAllocator) IsIPv6Quarantined(name, ipAddress string) (bool, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return false, fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return false, err
+	}
+
+	return subnet.quarantined[ip], nil
+}
+
+func (a *IPAllocator) IsIPv6Allocated(name, ipAddress string) (bool, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return false, fmt.Errorf("network %s does not exist", name)
+	}
+
+	ip, err := parseIPv6(ipAddress)
+	if err != nil {
+		return false, err
+	}
+
+	return subnet.allocated[ip], nil
+}
+
+func (a *IPAllocator) GetIPv6Used(name string) (int, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	// Sanity check
+	subnet, exists := a.ipam6[name]
+	if !exists {
+		return 0, fmt.Errorf("network %s does not exist", name)
+	}
+
+	return len(subnet.allocated), nil
+}
@@ -0,0 +1,230 @@
+package ipam
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewIPv6Subnet(t *testing.T) {
+	testIPv6Subnets := []struct {
+		name   string
+		prefix string
+		start  string
+		end    string
+		want   error
+	}{
+		{
+			name:   "default/net-ok",
+			prefix: "fd00:48::/64",
+			start:  "fd00:48::100",
+			end:    "fd00:48::ffff:ffff:ffff",
+			want:   nil,
+		},
+		{
+			name:   "default/net-prefix-error",
+			prefix: "192.168.0.0/24",
+			start:  "fd00:48::100",
+			end:    "fd00:48::200",
+			want:   fmt.Errorf("192.168.0.0/24 is not a valid ipv6 prefix"),
+		},
+		{
+			name:   "default/net-start-error",
+			prefix: "fd00:48::/64",
+			start:  "fd00:49::100",
+			end:    "fd00:48::200",
+			want:   fmt.Errorf("start ip address fd00:49::100 is not within subnet fd00:48::/64 range"),
+		},
+		{
+			name:   "default/net-end-error",
+			prefix: "fd00:48::/64",
+			start:  "fd00:48::100",
+			end:    "fd00:49::200",
+			want:   fmt.Errorf("end ip address fd00:49::200 is not within subnet fd00:48::/64 range"),
+		},
+		{
+			name:   "default/net-smaller-error",
+			prefix: "fd00:48::/64",
+			start:  "fd00:48::200",
+			end:    "fd00:48::100",
+			want:   fmt.Errorf("end ip address fd00:48::100 is less than start ip address fd00:48::200"),
+		},
+	}
+
+	ti := New()
+
+	for _, tc := range testIPv6Subnets {
+		got := ti.NewIPv6Subnet(tc.name, tc.prefix, tc.start, tc.end)
+		if got == nil || tc.want == nil {
+			if got != tc.want {
+				t.Errorf("%s: got %v, wanted %v", tc.name, got, tc.want)
+			}
+		} else if got.Error() != tc.want.Error() {
+			t.Errorf("%s: got %q, wanted %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAllocateIPv6(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::10", "fd00:48::13").
+		AllocateIPv6("default/net-1", "fd00:48::11").
+		Build()
+	_ = ti.RevokeIPv6("default/net-1", "fd00:48::12")
+
+	allocateIPs := []struct {
+		subnetName string
+		ip         string
+		wantIP     string
+		wantErr    error
+	}{
+		{
+			subnetName: "default/not-existing-network",
+			ip:         "",
+			wantErr:    fmt.Errorf("network default/not-existing-network does not exist"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "fd00:49::10",
+			wantErr:    fmt.Errorf("designated ip fd00:49::10 is not in range fd00:48::10-fd00:48::13"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "fd00:48::11",
+			wantErr:    fmt.Errorf("designated ip fd00:48::11 is already allocated"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "fd00:48::12",
+			wantErr:    fmt.Errorf("designated ip fd00:48::12 is excluded"),
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "",
+			wantIP:     "fd00:48::10",
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "",
+			wantIP:     "fd00:48::13",
+		},
+		{
+			subnetName: "default/net-1",
+			ip:         "",
+			wantErr:    fmt.Errorf("no more ip addresses left in network default/net-1 ipam"),
+		},
+	}
+
+	for _, tc := range allocateIPs {
+		ip, err := ti.AllocateIPv6(tc.subnetName, tc.ip)
+		if err == nil || tc.wantErr == nil {
+			if err != tc.wantErr {
+				t.Errorf("got %v, wanted %v", err, tc.wantErr)
+			}
+		} else if err.Error() != tc.wantErr.Error() {
+			t.Errorf("got %q, wanted %q", err, tc.wantErr)
+		}
+		if ip != tc.wantIP {
+			t.Errorf("got %s, wanted %s", ip, tc.wantIP)
+		}
+	}
+
+	used, err := ti.GetIPv6Used("default/net-1")
+	if err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if used != 3 {
+		t.Errorf("got %d, wanted 3", used)
+	}
+
+	// The search wraps around to the deallocated address
+	if err := ti.DeallocateIPv6("default/net-1", "fd00:48::11"); err != nil {
+		t.Errorf("%s", err.Error())
+	}
+	if got := ti.DeallocateIPv6("default/net-1", "fd00:48::11"); got == nil {
+		t.Errorf("got nil, wanted error")
+	} else if got.Error() != "to-be-deallocated ip fd00:48::11 was not allocated" {
+		t.Errorf("got %q", got)
+	}
+	if ip, err := ti.AllocateIPv6("default/net-1", ""); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "fd00:48::11" {
+		t.Errorf("got %s, wanted fd00:48::11", ip)
+	}
+}
+
+func TestUpdateIPv6Subnet(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::10", "fd00:48::13").
+		AllocateIPv6("default/net-1", "fd00:48::11").
+		Build()
+
+	if err := ti.UpdateIPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::12", "fd00:48::20", nil); err == nil ||
+		err.Error() != "allocated ip fd00:48::11 is not within the new range of network default/net-1 ipv6 ipam" {
+		t.Errorf("got %v, wanted allocated ip fd00:48::11 is not within the new range of network default/net-1 ipv6 ipam", err)
+	}
+
+	if err := ti.UpdateIPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::10", "fd00:48::20", []string{"fd00:48::10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if allocated, _ := ti.IsIPv6Allocated("default/net-1", "fd00:48::11"); !allocated {
+		t.Errorf("got fd00:48::11 not allocated, wanted it carried over")
+	}
+	if _, err := ti.AllocateIPv6("default/net-1", "fd00:48::10"); err == nil || err.Error() != "designated ip fd00:48::10 is excluded" {
+		t.Errorf("got %v, wanted designated ip fd00:48::10 is excluded", err)
+	}
+	if _, err := ti.AllocateIPv6("default/net-1", "fd00:48::20"); err != nil {
+		t.Errorf("got %v, wanted fd00:48::20 allocated within the widened range", err)
+	}
+}
+
+func TestQuarantineIPv6(t *testing.T) {
+	ti := NewIPAllocatorBuilder().
+		IPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::10", "fd00:48::11").
+		AllocateIPv6("default/net-1", "fd00:48::10").
+		Build()
+
+	if err := ti.QuarantineIPv6("default/net-1", "fd00:49::10"); err == nil ||
+		err.Error() != "to-be-quarantined ip fd00:49::10 was not found in network default/net-1 ipv6 ipam" {
+		t.Errorf("got %v, wanted to-be-quarantined ip fd00:49::10 was not found in network default/net-1 ipv6 ipam", err)
+	}
+
+	// The declined address is deallocated and kept out of circulation
+	if err := ti.QuarantineIPv6("default/net-1", "fd00:48::10"); err != nil {
+		t.Fatal(err)
+	}
+	if allocated, _ := ti.IsIPv6Allocated("default/net-1", "fd00:48::10"); allocated {
+		t.Errorf("got fd00:48::10 allocated, wanted it deallocated")
+	}
+	if quarantined, _ := ti.IsIPv6Quarantined("default/net-1", "fd00:48::10"); !quarantined {
+		t.Errorf("got fd00:48::10 not quarantined, wanted it quarantined")
+	}
+	if _, err := ti.AllocateIPv6("default/net-1", "fd00:48::10"); err == nil || err.Error() != "designated ip fd00:48::10 is quarantined" {
+		t.Errorf("got %v, wanted designated ip fd00:48::10 is quarantined", err)
+	}
+	if ip, err := ti.AllocateIPv6("default/net-1", ""); err != nil || ip != "fd00:48::11" {
+		t.Errorf("got %s, %v, wanted fd00:48::11", ip, err)
+	}
+	if _, err := ti.AllocateIPv6("default/net-1", ""); err == nil || err.Error() != "no more ip addresses left in network default/net-1 ipam" {
+		t.Errorf("got %v, wanted no more ip addresses left in network default/net-1 ipam", err)
+	}
+
+	// Quarantined addresses survive the update of the range
+	if err := ti.UpdateIPv6Subnet("default/net-1", "fd00:48::/64", "fd00:48::10", "fd00:48::12", nil); err != nil {
+		t.Fatal(err)
+	}
+	if quarantined, _ := ti.IsIPv6Quarantined("default/net-1", "fd00:48::10"); !quarantined {
+		t.Errorf("got fd00:48::10 not quarantined, wanted it carried over")
+	}
+
+	// The address is allocatable again once its quarantine is over
+	if err := ti.UnquarantineIPv6("default/net-1", "fd00:48::10"); err != nil {
+		t.Fatal(err)
+	}
+	if quarantined, _ := ti.IsIPv6Quarantined("default/net-1", "fd00:48::10"); quarantined {
+		t.Errorf("got fd00:48::10 quarantined, wanted it unquarantined")
+	}
+	if _, err := ti.AllocateIPv6("default/net-1", "fd00:48::10"); err != nil {
+		t.Errorf("got %v, wanted fd00:48::10 allocated", err)
+	}
+}
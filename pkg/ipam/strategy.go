@@ -0,0 +1,97 @@
+package ipam
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+)
+
+const (
+	// LowestFree allocates the lowest free IP address
+	LowestFree = "lowest-free"
+	// Random allocates a free IP address at random
+	Random = "random"
+	// RoundRobin allocates the next free IP address after the last allocated
+	// one, so that recently used IP addresses are the last to be reused
+	RoundRobin = "round-robin"
+	// MACHash allocates the IP address derived from the MAC address, or else
+	// the next free one after it
+	MACHash = "mac-hash"
+)
+
+// Pool is the range of IP addresses an allocation strategy picks from. The IP
+// addresses are indexed in ascending order from 0.
+type Pool interface {
+	// Size returns the number of IP addresses in the pool.
+	Size() int
+	// NextFree returns the index of the first free IP address from the i-th
+	// one on, wrapping around at the end, or false if there's none.
+	NextFree(i int) (int, bool)
+}
+
+// AllocationStrategy picks the IP address to be allocated when none is
+// designated.
+type AllocationStrategy interface {
+	// Pick returns the index of the IP address of pool to be allocated to
+	// macAddress, which may be empty. last is the index of the IP address
+	// picked previously, or -1 if there's none. It returns false if there's
+	// no free IP address left.
+	Pick(pool Pool, last int, macAddress string) (int, bool)
+}
+
+// NewAllocationStrategy returns the allocation strategy of the name. The
+// empty name stands for LowestFree.
+func NewAllocationStrategy(name string) (AllocationStrategy, error) {
+	switch name {
+	case "", LowestFree:
+		return lowestFreeStrategy{}, nil
+	case Random:
+		return randomStrategy{}, nil
+	case RoundRobin:
+		return roundRobinStrategy{}, nil
+	case MACHash:
+		return macHashStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown allocation strategy %s", name)
+	}
+}
+
+type lowestFreeStrategy struct{}
+
+func (lowestFreeStrategy) Pick(pool Pool, _ int, _ string) (int, bool) {
+	return pool.NextFree(0)
+}
+
+type randomStrategy struct{}
+
+func (randomStrategy) Pick(pool Pool, _ int, _ string) (int, bool) {
+	if pool.Size() == 0 {
+		return 0, false
+	}
+	return pool.NextFree(rand.Intn(pool.Size()))
+}
+
+type roundRobinStrategy struct{}
+
+func (roundRobinStrategy) Pick(pool Pool, last int, _ string) (int, bool) {
+	if pool.Size() == 0 {
+		return 0, false
+	}
+	return pool.NextFree((last + 1) % pool.Size())
+}
+
+type macHashStrategy struct{}
+
+func (macHashStrategy) Pick(pool Pool, _ int, macAddress string) (int, bool) {
+	if pool.Size() == 0 {
+		return 0, false
+	}
+	var i int
+	if macAddress != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(strings.ToLower(macAddress)))
+		i = int(h.Sum32() % uint32(pool.Size()))
+	}
+	return pool.NextFree(i)
+}
@@ -0,0 +1,174 @@
+package ipam
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+const (
+	testNetwork = "default/net-1"
+	testMAC1    = "11:22:33:44:55:66"
+)
+
+func newTestStrategyAllocator(t *testing.T, strategyName string) *IPAllocator {
+	ti := NewIPAllocatorBuilder().
+		IPSubnet(testNetwork, "192.168.0.0/24", "192.168.0.10", "192.168.0.13").
+		Build()
+	strategy, err := NewAllocationStrategy(strategyName)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if err := ti.SetAllocationStrategy(testNetwork, strategy); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	return ti
+}
+
+func TestNewAllocationStrategy(t *testing.T) {
+	if _, err := NewAllocationStrategy("most-free"); err == nil {
+		t.Errorf("got nil, wanted error")
+	} else if err.Error() != "unknown allocation strategy most-free" {
+		t.Errorf("got %q", err)
+	}
+}
+
+func TestAllocationStrategyExhaustion(t *testing.T) {
+	for _, strategyName := range []string{LowestFree, Random, RoundRobin, MACHash} {
+		t.Run(strategyName, func(t *testing.T) {
+			ti := newTestStrategyAllocator(t, strategyName)
+
+			allocated := make(map[string]bool)
+			for i := 0; i < 4; i++ {
+				ip, err := ti.AllocateIPWithMAC(testNetwork, "", fmt.Sprintf("11:22:33:44:55:%02x", i))
+				if err != nil {
+					t.Fatalf("%s", err.Error())
+				}
+				if allocated[ip] {
+					t.Errorf("got %s allocated twice", ip)
+				}
+				allocated[ip] = true
+			}
+
+			if _, err := ti.AllocateIPWithMAC(testNetwork, "", testMAC1); err == nil {
+				t.Errorf("got nil, wanted error")
+			} else if err.Error() != "no more ip addresses left in network default/net-1 ipam" {
+				t.Errorf("got %q", err)
+			}
+
+			// The only address left is the one to be allocated
+			if err := ti.DeallocateIP(testNetwork, "192.168.0.12"); err != nil {
+				t.Fatalf("%s", err.Error())
+			}
+			if ip, err := ti.AllocateIPWithMAC(testNetwork, "", testMAC1); err != nil {
+				t.Errorf("%s", err.Error())
+			} else if ip != "192.168.0.12" {
+				t.Errorf("got %s, wanted 192.168.0.12", ip)
+			}
+		})
+	}
+}
+
+func TestLowestFreeStrategy(t *testing.T) {
+	ti := newTestStrategyAllocator(t, LowestFree)
+
+	for _, want := range []string{"192.168.0.10", "192.168.0.11"} {
+		if ip, err := ti.AllocateIP(testNetwork, ""); err != nil {
+			t.Errorf("%s", err.Error())
+		} else if ip != want {
+			t.Errorf("got %s, wanted %s", ip, want)
+		}
+	}
+
+	if err := ti.DeallocateIP(testNetwork, "192.168.0.10"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if ip, err := ti.AllocateIP(testNetwork, ""); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "192.168.0.10" {
+		t.Errorf("got %s, wanted 192.168.0.10", ip)
+	}
+}
+
+func TestRoundRobinStrategy(t *testing.T) {
+	ti := newTestStrategyAllocator(t, RoundRobin)
+
+	for _, want := range []string{"192.168.0.10", "192.168.0.11"} {
+		if ip, err := ti.AllocateIP(testNetwork, ""); err != nil {
+			t.Errorf("%s", err.Error())
+		} else if ip != want {
+			t.Errorf("got %s, wanted %s", ip, want)
+		}
+	}
+
+	// Recently released addresses are the last to be reused
+	if err := ti.DeallocateIP(testNetwork, "192.168.0.10"); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if ip, err := ti.AllocateIP(testNetwork, ""); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != "192.168.0.12" {
+		t.Errorf("got %s, wanted 192.168.0.12", ip)
+	}
+
+	// Restart with the allocated addresses and the last allocated one
+	// restored
+	lastAllocated, err := ti.GetLastAllocated(testNetwork)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	if lastAllocated != "192.168.0.12" {
+		t.Errorf("got %s, wanted 192.168.0.12", lastAllocated)
+	}
+	restarted := newTestStrategyAllocator(t, RoundRobin)
+	for _, ip := range []string{"192.168.0.11", "192.168.0.12"} {
+		if _, err := restarted.AllocateIP(testNetwork, ip); err != nil {
+			t.Fatalf("%s", err.Error())
+		}
+	}
+	if err := restarted.SetLastAllocated(testNetwork, lastAllocated); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	for _, want := range []string{"192.168.0.13", "192.168.0.10"} {
+		if ip, err := restarted.AllocateIP(testNetwork, ""); err != nil {
+			t.Errorf("%s", err.Error())
+		} else if ip != want {
+			t.Errorf("got %s, wanted %s", ip, want)
+		}
+	}
+}
+
+func TestMACHashStrategy(t *testing.T) {
+	ti := newTestStrategyAllocator(t, MACHash)
+
+	ip1, err := ti.AllocateIPWithMAC(testNetwork, "", testMAC1)
+	if err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+
+	// The same MAC address, in whatever case, is preferred the same address
+	// after a restart
+	restarted := newTestStrategyAllocator(t, MACHash)
+	if ip, err := restarted.AllocateIPWithMAC(testNetwork, "", strings.ToUpper(testMAC1)); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != ip1 {
+		t.Errorf("got %s, wanted %s", ip, ip1)
+	}
+
+	// The next free address is allocated if the preferred one is taken
+	restarted = newTestStrategyAllocator(t, MACHash)
+	if _, err := restarted.AllocateIP(testNetwork, ip1); err != nil {
+		t.Fatalf("%s", err.Error())
+	}
+	want := netip.MustParseAddr(ip1).Next()
+	if want.String() == "192.168.0.14" {
+		want = netip.MustParseAddr("192.168.0.10")
+	}
+	if ip, err := restarted.AllocateIPWithMAC(testNetwork, "", testMAC1); err != nil {
+		t.Errorf("%s", err.Error())
+	} else if ip != want.String() {
+		t.Errorf("got %s, wanted %s", ip, want)
+	}
+}
@@ -0,0 +1,184 @@
+package ra
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv6"
+)
+
+// allNodes is where unsolicited Router Advertisements go (RFC 4861 section
+// 6.2.4)
+var allNodes = &net.IPAddr{IP: net.IPv6linklocalallnodes}
+
+// Advertiser sends Router Advertisements on a network interface, both
+// periodically and in response to Router Solicitations.
+type Advertiser struct {
+	nic      string
+	config   *Config
+	configCh chan struct{}
+	mutex    sync.RWMutex
+}
+
+func NewAdvertiser(nic string) *Advertiser {
+	return &Advertiser{
+		nic:      nic,
+		configCh: make(chan struct{}, 1),
+	}
+}
+
+// SetConfig replaces the configuration of the Router Advertisements. A nil
+// config stops sending them.
+func (a *Advertiser) SetConfig(config *Config) {
+	a.mutex.Lock()
+	a.config = config
+	a.mutex.Unlock()
+
+	select {
+	case a.configCh <- struct{}{}:
+	default:
+	}
+}
+
+func (a *Advertiser) getConfig() *Config {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.config
+}
+
+func (a *Advertiser) Run(ctx context.Context) error {
+	logrus.Infof("(ra.Run) starting router advertisement service on nic %s", a.nic)
+
+	iface, err := net.InterfaceByName(a.nic)
+	if err != nil {
+		return err
+	}
+
+	conn, err := icmp.ListenPacket("ip6:ipv6-icmp", "::")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	pc := conn.IPv6PacketConn()
+	// Neighbor Discovery messages are only accepted with a hop limit of 255
+	// (RFC 4861 section 6.1.2)
+	if err := pc.SetMulticastHopLimit(255); err != nil {
+		return err
+	}
+	if err := pc.SetHopLimit(255); err != nil {
+		return err
+	}
+	if err := pc.SetMulticastInterface(iface); err != nil {
+		return err
+	}
+	if err := pc.JoinGroup(iface, &net.IPAddr{IP: net.ParseIP("ff02::2")}); err != nil {
+		return err
+	}
+	if err := pc.SetControlMessage(ipv6.FlagInterface, true); err != nil {
+		return err
+	}
+	var filter ipv6.ICMPFilter
+	filter.SetAll(true)
+	filter.Accept(ipv6.ICMPTypeRouterSolicitation)
+	if err := pc.SetICMPFilter(&filter); err != nil {
+		return err
+	}
+
+	send := func(b []byte, dst net.Addr) {
+		cm := &ipv6.ControlMessage{HopLimit: 255, IfIndex: iface.Index}
+		if _, err := pc.WriteTo(b, cm, dst); err != nil {
+			logrus.Errorf("(ra.Run) cannot send router advertisement on nic %s: %v", a.nic, err)
+		}
+	}
+
+	solicited := make(chan net.Addr)
+	go a.listen(ctx, pc, iface, solicited)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	var last *Config
+	for {
+		select {
+		case <-ctx.Done():
+			// Withdraw the agent as default router of the guests
+			if last != nil && last.RouterLifetime > 0 {
+				send(BuildFinal(last, iface.HardwareAddr), allNodes)
+			}
+			logrus.Infof("(ra.Run) router advertisement service on nic %s terminated", a.nic)
+			return nil
+		case <-a.configCh:
+			if config := a.getConfig(); config == nil && last != nil && last.RouterLifetime > 0 {
+				send(BuildFinal(last, iface.HardwareAddr), allNodes)
+			}
+			resetTimer(timer, 0)
+		case <-timer.C:
+			last = a.getConfig()
+			if last == nil {
+				continue
+			}
+			send(Build(last, iface.HardwareAddr), allNodes)
+			resetTimer(timer, nextInterval(last.Interval))
+		case dst := <-solicited:
+			if config := a.getConfig(); config != nil {
+				send(Build(config, iface.HardwareAddr), dst)
+			}
+		}
+	}
+}
+
+// listen passes on the source address of the Router Solicitations received
+// on iface.
+func (a *Advertiser) listen(ctx context.Context, pc *ipv6.PacketConn, iface *net.Interface, solicited chan<- net.Addr) {
+	b := make([]byte, 1500)
+	for {
+		n, cm, src, err := pc.ReadFrom(b)
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.Errorf("(ra.listen) cannot receive on nic %s: %v", a.nic, err)
+			}
+			return
+		}
+		if n == 0 || b[0] != icmpTypeRouterSolicitation || (cm != nil && cm.IfIndex != iface.Index) {
+			continue
+		}
+
+		// Solicitations from the unspecified address are answered to all
+		// nodes (RFC 4861 section 6.2.6)
+		dst := src
+		if ipAddr, ok := src.(*net.IPAddr); !ok || ipAddr.IP.IsUnspecified() {
+			dst = allNodes
+		}
+
+		select {
+		case solicited <- dst:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// nextInterval picks the time until the next unsolicited Router
+// Advertisement uniformly between a third of and the maximum interval (RFC
+// 4861 section 6.2.4).
+func nextInterval(maxInterval int) time.Duration {
+	minInterval := time.Duration(maxInterval) * time.Second / 3
+	return minInterval + time.Duration(rand.Int63n(int64(time.Duration(maxInterval)*time.Second-minInterval)))
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
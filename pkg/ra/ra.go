@@ -0,0 +1,180 @@
+package ra
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/insomniacslk/dhcp/rfc1035label"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+const (
+	// Default protocol constants of RFC 4861 section 6.2.1
+	DefaultInterval          = 600
+	DefaultValidLifetime     = 2592000
+	DefaultPreferredLifetime = 604800
+
+	icmpTypeRouterSolicitation  = 133
+	icmpTypeRouterAdvertisement = 134
+
+	optionSourceLinkLayerAddress = 1
+	optionPrefixInformation      = 3
+	optionRDNSS                  = 25
+	optionDNSSL                  = 31
+
+	curHopLimit = 64
+)
+
+// Config is what goes into the Router Advertisements of an IPPool.
+type Config struct {
+	Managed        bool
+	Other          bool
+	Prefix         netip.Prefix
+	Autonomous     bool
+	Interval       int
+	RouterLifetime int
+	DNS            []netip.Addr
+	DomainSearch   []string
+}
+
+// NewConfig derives the Router Advertisement configuration from the IPv6
+// configuration of an IPPool. It returns nil if no Router Advertisements are
+// wanted.
+func NewConfig(ipv6Config *networkv1.IPv6Config) (*Config, error) {
+	if ipv6Config == nil || ipv6Config.RouterAdvertisement == nil {
+		return nil, nil
+	}
+	raConfig := ipv6Config.RouterAdvertisement
+
+	prefix, err := netip.ParsePrefix(ipv6Config.Prefix)
+	if err != nil || !prefix.Addr().Is6() {
+		return nil, fmt.Errorf("prefix %s is not a valid ipv6 cidr", ipv6Config.Prefix)
+	}
+
+	// The agent is not a default router unless asked to, as it does not route
+	// any traffic itself
+	config := &Config{
+		Prefix:   prefix.Masked(),
+		Interval: DefaultInterval,
+	}
+
+	switch raConfig.Mode {
+	case networkv1.RAModeStateful:
+		config.Managed = true
+		config.Other = true
+	case networkv1.RAModeStateless:
+		config.Other = true
+		config.Autonomous = true
+	case networkv1.RAModeSLAAC:
+		config.Autonomous = true
+	default:
+		return nil, fmt.Errorf("router advertisement mode %s is unknown", raConfig.Mode)
+	}
+
+	if config.Autonomous && config.Prefix.Bits() != 64 {
+		return nil, fmt.Errorf("prefix %s must be a /64 for slaac", ipv6Config.Prefix)
+	}
+
+	if raConfig.Interval != nil {
+		config.Interval = *raConfig.Interval
+	}
+	if raConfig.RouterLifetime != nil {
+		config.RouterLifetime = *raConfig.RouterLifetime
+	}
+	// RFC 4861 section 6.2.1
+	if config.RouterLifetime != 0 && config.RouterLifetime < config.Interval {
+		return nil, fmt.Errorf("router lifetime %d must be 0 or no less than interval %d", config.RouterLifetime, config.Interval)
+	}
+
+	for _, dns := range ipv6Config.DNS {
+		addr, err := netip.ParseAddr(dns)
+		if err != nil || !addr.Is6() {
+			return nil, fmt.Errorf("dns server %s is not a valid ipv6 address", dns)
+		}
+		config.DNS = append(config.DNS, addr)
+	}
+	config.DomainSearch = ipv6Config.DomainSearch
+
+	return config, nil
+}
+
+// Build encodes the ICMPv6 Router Advertisement message (RFC 4861 section
+// 4.2) sent from the interface with hwAddr. The checksum is left to the
+// kernel.
+func Build(config *Config, hwAddr net.HardwareAddr) []byte {
+	return build(config, hwAddr, config.RouterLifetime)
+}
+
+// BuildFinal encodes the Router Advertisement sent when the agent stops,
+// which tells guests to stop using it as their default router.
+func BuildFinal(config *Config, hwAddr net.HardwareAddr) []byte {
+	return build(config, hwAddr, 0)
+}
+
+func build(config *Config, hwAddr net.HardwareAddr, routerLifetime int) []byte {
+	b := make([]byte, 16)
+	b[0] = icmpTypeRouterAdvertisement
+	b[4] = curHopLimit
+	if config.Managed {
+		b[5] |= 0x80
+	}
+	if config.Other {
+		b[5] |= 0x40
+	}
+	binary.BigEndian.PutUint16(b[6:8], uint16(routerLifetime))
+	// Reachable time and retrans timer are left unspecified
+
+	if len(hwAddr) > 0 {
+		b = append(b, buildOption(optionSourceLinkLayerAddress, hwAddr)...)
+	}
+
+	prefixInfo := make([]byte, 30)
+	prefixInfo[0] = uint8(config.Prefix.Bits())
+	// On-link
+	prefixInfo[1] = 0x80
+	if config.Autonomous {
+		prefixInfo[1] |= 0x40
+	}
+	binary.BigEndian.PutUint32(prefixInfo[2:6], DefaultValidLifetime)
+	binary.BigEndian.PutUint32(prefixInfo[6:10], DefaultPreferredLifetime)
+	prefix := config.Prefix.Addr().As16()
+	copy(prefixInfo[14:], prefix[:])
+	b = append(b, buildOption(optionPrefixInformation, prefixInfo)...)
+
+	// DNS lifetime of three times the interval as recommended by RFC 8106
+	// section 5.1
+	dnsLifetime := uint32(3 * config.Interval)
+
+	if len(config.DNS) > 0 {
+		rdnss := make([]byte, 6, 6+16*len(config.DNS))
+		binary.BigEndian.PutUint32(rdnss[2:6], dnsLifetime)
+		for _, dns := range config.DNS {
+			addr := dns.As16()
+			rdnss = append(rdnss, addr[:]...)
+		}
+		b = append(b, buildOption(optionRDNSS, rdnss)...)
+	}
+
+	if len(config.DomainSearch) > 0 {
+		dnssl := make([]byte, 6)
+		binary.BigEndian.PutUint32(dnssl[2:6], dnsLifetime)
+		dnssl = append(dnssl, (&rfc1035label.Labels{Labels: config.DomainSearch}).ToBytes()...)
+		b = append(b, buildOption(optionDNSSL, dnssl)...)
+	}
+
+	return b
+}
+
+// buildOption encodes an NDP option with data padded to a multiple of 8
+// octets.
+func buildOption(optionType uint8, data []byte) []byte {
+	length := (2 + len(data) + 7) / 8
+	option := make([]byte, 8*length)
+	option[0] = optionType
+	option[1] = uint8(length)
+	copy(option[2:], data)
+	return option
+}
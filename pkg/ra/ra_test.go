@@ -0,0 +1,267 @@
+package ra
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNewConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		ipv6Config *networkv1.IPv6Config
+		want       *Config
+		wantErr    error
+	}{
+		{
+			name:       "no ipv6 config",
+			ipv6Config: nil,
+		},
+		{
+			name: "no router advertisement",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/64",
+			},
+		},
+		{
+			name: "stateful",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/64",
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode: networkv1.RAModeStateful,
+				},
+			},
+			want: &Config{
+				Managed:  true,
+				Other:    true,
+				Prefix:   netip.MustParsePrefix("fd00:48::/64"),
+				Interval: 600,
+			},
+		},
+		{
+			name: "stateless with dns",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix:       "fd00:48::/64",
+				DNS:          []string{"2606:4700:4700::1111"},
+				DomainSearch: []string{"aibao.moe"},
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode:     networkv1.RAModeStateless,
+					Interval: intPtr(60),
+				},
+			},
+			want: &Config{
+				Other:        true,
+				Prefix:       netip.MustParsePrefix("fd00:48::/64"),
+				Autonomous:   true,
+				Interval:     60,
+				DNS:          []netip.Addr{netip.MustParseAddr("2606:4700:4700::1111")},
+				DomainSearch: []string{"aibao.moe"},
+			},
+		},
+		{
+			name: "slaac with default router",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/64",
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode:           networkv1.RAModeSLAAC,
+					RouterLifetime: intPtr(1800),
+				},
+			},
+			want: &Config{
+				Prefix:         netip.MustParsePrefix("fd00:48::/64"),
+				Autonomous:     true,
+				Interval:       600,
+				RouterLifetime: 1800,
+			},
+		},
+		{
+			name: "slaac with non-64 prefix",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/56",
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode: networkv1.RAModeSLAAC,
+				},
+			},
+			wantErr: fmt.Errorf("prefix fd00:48::/56 must be a /64 for slaac"),
+		},
+		{
+			name: "router lifetime shorter than interval",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/64",
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode:           networkv1.RAModeStateful,
+					Interval:       intPtr(600),
+					RouterLifetime: intPtr(300),
+				},
+			},
+			wantErr: fmt.Errorf("router lifetime 300 must be 0 or no less than interval 600"),
+		},
+		{
+			name: "unknown mode",
+			ipv6Config: &networkv1.IPv6Config{
+				Prefix: "fd00:48::/64",
+				RouterAdvertisement: &networkv1.RouterAdvertisementConfig{
+					Mode: "dhcp",
+				},
+			},
+			wantErr: fmt.Errorf("router advertisement mode dhcp is unknown"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewConfig(tc.ipv6Config)
+			if err == nil || tc.wantErr == nil {
+				if err != tc.wantErr {
+					t.Fatalf("got %v, wanted %v", err, tc.wantErr)
+				}
+			} else if err.Error() != tc.wantErr.Error() {
+				t.Fatalf("got %q, wanted %q", err, tc.wantErr)
+			}
+			if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", tc.want) {
+				t.Errorf("got %+v, wanted %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// parseOptions splits the options of the Router Advertisement b by type.
+func parseOptions(t *testing.T, b []byte) map[uint8][]byte {
+	options := make(map[uint8][]byte)
+	for b = b[16:]; len(b) > 0; {
+		if len(b) < 8 || b[1] == 0 || len(b) < 8*int(b[1]) {
+			t.Fatalf("malformed option %v", b)
+		}
+		options[b[0]] = b[:8*int(b[1])]
+		b = b[8*int(b[1]):]
+	}
+	return options
+}
+
+func TestBuild(t *testing.T) {
+	hwAddr, _ := net.ParseMAC("52:54:00:00:00:01")
+
+	tests := []struct {
+		name           string
+		config         *Config
+		wantFlags      uint8
+		wantPrefixFlag uint8
+		wantRDNSS      bool
+		wantDNSSL      bool
+	}{
+		{
+			name: "stateful",
+			config: &Config{
+				Managed:        true,
+				Other:          true,
+				Prefix:         netip.MustParsePrefix("fd00:48::/64"),
+				Interval:       600,
+				RouterLifetime: 1800,
+			},
+			wantFlags:      0xc0,
+			wantPrefixFlag: 0x80,
+		},
+		{
+			name: "stateless",
+			config: &Config{
+				Other:          true,
+				Prefix:         netip.MustParsePrefix("fd00:48::/64"),
+				Autonomous:     true,
+				Interval:       600,
+				RouterLifetime: 1800,
+				DNS:            []netip.Addr{netip.MustParseAddr("2606:4700:4700::1111")},
+				DomainSearch:   []string{"aibao.moe"},
+			},
+			wantFlags:      0x40,
+			wantPrefixFlag: 0xc0,
+			wantRDNSS:      true,
+			wantDNSSL:      true,
+		},
+		{
+			name: "slaac",
+			config: &Config{
+				Prefix:         netip.MustParsePrefix("fd00:48::/64"),
+				Autonomous:     true,
+				Interval:       600,
+				RouterLifetime: 1800,
+			},
+			wantFlags:      0x00,
+			wantPrefixFlag: 0xc0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := Build(tc.config, hwAddr)
+
+			if b[0] != icmpTypeRouterAdvertisement || b[1] != 0 {
+				t.Errorf("got type %d code %d, wanted type 134 code 0", b[0], b[1])
+			}
+			if b[5] != tc.wantFlags {
+				t.Errorf("got flags %#x, wanted %#x", b[5], tc.wantFlags)
+			}
+			if lifetime := binary.BigEndian.Uint16(b[6:8]); int(lifetime) != tc.config.RouterLifetime {
+				t.Errorf("got router lifetime %d, wanted %d", lifetime, tc.config.RouterLifetime)
+			}
+
+			options := parseOptions(t, b)
+
+			if sll := options[optionSourceLinkLayerAddress]; !bytes.Equal(sll[2:8], hwAddr) {
+				t.Errorf("got source link-layer address %v, wanted %s", sll, hwAddr)
+			}
+
+			prefixInfo := options[optionPrefixInformation]
+			if len(prefixInfo) != 32 {
+				t.Fatalf("got prefix information %v", prefixInfo)
+			}
+			if prefixInfo[2] != 64 || prefixInfo[3] != tc.wantPrefixFlag {
+				t.Errorf("got prefix length %d flags %#x, wanted 64 and %#x", prefixInfo[2], prefixInfo[3], tc.wantPrefixFlag)
+			}
+			if prefix, _ := netip.AddrFromSlice(prefixInfo[16:32]); prefix != tc.config.Prefix.Addr() {
+				t.Errorf("got prefix %s, wanted %s", prefix, tc.config.Prefix.Addr())
+			}
+
+			rdnss, ok := options[optionRDNSS]
+			if ok != tc.wantRDNSS {
+				t.Errorf("got rdnss %v, wanted %t", rdnss, tc.wantRDNSS)
+			} else if ok {
+				if dns, _ := netip.AddrFromSlice(rdnss[8:24]); dns != tc.config.DNS[0] {
+					t.Errorf("got dns %s, wanted %s", dns, tc.config.DNS[0])
+				}
+				if lifetime := binary.BigEndian.Uint32(rdnss[4:8]); lifetime != 1800 {
+					t.Errorf("got dns lifetime %d, wanted 1800", lifetime)
+				}
+			}
+
+			dnssl, ok := options[optionDNSSL]
+			if ok != tc.wantDNSSL {
+				t.Errorf("got dnssl %v, wanted %t", dnssl, tc.wantDNSSL)
+			} else if ok && !bytes.HasPrefix(dnssl[8:], []byte("\x05aibao\x03moe\x00")) {
+				t.Errorf("got domain search list %v", dnssl[8:])
+			}
+		})
+	}
+
+	final := BuildFinal(tests[0].config, hwAddr)
+	if lifetime := binary.BigEndian.Uint16(final[6:8]); lifetime != 0 {
+		t.Errorf("got final router lifetime %d, wanted 0", lifetime)
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := nextInterval(60); d < 20*time.Second || d >= 60*time.Second {
+			t.Fatalf("got %s, wanted between 20s and 60s", d)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ParseAgentPodTemplateAnnotation returns the pod template the IPPool
+// overrides its agent pod with by the annotation, or nil if there's no such
+// annotation. The template may set nothing but the node selector,
+// tolerations, priority class and resources of the containers.
+func ParseAgentPodTemplateAnnotation(annotations map[string]string) (*corev1.PodTemplateSpec, error) {
+	val, ok := annotations[AgentPodTemplateAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var template corev1.PodTemplateSpec
+	decoder := json.NewDecoder(bytes.NewReader([]byte(val)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&template); err != nil {
+		return nil, fmt.Errorf("annotation %s is malformed: %w", AgentPodTemplateAnnotationKey, err)
+	}
+
+	if !reflect.DeepEqual(&template, restrictAgentPodTemplate(&template)) {
+		return nil, fmt.Errorf("annotation %s may only set the node selector, tolerations, priority class and container resources", AgentPodTemplateAnnotationKey)
+	}
+
+	return &template, nil
+}
+
+// restrictAgentPodTemplate returns a copy of template with nothing but the
+// fields an IPPool is allowed to override.
+func restrictAgentPodTemplate(template *corev1.PodTemplateSpec) *corev1.PodTemplateSpec {
+	restricted := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			NodeSelector:      template.Spec.NodeSelector,
+			Tolerations:       template.Spec.Tolerations,
+			PriorityClassName: template.Spec.PriorityClassName,
+		},
+	}
+	restricted.Spec.InitContainers = restrictContainers(template.Spec.InitContainers)
+	restricted.Spec.Containers = restrictContainers(template.Spec.Containers)
+	return restricted
+}
+
+func restrictContainers(containers []corev1.Container) []corev1.Container {
+	if containers == nil {
+		return nil
+	}
+	restricted := make([]corev1.Container, 0, len(containers))
+	for _, c := range containers {
+		restricted = append(restricted, corev1.Container{
+			Name:      c.Name,
+			Resources: c.Resources,
+		})
+	}
+	return restricted
+}
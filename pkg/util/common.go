@@ -4,18 +4,94 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"strings"
+	"time"
 )
 
 const (
 	ExcludedMark = "EXCLUDED"
 	ReservedMark = "RESERVED"
+	// QuarantinedMark marks an IP address declined by a DHCP client. It's
+	// followed by the decline time, e.g., "QUARANTINED@2024-01-01T00:00:00Z".
+	QuarantinedMark = "QUARANTINED"
+	// ReleasedMark marks an IP address released but still in its quarantine
+	// period. It's followed by the release time, e.g.,
+	// "RELEASED@2024-01-01T00:00:00Z".
+	ReleasedMark = "RELEASED"
+
+	// LeaseStateBound is the state of the leases held by DHCP clients, as
+	// reported by the agent in the lease activities of IPPools
+	LeaseStateBound = "bound"
 
 	AgentSuffixName        = "agent"
 	NodeArgsAnnotationKey  = "rke2.io/node-args"
 	ServiceCIDRFlag        = "--service-cidr"
 	ManagementNodeLabelKey = "node-role.kubernetes.io/control-plane"
+
+	// IPAddressesAnnotationKey is the annotation of a VM mapping the names of
+	// its network interfaces to the IPv4 addresses they ask for, in JSON, e.g.,
+	// {"nic-1":"192.168.0.100"}
+	IPAddressesAnnotationKey = "network.harvesterhci.io/ip-addresses"
+	// AgentPodTemplateAnnotationKey is the annotation of an IPPool overriding
+	// the scheduling and resources of its agent pod with a pod template in
+	// JSON, e.g., {"spec":{"nodeSelector":{"rack":"r1"}}}
+	AgentPodTemplateAnnotationKey = "network.harvesterhci.io/agent-pod-template"
 )
 
+// NewReleasedMark returns the mark of an IP address released at t.
+func NewReleasedMark(t time.Time) string {
+	return ReleasedMark + "@" + t.UTC().Format(time.RFC3339)
+}
+
+// ParseReleasedMark returns the release time of the IP address marked with
+// val, and whether val is a released mark at all.
+func ParseReleasedMark(val string) (time.Time, bool) {
+	timestamp, found := strings.CutPrefix(val, ReleasedMark+"@")
+	if !found {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// NewQuarantinedMark returns the mark of an IP address declined at t.
+func NewQuarantinedMark(t time.Time) string {
+	return QuarantinedMark + "@" + t.UTC().Format(time.RFC3339)
+}
+
+// ParseQuarantinedMark returns the decline time of the IP address marked with
+// val, and whether val is a quarantined mark at all. The zero time is returned
+// for the bare marks left by earlier versions.
+func ParseQuarantinedMark(val string) (time.Time, bool) {
+	if val == QuarantinedMark {
+		return time.Time{}, true
+	}
+	timestamp, found := strings.CutPrefix(val, QuarantinedMark+"@")
+	if !found {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// IsMark tells if val is a mark rather than the MAC address of the IP address
+// allocated to.
+func IsMark(val string) bool {
+	if val == ExcludedMark || val == ReservedMark {
+		return true
+	}
+	if _, quarantined := ParseQuarantinedMark(val); quarantined {
+		return true
+	}
+	_, released := ParseReleasedMark(val)
+	return released
+}
+
 func agentConcatName(name ...string) string {
 	return strings.Join(append(name, AgentSuffixName), "-")
 }
@@ -2,6 +2,7 @@ package fakeclient
 
 import (
 	"context"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -11,6 +12,7 @@ import (
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	typenetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/typed/network.harvesterhci.io/v1alpha1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
 )
 
 type IPPoolClient func(string) typenetworkv1.IPPoolInterface
@@ -61,5 +63,41 @@ func (c IPPoolCache) AddIndexer(indexName string, indexer ctlnetworkv1.IPPoolInd
 	panic("implement me")
 }
 func (c IPPoolCache) GetByIndex(indexName, key string) ([]*networkv1.IPPool, error) {
-	panic("implement me")
+	if indexName != indexer.IPPoolByNetworkIndex {
+		panic("implement me")
+	}
+
+	ipPools, err := c.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*networkv1.IPPool
+	for _, ipPool := range ipPools {
+		keys, err := indexer.IPPoolByNetwork(ipPool)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k == key {
+				result = append(result, ipPool)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// IPPoolController records the IPPools enqueued after a delay by their keys.
+// The rest of the controller is left unimplemented.
+type IPPoolController struct {
+	ctlnetworkv1.IPPoolController
+
+	EnqueuedAfter map[string]time.Duration
+}
+
+func (c *IPPoolController) EnqueueAfter(namespace, name string, duration time.Duration) {
+	if c.EnqueuedAfter == nil {
+		c.EnqueuedAfter = make(map[string]time.Duration)
+	}
+	c.EnqueuedAfter[namespace+"/"+name] = duration
 }
@@ -0,0 +1,35 @@
+package fakeclient
+
+import (
+	"context"
+
+	ctlcoordinationv1 "github.com/rancher/wrangler/pkg/generated/controllers/coordination.k8s.io/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	typecoordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+type LeaseCache func(string) typecoordinationv1.LeaseInterface
+
+func (c LeaseCache) Get(namespace, name string) (*coordinationv1.Lease, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+func (c LeaseCache) List(namespace string, selector labels.Selector) ([]*coordinationv1.Lease, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*coordinationv1.Lease, 0, len(list.Items))
+	for _, lease := range list.Items {
+		l := lease
+		result = append(result, &l)
+	}
+	return result, err
+}
+func (c LeaseCache) AddIndexer(indexName string, indexer ctlcoordinationv1.LeaseIndexer) {
+	panic("implement me")
+}
+func (c LeaseCache) GetByIndex(indexName, key string) ([]*coordinationv1.Lease, error) {
+	panic("implement me")
+}
@@ -0,0 +1,36 @@
+package fakeclient
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	typekubevirtv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/typed/kubevirt.io/v1"
+	ctlkubevirtv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/kubevirt.io/v1"
+)
+
+type VirtualMachineCache func(string) typekubevirtv1.VirtualMachineInterface
+
+func (c VirtualMachineCache) Get(namespace, name string) (*kubevirtv1.VirtualMachine, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+func (c VirtualMachineCache) List(namespace string, selector labels.Selector) ([]*kubevirtv1.VirtualMachine, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*kubevirtv1.VirtualMachine, 0, len(list.Items))
+	for _, vm := range list.Items {
+		v := vm
+		result = append(result, &v)
+	}
+	return result, err
+}
+func (c VirtualMachineCache) AddIndexer(indexName string, indexer ctlkubevirtv1.VirtualMachineIndexer) {
+	panic("implement me")
+}
+func (c VirtualMachineCache) GetByIndex(indexName, key string) ([]*kubevirtv1.VirtualMachine, error) {
+	panic("implement me")
+}
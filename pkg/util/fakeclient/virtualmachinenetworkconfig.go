@@ -11,6 +11,7 @@ import (
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
 	typenetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/typed/network.harvesterhci.io/v1alpha1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
 )
 
 type VirtualMachineNetworkConfigClient func(string) typenetworkv1.VirtualMachineNetworkConfigInterface
@@ -61,5 +62,32 @@ func (c VirtualMachineNetworkConfigCache) AddIndexer(indexName string, indexer c
 	panic("implement me")
 }
 func (c VirtualMachineNetworkConfigCache) GetByIndex(indexName, key string) ([]*networkv1.VirtualMachineNetworkConfig, error) {
-	panic("implement me")
+	var indexFunc ctlnetworkv1.VirtualMachineNetworkConfigIndexer
+	switch indexName {
+	case indexer.VmNetCfgByNetworkIndex:
+		indexFunc = indexer.VmNetCfgByNetwork
+	case indexer.VmNetCfgByMACAddressIndex:
+		indexFunc = indexer.VmNetCfgByMACAddress
+	default:
+		panic("implement me")
+	}
+
+	vmNetCfgs, err := c.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*networkv1.VirtualMachineNetworkConfig
+	for _, vmNetCfg := range vmNetCfgs {
+		keys, err := indexFunc(vmNetCfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k == key {
+				result = append(result, vmNetCfg)
+				break
+			}
+		}
+	}
+	return result, nil
 }
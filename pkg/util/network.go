@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -17,8 +18,15 @@ type PoolInfo struct {
 	BroadcastIPAddr netip.Addr
 	StartIPAddr     netip.Addr
 	EndIPAddr       netip.Addr
-	ServerIPAddr    netip.Addr
-	RouterIPAddr    netip.Addr
+	// Ranges are the ranges besides the one from StartIPAddr to EndIPAddr
+	Ranges       []IPAddrRange
+	ServerIPAddr netip.Addr
+	RouterIPAddr netip.Addr
+}
+
+type IPAddrRange struct {
+	StartIPAddr netip.Addr
+	EndIPAddr   netip.Addr
 }
 
 func GetServiceCIDRFromNode(node *corev1.Node) (string, error) {
@@ -101,6 +109,19 @@ func LoadPool(ipPool *networkv1.IPPool) (pi PoolInfo, err error) {
 		}
 	}
 
+	for _, r := range ipPool.Spec.IPv4Config.Pool.Ranges {
+		var ipAddrRange IPAddrRange
+		ipAddrRange.StartIPAddr, err = netip.ParseAddr(r.Start)
+		if err != nil {
+			return
+		}
+		ipAddrRange.EndIPAddr, err = netip.ParseAddr(r.End)
+		if err != nil {
+			return
+		}
+		pi.Ranges = append(pi.Ranges, ipAddrRange)
+	}
+
 	if ipPool.Spec.IPv4Config.ServerIP != "" {
 		pi.ServerIPAddr, err = netip.ParseAddr(ipPool.Spec.IPv4Config.ServerIP)
 		if err != nil {
@@ -148,6 +169,39 @@ func IsIPAddrInList(ipAddr netip.Addr, ipAddrList []netip.Addr) bool {
 	return false
 }
 
+// IsIPInPool tells if ip is in any range of pool.
+func IsIPInPool(ip string, pool networkv1.Pool) bool {
+	if IsIPInBetweenOf(ip, pool.Start, pool.End) {
+		return true
+	}
+	for _, r := range pool.Ranges {
+		if IsIPInBetweenOf(ip, r.Start, r.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIPExcluded tells if ip is one of the excluded IP addresses of pool.
+func IsIPExcluded(ip string, pool networkv1.Pool) bool {
+	for _, eIP := range pool.Exclude {
+		if eIP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// ReservationOwner returns who the IP address of the reservation is held for
+// in the IPAM, i.e., the lower-cased MAC address or the namespaced name of the
+// VM.
+func ReservationOwner(reservation networkv1.Reservation) string {
+	if reservation.MACAddress != "" {
+		return strings.ToLower(reservation.MACAddress)
+	}
+	return reservation.VM
+}
+
 func IsIPInBetweenOf(ip, ip1, ip2 string) bool {
 	ipAddr, err := netip.ParseAddr(ip)
 	if err != nil {
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseIPAddressesAnnotation returns the IPv4 addresses the network
+// interfaces ask for by the annotation of the VM, keyed by the interface
+// names, or nil if there's no such annotation.
+func ParseIPAddressesAnnotation(annotations map[string]string) (map[string]string, error) {
+	val, ok := annotations[IPAddressesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var ipAddresses map[string]string
+	if err := json.Unmarshal([]byte(val), &ipAddresses); err != nil {
+		return nil, fmt.Errorf("annotation %s is malformed: %w", IPAddressesAnnotationKey, err)
+	}
+
+	return ipAddresses, nil
+}
@@ -137,12 +137,25 @@ func ensureServerIP(server string, cidr, router string, excludes []string) (*str
 }
 
 func ensurePoolRange(pool networkv1.Pool, cidr string) (*networkv1.Pool, error) {
-	startIPAddr, err := netip.ParseAddr(pool.Start)
+	newPool := *pool.DeepCopy()
+
+	// Take the first of the ranges as the one from the start to the end IP
+	// address if there's none
+	if newPool.Start == "" && newPool.End == "" && len(newPool.Ranges) > 0 {
+		newPool.Start = newPool.Ranges[0].Start
+		newPool.End = newPool.Ranges[0].End
+		newPool.Ranges = newPool.Ranges[1:]
+		if len(newPool.Ranges) == 0 {
+			newPool.Ranges = nil
+		}
+	}
+
+	startIPAddr, err := netip.ParseAddr(newPool.Start)
 	if err != nil {
 		startIPAddr = netip.Addr{}
 	}
 
-	endIPAddr, err := netip.ParseAddr(pool.End)
+	endIPAddr, err := netip.ParseAddr(newPool.End)
 	if err != nil {
 		endIPAddr = netip.Addr{}
 	}
@@ -152,8 +165,6 @@ func ensurePoolRange(pool networkv1.Pool, cidr string) (*networkv1.Pool, error)
 		return nil, err
 	}
 
-	newPool := pool
-
 	if !startIPAddr.IsValid() {
 		startIPAddr = networkIPAddr.Next()
 
@@ -217,6 +217,34 @@ func TestMutator_Create(t *testing.T) {
 				err: fmt.Errorf("cannot create IPPool %s/%s because fail to assign ip for dhcp server", testIPPoolNamespace, testIPPoolName),
 			},
 		},
+		{
+			given: input{
+				name: "ippool with ranges but start and end ips undefined",
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					ServerIP("192.168.0.2").
+					Range("192.168.0.10", "192.168.0.49").
+					Range("192.168.0.100", "192.168.0.149").Build(),
+			},
+			expected: output{
+				patch: admission.Patch{
+					{
+						Op:   admission.PatchOpReplace,
+						Path: "/spec/ipv4Config/pool",
+						Value: networkv1.Pool{
+							Start: "192.168.0.10",
+							End:   "192.168.0.49",
+							Ranges: []networkv1.IPRange{
+								{
+									Start: "192.168.0.100",
+									End:   "192.168.0.149",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			given: input{
 				name: "server ip and router are in the middle of pool range",
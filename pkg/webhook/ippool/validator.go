@@ -1,40 +1,55 @@
 package ippool
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/netip"
+	"sort"
 	"strings"
 
 	"github.com/harvester/webhook/pkg/server/admission"
 	"github.com/rancher/wrangler/pkg/kv"
 	"github.com/sirupsen/logrus"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io"
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
 	ctlcniv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/k8s.cni.cncf.io/v1"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
+	"github.com/harvester/vm-dhcp-controller/pkg/ra"
 	"github.com/harvester/vm-dhcp-controller/pkg/util"
 	"github.com/harvester/vm-dhcp-controller/pkg/webhook"
 )
 
+const clusterNetworkLabelKey = network.GroupName + "/clusternetwork"
+
 type Validator struct {
 	admission.DefaultValidator
 
 	serviceCIDR string
 
 	nadCache      ctlcniv1.NetworkAttachmentDefinitionCache
+	ippoolCache   ctlnetworkv1.IPPoolCache
 	vmnetcfgCache ctlnetworkv1.VirtualMachineNetworkConfigCache
 }
 
 func NewValidator(
 	serviceCIDR string,
 	nadCache ctlcniv1.NetworkAttachmentDefinitionCache,
+	ippoolCache ctlnetworkv1.IPPoolCache,
 	vmnetcfgCache ctlnetworkv1.VirtualMachineNetworkConfigCache,
 ) *Validator {
 	return &Validator{
 		serviceCIDR:   serviceCIDR,
 		nadCache:      nadCache,
+		ippoolCache:   ippoolCache,
 		vmnetcfgCache: vmnetcfgCache,
 	}
 }
@@ -53,10 +68,18 @@ func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
 		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkNetworkName(ipPool); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	if err := v.checkCIDR(ipPool.Spec.IPv4Config.CIDR); err != nil {
 		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkOverlappingIPPools(ipPool); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	if err := v.checkPoolRange(poolInfo); err != nil {
 		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
@@ -69,10 +92,43 @@ func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
 		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkReservations(ipPool); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkRoutes(poolInfo, ipPool.Spec.IPv4Config.Routes); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := dhcp.CheckCustomOptions(ipPool.Spec.IPv4Config.CustomOptions, nil); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkIPv6Config(ipPool.Spec.IPv6Config); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkServedBy(ipPool); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkReclaimPolicy(ipPool.Spec.Reclaim); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkQuarantineDuration(ipPool.Spec.IPv4Config.QuarantineDuration); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if _, err := util.ParseAgentPodTemplateAnnotation(ipPool.Annotations); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	return nil
 }
 
-func (v *Validator) Update(_ *admission.Request, _, newObj runtime.Object) error {
+func (v *Validator) Update(_ *admission.Request, oldObj, newObj runtime.Object) error {
+	oldIPPool := oldObj.(*networkv1.IPPool)
 	ipPool := newObj.(*networkv1.IPPool)
 
 	if ipPool.DeletionTimestamp != nil {
@@ -99,10 +155,18 @@ func (v *Validator) Update(_ *admission.Request, _, newObj runtime.Object) error
 		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkNetworkName(ipPool); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	if err := v.checkCIDR(ipPool.Spec.IPv4Config.CIDR); err != nil {
 		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkOverlappingIPPools(ipPool); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	if err := v.checkPoolRange(poolInfo); err != nil {
 		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
@@ -115,6 +179,46 @@ func (v *Validator) Update(_ *admission.Request, _, newObj runtime.Object) error
 		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
 	}
 
+	if err := v.checkAllocated(ipPool); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkReservations(ipPool); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkRoutes(poolInfo, ipPool.Spec.IPv4Config.Routes); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	var oldCustomOptions []networkv1.DHCPOption
+	if oldIPPool != nil {
+		oldCustomOptions = oldIPPool.Spec.IPv4Config.CustomOptions
+	}
+	if err := dhcp.CheckCustomOptions(ipPool.Spec.IPv4Config.CustomOptions, oldCustomOptions); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkIPv6Config(ipPool.Spec.IPv6Config); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkServedBy(ipPool); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkReclaimPolicy(ipPool.Spec.Reclaim); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if err := v.checkQuarantineDuration(ipPool.Spec.IPv4Config.QuarantineDuration); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
+	if _, err := util.ParseAgentPodTemplateAnnotation(ipPool.Annotations); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "IPPool", ipPool.Namespace, ipPool.Name, err)
+	}
+
 	return nil
 }
 
@@ -172,37 +276,288 @@ func (v *Validator) checkCIDR(cidr string) error {
 	return nil
 }
 
+// checkNetworkName checks whether the network of the IPPool is free of other
+// IPPools. The IPAM and MAC caches are keyed by network names, and an agent is
+// deployed for each IPPool, so two IPPools of a network would clobber each
+// other.
+func (v *Validator) checkNetworkName(ipPool *networkv1.IPPool) error {
+	networkName := indexer.NamespacedNetworkName(ipPool.Spec.NetworkName)
+	ipPools, err := v.ippoolCache.GetByIndex(indexer.IPPoolByNetworkIndex, networkName)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range ipPools {
+		if other.Namespace == ipPool.Namespace && other.Name == ipPool.Name {
+			continue
+		}
+		return fmt.Errorf("network %s is already served by ippool %s/%s", ipPool.Spec.NetworkName, other.Namespace, other.Name)
+	}
+
+	return nil
+}
+
+// checkOverlappingIPPools checks whether the CIDR of the IPPool overlaps with
+// the ones of the other IPPools on the same L2 segment, i.e., the same cluster
+// network and VLAN. Overlapping CIDRs on different VLANs of the same cluster
+// network are allowed but logged, as they're likely to be mistakes.
+func (v *Validator) checkOverlappingIPPools(ipPool *networkv1.IPPool) error {
+	ipNet, _, _, err := util.LoadCIDR(ipPool.Spec.IPv4Config.CIDR)
+	if err != nil {
+		return nil
+	}
+
+	clusterNetwork, vlan, err := v.getNetworkSegment(ipPool.Spec.NetworkName)
+	if err != nil || clusterNetwork == "" {
+		return err
+	}
+
+	ipPools, err := v.ippoolCache.List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+	sort.Slice(ipPools, func(i, j int) bool {
+		return ipPools[i].Namespace+"/"+ipPools[i].Name < ipPools[j].Namespace+"/"+ipPools[j].Name
+	})
+
+	for _, other := range ipPools {
+		if other.Namespace == ipPool.Namespace && other.Name == ipPool.Name {
+			continue
+		}
+
+		otherIPNet, _, _, err := util.LoadCIDR(other.Spec.IPv4Config.CIDR)
+		if err != nil {
+			continue
+		}
+		if !ipNet.Contains(otherIPNet.IP) && !otherIPNet.Contains(ipNet.IP) {
+			continue
+		}
+
+		otherClusterNetwork, otherVLAN, err := v.getNetworkSegment(other.Spec.NetworkName)
+		if err != nil || otherClusterNetwork != clusterNetwork {
+			continue
+		}
+		if otherVLAN == vlan {
+			return fmt.Errorf("cidr %s overlaps cidr %s of ippool %s/%s on cluster network %s vlan %d", ipNet, otherIPNet, other.Namespace, other.Name, clusterNetwork, vlan)
+		}
+		logrus.Warnf("cidr %s of ippool %s/%s overlaps cidr %s of ippool %s/%s on cluster network %s", ipNet, ipPool.Namespace, ipPool.Name, otherIPNet, other.Namespace, other.Name, clusterNetwork)
+	}
+
+	return nil
+}
+
+// getNetworkSegment returns the cluster network and the VLAN ID of the
+// NetworkAttachmentDefinition, or an empty cluster network if it's unknown.
+func (v *Validator) getNetworkSegment(namespacedName string) (clusterNetwork string, vlan int, err error) {
+	nadNamespace, nadName := kv.RSplit(namespacedName, "/")
+	if nadNamespace == "" {
+		nadNamespace = "default"
+	}
+
+	nad, err := v.nadCache.Get(nadNamespace, nadName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var config struct {
+		VLAN int `json:"vlan"`
+	}
+	if nad.Spec.Config != "" {
+		if err := json.Unmarshal([]byte(nad.Spec.Config), &config); err != nil {
+			return "", 0, fmt.Errorf("config of nad %s is malformed: %w", namespacedName, err)
+		}
+	}
+
+	return nad.Labels[clusterNetworkLabelKey], config.VLAN, nil
+}
+
+// checkPoolRange checks whether the start and end IP addresses of each range
+// of the pool:
+//   - are WITHIN the CIDR
+//   - are NOT the network IP address
+//   - are NOT the broadcast IP address
+//
+// and that none of the ranges are reversed or overlap.
 func (v *Validator) checkPoolRange(pi util.PoolInfo) error {
-	if pi.StartIPAddr.IsValid() {
-		if !pi.IPNet.Contains(pi.StartIPAddr.AsSlice()) {
-			return fmt.Errorf("start ip %s is not within subnet", pi.StartIPAddr)
+	ranges := append([]util.IPAddrRange{{StartIPAddr: pi.StartIPAddr, EndIPAddr: pi.EndIPAddr}}, pi.Ranges...)
+
+	for _, r := range ranges {
+		if err := v.checkPoolEndpoint(pi, Start, r.StartIPAddr); err != nil {
+			return err
 		}
+		if err := v.checkPoolEndpoint(pi, End, r.EndIPAddr); err != nil {
+			return err
+		}
+	}
 
-		if pi.StartIPAddr.As4() == pi.NetworkIPAddr.As4() {
-			return fmt.Errorf("start ip %s is the same as network ip", pi.StartIPAddr)
+	var completeRanges []util.IPAddrRange
+	for _, r := range ranges {
+		if !r.StartIPAddr.IsValid() || !r.EndIPAddr.IsValid() {
+			continue
 		}
+		if r.StartIPAddr.Compare(r.EndIPAddr) > 0 {
+			return fmt.Errorf("end ip %s is less than start ip %s", r.EndIPAddr, r.StartIPAddr)
+		}
+		completeRanges = append(completeRanges, r)
+	}
 
-		if pi.StartIPAddr.As4() == pi.BroadcastIPAddr.As4() {
-			return fmt.Errorf("start ip %s is the same as broadcast ip", pi.StartIPAddr)
+	sort.Slice(completeRanges, func(i, j int) bool {
+		return completeRanges[i].StartIPAddr.Less(completeRanges[j].StartIPAddr)
+	})
+	for i := 1; i < len(completeRanges); i++ {
+		prev, r := completeRanges[i-1], completeRanges[i]
+		if r.StartIPAddr.Compare(prev.EndIPAddr) <= 0 {
+			return fmt.Errorf("ip range %s-%s overlaps with ip range %s-%s", r.StartIPAddr, r.EndIPAddr, prev.StartIPAddr, prev.EndIPAddr)
 		}
 	}
 
-	if pi.EndIPAddr.IsValid() {
-		if !pi.IPNet.Contains(pi.EndIPAddr.AsSlice()) {
-			return fmt.Errorf("end ip %s is not within subnet", pi.EndIPAddr)
+	return nil
+}
+
+func (v *Validator) checkPoolEndpoint(pi util.PoolInfo, endpointType EndpointType, ipAddr netip.Addr) error {
+	if !ipAddr.IsValid() {
+		return nil
+	}
+
+	if !pi.IPNet.Contains(ipAddr.AsSlice()) {
+		return fmt.Errorf("%s ip %s is not within subnet", endpointType, ipAddr)
+	}
+
+	if ipAddr.As4() == pi.NetworkIPAddr.As4() {
+		return fmt.Errorf("%s ip %s is the same as network ip", endpointType, ipAddr)
+	}
+
+	if ipAddr.As4() == pi.BroadcastIPAddr.As4() {
+		return fmt.Errorf("%s ip %s is the same as broadcast ip", endpointType, ipAddr)
+	}
+
+	return nil
+}
+
+// checkAllocated checks whether the IP addresses allocated to network
+// interfaces are all still within the pool range and not excluded, so that
+// none of them is orphaned by changing the pool.
+func (v *Validator) checkAllocated(ipPool *networkv1.IPPool) error {
+	if ipPool.Status.IPv4 == nil {
+		return nil
+	}
+
+	ips := make([]string, 0, len(ipPool.Status.IPv4.Allocated))
+	for ip := range ipPool.Status.IPv4.Allocated {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	for _, ip := range ips {
+		mac := ipPool.Status.IPv4.Allocated[ip]
+		if util.IsMark(mac) {
+			continue
 		}
+		if !util.IsIPInPool(ip, ipPool.Spec.IPv4Config.Pool) {
+			return fmt.Errorf("ip %s allocated to %s is not within the pool range", ip, mac)
+		}
+		if util.IsIPExcluded(ip, ipPool.Spec.IPv4Config.Pool) {
+			return fmt.Errorf("ip %s allocated to %s cannot be excluded", ip, mac)
+		}
+	}
 
-		if pi.EndIPAddr.As4() == pi.NetworkIPAddr.As4() {
-			return fmt.Errorf("end ip %s is the same as network ip", pi.EndIPAddr)
+	return nil
+}
+
+// checkReservations checks whether each of the reservations:
+//   - has an IP address WITHIN the pool range
+//   - has an IP address which is NOT excluded
+//   - has an IP address which is NOT the server or router IP address
+//   - does NOT share the IP address, MAC address, or VM with another one
+//   - does NOT hold an IP address allocated to some other network interface
+func (v *Validator) checkReservations(ipPool *networkv1.IPPool) error {
+	var allocated map[string]string
+	if ipPool.Status.IPv4 != nil {
+		allocated = ipPool.Status.IPv4.Allocated
+	}
+
+	ips := make(map[string]bool)
+	owners := make(map[string]bool)
+	for _, r := range ipPool.Spec.IPv4Config.Reservations {
+		switch {
+		case r.MACAddress != "":
+			if _, err := net.ParseMAC(r.MACAddress); err != nil {
+				return fmt.Errorf("mac address %s of reserved ip %s is invalid", r.MACAddress, r.IPAddress)
+			}
+		case r.VM != "":
+			if vmNamespace, vmName := kv.RSplit(r.VM, "/"); vmNamespace == "" || vmName == "" {
+				return fmt.Errorf("vm %s of reserved ip %s is not a namespaced name", r.VM, r.IPAddress)
+			}
+		default:
+			return fmt.Errorf("reserved ip %s has neither mac address nor vm", r.IPAddress)
 		}
 
-		if pi.EndIPAddr.As4() == pi.BroadcastIPAddr.As4() {
-			return fmt.Errorf("end ip %s is the same as broadcast ip", pi.EndIPAddr)
+		owner := util.ReservationOwner(r)
+
+		if !util.IsIPInPool(r.IPAddress, ipPool.Spec.IPv4Config.Pool) {
+			return fmt.Errorf("reserved ip %s is not within the pool range", r.IPAddress)
+		}
+		if util.IsIPExcluded(r.IPAddress, ipPool.Spec.IPv4Config.Pool) {
+			return fmt.Errorf("reserved ip %s cannot be excluded", r.IPAddress)
+		}
+		if r.IPAddress == ipPool.Spec.IPv4Config.ServerIP {
+			return fmt.Errorf("reserved ip %s is the same as server ip", r.IPAddress)
+		}
+		if r.IPAddress == ipPool.Spec.IPv4Config.Router {
+			return fmt.Errorf("reserved ip %s is the same as router ip", r.IPAddress)
+		}
+		if ips[r.IPAddress] {
+			return fmt.Errorf("ip %s is reserved more than once", r.IPAddress)
+		}
+		ips[r.IPAddress] = true
+		if owners[owner] {
+			return fmt.Errorf("%s has more than one reservation", owner)
+		}
+		owners[owner] = true
+
+		mac, ok := allocated[r.IPAddress]
+		if !ok || util.IsMark(mac) {
+			continue
+		}
+		if r.MACAddress != "" {
+			if !strings.EqualFold(r.MACAddress, mac) {
+				return fmt.Errorf("reserved ip %s is already allocated to %s", r.IPAddress, mac)
+			}
+			continue
+		}
+		ok, err := v.isVMInterface(r.VM, mac)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("reserved ip %s is already allocated to %s", r.IPAddress, mac)
 		}
 	}
+
 	return nil
 }
 
+// isVMInterface tells if the MAC address is of a network interface of the VM,
+// which is given by its namespaced name.
+func (v *Validator) isVMInterface(vm, macAddress string) (bool, error) {
+	vmNamespace, vmName := kv.RSplit(vm, "/")
+	vmNetCfg, err := v.vmnetcfgCache.Get(vmNamespace, vmName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, nc := range vmNetCfg.Spec.NetworkConfigs {
+		if strings.EqualFold(nc.MACAddress, macAddress) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // checkServerIP checks whether the server IP address:
 //   - is WITHIN the CIDR
 //   - is NOT the network IP address
@@ -210,10 +565,10 @@ func (v *Validator) checkPoolRange(pi util.PoolInfo) error {
 //   - is NOT the same as the router IP address (if there is one)
 //   - does NOT collide with any allocated or excluded IP addresses
 //
-// It does not compare the server IP address with any reserved ones, as
-// currently, the reserved IP addresses could only be the server or router IP
-// address. So the unallocatables slice only consists of the allocated and
-// excluded IP addresses.
+// It does not compare the server IP address with the reserved ones of
+// spec.ipv4Config.reservations, as checkReservations already rejects
+// reservations on the server IP address. So the unallocatables slice only
+// consists of the allocated and excluded IP addresses.
 func (v *Validator) checkServerIP(pi util.PoolInfo, unallocatables ...netip.Addr) error {
 	if !pi.ServerIPAddr.IsValid() {
 		return nil
@@ -264,6 +619,152 @@ func (v *Validator) checkRouter(pi util.PoolInfo) error {
 	return nil
 }
 
+// checkRoutes checks whether each of the static routes:
+//   - has a valid IPv4 destination CIDR
+//   - has a gateway WITHIN the CIDR
+//   - has a gateway which is NOT the network IP address
+//   - has a gateway which is NOT the broadcast IP address
+func (v *Validator) checkRoutes(pi util.PoolInfo, routes []networkv1.Route) error {
+	for _, route := range routes {
+		_, dest, err := net.ParseCIDR(route.Destination)
+		if err != nil || dest.IP.To4() == nil {
+			return fmt.Errorf("route destination %s is not a valid ipv4 cidr", route.Destination)
+		}
+
+		gatewayIPAddr, err := netip.ParseAddr(route.Gateway)
+		if err != nil {
+			return err
+		}
+
+		if !pi.IPNet.Contains(gatewayIPAddr.AsSlice()) {
+			return fmt.Errorf("route gateway ip %s is not within subnet", gatewayIPAddr)
+		}
+
+		if gatewayIPAddr == pi.NetworkIPAddr {
+			return fmt.Errorf("route gateway ip %s is the same as network ip", gatewayIPAddr)
+		}
+
+		if gatewayIPAddr == pi.BroadcastIPAddr {
+			return fmt.Errorf("route gateway ip %s is the same as broadcast ip", gatewayIPAddr)
+		}
+	}
+
+	return nil
+}
+
+// checkIPv6Config checks whether the IPv6 configuration, if any:
+//   - has a valid IPv6 prefix
+//   - has a pool range WITHIN the prefix, with start NOT greater than end
+//   - has excluded IP addresses WITHIN the pool range
+//   - has valid IPv6 DNS servers
+//   - has a valid Router Advertisement configuration
+func (v *Validator) checkIPv6Config(ipv6Config *networkv1.IPv6Config) error {
+	if ipv6Config == nil {
+		return nil
+	}
+
+	prefix, err := netip.ParsePrefix(ipv6Config.Prefix)
+	if err != nil || !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return fmt.Errorf("prefix %s is not a valid ipv6 cidr", ipv6Config.Prefix)
+	}
+
+	parseIPv6 := func(ip string) (netip.Addr, error) {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil || !addr.Is6() || addr.Is4In6() {
+			return netip.Addr{}, fmt.Errorf("%s is not a valid ipv6 address", ip)
+		}
+		return addr, nil
+	}
+
+	start, err := parseIPv6(ipv6Config.Pool.Start)
+	if err != nil {
+		return err
+	}
+	if !prefix.Contains(start) {
+		return fmt.Errorf("start ip %s is not within prefix %s", start, prefix)
+	}
+
+	end, err := parseIPv6(ipv6Config.Pool.End)
+	if err != nil {
+		return err
+	}
+	if !prefix.Contains(end) {
+		return fmt.Errorf("end ip %s is not within prefix %s", end, prefix)
+	}
+
+	if start.Compare(end) > 0 {
+		return fmt.Errorf("end ip %s is less than start ip %s", end, start)
+	}
+
+	for _, eIP := range ipv6Config.Pool.Exclude {
+		excluded, err := parseIPv6(eIP)
+		if err != nil {
+			return err
+		}
+		if excluded.Compare(start) < 0 || excluded.Compare(end) > 0 {
+			return fmt.Errorf("excluded ip %s is not within range %s-%s", excluded, start, end)
+		}
+	}
+
+	for _, dns := range ipv6Config.DNS {
+		if _, err := parseIPv6(dns); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ra.NewConfig(ipv6Config); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkServedBy checks whether the IPPool serving ipPool, if any, is referred
+// to by its namespaced name other than the one of ipPool.
+func (v *Validator) checkServedBy(ipPool *networkv1.IPPool) error {
+	if ipPool.Spec.ServedBy == "" {
+		return nil
+	}
+
+	namespace, name := kv.RSplit(ipPool.Spec.ServedBy, "/")
+	if namespace == "" || name == "" {
+		return fmt.Errorf("served by %s is not a namespaced name", ipPool.Spec.ServedBy)
+	}
+
+	if namespace == ipPool.Namespace && name == ipPool.Name {
+		return fmt.Errorf("ippool cannot be served by itself")
+	}
+
+	return nil
+}
+
+// checkReclaimPolicy checks whether the periods of the reclaim policy, if
+// any, are positive.
+func (v *Validator) checkReclaimPolicy(policy *networkv1.ReclaimPolicy) error {
+	if policy == nil {
+		return nil
+	}
+
+	if policy.InactivePeriod != nil && policy.InactivePeriod.Duration <= 0 {
+		return fmt.Errorf("inactive period %s is not positive", policy.InactivePeriod.Duration)
+	}
+
+	if policy.StoppedGracePeriod != nil && policy.StoppedGracePeriod.Duration <= 0 {
+		return fmt.Errorf("stopped grace period %s is not positive", policy.StoppedGracePeriod.Duration)
+	}
+
+	return nil
+}
+
+// checkQuarantineDuration checks whether the quarantine duration, if any, is
+// not negative. Zero means released IP addresses are not quarantined.
+func (v *Validator) checkQuarantineDuration(quarantineDuration *metav1.Duration) error {
+	if quarantineDuration != nil && quarantineDuration.Duration < 0 {
+		return fmt.Errorf("quarantine duration %s is negative", quarantineDuration.Duration)
+	}
+	return nil
+}
+
 func (v *Validator) checkVmNetCfgs(ipPool *networkv1.IPPool) error {
 	vmnetcfgGetter := util.VmnetcfgGetter{
 		VmnetcfgCache: v.vmnetcfgCache,
@@ -3,6 +3,7 @@ package ippool
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/harvester/webhook/pkg/server/admission"
 	cniv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
@@ -32,6 +33,7 @@ const (
 	testRouter              = "192.168.0.1"
 	testExcludedIP          = "192.168.0.100"
 	testNetworkName         = testNADNamespace + "/" + testNADName
+	testClusterNetwork      = "provider"
 )
 
 func newTestIPPoolBuilder() *ippool.IPPoolBuilder {
@@ -44,9 +46,11 @@ func newTestNetworkAttachmentDefinitionBuilder() *ippool.NetworkAttachmentDefini
 
 func TestValidator_Create(t *testing.T) {
 	type input struct {
-		ipPool *networkv1.IPPool
-		nad    *cniv1.NetworkAttachmentDefinition
-		node   *corev1.Node
+		ipPool       *networkv1.IPPool
+		nad          *cniv1.NetworkAttachmentDefinition
+		node         *corev1.Node
+		otherIPPools []*networkv1.IPPool
+		otherNADs    []*cniv1.NetworkAttachmentDefinition
 	}
 
 	type output struct {
@@ -173,108 +177,734 @@ func TestValidator_Create(t *testing.T) {
 				err: fmt.Errorf("cannot create IPPool %s/%s because router ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
 			},
 		},
+		{
+			name: "valid routes",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Route("10.10.0.0/16", "192.168.0.3").
+					Route("172.16.0.0/12", "192.168.0.4").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid route destination which is malformed",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Route("10.10.0.0", "192.168.0.3").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because route destination %s is not a valid ipv4 cidr", testIPPoolNamespace, testIPPoolName, "10.10.0.0"),
+			},
+		},
+		{
+			name: "invalid route gateway which is out of subnet",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Route("10.10.0.0/16", "192.168.1.3").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because route gateway ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.3"),
+			},
+		},
+		{
+			name: "invalid route gateway which is the same as broadcast ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Route("10.10.0.0/16", "192.168.0.255").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because route gateway ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
+			},
+		},
+		{
+			name: "valid custom options",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					CustomOption(44, networkv1.DHCPOptionTypeIPList, "192.168.0.5,192.168.0.6").
+					CustomOption(150, networkv1.DHCPOptionTypeIP, "192.168.0.7").
+					CustomOption(252, networkv1.DHCPOptionTypeString, "http://192.168.0.8/wpad.dat").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid custom option with unknown type",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					CustomOption(150, "ipv6", "fd00::1").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because custom option %d type %s is unknown", testIPPoolNamespace, testIPPoolName, 150, "ipv6"),
+			},
+		},
+		{
+			name: "invalid custom option with malformed value",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					CustomOption(150, networkv1.DHCPOptionTypeUint8, "256").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because custom option %d value %s is not a valid uint8", testIPPoolNamespace, testIPPoolName, 150, "256"),
+			},
+		},
+		{
+			name: "invalid custom option which is managed by the server",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					CustomOption(6, networkv1.DHCPOptionTypeIP, "192.168.0.5").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because custom option %d is managed by the server", testIPPoolNamespace, testIPPoolName, 6),
+			},
+		},
+		{
+			name: "invalid custom option which is duplicated",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					CustomOption(150, networkv1.DHCPOptionTypeIP, "192.168.0.5").
+					CustomOption(150, networkv1.DHCPOptionTypeIP, "192.168.0.6").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because custom option %d is duplicated", testIPPoolNamespace, testIPPoolName, 150),
+			},
+		},
+		{
+			name: "valid ipv6 config",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::100", "fd00:48::1ff").
+					IPv6Exclude("fd00:48::150").
+					IPv6DNS("2606:4700:4700::1111").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid ipv6 prefix which is ipv4",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("192.168.1.0/24", "fd00:48::100", "fd00:48::1ff").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because prefix %s is not a valid ipv6 cidr", testIPPoolNamespace, testIPPoolName, "192.168.1.0/24"),
+			},
+		},
+		{
+			name: "invalid ipv6 start ip which is out of prefix",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:49::100", "fd00:48::1ff").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is not within prefix %s", testIPPoolNamespace, testIPPoolName, "fd00:49::100", "fd00:48::/64"),
+			},
+		},
+		{
+			name: "invalid ipv6 end ip which is less than start ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::1ff", "fd00:48::100").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is less than start ip %s", testIPPoolNamespace, testIPPoolName, "fd00:48::100", "fd00:48::1ff"),
+			},
+		},
+		{
+			name: "invalid ipv6 excluded ip which is out of range",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::100", "fd00:48::1ff").
+					IPv6Exclude("fd00:48::200").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because excluded ip %s is not within range %s-%s", testIPPoolNamespace, testIPPoolName, "fd00:48::200", "fd00:48::100", "fd00:48::1ff"),
+			},
+		},
+		{
+			name: "invalid ipv6 dns server which is ipv4",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::100", "fd00:48::1ff").
+					IPv6DNS("1.1.1.1").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because %s is not a valid ipv6 address", testIPPoolNamespace, testIPPoolName, "1.1.1.1"),
+			},
+		},
+		{
+			name: "valid ipv6 config with router advertisement",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::100", "fd00:48::1ff").
+					IPv6RouterAdvertisement(networkv1.RAModeStateless, nil, nil).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid router advertisement for slaac with non-64 prefix",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/56", "fd00:48::100", "fd00:48::1ff").
+					IPv6RouterAdvertisement(networkv1.RAModeSLAAC, nil, nil).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because prefix %s must be a /64 for slaac", testIPPoolNamespace, testIPPoolName, "fd00:48::/56"),
+			},
+		},
+		{
+			name: "invalid router advertisement with router lifetime shorter than interval",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					IPv6Config("fd00:48::/64", "fd00:48::100", "fd00:48::1ff").
+					IPv6RouterAdvertisement(networkv1.RAModeStateful, func(i int) *int { return &i }(600), func(i int) *int { return &i }(300)).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because router lifetime %d must be 0 or no less than interval %d", testIPPoolNamespace, testIPPoolName, 300, 600),
+			},
+		},
+		{
+			name: "valid served by",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					ServedBy("default/transit").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid served by which is not a namespaced name",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					ServedBy("transit").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because served by %s is not a namespaced name", testIPPoolNamespace, testIPPoolName, "transit"),
+			},
+		},
+		{
+			name: "invalid served by which is the ippool itself",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					ServedBy(testIPPoolNamespace + "/" + testIPPoolName).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because ippool cannot be served by itself", testIPPoolNamespace, testIPPoolName),
+			},
+		},
+		{
+			name: "valid reclaim policy",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Reclaim(&metav1.Duration{Duration: 720 * time.Hour}, &metav1.Duration{Duration: 168 * time.Hour}).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid reclaim policy with non-positive inactive period",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Reclaim(&metav1.Duration{}, nil).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because inactive period %s is not positive", testIPPoolNamespace, testIPPoolName, "0s"),
+			},
+		},
+		{
+			name: "invalid quarantine duration which is negative",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					QuarantineDuration(&metav1.Duration{Duration: -time.Hour}).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because quarantine duration %s is negative", testIPPoolNamespace, testIPPoolName, "-1h0m0s"),
+			},
+		},
+		{
+			name: "valid agent pod template",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Annotation(util.AgentPodTemplateAnnotationKey, `{"spec":{"priorityClassName":"system-node-critical","tolerations":[{"key":"network","operator":"Exists"}]}}`).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid agent pod template which sets the service account",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					Annotation(util.AgentPodTemplateAnnotationKey, `{"spec":{"serviceAccountName":"admin"}}`).
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because annotation %s may only set the node selector, tolerations, priority class and container resources", testIPPoolNamespace, testIPPoolName, util.AgentPodTemplateAnnotationKey),
+			},
+		},
 		{
 			name: "invalid start ip which is malformed",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("192.168.0.1000", "").
+					PoolRange("192.168.0.1000", "").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because ParseAddr(\"%s\"): IPv4 field has value >255", testIPPoolNamespace, testIPPoolName, "192.168.0.1000"),
+			},
+		},
+		{
+			name: "invalid start ip which is out of subnet",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.1.100", "").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.100"),
+			},
+		},
+		{
+			name: "invalid start ip which is the same as network ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.0", "").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is the same as network ip", testIPPoolNamespace, testIPPoolName, "192.168.0.0"),
+			},
+		},
+		{
+			name: "invalid start ip which is the same as broadcast ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.255", "").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
+			},
+		},
+		{
+			name: "invalid end ip which is malformed",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("", "192.168.0.1000").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because ParseAddr(\"%s\"): IPv4 field has value >255", testIPPoolNamespace, testIPPoolName, "192.168.0.1000"),
+			},
+		},
+		{
+			name: "invalid end ip which is out of subnet",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("", "192.168.1.100").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.100"),
+			},
+		},
+		{
+			name: "invalid emd ip which is the same as network ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("", "192.168.0.0").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is the same as network ip", testIPPoolNamespace, testIPPoolName, "192.168.0.0"),
+			},
+		},
+		{
+			name: "invalid end ip which is the same as broadcast ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("", "192.168.0.255").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
+			},
+		},
+		{
+			name: "valid multiple pool ranges",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Range("192.168.0.100", "192.168.0.149").
+					Range("192.168.0.200", "192.168.0.249").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid pool range which is out of subnet",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Range("192.168.0.100", "192.168.1.149").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.149"),
+			},
+		},
+		{
+			name: "invalid pool range whose end ip is less than start ip",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Range("192.168.0.149", "192.168.0.100").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is less than start ip %s", testIPPoolNamespace, testIPPoolName, "192.168.0.100", "192.168.0.149"),
+			},
+		},
+		{
+			name: "invalid pool ranges which overlap",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Range("192.168.0.100", "192.168.0.149").
+					Range("192.168.0.40", "192.168.0.59").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because ParseAddr(\"%s\"): IPv4 field has value >255", testIPPoolNamespace, testIPPoolName, "192.168.0.1000"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because ip range %s-%s overlaps with ip range %s-%s", testIPPoolNamespace, testIPPoolName, "192.168.0.40", "192.168.0.59", "192.168.0.10", "192.168.0.49"),
 			},
 		},
 		{
-			name: "invalid start ip which is out of subnet",
+			name: "valid reservations",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("192.168.1.100", "").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "11:22:33:44:55:66", "").
+					Reservation("192.168.0.11", "", "default/vm-1").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid reservation with malformed mac address",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "11:22:33:44:55", "").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.100"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because mac address %s of reserved ip %s is invalid", testIPPoolNamespace, testIPPoolName, "11:22:33:44:55", "192.168.0.10"),
 			},
 		},
 		{
-			name: "invalid start ip which is the same as network ip",
+			name: "invalid reservation with vm lacking namespace",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("192.168.0.0", "").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "", "vm-1").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is the same as network ip", testIPPoolNamespace, testIPPoolName, "192.168.0.0"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because vm %s of reserved ip %s is not a namespaced name", testIPPoolNamespace, testIPPoolName, "vm-1", "192.168.0.10"),
 			},
 		},
 		{
-			name: "invalid start ip which is the same as broadcast ip",
+			name: "invalid reservation without owner",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("192.168.0.255", "").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "", "").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because start ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because reserved ip %s has neither mac address nor vm", testIPPoolNamespace, testIPPoolName, "192.168.0.10"),
 			},
 		},
 		{
-			name: "invalid end ip which is malformed",
+			name: "invalid reservation which is out of the pool range",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("", "192.168.0.1000").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.50", "11:22:33:44:55:66", "").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because ParseAddr(\"%s\"): IPv4 field has value >255", testIPPoolNamespace, testIPPoolName, "192.168.0.1000"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because reserved ip %s is not within the pool range", testIPPoolNamespace, testIPPoolName, "192.168.0.50"),
 			},
 		},
 		{
-			name: "invalid end ip which is out of subnet",
+			name: "invalid reservation which is excluded",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("", "192.168.1.100").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Exclude("192.168.0.10").
+					Reservation("192.168.0.10", "11:22:33:44:55:66", "").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is not within subnet", testIPPoolNamespace, testIPPoolName, "192.168.1.100"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because reserved ip %s cannot be excluded", testIPPoolNamespace, testIPPoolName, "192.168.0.10"),
 			},
 		},
 		{
-			name: "invalid emd ip which is the same as network ip",
+			name: "invalid reservation which is the same as server ip",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("", "192.168.0.0").
+					ServerIP("192.168.0.10").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "11:22:33:44:55:66", "").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is the same as network ip", testIPPoolNamespace, testIPPoolName, "192.168.0.0"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because reserved ip %s is the same as server ip", testIPPoolNamespace, testIPPoolName, "192.168.0.10"),
 			},
 		},
 		{
-			name: "invalid end ip which is the same as broadcast ip",
+			name: "invalid reservations which share the ip",
 			given: input{
 				ipPool: newTestIPPoolBuilder().
 					CIDR("192.168.0.0/24").
-					PoolRange("", "192.168.0.255").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "11:22:33:44:55:66", "").
+					Reservation("192.168.0.10", "", "default/vm-1").
 					NetworkName(testNetworkName).Build(),
 				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
 			},
 			expected: output{
-				err: fmt.Errorf("cannot create IPPool %s/%s because end ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
+				err: fmt.Errorf("cannot create IPPool %s/%s because ip %s is reserved more than once", testIPPoolNamespace, testIPPoolName, "192.168.0.10"),
+			},
+		},
+		{
+			name: "invalid reservations which share the mac address",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					PoolRange("192.168.0.10", "192.168.0.49").
+					Reservation("192.168.0.10", "11:22:33:44:55:66", "").
+					Reservation("192.168.0.11", "11:22:33:44:55:66", "").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because %s has more than one reservation", testIPPoolNamespace, testIPPoolName, "11:22:33:44:55:66"),
+			},
+		},
+		{
+			name: "invalid network which is served by another ippool",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").
+						CIDR("192.168.0.0/24").
+						NetworkName(testNetworkName).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because network %s is already served by ippool %s/%s", testIPPoolNamespace, testIPPoolName, testNetworkName, testIPPoolNamespace, "net-2"),
+			},
+		},
+		{
+			name: "invalid network which is served by another ippool with the network name in the short form",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").
+						CIDR("192.168.0.0/24").
+						NetworkName(testNADName).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because network %s is already served by ippool %s/%s", testIPPoolNamespace, testIPPoolName, testNetworkName, testIPPoolNamespace, "net-2"),
+			},
+		},
+		{
+			name: "invalid network which is served by another ippool outside the default namespace with the network name in the short form",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder("tenant", "net-2").
+						CIDR("192.168.0.0/24").
+						NetworkName(testNADName).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because network %s is already served by ippool %s/%s", testIPPoolNamespace, testIPPoolName, testNetworkName, "tenant", "net-2"),
+			},
+		},
+		{
+			name: "invalid network in the short form of an ippool outside the default namespace which is served by another ippool",
+			given: input{
+				ipPool: ippool.NewIPPoolBuilder("tenant", testIPPoolName).
+					CIDR("192.168.0.0/24").
+					ServerIP(testServerIPWithinRange).
+					NetworkName(testNADName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").
+						CIDR("192.168.0.0/24").
+						NetworkName(testNetworkName).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because network %s is already served by ippool %s/%s", "tenant", testIPPoolName, testNADName, testIPPoolNamespace, "net-2"),
+			},
+		},
+		{
+			name: "invalid cidr which overlaps another ippool on the same vlan",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().
+					Label(clusterNetworkLabelKey, testClusterNetwork).
+					Config(`{"type":"bridge","vlan":48}`).Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").
+						CIDR("192.168.0.0/16").
+						NetworkName(testIPPoolNamespace + "/net-2").Build(),
+				},
+				otherNADs: []*cniv1.NetworkAttachmentDefinition{
+					ippool.NewNetworkAttachmentDefinitionBuilder(testNADNamespace, "net-2").
+						Label(clusterNetworkLabelKey, testClusterNetwork).
+						Config(`{"type":"bridge","vlan":48}`).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create IPPool %s/%s because cidr %s overlaps cidr %s of ippool %s/%s on cluster network %s vlan %d", testIPPoolNamespace, testIPPoolName, "192.168.0.0/24", "192.168.0.0/16", testIPPoolNamespace, "net-2", testClusterNetwork, 48),
+			},
+		},
+		{
+			name: "valid cidr which overlaps another ippool on a different vlan",
+			given: input{
+				ipPool: newTestIPPoolBuilder().
+					CIDR("192.168.0.0/24").
+					NetworkName(testNetworkName).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().
+					Label(clusterNetworkLabelKey, testClusterNetwork).
+					Config(`{"type":"bridge","vlan":48}`).Build(),
+				otherIPPools: []*networkv1.IPPool{
+					ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").
+						CIDR("192.168.0.0/24").
+						NetworkName(testIPPoolNamespace + "/net-2").Build(),
+				},
+				otherNADs: []*cniv1.NetworkAttachmentDefinition{
+					ippool.NewNetworkAttachmentDefinitionBuilder(testNADNamespace, "net-2").
+						Label(clusterNetworkLabelKey, testClusterNetwork).
+						Config(`{"type":"bridge","vlan":49}`).Build(),
+				},
 			},
 		},
 		{
@@ -344,6 +974,14 @@ func TestValidator_Create(t *testing.T) {
 		clientset := fake.NewSimpleClientset()
 		err := clientset.Tracker().Create(nadGVR, tc.given.nad, tc.given.nad.Namespace)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		for _, nad := range tc.given.otherNADs {
+			err := clientset.Tracker().Create(nadGVR, nad, nad.Namespace)
+			assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		}
+		for _, ipPool := range tc.given.otherIPPools {
+			err := clientset.Tracker().Add(ipPool)
+			assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		}
 
 		k8sclientset := k8sfake.NewSimpleClientset()
 		if tc.given.node != nil {
@@ -353,7 +991,8 @@ func TestValidator_Create(t *testing.T) {
 
 		nadCache := fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions)
 		vmnetCache := fakeclient.VirtualMachineNetworkConfigCache(clientset.NetworkV1alpha1().VirtualMachineNetworkConfigs)
-		validator := NewValidator(testServiceCIDR, nadCache, vmnetCache)
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		validator := NewValidator(testServiceCIDR, nadCache, ippoolCache, vmnetCache)
 
 		err = validator.Create(&admission.Request{}, tc.given.ipPool)
 
@@ -371,6 +1010,7 @@ func TestValidator_Update(t *testing.T) {
 		newIPPool *networkv1.IPPool
 		nad       *cniv1.NetworkAttachmentDefinition
 		node      *corev1.Node
+		vmNetCfg  *networkv1.VirtualMachineNetworkConfig
 	}
 
 	type output struct {
@@ -486,6 +1126,126 @@ func TestValidator_Update(t *testing.T) {
 				err: fmt.Errorf("cannot update IPPool %s/%s because server ip %s is already occupied", testIPPoolNamespace, testIPPoolName, "192.168.0.100"),
 			},
 		},
+		{
+			name: "valid custom option which was set before being managed by the server",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					NetworkName(testNetworkName).
+					CustomOption(26, networkv1.DHCPOptionTypeUint16, "9000").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					NetworkName(testNetworkName).
+					CustomOption(26, networkv1.DHCPOptionTypeUint16, "9000").
+					CustomOption(150, networkv1.DHCPOptionTypeIP, "192.168.0.10").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid custom option which is managed by the server and changed",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					NetworkName(testNetworkName).
+					CustomOption(26, networkv1.DHCPOptionTypeUint16, "9000").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					NetworkName(testNetworkName).
+					CustomOption(26, networkv1.DHCPOptionTypeUint16, "1500").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update IPPool %s/%s because custom option %d is managed by the server", testIPPoolNamespace, testIPPoolName, 26),
+			},
+		},
+		{
+			name: "valid pool range which is widened",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.199").
+					Range("192.168.0.10", "192.168.0.19").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid pool range which orphans allocated ips",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.101", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update IPPool %s/%s because ip %s allocated to %s is not within the pool range", testIPPoolNamespace, testIPPoolName, "192.168.0.100", "11:22:33:44:55:66"),
+			},
+		},
+		{
+			name: "valid excluded ips which are changed",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Exclude("192.168.0.101").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").
+					Allocated("192.168.0.101", util.ExcludedMark).Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Exclude("192.168.0.102").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").
+					Allocated("192.168.0.101", util.ExcludedMark).Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid excluded ip which is allocated",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					ServerIP("192.168.0.2").
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Exclude("192.168.0.100").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update IPPool %s/%s because ip %s allocated to %s cannot be excluded", testIPPoolNamespace, testIPPoolName, "192.168.0.100", "11:22:33:44:55:66"),
+			},
+		},
 		{
 			name: "invalid router ip which is malformed",
 			given: input{
@@ -642,6 +1402,78 @@ func TestValidator_Update(t *testing.T) {
 				err: fmt.Errorf("cannot update IPPool %s/%s because end ip %s is the same as broadcast ip", testIPPoolNamespace, testIPPoolName, "192.168.0.255"),
 			},
 		},
+		{
+			name: "valid reservation of the ip allocated to the vm",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Reservation("192.168.0.100", "", "default/vm-1").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+				vmNetCfg: &networkv1.VirtualMachineNetworkConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "default",
+						Name:      "vm-1",
+					},
+					Spec: networkv1.VirtualMachineNetworkConfigSpec{
+						VMName: "vm-1",
+						NetworkConfigs: []networkv1.NetworkConfig{
+							{
+								MACAddress:  "11:22:33:44:55:66",
+								NetworkName: testNetworkName,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid reservation of the ip allocated to another vm",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Reservation("192.168.0.100", "", "default/vm-2").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update IPPool %s/%s because reserved ip %s is already allocated to %s", testIPPoolNamespace, testIPPoolName, "192.168.0.100", "11:22:33:44:55:66"),
+			},
+		},
+		{
+			name: "invalid reservation of the ip allocated to another mac address",
+			given: input{
+				oldIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				newIPPool: newTestIPPoolBuilder().
+					CIDR(testCIDR).
+					PoolRange("192.168.0.100", "192.168.0.149").
+					Reservation("192.168.0.100", "22:33:44:55:66:77", "").
+					NetworkName(testNetworkName).
+					Allocated("192.168.0.100", "11:22:33:44:55:66").Build(),
+				nad: newTestNetworkAttachmentDefinitionBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update IPPool %s/%s because reserved ip %s is already allocated to %s", testIPPoolNamespace, testIPPoolName, "192.168.0.100", "11:22:33:44:55:66"),
+			},
+		},
 		{
 			name: "non-existed network name",
 			given: input{
@@ -759,6 +1591,10 @@ func TestValidator_Update(t *testing.T) {
 		clientset := fake.NewSimpleClientset()
 		err := clientset.Tracker().Create(nadGVR, tc.given.nad, tc.given.nad.Namespace)
 		assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		if tc.given.vmNetCfg != nil {
+			err := clientset.Tracker().Add(tc.given.vmNetCfg)
+			assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		}
 
 		k8sclientset := k8sfake.NewSimpleClientset()
 		if tc.given.node != nil {
@@ -768,7 +1604,8 @@ func TestValidator_Update(t *testing.T) {
 
 		nadCache := fakeclient.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions)
 		vmnetCache := fakeclient.VirtualMachineNetworkConfigCache(clientset.NetworkV1alpha1().VirtualMachineNetworkConfigs)
-		validator := NewValidator(testServiceCIDR, nadCache, vmnetCache)
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		validator := NewValidator(testServiceCIDR, nadCache, ippoolCache, vmnetCache)
 
 		err = validator.Update(&admission.Request{}, tc.given.oldIPPool, tc.given.newIPPool)
 
@@ -0,0 +1,234 @@
+package vm
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/harvester/webhook/pkg/server/admission"
+	"github.com/rancher/wrangler/pkg/kv"
+	"github.com/sirupsen/logrus"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+	"github.com/harvester/vm-dhcp-controller/pkg/webhook"
+)
+
+type Validator struct {
+	admission.DefaultValidator
+
+	ippoolCache ctlnetworkv1.IPPoolCache
+}
+
+func NewValidator(ippoolCache ctlnetworkv1.IPPoolCache) *Validator {
+	return &Validator{
+		ippoolCache: ippoolCache,
+	}
+}
+
+func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
+	vm := newObj.(*kubevirtv1.VirtualMachine)
+	if _, ok := vm.Annotations[util.IPAddressesAnnotationKey]; !ok {
+		return nil
+	}
+
+	logrus.Debugf("create vm %s/%s", vm.Namespace, vm.Name)
+
+	if err := v.checkIPAddresses(nil, vm); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "VirtualMachine", vm.Namespace, vm.Name, err)
+	}
+
+	return nil
+}
+
+func (v *Validator) Update(_ *admission.Request, oldObj, newObj runtime.Object) error {
+	oldVM := oldObj.(*kubevirtv1.VirtualMachine)
+	vm := newObj.(*kubevirtv1.VirtualMachine)
+
+	if vm.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if _, ok := vm.Annotations[util.IPAddressesAnnotationKey]; !ok {
+		return nil
+	}
+
+	logrus.Debugf("update vm %s/%s", vm.Namespace, vm.Name)
+
+	if err := v.checkIPAddresses(oldVM, vm); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "VirtualMachine", vm.Namespace, vm.Name, err)
+	}
+
+	return nil
+}
+
+func (v *Validator) Resource() admission.Resource {
+	return admission.Resource{
+		Names:      []string{"virtualmachines"},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   kubevirtv1.SchemeGroupVersion.Group,
+		APIVersion: kubevirtv1.SchemeGroupVersion.Version,
+		ObjectType: &kubevirtv1.VirtualMachine{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+// checkIPAddresses checks whether each of the IP addresses asked for by the
+// annotation of the VM:
+//   - is for a network interface attached to a Multus network
+//   - is a valid IPv4 address
+//   - is WITHIN the pool range of the IPPool of the network
+//   - is NOT excluded
+//   - is NOT the server or router IP address
+//   - is NOT asked for by another network interface of the VM
+//   - is NOT allocated to, quarantined for, or reserved for someone else
+//
+// IP addresses already asked for by the same network interface of oldVM are
+// left alone, so that updating the VM for an unrelated reason does not fail
+// once such an IP address has been allocated.
+func (v *Validator) checkIPAddresses(oldVM, vm *kubevirtv1.VirtualMachine) error {
+	ipAddresses, err := util.ParseIPAddressesAnnotation(vm.Annotations)
+	if err != nil {
+		return err
+	}
+
+	macAddresses, networkNames := nicsOf(vm)
+
+	nicNames := make([]string, 0, len(ipAddresses))
+	for name := range ipAddresses {
+		nicNames = append(nicNames, name)
+	}
+	sort.Strings(nicNames)
+
+	designated := make(map[string]string)
+	for _, name := range nicNames {
+		ipAddress := ipAddresses[name]
+
+		macAddress, ok := macAddresses[name]
+		if !ok {
+			return fmt.Errorf("network interface %s does not exist", name)
+		}
+		networkName, ok := networkNames[name]
+		if !ok {
+			return fmt.Errorf("network interface %s is not attached to a multus network", name)
+		}
+
+		ipAddr, err := netip.ParseAddr(ipAddress)
+		if err != nil || !ipAddr.Is4() {
+			return fmt.Errorf("ip %s of network interface %s is not a valid ipv4 address", ipAddress, name)
+		}
+
+		ipPoolNamespace, ipPoolName := kv.RSplit(networkName, "/")
+		if ipPoolNamespace == "" {
+			ipPoolNamespace = "default"
+		}
+
+		key := ipPoolNamespace + "/" + ipPoolName + "/" + ipAddress
+		if other, ok := designated[key]; ok {
+			return fmt.Errorf("ip %s is asked for by both network interfaces %s and %s", ipAddress, other, name)
+		}
+		designated[key] = name
+
+		if isDesignatedBefore(oldVM, name, ipAddress, macAddress, networkName) {
+			continue
+		}
+
+		ipPool, err := v.ippoolCache.Get(ipPoolNamespace, ipPoolName)
+		if err != nil {
+			return err
+		}
+
+		if err := checkIPAddress(ipPool, ipAddress, macAddress, vm.Namespace+"/"+vm.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nicsOf returns the MAC addresses and the Multus network names of the network
+// interfaces of the VM, keyed by the name of the network interface.
+func nicsOf(vm *kubevirtv1.VirtualMachine) (map[string]string, map[string]string) {
+	macAddresses := make(map[string]string)
+	networkNames := make(map[string]string)
+	if vm.Spec.Template == nil {
+		return macAddresses, networkNames
+	}
+	for _, nic := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+		macAddresses[nic.Name] = nic.MacAddress
+	}
+	for _, network := range vm.Spec.Template.Spec.Networks {
+		if network.NetworkSource.Multus == nil {
+			continue
+		}
+		networkNames[network.Name] = network.Multus.NetworkName
+	}
+	return macAddresses, networkNames
+}
+
+// isDesignatedBefore returns true if the IP address was already asked for by
+// the network interface of oldVM with the same MAC address on the same
+// network.
+func isDesignatedBefore(oldVM *kubevirtv1.VirtualMachine, nicName, ipAddress, macAddress, networkName string) bool {
+	if oldVM == nil {
+		return false
+	}
+	oldIPAddresses, err := util.ParseIPAddressesAnnotation(oldVM.Annotations)
+	if err != nil || oldIPAddresses[nicName] != ipAddress {
+		return false
+	}
+	oldMACAddresses, oldNetworkNames := nicsOf(oldVM)
+	oldMACAddress, ok := oldMACAddresses[nicName]
+	if !ok || !strings.EqualFold(oldMACAddress, macAddress) {
+		return false
+	}
+	oldNetworkName, ok := oldNetworkNames[nicName]
+	return ok && oldNetworkName == networkName
+}
+
+// checkIPAddress checks whether the IP address can be allocated from ipPool to
+// the network interface with the MAC address, which may still be empty, of
+// the VM.
+func checkIPAddress(ipPool *networkv1.IPPool, ipAddress, macAddress, vm string) error {
+	ipv4Config := ipPool.Spec.IPv4Config
+
+	if !util.IsIPInPool(ipAddress, ipv4Config.Pool) {
+		return fmt.Errorf("ip %s is not within the pool range of ippool %s/%s", ipAddress, ipPool.Namespace, ipPool.Name)
+	}
+	if util.IsIPExcluded(ipAddress, ipv4Config.Pool) {
+		return fmt.Errorf("ip %s is excluded from ippool %s/%s", ipAddress, ipPool.Namespace, ipPool.Name)
+	}
+	if ipAddress == ipv4Config.ServerIP || ipAddress == ipv4Config.Router {
+		return fmt.Errorf("ip %s is the server or router ip of ippool %s/%s", ipAddress, ipPool.Namespace, ipPool.Name)
+	}
+
+	if ipPool.Status.IPv4 != nil {
+		if allocatedTo, ok := ipPool.Status.IPv4.Allocated[ipAddress]; ok {
+			if util.IsMark(allocatedTo) {
+				return fmt.Errorf("ip %s is not allocatable in ippool %s/%s", ipAddress, ipPool.Namespace, ipPool.Name)
+			}
+			if macAddress == "" || !strings.EqualFold(allocatedTo, macAddress) {
+				return fmt.Errorf("ip %s is already allocated to %s", ipAddress, allocatedTo)
+			}
+		}
+	}
+
+	for _, r := range ipv4Config.Reservations {
+		if r.IPAddress != ipAddress {
+			continue
+		}
+		if owner := util.ReservationOwner(r); owner != vm && owner != strings.ToLower(macAddress) {
+			return fmt.Errorf("ip %s is reserved for %s", ipAddress, owner)
+		}
+	}
+
+	return nil
+}
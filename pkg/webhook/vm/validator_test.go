@@ -0,0 +1,304 @@
+package vm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/harvester/webhook/pkg/server/admission"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/controller/ippool"
+	"github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+	"github.com/harvester/vm-dhcp-controller/pkg/util/fakeclient"
+)
+
+const (
+	testVMNamespace     = "default"
+	testVMName          = "vm-1"
+	testIPPoolNamespace = "default"
+	testIPPoolName      = "net-1"
+	testNetworkName     = testIPPoolNamespace + "/" + testIPPoolName
+	testCIDR            = "192.168.0.0/24"
+	testServerIP        = "192.168.0.2"
+	testStartIP         = "192.168.0.101"
+	testEndIP           = "192.168.0.200"
+	testMAC1            = "11:22:33:44:55:66"
+	testMAC2            = "22:33:44:55:66:77"
+)
+
+func newTestIPPoolBuilder() *ippool.IPPoolBuilder {
+	return ippool.NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+		CIDR(testCIDR).
+		ServerIP(testServerIP).
+		PoolRange(testStartIP, testEndIP).
+		NetworkName(testNetworkName)
+}
+
+// newTestVM returns a VM with a network interface named nic-i on the test
+// network for each of the MAC addresses, annotated with ipAddresses if given.
+func newTestVM(ipAddresses string, macAddresses ...string) *kubevirtv1.VirtualMachine {
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testVMNamespace,
+			Name:      testVMName,
+		},
+		Spec: kubevirtv1.VirtualMachineSpec{
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+		},
+	}
+	if ipAddresses != "" {
+		vm.Annotations = map[string]string{
+			util.IPAddressesAnnotationKey: ipAddresses,
+		}
+	}
+	for i, macAddress := range macAddresses {
+		name := fmt.Sprintf("nic-%d", i+1)
+		vm.Spec.Template.Spec.Domain.Devices.Interfaces = append(vm.Spec.Template.Spec.Domain.Devices.Interfaces, kubevirtv1.Interface{
+			Name:       name,
+			MacAddress: macAddress,
+		})
+		vm.Spec.Template.Spec.Networks = append(vm.Spec.Template.Spec.Networks, kubevirtv1.Network{
+			Name: name,
+			NetworkSource: kubevirtv1.NetworkSource{
+				Multus: &kubevirtv1.MultusNetwork{
+					NetworkName: testNetworkName,
+				},
+			},
+		})
+	}
+	return vm
+}
+
+func TestValidator_Create(t *testing.T) {
+	type input struct {
+		vm     *kubevirtv1.VirtualMachine
+		ipPool *networkv1.IPPool
+	}
+
+	type output struct {
+		err error
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "vm without annotation",
+			given: input{
+				vm:     newTestVM("", testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+		},
+		{
+			name: "valid ip",
+			given: input{
+				vm:     newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+		},
+		{
+			name: "valid ip which is allocated to the network interface already",
+			given: input{
+				vm: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Allocated("192.168.0.111", testMAC1).Build(),
+			},
+		},
+		{
+			name: "valid ip which is reserved for the vm",
+			given: input{
+				vm: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Reservation("192.168.0.111", "", testVMNamespace+"/"+testVMName).Build(),
+			},
+		},
+		{
+			name: "invalid annotation which is malformed",
+			given: input{
+				vm:     newTestVM(`nic-1=192.168.0.111`, testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because annotation %s is malformed: invalid character 'i' in literal null (expecting 'u')", testVMNamespace, testVMName, util.IPAddressesAnnotationKey),
+			},
+		},
+		{
+			name: "invalid network interface which does not exist",
+			given: input{
+				vm:     newTestVM(`{"nic-2":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because network interface %s does not exist", testVMNamespace, testVMName, "nic-2"),
+			},
+		},
+		{
+			name: "invalid ip which is ipv6",
+			given: input{
+				vm:     newTestVM(`{"nic-1":"fd00::1"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s of network interface %s is not a valid ipv4 address", testVMNamespace, testVMName, "fd00::1", "nic-1"),
+			},
+		},
+		{
+			name: "invalid ip which is out of the pool range",
+			given: input{
+				vm:     newTestVM(`{"nic-1":"192.168.0.201"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s is not within the pool range of ippool %s", testVMNamespace, testVMName, "192.168.0.201", testNetworkName),
+			},
+		},
+		{
+			name: "invalid ip which is excluded",
+			given: input{
+				vm: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Exclude("192.168.0.111").Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s is excluded from ippool %s", testVMNamespace, testVMName, "192.168.0.111", testNetworkName),
+			},
+		},
+		{
+			name: "invalid ip which is asked for by two network interfaces",
+			given: input{
+				vm:     newTestVM(`{"nic-1":"192.168.0.111","nic-2":"192.168.0.111"}`, testMAC1, testMAC2),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s is asked for by both network interfaces %s and %s", testVMNamespace, testVMName, "192.168.0.111", "nic-1", "nic-2"),
+			},
+		},
+		{
+			name: "invalid ip which is allocated to another network interface",
+			given: input{
+				vm: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Allocated("192.168.0.111", testMAC2).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s is already allocated to %s", testVMNamespace, testVMName, "192.168.0.111", testMAC2),
+			},
+		},
+		{
+			name: "invalid ip which is reserved for another network interface",
+			given: input{
+				vm: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Reservation("192.168.0.111", testMAC2, "").Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachine %s/%s because ip %s is reserved for %s", testVMNamespace, testVMName, "192.168.0.111", testMAC2),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clientset := fake.NewSimpleClientset(tc.given.ipPool)
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		validator := NewValidator(ippoolCache)
+
+		err := validator.Create(&admission.Request{}, tc.given.vm)
+
+		if tc.expected.err != nil {
+			assert.Equal(t, tc.expected.err.Error(), err.Error(), tc.name)
+		} else {
+			assert.Nil(t, err, tc.name)
+		}
+	}
+}
+
+func TestValidator_Update(t *testing.T) {
+	type input struct {
+		oldVM  *kubevirtv1.VirtualMachine
+		newVM  *kubevirtv1.VirtualMachine
+		ipPool *networkv1.IPPool
+	}
+
+	type output struct {
+		err error
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "vm without annotation on a network whose ippool does not exist",
+			given: input{
+				oldVM:  newTestVM("", testMAC1),
+				newVM:  newTestVM("", testMAC1),
+				ipPool: ippool.NewIPPoolBuilder(testIPPoolNamespace, "net-2").Build(),
+			},
+		},
+		{
+			name: "unchanged ip which is allocated to another network interface",
+			given: input{
+				oldVM: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				newVM: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Allocated("192.168.0.111", testMAC2).Build(),
+			},
+		},
+		{
+			name: "changed ip which is allocated to another network interface",
+			given: input{
+				oldVM: newTestVM(`{"nic-1":"192.168.0.110"}`, testMAC1),
+				newVM: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				ipPool: newTestIPPoolBuilder().
+					Allocated("192.168.0.111", testMAC2).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update VirtualMachine %s/%s because ip %s is already allocated to %s", testVMNamespace, testVMName, "192.168.0.111", testMAC2),
+			},
+		},
+		{
+			name: "unchanged ip of a network interface whose mac address is changed",
+			given: input{
+				oldVM: newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1),
+				newVM: newTestVM(`{"nic-1":"192.168.0.111"}`, "33:44:55:66:77:88"),
+				ipPool: newTestIPPoolBuilder().
+					Allocated("192.168.0.111", testMAC2).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update VirtualMachine %s/%s because ip %s is already allocated to %s", testVMNamespace, testVMName, "192.168.0.111", testMAC2),
+			},
+		},
+		{
+			name: "unchanged ip which is asked for by another network interface",
+			given: input{
+				oldVM:  newTestVM(`{"nic-1":"192.168.0.111"}`, testMAC1, testMAC2),
+				newVM:  newTestVM(`{"nic-1":"192.168.0.111","nic-2":"192.168.0.111"}`, testMAC1, testMAC2),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update VirtualMachine %s/%s because ip %s is asked for by both network interfaces %s and %s", testVMNamespace, testVMName, "192.168.0.111", "nic-1", "nic-2"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clientset := fake.NewSimpleClientset(tc.given.ipPool)
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		validator := NewValidator(ippoolCache)
+
+		err := validator.Update(&admission.Request{}, tc.given.oldVM, tc.given.newVM)
+
+		if tc.expected.err != nil {
+			assert.Equal(t, tc.expected.err.Error(), err.Error(), tc.name)
+		} else {
+			assert.Nil(t, err, tc.name)
+		}
+	}
+}
@@ -2,42 +2,63 @@ package vmnetcfg
 
 import (
 	"fmt"
+	"net/netip"
+	"strings"
 
+	"github.com/harvester/webhook/pkg/server/admission"
+	"github.com/rancher/wrangler/pkg/kv"
+	"github.com/sirupsen/logrus"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/dhcp"
 	ctlnetworkv1 "github.com/harvester/vm-dhcp-controller/pkg/generated/controllers/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/indexer"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
 	"github.com/harvester/vm-dhcp-controller/pkg/webhook"
-	"github.com/harvester/webhook/pkg/server/admission"
-	"github.com/rancher/wrangler/pkg/kv"
-	"github.com/sirupsen/logrus"
 )
 
 type Validator struct {
 	admission.DefaultValidator
 
-	ippoolCache ctlnetworkv1.IPPoolCache
+	ippoolCache   ctlnetworkv1.IPPoolCache
+	vmnetcfgCache ctlnetworkv1.VirtualMachineNetworkConfigCache
 }
 
-func NewValidator(ippoolCache ctlnetworkv1.IPPoolCache) *Validator {
+func NewValidator(
+	ippoolCache ctlnetworkv1.IPPoolCache,
+	vmnetcfgCache ctlnetworkv1.VirtualMachineNetworkConfigCache,
+) *Validator {
 	return &Validator{
-		ippoolCache: ippoolCache,
+		ippoolCache:   ippoolCache,
+		vmnetcfgCache: vmnetcfgCache,
 	}
 }
 
-func (v *Validator) Create(request *admission.Request, newObj runtime.Object) error {
+func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
 	vmNetCfg := newObj.(*networkv1.VirtualMachineNetworkConfig)
 	logrus.Infof("create vmnetcfg %s/%s", vmNetCfg.Namespace, vmNetCfg.Name)
 
-	for _, nc := range vmNetCfg.Spec.NetworkConfigs {
-		ipPoolNamespace, ipPoolName := kv.RSplit(nc.NetworkName, "/")
-		if ipPoolNamespace == "" {
-			ipPoolNamespace = "default"
-		}
-		if _, err := v.ippoolCache.Get(ipPoolNamespace, ipPoolName); err != nil {
-			return fmt.Errorf(webhook.CreateErr, vmNetCfg.Kind, vmNetCfg.Namespace, vmNetCfg.Name, err)
-		}
+	if err := v.checkNetworkConfigs(vmNetCfg, nil); err != nil {
+		return fmt.Errorf(webhook.CreateErr, "VirtualMachineNetworkConfig", vmNetCfg.Namespace, vmNetCfg.Name, err)
+	}
+
+	return nil
+}
+
+func (v *Validator) Update(_ *admission.Request, oldObj, newObj runtime.Object) error {
+	oldVmNetCfg := oldObj.(*networkv1.VirtualMachineNetworkConfig)
+	vmNetCfg := newObj.(*networkv1.VirtualMachineNetworkConfig)
+
+	if vmNetCfg.DeletionTimestamp != nil {
+		return nil
+	}
+
+	logrus.Infof("update vmnetcfg %s/%s", vmNetCfg.Namespace, vmNetCfg.Name)
+
+	if err := v.checkNetworkConfigs(vmNetCfg, oldVmNetCfg); err != nil {
+		return fmt.Errorf(webhook.UpdateErr, "VirtualMachineNetworkConfig", vmNetCfg.Namespace, vmNetCfg.Name, err)
 	}
 
 	return nil
@@ -52,6 +73,172 @@ func (v *Validator) Resource() admission.Resource {
 		ObjectType: &networkv1.VirtualMachineNetworkConfig{},
 		OperationTypes: []admissionregv1.OperationType{
 			admissionregv1.Create,
+			admissionregv1.Update,
 		},
 	}
 }
+
+// checkNetworkConfigs checks whether each of the network configs:
+//   - has an existing IPPool for its network
+//   - has a MAC address which is NOT used by another network config in the
+//     network
+//   - has valid custom options
+//   - has a designated IP address, if any, which can be allocated to it
+//
+// The designated IP addresses left as they were in oldVmNetCfg, if given, are
+// not checked again, as they might have been allocated or quarantined since.
+func (v *Validator) checkNetworkConfigs(vmNetCfg, oldVmNetCfg *networkv1.VirtualMachineNetworkConfig) error {
+	macAddresses := make(map[string]bool)
+	ipAddresses := make(map[string]bool)
+
+	for _, nc := range vmNetCfg.Spec.NetworkConfigs {
+		ipPoolNamespace, ipPoolName := kv.RSplit(nc.NetworkName, "/")
+		if ipPoolNamespace == "" {
+			ipPoolNamespace = "default"
+		}
+		ipPool, err := v.ippoolCache.Get(ipPoolNamespace, ipPoolName)
+		if err != nil {
+			return err
+		}
+
+		macKey := indexer.MACAddressKey(nc.NetworkName, nc.MACAddress)
+		if macAddresses[macKey] {
+			return fmt.Errorf("mac address %s is used more than once in network %s", nc.MACAddress, nc.NetworkName)
+		}
+		macAddresses[macKey] = true
+
+		if err := v.checkMACAddress(vmNetCfg, nc); err != nil {
+			return err
+		}
+
+		var oldCustomOptions []networkv1.DHCPOption
+		if oldNc := findNetworkConfig(oldVmNetCfg, nc); oldNc != nil {
+			oldCustomOptions = oldNc.CustomOptions
+		}
+		if err := dhcp.CheckCustomOptions(nc.CustomOptions, oldCustomOptions); err != nil {
+			return err
+		}
+
+		if nc.IPAddress == nil {
+			continue
+		}
+
+		ipKey := nc.NetworkName + "/" + *nc.IPAddress
+		if ipAddresses[ipKey] {
+			return fmt.Errorf("ip %s is designated more than once in network %s", *nc.IPAddress, nc.NetworkName)
+		}
+		ipAddresses[ipKey] = true
+
+		if isDesignatedBefore(oldVmNetCfg, nc) {
+			continue
+		}
+
+		if err := checkDesignatedIP(vmNetCfg, nc, ipPool); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findNetworkConfig returns the network config in oldVmNetCfg, if any, of
+// the same network and MAC address as nc.
+func findNetworkConfig(oldVmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig) *networkv1.NetworkConfig {
+	if oldVmNetCfg == nil {
+		return nil
+	}
+	for i, oldNc := range oldVmNetCfg.Spec.NetworkConfigs {
+		if oldNc.NetworkName == nc.NetworkName && strings.EqualFold(oldNc.MACAddress, nc.MACAddress) {
+			return &oldVmNetCfg.Spec.NetworkConfigs[i]
+		}
+	}
+	return nil
+}
+
+// isDesignatedBefore tells if the network config designated the same IP address
+// in oldVmNetCfg.
+func isDesignatedBefore(oldVmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig) bool {
+	oldNc := findNetworkConfig(oldVmNetCfg, nc)
+	return oldNc != nil && oldNc.IPAddress != nil && *oldNc.IPAddress == *nc.IPAddress
+}
+
+// checkMACAddress checks whether the MAC address of the network config is
+// free of the other vmnetcfgs in the network. It requires adding the MAC
+// address indexer to the vmnetcfg cache.
+func (v *Validator) checkMACAddress(vmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig) error {
+	others, err := v.vmnetcfgCache.GetByIndex(indexer.VmNetCfgByMACAddressIndex, indexer.MACAddressKey(nc.NetworkName, nc.MACAddress))
+	if err != nil {
+		return err
+	}
+
+	for _, other := range others {
+		if other.Namespace == vmNetCfg.Namespace && other.Name == vmNetCfg.Name {
+			continue
+		}
+		return fmt.Errorf("mac address %s is already used by vmnetcfg %s/%s in network %s", nc.MACAddress, other.Namespace, other.Name, nc.NetworkName)
+	}
+
+	return nil
+}
+
+// checkDesignatedIP checks whether the designated IP address of the network
+// config:
+//   - is WITHIN the pool range
+//   - is NOT excluded
+//   - is NOT the server or router IP address
+//   - is NOT allocated to another MAC address or quarantined
+//   - is NOT reserved for another network interface or VM
+func checkDesignatedIP(vmNetCfg *networkv1.VirtualMachineNetworkConfig, nc networkv1.NetworkConfig, ipPool *networkv1.IPPool) error {
+	ipAddr, err := netip.ParseAddr(*nc.IPAddress)
+	if err != nil || !ipAddr.Is4() {
+		return fmt.Errorf("designated ip %s is not a valid ipv4 address", *nc.IPAddress)
+	}
+
+	poolInfo, err := util.LoadPool(ipPool)
+	if err != nil {
+		return err
+	}
+
+	if !util.IsIPInPool(ipAddr.String(), ipPool.Spec.IPv4Config.Pool) {
+		return fmt.Errorf("designated ip %s is not within the pool range of ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+	}
+
+	if ipAddr == poolInfo.ServerIPAddr || ipAddr == poolInfo.RouterIPAddr {
+		return fmt.Errorf("designated ip %s is the server or router ip of ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+	}
+
+	if util.IsIPExcluded(ipAddr.String(), ipPool.Spec.IPv4Config.Pool) {
+		return fmt.Errorf("designated ip %s is excluded from ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+	}
+
+	if ipPool.Status.IPv4 != nil {
+		allocatedList, excludedList, reservedList := util.LoadAllocated(ipPool.Status.IPv4.Allocated)
+		if util.IsIPAddrInList(ipAddr, excludedList) {
+			return fmt.Errorf("designated ip %s is excluded from ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+		}
+		if util.IsIPAddrInList(ipAddr, reservedList) {
+			return fmt.Errorf("designated ip %s is the server or router ip of ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+		}
+		if util.IsIPAddrInList(ipAddr, allocatedList) {
+			allocatedTo := ipPool.Status.IPv4.Allocated[ipAddr.String()]
+			if util.IsMark(allocatedTo) {
+				return fmt.Errorf("designated ip %s is quarantined in ippool %s/%s", ipAddr, ipPool.Namespace, ipPool.Name)
+			}
+			if !strings.EqualFold(allocatedTo, nc.MACAddress) {
+				return fmt.Errorf("designated ip %s is already allocated to %s", ipAddr, allocatedTo)
+			}
+		}
+	}
+
+	vm := vmNetCfg.Namespace + "/" + vmNetCfg.Spec.VMName
+	for _, r := range ipPool.Spec.IPv4Config.Reservations {
+		if r.IPAddress != ipAddr.String() {
+			continue
+		}
+		if owner := util.ReservationOwner(r); owner != vm && owner != strings.ToLower(nc.MACAddress) {
+			return fmt.Errorf("designated ip %s is reserved for %s", ipAddr, owner)
+		}
+	}
+
+	return nil
+}
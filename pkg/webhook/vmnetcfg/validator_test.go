@@ -0,0 +1,335 @@
+package vmnetcfg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/harvester/webhook/pkg/server/admission"
+	"github.com/stretchr/testify/assert"
+
+	networkv1 "github.com/harvester/vm-dhcp-controller/pkg/apis/network.harvesterhci.io/v1alpha1"
+	"github.com/harvester/vm-dhcp-controller/pkg/controller/ippool"
+	"github.com/harvester/vm-dhcp-controller/pkg/controller/vmnetcfg"
+	"github.com/harvester/vm-dhcp-controller/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/vm-dhcp-controller/pkg/util"
+	"github.com/harvester/vm-dhcp-controller/pkg/util/fakeclient"
+)
+
+const (
+	testNamespace       = "default"
+	testVmNetCfgName    = "vm-1"
+	testIPPoolNamespace = "default"
+	testIPPoolName      = "net-1"
+	testNetworkName     = testIPPoolNamespace + "/" + testIPPoolName
+	testCIDR            = "192.168.0.0/24"
+	testServerIP        = "192.168.0.2"
+	testRouter          = "192.168.0.1"
+	testStartIP         = "192.168.0.101"
+	testEndIP           = "192.168.0.200"
+	testIPAddress1      = "192.168.0.111"
+	testIPAddress2      = "192.168.0.177"
+	testMAC1            = "11:22:33:44:55:66"
+	testMAC2            = "22:33:44:55:66:77"
+)
+
+func newTestIPPoolBuilder() *ippool.IPPoolBuilder {
+	return ippool.NewIPPoolBuilder(testIPPoolNamespace, testIPPoolName).
+		CIDR(testCIDR).
+		ServerIP(testServerIP).
+		Router(testRouter).
+		PoolRange(testStartIP, testEndIP).
+		NetworkName(testNetworkName)
+}
+
+func newTestVmNetCfgBuilder() *vmnetcfg.VmNetCfgBuilder {
+	return vmnetcfg.NewVmNetCfgBuilder(testNamespace, testVmNetCfgName).
+		VMName(testVmNetCfgName)
+}
+
+func TestValidator_Create(t *testing.T) {
+	type input struct {
+		vmNetCfg       *networkv1.VirtualMachineNetworkConfig
+		ipPool         *networkv1.IPPool
+		otherVmNetCfgs []*networkv1.VirtualMachineNetworkConfig
+	}
+
+	type output struct {
+		err error
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "valid network configs",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).
+					WithNetworkConfig("", testMAC2, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+		},
+		{
+			name: "non-existed network name",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, "default/net-2").Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because ippools.network.harvesterhci.io \"%s\" not found", testNamespace, testVmNetCfgName, "net-2"),
+			},
+		},
+		{
+			name: "invalid mac address which is used twice",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).
+					WithNetworkConfig("", "11:22:33:44:55:66", testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because mac address %s is used more than once in network %s", testNamespace, testVmNetCfgName, testMAC1, testNetworkName),
+			},
+		},
+		{
+			name: "invalid mac address which is used by another vmnetcfg",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+				otherVmNetCfgs: []*networkv1.VirtualMachineNetworkConfig{
+					vmnetcfg.NewVmNetCfgBuilder(testNamespace, "vm-2").
+						WithNetworkConfig("", "11:22:33:44:55:66", testNetworkName).Build(),
+				},
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because mac address %s is already used by vmnetcfg %s/%s in network %s", testNamespace, testVmNetCfgName, testMAC1, testNamespace, "vm-2", testNetworkName),
+			},
+		},
+		{
+			name: "valid custom options",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).
+					CustomOption(150, networkv1.DHCPOptionTypeIP, "192.168.0.10").Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+		},
+		{
+			name: "invalid custom option with malformed value",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).
+					CustomOption(150, networkv1.DHCPOptionTypeUint8, "256").Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because custom option %d value %s is not a valid uint8", testNamespace, testVmNetCfgName, 150, "256"),
+			},
+		},
+		{
+			name: "invalid custom option which is managed by the server",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).
+					CustomOption(3, networkv1.DHCPOptionTypeIP, "192.168.0.254").Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because custom option %d is managed by the server", testNamespace, testVmNetCfgName, 3),
+			},
+		},
+		{
+			name: "invalid designated ip which is designated twice",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).
+					WithNetworkConfig(testIPAddress1, testMAC2, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because ip %s is designated more than once in network %s", testNamespace, testVmNetCfgName, testIPAddress1, testNetworkName),
+			},
+		},
+		{
+			name: "invalid designated ip which is malformed",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("192.168.0.1111", testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is not a valid ipv4 address", testNamespace, testVmNetCfgName, "192.168.0.1111"),
+			},
+		},
+		{
+			name: "invalid designated ip which is out of the pool range",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("192.168.0.201", testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is not within the pool range of ippool %s", testNamespace, testVmNetCfgName, "192.168.0.201", testNetworkName),
+			},
+		},
+		{
+			name: "invalid designated ip which is excluded",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Exclude(testIPAddress1).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is excluded from ippool %s", testNamespace, testVmNetCfgName, testIPAddress1, testNetworkName),
+			},
+		},
+		{
+			name: "invalid designated ip which is allocated to another mac address",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Allocated(testIPAddress1, testMAC2).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is already allocated to %s", testNamespace, testVmNetCfgName, testIPAddress1, testMAC2),
+			},
+		},
+		{
+			name: "invalid designated ip which is quarantined",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Allocated(testIPAddress1, util.QuarantinedMark).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is quarantined in ippool %s", testNamespace, testVmNetCfgName, testIPAddress1, testNetworkName),
+			},
+		},
+		{
+			name: "invalid designated ip which is reserved for another mac address",
+			given: input{
+				vmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Reservation(testIPAddress1, testMAC2, "").Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot create VirtualMachineNetworkConfig %s/%s because designated ip %s is reserved for %s", testNamespace, testVmNetCfgName, testIPAddress1, testMAC2),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clientset := fake.NewSimpleClientset(tc.given.ipPool)
+		for _, vmNetCfg := range tc.given.otherVmNetCfgs {
+			err := clientset.Tracker().Add(vmNetCfg)
+			assert.Nil(t, err, "mock resource should add into fake controller tracker")
+		}
+
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		vmnetcfgCache := fakeclient.VirtualMachineNetworkConfigCache(clientset.NetworkV1alpha1().VirtualMachineNetworkConfigs)
+		validator := NewValidator(ippoolCache, vmnetcfgCache)
+
+		err := validator.Create(&admission.Request{}, tc.given.vmNetCfg)
+
+		if tc.expected.err != nil {
+			assert.Equal(t, tc.expected.err.Error(), err.Error(), tc.name)
+		} else {
+			assert.Nil(t, err, tc.name)
+		}
+	}
+}
+
+func TestValidator_Update(t *testing.T) {
+	type input struct {
+		oldVmNetCfg *networkv1.VirtualMachineNetworkConfig
+		newVmNetCfg *networkv1.VirtualMachineNetworkConfig
+		ipPool      *networkv1.IPPool
+	}
+
+	type output struct {
+		err error
+	}
+
+	testCases := []struct {
+		name     string
+		given    input
+		expected output
+	}{
+		{
+			name: "valid designated ip which is allocated to the network interface",
+			given: input{
+				oldVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig("", testMAC1, testNetworkName).Build(),
+				newVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Allocated(testIPAddress1, testMAC1).Build(),
+			},
+		},
+		{
+			name: "valid designated ip which is quarantined but left unchanged",
+			given: input{
+				oldVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				newVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).
+					WithNetworkConfig("", testMAC2, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Allocated(testIPAddress1, util.QuarantinedMark).Build(),
+			},
+		},
+		{
+			name: "invalid designated ip which is changed to the server ip",
+			given: input{
+				oldVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				newVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testServerIP, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					PoolRange("192.168.0.2", testEndIP).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update VirtualMachineNetworkConfig %s/%s because designated ip %s is the server or router ip of ippool %s", testNamespace, testVmNetCfgName, testServerIP, testNetworkName),
+			},
+		},
+		{
+			name: "invalid designated ip which is changed to an allocated one",
+			given: input{
+				oldVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress1, testMAC1, testNetworkName).Build(),
+				newVmNetCfg: newTestVmNetCfgBuilder().
+					WithNetworkConfig(testIPAddress2, testMAC1, testNetworkName).Build(),
+				ipPool: newTestIPPoolBuilder().
+					Allocated(testIPAddress1, testMAC1).
+					Allocated(testIPAddress2, testMAC2).Build(),
+			},
+			expected: output{
+				err: fmt.Errorf("cannot update VirtualMachineNetworkConfig %s/%s because designated ip %s is already allocated to %s", testNamespace, testVmNetCfgName, testIPAddress2, testMAC2),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clientset := fake.NewSimpleClientset(tc.given.ipPool, tc.given.oldVmNetCfg)
+
+		ippoolCache := fakeclient.IPPoolCache(clientset.NetworkV1alpha1().IPPools)
+		vmnetcfgCache := fakeclient.VirtualMachineNetworkConfigCache(clientset.NetworkV1alpha1().VirtualMachineNetworkConfigs)
+		validator := NewValidator(ippoolCache, vmnetcfgCache)
+
+		err := validator.Update(&admission.Request{}, tc.given.oldVmNetCfg, tc.given.newVmNetCfg)
+
+		if tc.expected.err != nil {
+			assert.Equal(t, tc.expected.err.Error(), err.Error(), tc.name)
+		} else {
+			assert.Nil(t, err, tc.name)
+		}
+	}
+}